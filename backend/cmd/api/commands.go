@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/backup"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/notify"
+	"github.com/Felipalds/gemini-stocks/internal/openapi"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// runCommand runs a CLI subcommand (e.g. `api backup -o file.zip`).
+// It returns false when args do not name a subcommand, so the server should start.
+func runCommand(args []string, db *gorm.DB, sugar *zap.SugaredLogger) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	var err error
+	switch args[0] {
+	case "serve":
+		return false
+	case "backup":
+		err = backupCommand(args[1:], db)
+	case "restore":
+		err = restoreCommand(args[1:], db, sugar)
+	case "migrate":
+		err = migrateCommand(args[1:], db, sugar)
+	case "openapi":
+		err = openapiCommand(args[1:], db, sugar)
+	case "create-admin":
+		err = createAdminCommand(args[1:], db, sugar)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\nusage: api [serve | backup | restore | migrate | openapi | create-admin] [flags]\n", args[0])
+		os.Exit(2)
+	}
+
+	if err != nil {
+		sugar.Fatalf("%s failed: %v", args[0], err)
+	}
+	return true
+}
+
+// backupCommand writes a backup archive to a file.
+// Stdout is not an option because the GORM logger writes there.
+func backupCommand(args []string, db *gorm.DB) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("o", "", "output file (required)")
+	format := fs.String("format", backup.FormatZIP, "archive format: zip or json")
+	fs.Parse(args)
+
+	if *output == "" {
+		return fmt.Errorf("-o is required")
+	}
+
+	archive, err := backup.Dump(db)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := archive.Write(f, *format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// restoreCommand loads a backup archive from a file
+func restoreCommand(args []string, db *gorm.DB, sugar *zap.SugaredLogger) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	input := fs.String("i", "", "input file (required)")
+	strategyName := fs.String("strategy", string(backup.StrategySkip), "conflict strategy: skip, overwrite, replace or fail")
+	fs.Parse(args)
+
+	strategy, err := backup.ParseStrategy(*strategyName)
+	if err != nil {
+		return err
+	}
+
+	if *input == "" {
+		return fmt.Errorf("-i is required")
+	}
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		return err
+	}
+
+	archive, err := backup.Read(data)
+	if err != nil {
+		return err
+	}
+
+	result, err := backup.Restore(db, archive, strategy)
+	if err != nil {
+		return err
+	}
+
+	// Archives from before portfolios existed have no owners: users get a personal
+	// portfolio and the first admin takes the rows
+	if err := auth.CreatePersonalPortfolios(db); err != nil {
+		sugar.Warnf("Failed to create personal portfolios: %v", err)
+	}
+	var admin models.User
+	if err := db.Where("is_admin = ?", true).Order("id").First(&admin).Error; err == nil {
+		if membership, err := auth.DefaultMembership(db, admin.ID); err == nil {
+			if err := auth.ClaimOrphans(db, membership.PortfolioID, admin.ID); err != nil {
+				sugar.Warnf("Failed to assign restored rows to %s: %v", admin.Email, err)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(result.Tables))
+	for name := range result.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := result.Tables[name]
+		sugar.Infof("%s: %d inserted, %d updated, %d skipped", name, t.Inserted, t.Updated, t.Skipped)
+	}
+	return nil
+}
+
+// createAdminCommand makes a user an admin, creating the account when there is
+// none, and gives them the data created before accounts existed:
+//
+//	api create-admin -email me@example.com [-name Me]
+//
+// The password of a new account is read from ADMIN_PASSWORD, or else from the
+// first line of stdin, so it doesn't end up in the shell history.
+func createAdminCommand(args []string, db *gorm.DB, sugar *zap.SugaredLogger) error {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	email := fs.String("email", "", "the admin's e-mail (required)")
+	name := fs.String("name", "", "the admin's name, for a new account")
+	fs.Parse(args)
+
+	if strings.TrimSpace(*email) == "" {
+		return fmt.Errorf("-email is required")
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		var exists int64
+		if err := db.Model(&models.User{}).Where("email = ?", strings.ToLower(strings.TrimSpace(*email))).Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists == 0 {
+			fmt.Fprint(os.Stderr, "Password: ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("no password given (set ADMIN_PASSWORD or pipe it to stdin)")
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+	}
+
+	user, created, err := auth.BootstrapAdmin(db, *email, *name, password)
+	if err != nil {
+		return err
+	}
+	if created {
+		sugar.Infof("Admin %s created", user.Email)
+	} else {
+		sugar.Infof("%s is an admin", user.Email)
+	}
+	return nil
+}
+
+// migrateCommand applies, reverts or lists the schema migrations:
+//
+//	api migrate [up] [-to N]   apply the pending migrations (up to version N)
+//	api migrate down [-to N]   revert the newest migration (or every one after version N)
+//	api migrate status         list the migrations and when they were applied
+func migrateCommand(args []string, db *gorm.DB, sugar *zap.SugaredLogger) error {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+
+	fs := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	to := fs.Int("to", -1, "target version")
+	fs.Parse(args)
+
+	switch action {
+	case "up":
+		target := max(*to, 0)
+		count, err := migrations.Up(db, sugar, target)
+		if err != nil {
+			return err
+		}
+		sugar.Infof("%d migrations applied", count)
+
+	case "down":
+		target := *to
+		if target < 0 {
+			current, err := migrations.Current(db)
+			if err != nil {
+				return err
+			}
+			if current == 0 {
+				sugar.Info("No migrations to revert")
+				return nil
+			}
+			target = current - 1
+		}
+		count, err := migrations.Down(db, sugar, target)
+		if err != nil {
+			return err
+		}
+		sugar.Infof("%d migrations reverted", count)
+
+	case "status":
+		list, err := migrations.List(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range list {
+			state := "pending"
+			if m.AppliedAt != nil {
+				state = "applied " + m.AppliedAt.Format(time.RFC3339)
+			}
+			if !m.Reversible {
+				state += " (irreversible)"
+			}
+			fmt.Printf("%4d  %-24s %s\n", m.Version, m.Name, state)
+		}
+
+	default:
+		return fmt.Errorf("unknown migrate action %q (use up, down or status)", action)
+	}
+	return nil
+}
+
+// openapiCommand writes the OpenAPI document and checks it against the router.
+// It fails when a route is missing on either side, so it can run in CI.
+func openapiCommand(args []string, db *gorm.DB, sugar *zap.SugaredLogger) error {
+	fs := flag.NewFlagSet("openapi", flag.ExitOnError)
+	output := fs.String("o", "", "write the document to this file")
+	fs.Parse(args)
+
+	if *output != "" {
+		raw, err := json.MarshalIndent(openapi.Spec(), "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*output, append(raw, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+
+	problems, err := openapi.Verify(newRouter(db, sugar, events.NewBus(), services.NewJobService(db, sugar), services.NewWebhookService(db, sugar),
+		notify.NewService(db, sugar, nil, nil), auth.Registration{Open: true}))
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		sugar.Error(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("OpenAPI document is out of sync with the router (%d problems)", len(problems))
+	}
+
+	sugar.Info("OpenAPI document matches the router")
+	return nil
+}
@@ -1,20 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/joho/godotenv"
 	"go.uber.org/zap"
 
+	"github.com/Felipalds/gemini-stocks/internal/auth"
 	"github.com/Felipalds/gemini-stocks/internal/database" // Update with your actual module path
-	"github.com/Felipalds/gemini-stocks/internal/handlers" // Update with your actual module path
-	"github.com/Felipalds/gemini-stocks/internal/models"   // Update with your actual module path
-	"github.com/Felipalds/gemini-stocks/internal/services" // Update with your actual module path
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/notify"
+	"github.com/Felipalds/gemini-stocks/internal/openapi" // Update with your actual module path
+	"github.com/Felipalds/gemini-stocks/internal/services"
 )
 
 func main() {
@@ -35,77 +36,81 @@ func main() {
 		sugar.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Auto-Migrate Models
-	// This will create the "transactions" table in SQLite automatically
-	if err := db.AutoMigrate(&models.Transaction{}, &models.Ticker{}, &models.PortfolioGoal{}, &models.GoalAllocation{}, &models.Currency{}); err != nil {
-		sugar.Fatalf("Database migration failed: %v", err)
+	sugar.Infof("Database connection established (%s)", db.Dialector.Name())
+
+	// Apply pending migrations. The migrate subcommand manages them by hand instead.
+	if len(os.Args) < 2 || os.Args[1] != "migrate" {
+		if _, err := migrations.Up(db, sugar, 0); err != nil {
+			sugar.Fatalf("Database migration failed: %v", err)
+		}
+		sugar.Info("Database migrations completed successfully")
+	}
+
+	// Subcommands (backup, restore, migrate, openapi) run against the database and exit
+	if runCommand(os.Args[1:], db, sugar) {
+		return
+	}
+
+	// Deleted transactions are purged for good after the retention period
+	retentionDays, err := database.RetentionDays()
+	if err != nil {
+		sugar.Fatal(err)
 	}
+	go database.RunRetention(context.Background(), db, sugar, retentionDays)
+
+	// Summaries are reported in the base currency unless a request asks for another
+	baseCurrency, err := services.BaseCurrency()
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	sugar.Infof("Base currency: %s", baseCurrency)
+
+	// Jobs do not survive a restart
+	jobs := services.NewJobService(db, sugar)
+	if err := jobs.FailInterrupted(); err != nil {
+		sugar.Fatalf("Failed to clean up interrupted jobs: %v", err)
+	}
+
+	// Events are delivered to the webhooks in the background
+	bus := events.NewBus()
+	webhooks := services.NewWebhookService(db, sugar)
+	bus.Subscribe(webhooks.Handle)
+	go webhooks.Run(context.Background())
 
-	sugar.Info("Database migrations completed successfully")
-
-	sugar.Info("SQLite connection established successfully!")
-
-	// 4. Configure Router (Chi)
-	r := chi.NewRouter()
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"https://*", "http://*"}, // Allow localhost
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
-
-	//services
-	financeService := services.NewFinanceService(sugar)
-
-	//handlers
-
-	dataHandler := handlers.NewDataHandler(db, sugar)
-	transactionHandler := handlers.NewTransactionHandler(db, sugar, financeService)
-	priceHandler := handlers.NewPriceHandler(db, sugar, financeService)
-	goalHandler := handlers.NewGoalHandler(db, sugar)
-	currencyHandler := handlers.NewCurrencyHandler(db, sugar, financeService)
-
-	// Basic Middleware
-	r.Use(middleware.RequestID) // Unique ID for each request
-	r.Use(middleware.RealIP)    // Get real client IP
-	r.Use(middleware.Logger)    // Log API requests
-	r.Use(middleware.Recoverer) // Recover from panics without crashing
-
-	// 5. Routes (Health Check)
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("API is running 🚀"))
-	})
-
-	r.Route("/transactions", func(r chi.Router) {
-		r.Post("/", transactionHandler.Create)
-		r.Get("/", transactionHandler.GetAll)
-		r.Post("/import", transactionHandler.ImportExcel)
-		r.Put("/{id}", transactionHandler.Update)
-		r.Delete("/{id}", transactionHandler.Delete)
-	})
-
-	r.Route("/data", func(r chi.Router) {
-		r.Get("/summary", dataHandler.GetSummary)
-	})
-
-	r.Route("/prices", func(r chi.Router) {
-		r.Get("/", priceHandler.GetAll)
-		r.Post("/refresh", priceHandler.RefreshPrices)
-		r.Put("/", priceHandler.UpdatePrice)
-	})
-
-	r.Route("/goal", func(r chi.Router) {
-		r.Get("/", goalHandler.GetGoal)
-		r.Post("/", goalHandler.SaveGoal)
-	})
-
-	r.Route("/currencies", func(r chi.Router) {
-		r.Get("/", currencyHandler.GetCurrencies)
-		r.Get("/usd", currencyHandler.GetUSDRate)
-		r.Post("/refresh", currencyHandler.RefreshUSDRate)
-	})
+	// Users are told about their alerts and monthly tax summaries on the configured channels
+	notifiers, err := notify.FromEnv()
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	templates, err := notify.LoadTemplates(os.Getenv("NOTIFY_TEMPLATES_DIR"))
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	notifications := notify.NewService(db, sugar, notifiers, templates)
+	bus.Subscribe(notifications.Handle)
+	go notifications.RunMonthly(context.Background())
+	sugar.Infof("Notification channels: %v", notify.Channels(notifiers))
+
+	// Accounts are open to anyone unless REGISTRATION_OPEN=false; ADMIN_EMAIL registers the admin
+	registration, err := auth.RegistrationFromEnv()
+	if err != nil {
+		sugar.Fatal(err)
+	}
+	if registration.AdminEmail == "" && !registration.Open {
+		sugar.Warn("Registration is closed and ADMIN_EMAIL is not set; create accounts with `api create-admin`")
+	}
+
+	// 4. Configure Router (Chi) and Routes
+	r := newRouter(db, sugar, bus, jobs, webhooks, notifications, registration)
+
+	// 5. Make sure the OpenAPI document covers every route
+	if problems, err := openapi.Verify(r); err != nil {
+		sugar.Warnf("Could not verify the OpenAPI document: %v", err)
+	} else {
+		for _, p := range problems {
+			sugar.Warnf("OpenAPI document out of sync: %s", p)
+		}
+	}
 
 	// 6. Start Server
 	port := os.Getenv("PORT")
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/cors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/handlers"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/notify"
+	"github.com/Felipalds/gemini-stocks/internal/openapi"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// newRouter wires the services, handlers, middleware and routes.
+// Every route registered here must also be documented in internal/openapi/routes.go.
+// Handlers publish on bus; webhooks and notifications send what follows from it.
+// Long work, like price refreshes, runs as jobs. registration decides who may sign up.
+func newRouter(db *gorm.DB, sugar *zap.SugaredLogger, bus *events.Bus, jobs *services.JobService,
+	webhooks *services.WebhookService, notifications *notify.Service, registration auth.Registration) *chi.Mux {
+	r := chi.NewRouter()
+	r.Use(cors.Handler(cors.Options{
+		AllowedOrigins: allowedOrigins(),
+		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", auth.PortfolioHeader},
+		ExposedHeaders: []string{"Link", "X-Total-Count", "X-Next-Cursor"},
+		MaxAge:         300,
+	}))
+
+	//services
+	financeService := services.NewFinanceService(sugar)
+	symbolService := services.NewSymbolService(db, sugar, financeService)
+
+	//handlers
+
+	dataHandler := handlers.NewDataHandler(db, sugar)
+	transactionHandler := handlers.NewTransactionHandler(db, sugar, financeService, symbolService, bus)
+	priceHandler := handlers.NewPriceHandler(db, sugar, financeService, symbolService, bus, jobs)
+	symbolHandler := handlers.NewSymbolHandler(db, sugar, symbolService)
+	goalHandler := handlers.NewGoalHandler(db, sugar, bus)
+	currencyHandler := handlers.NewCurrencyHandler(db, sugar, financeService)
+	alertHandler := handlers.NewAlertHandler(db, sugar)
+	webhookHandler := handlers.NewWebhookHandler(db, sugar, webhooks)
+	notificationHandler := handlers.NewNotificationHandler(db, sugar, notifications)
+	eventHandler := handlers.NewEventHandler(db, sugar, bus)
+	jobHandler := handlers.NewJobHandler(db, sugar, jobs)
+	exportHandler := handlers.NewExportHandler(db, sugar)
+	adminHandler := handlers.NewAdminHandler(db, sugar)
+	authHandler := handlers.NewAuthHandler(db, sugar, registration)
+	tokenHandler := handlers.NewTokenHandler(db, sugar)
+	portfolioHandler := handlers.NewPortfolioHandler(db, sugar)
+	auditHandler := handlers.NewAuditHandler(db, sugar)
+
+	// Basic Middleware
+	r.Use(middleware.RequestID) // Unique ID for each request
+	r.Use(middleware.RealIP)    // Get real client IP
+	r.Use(middleware.Logger)    // Log API requests
+	r.Use(middleware.Recoverer) // Recover from panics without crashing
+
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		httpx.NotFound(w, r, "Route not found")
+	})
+	r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		httpx.Error(w, r, http.StatusMethodNotAllowed, httpx.CodeMethod, "Method not allowed")
+	})
+
+	// Routes (Health Check)
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("API is running 🚀"))
+	})
+	r.Get("/openapi.json", openapi.Handler)
+
+	requireAuth := auth.Middleware(db, sugar)
+
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/register", authHandler.Register)
+		r.Post("/login", authHandler.Login)
+		r.With(requireAuth).Post("/logout", authHandler.Logout)
+		r.With(requireAuth).Get("/me", authHandler.Me)
+	})
+
+	// Everything below requires a session or API token
+	r.Group(func(r chi.Router) {
+		r.Use(requireAuth)
+
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(auth.RequireSession)
+			r.Get("/", tokenHandler.ListTokens)
+			r.Post("/", tokenHandler.CreateToken)
+			r.Delete("/{id}", tokenHandler.RevokeToken)
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(auth.RequireSession)
+			r.Get("/", notificationHandler.ListNotifications)
+			r.Put("/{channel}", notificationHandler.SaveNotification)
+			r.Delete("/{channel}", notificationHandler.DeleteNotification)
+			r.Post("/{channel}/test", notificationHandler.TestNotification)
+			r.Post("/{channel}/tax-summary", notificationHandler.SendTaxSummary)
+		})
+
+		// Portfolio data: API tokens need read:portfolio to read and write:transactions to change it
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireReadWrite(auth.ScopeReadPortfolio, auth.ScopeWriteTransactions))
+
+			r.Route("/portfolios", func(r chi.Router) {
+				r.Get("/", portfolioHandler.ListPortfolios)
+				r.Get("/{id}/members", portfolioHandler.ListMembers)
+
+				// Creating portfolios and changing who is in them needs a login session
+				r.Group(func(r chi.Router) {
+					r.Use(auth.RequireSession)
+					r.Post("/", portfolioHandler.CreatePortfolio)
+					r.Put("/{id}", portfolioHandler.UpdatePortfolio)
+					r.Post("/{id}/members", portfolioHandler.AddMember)
+					r.Put("/{id}/members/{userID}", portfolioHandler.UpdateMember)
+					r.Delete("/{id}/members/{userID}", portfolioHandler.RemoveMember)
+				})
+			})
+
+			// Market data listings, shared by every portfolio
+			r.Get("/symbols/search", symbolHandler.SearchSymbols)
+
+			// The portfolio comes from the X-Portfolio-ID header; handlers check the member's role
+			r.Group(func(r chi.Router) {
+				r.Use(auth.PortfolioMiddleware(db, sugar))
+
+				// Live updates, as Server-Sent Events
+				r.Get("/events", eventHandler.StreamEvents)
+
+				r.Route("/transactions", func(r chi.Router) {
+					r.Post("/", transactionHandler.Create)
+					r.Get("/", transactionHandler.GetAll)
+					r.Post("/import", transactionHandler.ImportExcel)
+					r.Get("/deleted", transactionHandler.ListDeleted)
+					r.Delete("/deleted", transactionHandler.EmptyTrash)
+					r.Post("/deleted/{id}/restore", transactionHandler.Restore)
+					r.Delete("/deleted/{id}", transactionHandler.Purge)
+					r.Put("/{id}", transactionHandler.Update)
+					r.Delete("/{id}", transactionHandler.Delete)
+				})
+
+				r.Route("/data", func(r chi.Router) {
+					r.Get("/summary", dataHandler.GetSummary)
+					r.Get("/allocation", dataHandler.GetAllocation)
+				})
+
+				r.Route("/prices", func(r chi.Router) {
+					r.Get("/", priceHandler.GetAll)
+					r.Post("/refresh", priceHandler.RefreshPrices)
+					r.Put("/", priceHandler.UpdatePrice)
+					r.Post("/metadata", priceHandler.RefreshMetadata)
+				})
+
+				r.Route("/jobs", func(r chi.Router) {
+					r.Get("/", jobHandler.ListJobs)
+					r.Get("/{id}", jobHandler.GetJob)
+					r.Post("/{id}/cancel", jobHandler.CancelJob)
+				})
+
+				r.Route("/goal", func(r chi.Router) {
+					r.Get("/", goalHandler.GetGoal)
+					r.Post("/", goalHandler.SaveGoal)
+				})
+
+				r.Route("/alerts", func(r chi.Router) {
+					r.Get("/", alertHandler.ListAlerts)
+					r.Post("/", alertHandler.CreateAlert)
+					r.Get("/events", alertHandler.ListAlertEvents)
+					r.Put("/{id}", alertHandler.UpdateAlert)
+					r.Delete("/{id}", alertHandler.DeleteAlert)
+				})
+
+				// Webhooks send portfolio data to any URL: login session only
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Use(auth.RequireSession)
+					r.Get("/", webhookHandler.ListWebhooks)
+					r.Post("/", webhookHandler.CreateWebhook)
+					r.Put("/{id}", webhookHandler.UpdateWebhook)
+					r.Delete("/{id}", webhookHandler.DeleteWebhook)
+					r.Post("/{id}/ping", webhookHandler.PingWebhook)
+					r.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+					r.Post("/{id}/deliveries/{deliveryID}/redeliver", webhookHandler.Redeliver)
+				})
+
+				r.Route("/currencies", func(r chi.Router) {
+					r.Get("/", currencyHandler.GetCurrencies)
+					r.Get("/rate", currencyHandler.GetRate)
+					r.Put("/rates", currencyHandler.SetRate)
+					r.Post("/refresh", currencyHandler.RefreshRates)
+					r.Get("/history", currencyHandler.GetHistory)
+					r.Post("/history/backfill", currencyHandler.BackfillHistory)
+					r.Post("/history/import", currencyHandler.ImportHistory)
+				})
+
+				r.Route("/audit", func(r chi.Router) {
+					r.Get("/", auditHandler.ListAudit)
+					r.Post("/{id}/revert", auditHandler.Revert)
+				})
+
+				r.Route("/export", func(r chi.Router) {
+					r.Get("/transactions", exportHandler.ExportTransactions)
+					r.Get("/positions", exportHandler.ExportPositions)
+					r.Get("/tax", exportHandler.ExportTaxReport)
+					r.Get("/income", exportHandler.ExportIncome)
+				})
+			})
+		})
+
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.RequireAdmin)
+			r.Get("/backup", adminHandler.Backup)
+			r.Post("/restore", adminHandler.Restore)
+		})
+	})
+
+	return r
+}
+
+// allowedOrigins reads the comma-separated CORS_ORIGINS variable.
+// The default is the Vite dev server.
+func allowedOrigins() []string {
+	origins := os.Getenv("CORS_ORIGINS")
+	if origins == "" {
+		return []string{"http://localhost:5173", "http://127.0.0.1:5173"}
+	}
+	var list []string
+	for _, o := range strings.Split(origins, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			list = append(list, o)
+		}
+	}
+	return list
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/notify"
+	"github.com/Felipalds/gemini-stocks/internal/openapi"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// TestOpenAPIMatchesRouter fails when a route is added or removed without
+// updating the OpenAPI document (or the other way round).
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	db := dbtest.SQLite(t)
+	sugar := zap.NewNop().Sugar()
+	r := newRouter(db, sugar, events.NewBus(), services.NewJobService(db, sugar), services.NewWebhookService(db, sugar),
+		notify.NewService(db, sugar, nil, nil), auth.Registration{Open: true})
+
+	problems, err := openapi.Verify(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range problems {
+		t.Error(p)
+	}
+}
+
+// TestAPITokenScopes checks which routes a personal API token reaches. Tokens
+// must not change portfolio members or webhooks, whatever their scopes.
+func TestAPITokenScopes(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		sugar := zap.NewNop().Sugar()
+		if _, err := migrations.Up(db, sugar, 0); err != nil {
+			t.Fatal(err)
+		}
+		r := newRouter(db, sugar, events.NewBus(), services.NewJobService(db, sugar), services.NewWebhookService(db, sugar),
+			notify.NewService(db, sugar, nil, nil), auth.Registration{Open: true})
+
+		user := models.User{Email: "ann@example.com", Name: "Ann", PasswordHash: "-"}
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatal(err)
+		}
+		if _, err := auth.CreatePortfolio(db, "Personal", user.ID); err != nil {
+			t.Fatal(err)
+		}
+		session, hash, _ := auth.NewToken()
+		if err := db.Create(&models.Session{TokenHash: hash, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}).Error; err != nil {
+			t.Fatal(err)
+		}
+		apiToken := func(scopes ...string) string {
+			token, hash, _ := auth.NewAPIToken()
+			if err := db.Create(&models.APIToken{UserID: user.ID, Name: "test", TokenHash: hash, Scopes: scopes}).Error; err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}
+		readWrite := apiToken(auth.ScopeReadPortfolio, auth.ScopeWriteTransactions)
+		write := apiToken(auth.ScopeWriteTransactions)
+
+		tests := []struct {
+			name   string
+			token  string
+			method string
+			target string
+			body   string
+			status int
+		}{
+			{"token reads transactions", readWrite, http.MethodGet, "/transactions", "", http.StatusOK},
+			{"token writes transactions", write, http.MethodPost, "/transactions", "{}", http.StatusUnprocessableEntity},
+			{"write-only token cannot read", write, http.MethodGet, "/transactions", "", http.StatusForbidden},
+			{"token lists portfolios", readWrite, http.MethodGet, "/portfolios", "", http.StatusOK},
+			{"token lists members", readWrite, http.MethodGet, "/portfolios/1/members", "", http.StatusOK},
+			{"token cannot create portfolios", write, http.MethodPost, "/portfolios", `{"name":"Other"}`, http.StatusForbidden},
+			{"token cannot rename portfolios", write, http.MethodPut, "/portfolios/1", `{"name":"Other"}`, http.StatusForbidden},
+			{"token cannot add members", write, http.MethodPost, "/portfolios/1/members", `{"email":"bob@example.com","role":"owner"}`, http.StatusForbidden},
+			{"token cannot change members", write, http.MethodPut, "/portfolios/1/members/1", `{"role":"viewer"}`, http.StatusForbidden},
+			{"token cannot remove members", write, http.MethodDelete, "/portfolios/1/members/1", "", http.StatusForbidden},
+			{"token cannot list webhooks", readWrite, http.MethodGet, "/webhooks", "", http.StatusForbidden},
+			{"token cannot register webhooks", write, http.MethodPost, "/webhooks", `{"url":"https://example.com/hook"}`, http.StatusForbidden},
+			{"session lists webhooks", session, http.MethodGet, "/webhooks", "", http.StatusOK},
+			{"session creates portfolios", session, http.MethodPost, "/portfolios", `{"name":"Other"}`, http.StatusCreated},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != tt.status {
+					t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+			})
+		}
+	})
+}
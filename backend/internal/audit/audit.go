@@ -0,0 +1,108 @@
+// Package audit keeps the append-only log of changes to transactions,
+// tickers, goals and exchange rates: the state before and after, who made the
+// change and in which request.
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// Change is one audited write. Before is nil for creates and After is nil for deletes.
+type Change struct {
+	Entity      string
+	EntityID    string
+	PortfolioID uint // 0 for market data
+	Before      any
+	After       any
+	RevertOf    *uint
+}
+
+// Record appends the change to the log. Pass the transaction that made the
+// change, so the record and its audit entry commit or roll back together.
+func Record(tx *gorm.DB, r *http.Request, c Change) error {
+	return record(tx, c, auth.UserID(r), middleware.GetReqID(r.Context()))
+}
+
+// RecordSystem appends a change the server made on its own, outside of any
+// request, like the recycle bin purge. Its actor is 0.
+func RecordSystem(tx *gorm.DB, c Change) error {
+	return record(tx, c, 0, "")
+}
+
+func record(tx *gorm.DB, c Change, actorID uint, requestID string) error {
+	before, err := snapshot(c.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(c.After)
+	if err != nil {
+		return err
+	}
+
+	entry := models.AuditEntry{
+		Entity:      c.Entity,
+		EntityID:    c.EntityID,
+		Action:      models.AuditUpdate,
+		Before:      before,
+		After:       after,
+		PortfolioID: c.PortfolioID,
+		ActorID:     actorID,
+		RequestID:   requestID,
+		RevertOfID:  c.RevertOf,
+	}
+	switch {
+	case before == nil:
+		entry.Action = models.AuditCreate
+	case after == nil:
+		entry.Action = models.AuditDelete
+	}
+	return tx.Create(&entry).Error
+}
+
+// snapshot encodes a record as JSON; nil and nil pointers mean "no record"
+func snapshot(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// UpgradeLegacy rewrites an entry about the old currencies table as one about
+// the matching CODE/BRL exchange rate. Other entries are left alone.
+func UpgradeLegacy(e *models.AuditEntry) error {
+	if e.Entity != models.EntityCurrency {
+		return nil
+	}
+	upgrade := func(raw json.RawMessage) (json.RawMessage, error) {
+		if len(raw) == 0 || string(raw) == "null" {
+			return raw, nil
+		}
+		var c models.LegacyCurrency
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, err
+		}
+		return json.Marshal(c.ExchangeRate())
+	}
+
+	var err error
+	if e.Before, err = upgrade(e.Before); err != nil {
+		return err
+	}
+	if e.After, err = upgrade(e.After); err != nil {
+		return err
+	}
+	e.Entity = models.EntityExchangeRate
+	e.EntityID = models.LegacyCurrency{Code: e.EntityID}.ExchangeRate().Pair()
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package auth handles passwords, session tokens and the middleware that
+// resolves the user of each request.
+//
+// Clients log in with POST /auth/login and send the returned token as
+// "Authorization: Bearer <token>". Personal API tokens (prefixed "gsk_") are
+// sent the same way and limited to their scopes. Only a SHA-256 of either
+// token is stored, so a leaked database does not leak usable credentials.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// SessionTTL is how long a login stays valid
+const SessionTTL = 30 * 24 * time.Hour
+
+// APITokenPrefix starts every personal API token, telling them apart from sessions
+const APITokenPrefix = "gsk_"
+
+// Scopes of personal API tokens. Sessions have every scope their user is allowed.
+const (
+	// ScopeReadPortfolio allows reading transactions, prices, goals, currencies and exports
+	ScopeReadPortfolio = "read:portfolio"
+	// ScopeWriteTransactions allows changing the portfolio: transactions, imports, goal and refreshes.
+	// Portfolio members and webhooks are not covered; they need a login session.
+	ScopeWriteTransactions = "write:transactions"
+	// ScopeAdmin allows the /admin endpoints and ticker edits; only admins can grant it
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists every valid scope
+var Scopes = []string{ScopeReadPortfolio, ScopeWriteTransactions, ScopeAdmin}
+
+// lastUsedResolution limits how often the last-used time of a token is written
+const lastUsedResolution = time.Minute
+
+// HashPassword hashes a password with bcrypt
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword reports whether password matches the bcrypt hash
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// NewToken returns a random bearer token and the hash to store for it
+func NewToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// NewAPIToken returns a random personal API token and the hash to store for it
+func NewAPIToken() (token, hash string, err error) {
+	token, _, err = NewToken()
+	if err != nil {
+		return "", "", err
+	}
+	token = APITokenPrefix + token
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the stored form of a token
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	sessionKey
+	tokenKey
+	membershipKey
+)
+
+// UserFrom returns the authenticated user, or nil outside the middleware
+func UserFrom(ctx context.Context) *models.User {
+	user, _ := ctx.Value(userKey).(*models.User)
+	return user
+}
+
+// SessionFrom returns the session of the request, or nil outside the middleware
+func SessionFrom(ctx context.Context) *models.Session {
+	session, _ := ctx.Value(sessionKey).(*models.Session)
+	return session
+}
+
+// TokenFrom returns the API token of the request, or nil for sessions
+func TokenFrom(ctx context.Context) *models.APIToken {
+	token, _ := ctx.Value(tokenKey).(*models.APIToken)
+	return token
+}
+
+// HasScope reports whether the request may use scope. Sessions may do anything
+// their user may; API tokens only what they were granted.
+func HasScope(ctx context.Context, scope string) bool {
+	user := UserFrom(ctx)
+	if user == nil {
+		return false
+	}
+	if scope == ScopeAdmin && !user.IsAdmin {
+		return false
+	}
+	token := TokenFrom(ctx)
+	if token == nil {
+		return SessionFrom(ctx) != nil
+	}
+	for _, s := range token.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// UserID returns the ID of the authenticated user, or 0 if there is none
+func UserID(r *http.Request) uint {
+	if user := UserFrom(r.Context()); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// OwnedByUser scopes a query to the rows of the authenticated user:
+//
+//	db.Scopes(auth.OwnedByUser(r)).Find(&tokens)
+func OwnedByUser(r *http.Request) func(*gorm.DB) *gorm.DB {
+	id := UserID(r)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_id = ?", id)
+	}
+}
+
+// bearerToken extracts the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// Middleware rejects requests without a valid session or API token and puts
+// the user (and the session or token) in the context
+func Middleware(db *gorm.DB, logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := bearerToken(r)
+			if token == "" {
+				httpx.Unauthorized(w, r, "Authentication required")
+				return
+			}
+
+			var (
+				ctx    context.Context
+				userID uint
+				err    error
+			)
+			if strings.HasPrefix(token, APITokenPrefix) {
+				ctx, userID, err = resolveAPIToken(r.Context(), db, token)
+			} else {
+				ctx, userID, err = resolveSession(r.Context(), db, token)
+			}
+			if err != nil {
+				if err != gorm.ErrRecordNotFound {
+					logger.Errorw("failed to authenticate request", "error", err)
+				}
+				httpx.Unauthorized(w, r, "Invalid or expired token")
+				return
+			}
+
+			var user models.User
+			if err := db.First(&user, userID).Error; err != nil {
+				httpx.Unauthorized(w, r, "Invalid or expired token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, userKey, &user)))
+		})
+	}
+}
+
+func resolveSession(ctx context.Context, db *gorm.DB, token string) (context.Context, uint, error) {
+	var session models.Session
+	err := db.Where("token_hash = ? AND expires_at > ?", HashToken(token), time.Now()).First(&session).Error
+	if err != nil {
+		return ctx, 0, err
+	}
+	return context.WithValue(ctx, sessionKey, &session), session.UserID, nil
+}
+
+func resolveAPIToken(ctx context.Context, db *gorm.DB, token string) (context.Context, uint, error) {
+	now := time.Now()
+
+	var apiToken models.APIToken
+	err := db.Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", HashToken(token), now).First(&apiToken).Error
+	if err != nil {
+		return ctx, 0, err
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > lastUsedResolution {
+		apiToken.LastUsedAt = &now
+		db.Model(&apiToken).UpdateColumn("last_used_at", now)
+	}
+	return context.WithValue(ctx, tokenKey, &apiToken), apiToken.UserID, nil
+}
+
+// RequireReadWrite requires read for safe methods (GET, HEAD) and write for the others
+func RequireReadWrite(read, write string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scope := write
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				scope = read
+			}
+			if !HasScope(r.Context(), scope) {
+				httpx.Forbidden(w, r, "Missing scope "+scope)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects API tokens, for endpoints only a logged-in user may use
+// (e.g. managing the tokens themselves)
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if SessionFrom(r.Context()) == nil {
+			httpx.Forbidden(w, r, "This endpoint needs a login session, not an API token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IsAdmin reports whether the request is made by an admin (API tokens also
+// need the admin scope). Shared data, like tickers, is theirs to change.
+func IsAdmin(r *http.Request) bool {
+	return HasScope(r.Context(), ScopeAdmin)
+}
+
+// RequireAdmin only lets admins through (API tokens also need the admin scope).
+// It must run after Middleware.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r) {
+			httpx.Forbidden(w, r, "Admin access required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// Registration decides who may create an account through POST /auth/register
+type Registration struct {
+	Open       bool   // anyone may register (REGISTRATION_OPEN, default true)
+	AdminEmail string // registering with this e-mail makes an admin (ADMIN_EMAIL), even when closed
+}
+
+// RegistrationFromEnv reads REGISTRATION_OPEN and ADMIN_EMAIL
+func RegistrationFromEnv() (Registration, error) {
+	reg := Registration{Open: true, AdminEmail: strings.ToLower(strings.TrimSpace(os.Getenv("ADMIN_EMAIL")))}
+	if raw := os.Getenv("REGISTRATION_OPEN"); raw != "" {
+		open, err := strconv.ParseBool(raw)
+		if err != nil {
+			return reg, fmt.Errorf("invalid REGISTRATION_OPEN %q: must be true or false", raw)
+		}
+		reg.Open = open
+	}
+	return reg, nil
+}
+
+// IsAdminEmail reports whether email is the configured admin's
+func (reg Registration) IsAdminEmail(email string) bool {
+	return reg.AdminEmail != "" && strings.ToLower(strings.TrimSpace(email)) == reg.AdminEmail
+}
+
+// BootstrapAdmin makes the user with this e-mail an admin, creating the account
+// with the password when there is none. The admin's portfolio takes over the
+// rows without one (data created before accounts existed). It reports whether
+// the account was created.
+func BootstrapAdmin(db *gorm.DB, email, name, password string) (models.User, bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	var user models.User
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("email = ?", email).First(&user).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			if len(password) < 8 || len(password) > 72 {
+				return fmt.Errorf("the password must be 8 to 72 characters long")
+			}
+			hash, err := HashPassword(password)
+			if err != nil {
+				return err
+			}
+			user = models.User{Email: email, Name: strings.TrimSpace(name), PasswordHash: hash, IsAdmin: true}
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			if _, err := CreatePortfolio(tx, "Personal", user.ID); err != nil {
+				return err
+			}
+			created = true
+		case err != nil:
+			return err
+		case !user.IsAdmin:
+			user.IsAdmin = true
+			if err := tx.Model(&user).Update("is_admin", true).Error; err != nil {
+				return err
+			}
+		}
+
+		membership, err := DefaultMembership(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		return ClaimOrphans(tx, membership.PortfolioID, user.ID)
+	})
+	return user, created, err
+}
@@ -0,0 +1,86 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestRegistrationFromEnv(t *testing.T) {
+	tests := []struct {
+		open, adminEmail string
+		want             auth.Registration
+		wantErr          bool
+	}{
+		{"", "", auth.Registration{Open: true}, false},
+		{"false", " Admin@Example.com ", auth.Registration{Open: false, AdminEmail: "admin@example.com"}, false},
+		{"1", "", auth.Registration{Open: true}, false},
+		{"maybe", "", auth.Registration{}, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("REGISTRATION_OPEN", tt.open)
+		t.Setenv("ADMIN_EMAIL", tt.adminEmail)
+		got, err := auth.RegistrationFromEnv()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("REGISTRATION_OPEN=%q: error = %v", tt.open, err)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("REGISTRATION_OPEN=%q ADMIN_EMAIL=%q: got %+v, want %+v", tt.open, tt.adminEmail, got, tt.want)
+		}
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	dbtest.Each(t, testBootstrapAdmin)
+}
+
+func testBootstrapAdmin(t *testing.T, db *gorm.DB) {
+	if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	orphan := models.Transaction{Symbol: "AAPL", Type: models.Buy, Quantity: decimal.NewFromInt(1), Price: decimal.NewFromInt(100)}
+	if err := db.Create(&orphan).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := auth.BootstrapAdmin(db, "admin@example.com", "Admin", "short"); err == nil {
+		t.Error("a new admin with a short password was created")
+	}
+
+	admin, created, err := auth.BootstrapAdmin(db, " Admin@Example.com", "Admin", "correct horse")
+	if err != nil || !created || !admin.IsAdmin || admin.Email != "admin@example.com" {
+		t.Fatalf("BootstrapAdmin = %+v, %v, %v", admin, created, err)
+	}
+	membership, err := auth.DefaultMembership(db, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.First(&orphan, "id = ?", orphan.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orphan.PortfolioID != membership.PortfolioID {
+		t.Errorf("orphaned transaction is in portfolio %d, want the admin's %d", orphan.PortfolioID, membership.PortfolioID)
+	}
+
+	// An existing user is promoted and keeps their password
+	user := models.User{Email: "user@example.com", PasswordHash: "hash"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.CreatePortfolio(db, "Personal", user.ID); err != nil {
+		t.Fatal(err)
+	}
+	promoted, created, err := auth.BootstrapAdmin(db, "user@example.com", "", "")
+	if err != nil || created || !promoted.IsAdmin || promoted.PasswordHash != "hash" {
+		t.Fatalf("BootstrapAdmin = %+v, %v, %v", promoted, created, err)
+	}
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// PortfolioHeader selects the portfolio of a request. The "portfolio" query
+// parameter works too, for downloads and links. Without either, the user's
+// default portfolio is used.
+const PortfolioHeader = "X-Portfolio-ID"
+
+// MembershipFrom returns the membership resolved by PortfolioMiddleware, or nil
+func MembershipFrom(ctx context.Context) *models.PortfolioMember {
+	m, _ := ctx.Value(membershipKey).(*models.PortfolioMember)
+	return m
+}
+
+// PortfolioID returns the portfolio of the request, or 0 outside PortfolioMiddleware
+func PortfolioID(r *http.Request) uint {
+	if m := MembershipFrom(r.Context()); m != nil {
+		return m.PortfolioID
+	}
+	return 0
+}
+
+// InPortfolio scopes a query to the rows of the request's portfolio:
+//
+//	db.Scopes(auth.InPortfolio(r)).Find(&transactions)
+func InPortfolio(r *http.Request) func(*gorm.DB) *gorm.DB {
+	id := PortfolioID(r)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("portfolio_id = ?", id)
+	}
+}
+
+// Can reports whether the user's role in the request's portfolio is at least
+// min. Otherwise it writes a 403 and returns false, so handlers can simply
+//
+//	if !auth.Can(w, r, models.RoleEditor) { return }
+func Can(w http.ResponseWriter, r *http.Request, min models.Role) bool {
+	m := MembershipFrom(r.Context())
+	if m == nil || !m.Role.AtLeast(min) {
+		httpx.Forbidden(w, r, "This requires the "+string(min)+" role in the portfolio")
+		return false
+	}
+	return true
+}
+
+// PortfolioMiddleware resolves the portfolio of the request and the user's
+// membership in it. Portfolios the user does not belong to are reported as not found.
+// It must run after Middleware.
+func PortfolioMiddleware(db *gorm.DB, logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := UserID(r)
+
+			raw := r.Header.Get(PortfolioHeader)
+			if raw == "" {
+				raw = r.URL.Query().Get("portfolio")
+			}
+
+			var (
+				membership models.PortfolioMember
+				err        error
+			)
+			if raw == "" {
+				membership, err = DefaultMembership(db, userID)
+			} else {
+				id, parseErr := strconv.ParseUint(raw, 10, 64)
+				if parseErr != nil {
+					httpx.BadRequest(w, r, "Invalid portfolio ID")
+					return
+				}
+				err = db.Where("portfolio_id = ? AND user_id = ?", id, userID).First(&membership).Error
+			}
+			if err != nil {
+				if err != gorm.ErrRecordNotFound {
+					logger.Errorw("failed to load portfolio membership", "error", err)
+					httpx.Internal(w, r, "Database error")
+					return
+				}
+				httpx.NotFound(w, r, "Portfolio not found")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), membershipKey, &membership)))
+		})
+	}
+}
+
+// DefaultMembership returns the membership used when a request names no
+// portfolio: the oldest portfolio the user owns, else the oldest they belong to
+func DefaultMembership(db *gorm.DB, userID uint) (models.PortfolioMember, error) {
+	var m models.PortfolioMember
+	err := db.Where("user_id = ?", userID).
+		Order(gorm.Expr("CASE WHEN role = ? THEN 0 ELSE 1 END", models.RoleOwner)).
+		Order("portfolio_id").
+		First(&m).Error
+	return m, err
+}
+
+// CreatePortfolio creates a portfolio with the user as its owner
+func CreatePortfolio(tx *gorm.DB, name string, userID uint) (models.Portfolio, error) {
+	portfolio := models.Portfolio{Name: name}
+	if err := tx.Create(&portfolio).Error; err != nil {
+		return portfolio, err
+	}
+	member := models.PortfolioMember{
+		PortfolioID: portfolio.ID,
+		UserID:      userID,
+		Role:        models.RoleOwner,
+		AddedByID:   userID,
+	}
+	return portfolio, tx.Create(&member).Error
+}
+
+// CreatePersonalPortfolios gives every user without a portfolio a personal
+// one, e.g. the users restored from a backup made before portfolios
+func CreatePersonalPortfolios(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var users []models.User
+		err := tx.Where("id NOT IN (?)", tx.Model(&models.PortfolioMember{}).Select("user_id")).Find(&users).Error
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if _, err := CreatePortfolio(tx, "Personal", user.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ClaimOrphans moves the rows without a portfolio into one: data created before
+// accounts existed, or restored from a backup made before portfolios
+func ClaimOrphans(tx *gorm.DB, portfolioID, userID uint) error {
+	err := tx.Unscoped().Model(&models.Transaction{}).
+		Where("portfolio_id = 0 OR portfolio_id IS NULL").
+		Updates(map[string]any{"portfolio_id": portfolioID, "created_by_id": userID}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.PortfolioGoal{}).
+		Where("portfolio_id = 0 OR portfolio_id IS NULL").
+		Update("portfolio_id", portfolioID).Error
+}
@@ -0,0 +1,486 @@
+// Package backup dumps every table of the database into a portable, versioned
+// archive and loads such archives back.
+//
+// An archive is either a ZIP file holding manifest.json plus one
+// tables/<name>.json file per table, or a single JSON document with the same
+// content ({"manifest": ..., "tables": {...}}).
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/database"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FormatVersion is bumped whenever the archive layout or a table shape changes
+// in a way older code cannot load.
+//
+// Version 2 added the users table and the user_id columns.
+// Version 3 replaced user_id with portfolios and their members.
+// Version 4 replaced the currencies table (rates to BRL) with exchange_rates
+// pairs; older archives are upgraded when read.
+// Version 5 added the historical_rates table.
+// Version 6 added the alert_rules and alert_events tables.
+// Version 7 added the webhooks table.
+// Version 8 added the notification_preferences table.
+const FormatVersion = 8
+
+const appName = "gemini-stocks"
+
+// Archive formats
+const (
+	FormatZIP  = "zip"
+	FormatJSON = "json"
+)
+
+// Manifest describes the archive content
+type Manifest struct {
+	App       string         `json:"app"`
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Tables    map[string]int `json:"tables"` // table name -> row count
+}
+
+// Archive is a decoded backup, with the raw rows of each table
+type Archive struct {
+	Manifest Manifest                   `json:"manifest"`
+	Tables   map[string]json.RawMessage `json:"tables"`
+}
+
+// Strategy decides what happens when a restored row already exists
+type Strategy string
+
+const (
+	StrategySkip      Strategy = "skip"      // keep the existing row
+	StrategyOverwrite Strategy = "overwrite" // replace the existing row with the archived one
+	StrategyReplace   Strategy = "replace"   // wipe the archived tables before loading
+	StrategyFail      Strategy = "fail"      // abort the restore if any row exists
+)
+
+// ParseStrategy validates a strategy name (default: skip)
+func ParseStrategy(s string) (Strategy, error) {
+	switch Strategy(s) {
+	case "":
+		return StrategySkip, nil
+	case StrategySkip, StrategyOverwrite, StrategyReplace, StrategyFail:
+		return Strategy(s), nil
+	}
+	return "", fmt.Errorf("unknown conflict strategy %q (use skip, overwrite, replace or fail)", s)
+}
+
+// TableResult reports what happened to the rows of one table
+type TableResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
+
+// Result reports a whole restore
+type Result struct {
+	Strategy Strategy               `json:"strategy"`
+	Tables   map[string]TableResult `json:"tables"`
+}
+
+// ErrConflict is returned by the fail strategy when a row already exists
+var ErrConflict = errors.New("row already exists")
+
+// table knows how to dump and load one model
+type table struct {
+	name  string
+	dump  func(db *gorm.DB) (any, int, error)
+	load  func(tx *gorm.DB, raw json.RawMessage, strategy Strategy) (TableResult, error)
+	model any
+}
+
+// tables lists every backed up table, parents before children.
+// Restores insert in this order and wipes in reverse order.
+// Jobs, like webhook deliveries, are a log of work done and are not backed up.
+// Neither are symbol searches, a cache of the market data provider.
+var tables = []table{
+	tableOf[userRow]("users"),
+	tableOf[models.Portfolio]("portfolios"),
+	tableOf[models.PortfolioMember]("portfolio_members"),
+	tableOf[models.Ticker]("tickers"),
+	tableOf[models.ExchangeRate]("exchange_rates"),
+	tableOf[models.HistoricalRate]("historical_rates"),
+	tableOf[models.Transaction]("transactions"),
+	tableOf[models.PortfolioGoal]("portfolio_goals"),
+	tableOf[models.GoalAllocation]("goal_allocations"),
+	tableOf[models.AlertRule]("alert_rules"),
+	tableOf[models.AlertEvent]("alert_events"),
+	tableOf[webhookRow]("webhooks"),
+	tableOf[models.NotificationPreference]("notification_preferences"),
+	tableOf[models.AuditEntry]("audit_entries"),
+}
+
+// userRow is models.User including the password hash, which the API never sends,
+// so restored users can still log in. Sessions are not backed up.
+type userRow struct {
+	gorm.Model
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash"`
+	IsAdmin      bool   `json:"is_admin"`
+}
+
+func (userRow) TableName() string { return "users" }
+
+// webhookRow is models.Webhook including the signing secret, so restored
+// webhooks keep their signatures. The delivery log is not backed up.
+type webhookRow struct {
+	gorm.Model
+	PortfolioID uint     `json:"portfolio_id"`
+	CreatedByID uint     `json:"created_by_id"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" gorm:"serializer:json"`
+	Secret      string   `json:"secret"`
+	Enabled     bool     `json:"enabled"`
+}
+
+func (webhookRow) TableName() string { return "webhooks" }
+
+func tableOf[T any](name string) table {
+	return table{
+		name:  name,
+		model: new(T),
+		dump: func(db *gorm.DB) (any, int, error) {
+			var rows []T
+			// Unscoped: soft-deleted rows are part of the history too
+			if err := db.Unscoped().Omit(clause.Associations).Find(&rows).Error; err != nil {
+				return nil, 0, err
+			}
+			if rows == nil {
+				rows = []T{}
+			}
+			return rows, len(rows), nil
+		},
+		load: func(tx *gorm.DB, raw json.RawMessage, strategy Strategy) (TableResult, error) {
+			var result TableResult
+			var rows []T
+			if err := json.Unmarshal(raw, &rows); err != nil {
+				return result, fmt.Errorf("table %s: %w", name, err)
+			}
+
+			for i := range rows {
+				exists, err := rowExists(tx, &rows[i])
+				if err != nil {
+					return result, fmt.Errorf("table %s: %w", name, err)
+				}
+
+				switch {
+				case !exists:
+					if err := tx.Omit(clause.Associations).Create(&rows[i]).Error; err != nil {
+						return result, fmt.Errorf("table %s: %w", name, err)
+					}
+					result.Inserted++
+				case strategy == StrategyFail:
+					return result, fmt.Errorf("table %s: %w", name, ErrConflict)
+				case strategy == StrategyOverwrite:
+					if err := tx.Unscoped().Omit(clause.Associations).Save(&rows[i]).Error; err != nil {
+						return result, fmt.Errorf("table %s: %w", name, err)
+					}
+					result.Updated++
+				default:
+					result.Skipped++
+				}
+			}
+			return result, nil
+		},
+	}
+}
+
+// rowExists looks the row up by its primary key, including soft-deleted rows
+func rowExists(tx *gorm.DB, row any) (bool, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(row); err != nil {
+		return false, err
+	}
+
+	query := tx.Unscoped().Model(row)
+	for _, field := range stmt.Schema.PrimaryFields {
+		value, _ := field.ValueOf(tx.Statement.Context, reflect.ValueOf(row).Elem())
+		query = query.Where(fmt.Sprintf("%s = ?", tx.Statement.Quote(field.DBName)), value)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Dump reads every table into an archive
+func Dump(db *gorm.DB) (*Archive, error) {
+	archive := &Archive{
+		Manifest: Manifest{
+			App:       appName,
+			Version:   FormatVersion,
+			CreatedAt: time.Now().UTC(),
+			Tables:    make(map[string]int),
+		},
+		Tables: make(map[string]json.RawMessage),
+	}
+
+	for _, t := range tables {
+		rows, count, err := t.dump(db)
+		if err != nil {
+			return nil, fmt.Errorf("dump %s: %w", t.name, err)
+		}
+		raw, err := json.Marshal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s: %w", t.name, err)
+		}
+		archive.Tables[t.name] = raw
+		archive.Manifest.Tables[t.name] = count
+	}
+
+	return archive, nil
+}
+
+// Write encodes the archive in the given format
+func (a *Archive) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(a)
+	case FormatZIP:
+		zw := zip.NewWriter(w)
+
+		manifest, err := zw.Create("manifest.json")
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(manifest)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(a.Manifest); err != nil {
+			return err
+		}
+
+		for _, t := range tables {
+			f, err := zw.Create("tables/" + t.name + ".json")
+			if err != nil {
+				return err
+			}
+			if _, err := f.Write(a.Tables[t.name]); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+	return fmt.Errorf("unknown archive format %q", format)
+}
+
+// Read decodes a ZIP or JSON archive and validates it
+func Read(data []byte) (*Archive, error) {
+	var archive Archive
+
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip archive: %w", err)
+		}
+
+		archive.Tables = make(map[string]json.RawMessage)
+		for _, f := range zr.File {
+			content, err := readZipFile(f)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case f.Name == "manifest.json":
+				if err := json.Unmarshal(content, &archive.Manifest); err != nil {
+					return nil, fmt.Errorf("invalid manifest: %w", err)
+				}
+			case strings.HasPrefix(f.Name, "tables/") && strings.HasSuffix(f.Name, ".json"):
+				name := strings.TrimSuffix(strings.TrimPrefix(f.Name, "tables/"), ".json")
+				archive.Tables[name] = content
+			default:
+				return nil, fmt.Errorf("unexpected file %q in archive", f.Name)
+			}
+		}
+	} else if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("archive is neither a zip file nor valid JSON: %w", err)
+	}
+
+	if err := archive.validate(); err != nil {
+		return nil, err
+	}
+	if err := archive.upgrade(); err != nil {
+		return nil, err
+	}
+	return &archive, nil
+}
+
+// upgrade converts the tables of older archives to the current shapes
+func (a *Archive) upgrade() error {
+	if a.Manifest.Version >= 4 {
+		return nil
+	}
+
+	if raw, ok := a.Tables["currencies"]; ok {
+		var currencies []models.LegacyCurrency
+		if err := json.Unmarshal(raw, &currencies); err != nil {
+			return fmt.Errorf("table currencies: %w", err)
+		}
+		rates := make([]models.ExchangeRate, 0, len(currencies))
+		for _, c := range currencies {
+			rates = append(rates, c.ExchangeRate())
+		}
+		if err := a.setTable("exchange_rates", rates); err != nil {
+			return err
+		}
+		delete(a.Tables, "currencies")
+		delete(a.Manifest.Tables, "currencies")
+	}
+
+	if raw, ok := a.Tables["audit_entries"]; ok {
+		var entries []models.AuditEntry
+		if err := json.Unmarshal(raw, &entries); err != nil {
+			return fmt.Errorf("table audit_entries: %w", err)
+		}
+		for i := range entries {
+			if err := audit.UpgradeLegacy(&entries[i]); err != nil {
+				return fmt.Errorf("table audit_entries: %w", err)
+			}
+		}
+		if err := a.setTable("audit_entries", entries); err != nil {
+			return err
+		}
+	}
+
+	a.Manifest.Version = FormatVersion
+	return nil
+}
+
+func (a *Archive) setTable(name string, rows any) error {
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return fmt.Errorf("table %s: %w", name, err)
+	}
+	a.Tables[name] = raw
+	a.Manifest.Tables[name] = reflect.ValueOf(rows).Len()
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", f.Name, err)
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// validate checks the manifest against this build and the table contents
+func (a *Archive) validate() error {
+	m := a.Manifest
+	if m.App != appName {
+		return fmt.Errorf("not a %s backup (app %q)", appName, m.App)
+	}
+	if m.Version < 1 || m.Version > FormatVersion {
+		return fmt.Errorf("unsupported backup version %d (this build supports up to %d)", m.Version, FormatVersion)
+	}
+
+	known := make(map[string]bool)
+	for _, t := range tables {
+		known[t.name] = true
+	}
+
+	for name, raw := range a.Tables {
+		// Upgraded after validation, see upgrade
+		legacy := name == "currencies" && m.Version < 4
+		if !known[name] && !legacy {
+			return fmt.Errorf("unknown table %q in backup", name)
+		}
+		var rows []json.RawMessage
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return fmt.Errorf("table %s is not a JSON array: %w", name, err)
+		}
+		if expected, ok := m.Tables[name]; !ok || expected != len(rows) {
+			return fmt.Errorf("table %s has %d rows, manifest says %d", name, len(rows), expected)
+		}
+	}
+	for name := range m.Tables {
+		if _, ok := a.Tables[name]; !ok {
+			return fmt.Errorf("table %s is listed in the manifest but missing from the backup", name)
+		}
+	}
+	return nil
+}
+
+// Restore loads the archive in a single database transaction
+func Restore(db *gorm.DB, a *Archive, strategy Strategy) (Result, error) {
+	result := Result{Strategy: strategy, Tables: make(map[string]TableResult)}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if strategy == StrategyReplace {
+			for i := len(tables) - 1; i >= 0; i-- {
+				// Tables missing from older archives (e.g. users) are kept
+				if _, ok := a.Tables[tables[i].name]; !ok {
+					continue
+				}
+				if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(tables[i].model).Error; err != nil {
+					return fmt.Errorf("wipe %s: %w", tables[i].name, err)
+				}
+			}
+		}
+
+		for _, t := range tables {
+			raw, ok := a.Tables[t.name]
+			if !ok {
+				continue
+			}
+			tableResult, err := t.load(tx, raw, strategy)
+			if err != nil {
+				return err
+			}
+			result.Tables[t.name] = tableResult
+		}
+
+		if database.IsPostgres(tx) {
+			return resetSequences(tx, a)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+// resetSequences moves the PostgreSQL ID sequences past the restored rows,
+// which were inserted with their IDs. SQLite needs no help.
+func resetSequences(tx *gorm.DB, a *Archive) error {
+	for _, t := range tables {
+		if _, ok := a.Tables[t.name]; !ok {
+			continue
+		}
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(t.model); err != nil {
+			return err
+		}
+		pk := stmt.Schema.PrioritizedPrimaryField
+		if pk == nil || !pk.AutoIncrement {
+			continue
+		}
+
+		table, column := stmt.Schema.Table, pk.DBName
+		err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), MAX(%s)) FROM %s HAVING MAX(%s) IS NOT NULL",
+			table, column, tx.Statement.Quote(column), tx.Statement.Quote(table), tx.Statement.Quote(column))).Error
+		if err != nil {
+			return fmt.Errorf("reset %s sequence: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// seed migrates db and fills it with a user, a portfolio, a ticker, a rate and
+// two transactions, one of them soft-deleted
+func seed(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	rows := []any{
+		&userRow{Email: "ann@example.com", Name: "Ann", PasswordHash: "hash"},
+		&models.Portfolio{Name: "Personal"},
+		&models.PortfolioMember{PortfolioID: 1, UserID: 1, Role: models.RoleOwner, AddedByID: 1},
+		&models.Ticker{Symbol: "AAPL", Price: decimal.NewFromInt(100), Currency: "USD"},
+		&models.ExchangeRate{Base: "USD", Quote: "BRL", Rate: decimal.RequireFromString("5.1")},
+		&models.Transaction{ID: "tx-1", Symbol: "AAPL", Type: models.Buy, Quantity: decimal.NewFromInt(10),
+			Price: decimal.NewFromInt(100), Currency: "USD", Date: day, PortfolioID: 1},
+		&models.Transaction{ID: "tx-2", Symbol: "AAPL", Type: models.Sell, Quantity: decimal.NewFromInt(2),
+			Price: decimal.NewFromInt(120), Currency: "USD", Date: day, PortfolioID: 1},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Delete(&models.Transaction{}, "id = ?", "tx-2").Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// encode writes the archive in format and reads it back
+func encode(t *testing.T, a *Archive, format string) *Archive {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := a.Write(&buf, format); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return read
+}
+
+func tickerPrice(t *testing.T, db *gorm.DB, symbol string) string {
+	t.Helper()
+	var ticker models.Ticker
+	if err := db.First(&ticker, "symbol = ?", symbol).Error; err != nil {
+		return ""
+	}
+	return ticker.Price.String()
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{FormatZIP, FormatJSON} {
+		t.Run(format, func(t *testing.T) {
+			dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+				seed(t, db)
+				archive, err := Dump(db)
+				if err != nil {
+					t.Fatal(err)
+				}
+				read := encode(t, archive, format)
+				if read.Manifest.Version != FormatVersion || read.Manifest.Tables["transactions"] != 2 || read.Manifest.Tables["users"] != 1 {
+					t.Errorf("manifest = %+v", read.Manifest)
+				}
+
+				restored := dbtest.SQLite(t)
+				if _, err := migrations.Up(restored, zap.NewNop().Sugar(), 0); err != nil {
+					t.Fatal(err)
+				}
+				result, err := Restore(restored, read, StrategyFail)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r := result.Tables["transactions"]; r.Inserted != 2 {
+					t.Errorf("transactions: %+v, want 2 inserted", r)
+				}
+
+				var user userRow
+				if err := restored.First(&user).Error; err != nil || user.PasswordHash != "hash" {
+					t.Errorf("user = %+v (%v), want the password hash restored", user, err)
+				}
+				var deleted models.Transaction
+				if err := restored.Unscoped().First(&deleted, "id = ?", "tx-2").Error; err != nil || !deleted.DeletedAt.Valid {
+					t.Errorf("transaction tx-2 = %+v (%v), want it restored soft-deleted", deleted, err)
+				}
+				if got := tickerPrice(t, restored, "AAPL"); got != "100" {
+					t.Errorf("AAPL price = %q, want 100", got)
+				}
+			})
+		})
+	}
+}
+
+func TestRestoreStrategies(t *testing.T) {
+	tests := []struct {
+		strategy Strategy
+		err      error
+		aapl     string // price after the restore
+		msft     bool   // whether the ticker added after the dump is still there
+		tickers  TableResult
+	}{
+		{StrategySkip, nil, "999", true, TableResult{Skipped: 1}},
+		{StrategyOverwrite, nil, "100", true, TableResult{Updated: 1}},
+		{StrategyReplace, nil, "100", false, TableResult{Inserted: 1}},
+		{StrategyFail, ErrConflict, "999", true, TableResult{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+				seed(t, db)
+				archive, err := Dump(db)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Model(&models.Ticker{Symbol: "AAPL"}).Update("price", decimal.NewFromInt(999)).Error; err != nil {
+					t.Fatal(err)
+				}
+				if err := db.Create(&models.Ticker{Symbol: "MSFT", Price: decimal.NewFromInt(300)}).Error; err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := Restore(db, encode(t, archive, FormatJSON), tt.strategy)
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Restore error = %v, want %v", err, tt.err)
+				}
+				if got := tickerPrice(t, db, "AAPL"); got != tt.aapl {
+					t.Errorf("AAPL price = %q, want %q", got, tt.aapl)
+				}
+				if got := tickerPrice(t, db, "MSFT") != ""; got != tt.msft {
+					t.Errorf("MSFT kept = %v, want %v", got, tt.msft)
+				}
+				if err == nil && result.Tables["tickers"] != tt.tickers {
+					t.Errorf("tickers: %+v, want %+v", result.Tables["tickers"], tt.tickers)
+				}
+
+				// Restored IDs must not be handed out again
+				portfolio := models.Portfolio{Name: "New"}
+				if err := db.Create(&portfolio).Error; err != nil || portfolio.ID != 2 {
+					t.Errorf("new portfolio ID %d (%v), want 2", portfolio.ID, err)
+				}
+			})
+		})
+	}
+}
+
+func TestUpgrade(t *testing.T) {
+	// A version 3 backup, from when rates to BRL lived in the currencies table
+	old := `{
+		"manifest": {"app": "gemini-stocks", "version": 3, "tables": {"currencies": 1, "audit_entries": 1, "tickers": 0}},
+		"tables": {
+			"currencies": [{"code": "USD", "rate": "5.1", "updated_at": "2024-03-01T00:00:00Z"}],
+			"audit_entries": [{"id": 1, "entity": "currency", "entity_id": "USD", "action": "update",
+				"before": {"code": "USD", "rate": "5"}, "after": {"code": "USD", "rate": "5.1"}}],
+			"tickers": []
+		}
+	}`
+
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		archive, err := Read([]byte(old))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := archive.Tables["currencies"]; ok || archive.Manifest.Version != FormatVersion || archive.Manifest.Tables["exchange_rates"] != 1 {
+			t.Errorf("manifest = %+v, want the currencies upgraded to exchange_rates", archive.Manifest)
+		}
+
+		if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Restore(db, archive, StrategyFail); err != nil {
+			t.Fatal(err)
+		}
+		var rate models.ExchangeRate
+		if err := db.First(&rate, "base = ? AND quote = ?", "USD", "BRL").Error; err != nil || !rate.Rate.Equal(decimal.RequireFromString("5.1")) {
+			t.Errorf("USD/BRL = %+v (%v), want 5.1", rate, err)
+		}
+		var entry models.AuditEntry
+		if err := db.First(&entry).Error; err != nil {
+			t.Fatal(err)
+		}
+		var after models.ExchangeRate
+		if entry.Entity != models.EntityExchangeRate || entry.EntityID != "USD/BRL" ||
+			json.Unmarshal(entry.After, &after) != nil || after.Pair() != "USD/BRL" {
+			t.Errorf("audit entry = %s %s %s, want it about the USD/BRL rate", entry.Entity, entry.EntityID, entry.After)
+		}
+	})
+}
+
+func TestReadInvalid(t *testing.T) {
+	unexpected := func() string {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		zw.Create("notes.txt")
+		zw.Close()
+		return buf.String()
+	}
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{"not an archive", "hello", "neither a zip file nor valid JSON"},
+		{"another app", `{"manifest": {"app": "other", "version": 1}}`, "not a gemini-stocks backup"},
+		{"version 0", `{"manifest": {"app": "gemini-stocks", "version": 0}}`, "unsupported backup version 0"},
+		{"newer version", `{"manifest": {"app": "gemini-stocks", "version": 99}}`, "unsupported backup version 99"},
+		{"unknown table", `{"manifest": {"app": "gemini-stocks", "version": 8, "tables": {"foo": 0}}, "tables": {"foo": []}}`, `unknown table "foo"`},
+		{"currencies after version 4", `{"manifest": {"app": "gemini-stocks", "version": 4, "tables": {"currencies": 0}}, "tables": {"currencies": []}}`, `unknown table "currencies"`},
+		{"not an array", `{"manifest": {"app": "gemini-stocks", "version": 8, "tables": {"tickers": 1}}, "tables": {"tickers": {}}}`, "not a JSON array"},
+		{"row count", `{"manifest": {"app": "gemini-stocks", "version": 8, "tables": {"tickers": 2}}, "tables": {"tickers": [{}]}}`, "has 1 rows, manifest says 2"},
+		{"missing table", `{"manifest": {"app": "gemini-stocks", "version": 8, "tables": {"tickers": 0}}, "tables": {}}`, "missing from the backup"},
+		{"unexpected zip file", unexpected(), `unexpected file "notes.txt"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Read error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
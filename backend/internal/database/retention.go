@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// DefaultRetentionDays is how long deleted transactions stay in the recycle bin
+const DefaultRetentionDays = 30
+
+// RetentionDays reads TRASH_RETENTION_DAYS, the number of days deleted
+// transactions are kept before they are purged. 0 keeps them forever.
+func RetentionDays() (int, error) {
+	raw := os.Getenv("TRASH_RETENTION_DAYS")
+	if raw == "" {
+		return DefaultRetentionDays, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid TRASH_RETENTION_DAYS %q: must be a number of days (0 keeps deleted transactions forever)", raw)
+	}
+	return days, nil
+}
+
+// PurgeDeleted hard-deletes the transactions deleted before cutoff. Each one
+// is logged in the audit log like a purge from the recycle bin, by actor 0.
+func PurgeDeleted(db *gorm.DB, cutoff time.Time) (int64, error) {
+	var transactions []models.Transaction
+	if err := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&transactions).Error; err != nil {
+		return 0, err
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range transactions {
+			if err := tx.Unscoped().Where("id = ?", t.ID).Delete(&models.Transaction{}).Error; err != nil {
+				return err
+			}
+			change := audit.Change{Entity: models.EntityTransaction, EntityID: t.ID, PortfolioID: t.PortfolioID, Before: t}
+			if err := audit.RecordSystem(tx, change); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(transactions)), nil
+}
+
+// RunRetention purges the transactions deleted more than days ago, at start
+// and then once a day, until ctx is done. It does nothing when days is 0.
+func RunRetention(ctx context.Context, db *gorm.DB, logger *zap.SugaredLogger, days int) {
+	if days == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		purged, err := PurgeDeleted(db, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			logger.Errorw("failed to purge deleted transactions", "error", err)
+		} else if purged > 0 {
+			logger.Infow("Purged deleted transactions", "count", purged, "retention_days", days)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
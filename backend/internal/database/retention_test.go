@@ -0,0 +1,83 @@
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/database"
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+			t.Fatal(err)
+		}
+		cutoff := time.Date(2026, time.September, 18, 12, 0, 0, 0, time.UTC)
+		deletedAt := map[string]*time.Time{
+			"kept":           nil,
+			"before cutoff":  ptr(cutoff.Add(-time.Second)),
+			"long ago":       ptr(cutoff.AddDate(-1, 0, 0)),
+			"at the cutoff":  ptr(cutoff),
+			"after cutoff":   ptr(cutoff.Add(time.Second)),
+			"deleted recent": ptr(cutoff.AddDate(0, 0, 29)),
+		}
+		for id, at := range deletedAt {
+			tx := models.Transaction{ID: id, Symbol: "AAPL", Type: models.Buy, Quantity: decimal.NewFromInt(1),
+				Price: decimal.NewFromInt(100), Currency: "USD", Date: cutoff.AddDate(0, -2, 0), PortfolioID: 7}
+			if at != nil {
+				tx.DeletedAt = gorm.DeletedAt{Time: *at, Valid: true}
+			}
+			if err := db.Create(&tx).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		purged, err := database.PurgeDeleted(db, cutoff)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if purged != 2 {
+			t.Errorf("purged %d, want 2", purged)
+		}
+
+		var left []string
+		db.Unscoped().Model(&models.Transaction{}).Order("id").Pluck("id", &left)
+		want := []string{"after cutoff", "at the cutoff", "deleted recent", "kept"}
+		if len(left) != len(want) {
+			t.Fatalf("left %q, want %q", left, want)
+		}
+		for i := range want {
+			if left[i] != want[i] {
+				t.Errorf("left %q, want %q", left, want)
+				break
+			}
+		}
+
+		var entries []models.AuditEntry
+		db.Order("entity_id").Find(&entries)
+		if len(entries) != 2 {
+			t.Fatalf("%d audit entries, want one per purged transaction", len(entries))
+		}
+		for i, id := range []string{"before cutoff", "long ago"} {
+			e := entries[i]
+			if e.Entity != models.EntityTransaction || e.EntityID != id || e.Action != models.AuditDelete ||
+				e.PortfolioID != 7 || e.ActorID != 0 || len(e.Before) == 0 || e.After != nil {
+				t.Errorf("audit entry %d = %+v, want the purge of %q by the system", i, e, id)
+			}
+		}
+
+		// Nothing left to purge
+		if purged, err := database.PurgeDeleted(db, cutoff); err != nil || purged != 0 {
+			t.Errorf("second purge: %d, %v; want nothing", purged, err)
+		}
+	})
+}
+
+func ptr(t time.Time) *time.Time { return &t }
@@ -0,0 +1,118 @@
+// Package events carries what happens in a portfolio to whoever listens:
+// webhooks, notifications and live clients. Publishers do not know about
+// them; they publish on the Bus once their change is committed.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	TransactionCreated = "transaction.created"
+	TransactionUpdated = "transaction.updated"
+	TransactionDeleted = "transaction.deleted"
+	PricesRefreshed    = "prices.refreshed"
+	AlertTriggered     = "alert.triggered"
+	GoalUpdated        = "goal.updated"
+	TickerUpdated      = "ticker.updated"
+	Ping               = "ping" // sent by hand to test a subscriber
+
+	// RefreshProgress is sent after every ticker of a price refresh. It is
+	// only streamed to live clients, so it is not in Types.
+	RefreshProgress = "prices.refresh_progress"
+)
+
+// Types lists the event types subscribers can choose from
+var Types = []string{TransactionCreated, TransactionUpdated, TransactionDeleted, PricesRefreshed, AlertTriggered, GoalUpdated, TickerUpdated}
+
+// Event is one thing that happened. Data is the record it is about, e.g.
+// the created transaction.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	PortfolioID uint      `json:"portfolio_id"` // 0 for market data shared by every portfolio
+	CreatedAt   time.Time `json:"created_at"`
+	Data        any       `json:"data"`
+}
+
+// ForPortfolio reports whether members of the portfolio should see the event
+func (e Event) ForPortfolio(id uint) bool {
+	return e.PortfolioID == 0 || e.PortfolioID == id
+}
+
+// New returns an event with a fresh ID
+func New(typ string, portfolioID uint, data any) Event {
+	return Event{ID: uuid.New().String(), Type: typ, PortfolioID: portfolioID, CreatedAt: time.Now(), Data: data}
+}
+
+// historySize is how many of the latest events the bus keeps for Since
+const historySize = 256
+
+// Bus hands every published event to every subscriber, synchronously and in
+// the publisher's goroutine, so subscribers must not block. It also keeps the
+// latest events, so clients that reconnect can catch up.
+type Bus struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[int]func(Event)
+	history     []Event // oldest first
+}
+
+// NewBus returns a bus without subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[int]func(Event))}
+}
+
+// Subscribe registers fn for every event until the returned function is called
+func (b *Bus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.subscribers[id] = fn
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish sends a new event to the subscribers. A nil bus drops it.
+func (b *Bus) Publish(typ string, portfolioID uint, data any) {
+	if b == nil {
+		return
+	}
+	e := New(typ, portfolioID, data)
+
+	b.mu.Lock()
+	if len(b.history) == historySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, e)
+	subscribers := make([]func(Event), 0, len(b.subscribers))
+	for _, fn := range b.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	b.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(e)
+	}
+}
+
+// Since returns the events published after the one with the given ID, oldest
+// first. ok is false when the bus no longer has that event.
+func (b *Bus) Since(id string) (missed []Event, ok bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for i, e := range b.history {
+		if e.ID == id {
+			return append([]Event(nil), b.history[i+1:]...), true
+		}
+	}
+	return nil, false
+}
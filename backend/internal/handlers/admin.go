@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/backup"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxRestoreSize limits the size of an uploaded backup (100MB)
+const maxRestoreSize = 100 << 20
+
+type AdminHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+}
+
+func NewAdminHandler(db *gorm.DB, logger *zap.SugaredLogger) *AdminHandler {
+	return &AdminHandler{DB: db, Logger: logger}
+}
+
+// Backup handles GET /admin/backup?format=zip|json
+func (h *AdminHandler) Backup(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = backup.FormatZIP
+	}
+	if format != backup.FormatZIP && format != backup.FormatJSON {
+		httpx.BadRequest(w, r, "Invalid format. Use zip or json")
+		return
+	}
+
+	archive, err := backup.Dump(h.DB)
+	if err != nil {
+		h.Logger.Error("Failed to dump database", zap.Error(err))
+		httpx.Internal(w, r, "Failed to create backup")
+		return
+	}
+
+	// Encode in memory first so a failure can still be reported with a proper status
+	var buf bytes.Buffer
+	if err := archive.Write(&buf, format); err != nil {
+		h.Logger.Error("Failed to encode backup", zap.Error(err))
+		httpx.Internal(w, r, "Failed to create backup")
+		return
+	}
+
+	contentType := "application/zip"
+	if format == backup.FormatJSON {
+		contentType = "application/json"
+	}
+	filename := fmt.Sprintf("gemini-stocks-backup-%s.%s", time.Now().Format("20060102-150405"), format)
+
+	h.Logger.Infow("Backup created", "format", format, "tables", archive.Manifest.Tables)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(buf.Bytes())
+}
+
+// Restore handles POST /admin/restore?strategy=skip|overwrite|replace|fail
+// The archive is sent either as the "file" field of a multipart form or as the raw body
+func (h *AdminHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	strategy, err := backup.ParseStrategy(r.URL.Query().Get("strategy"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRestoreSize)
+
+	var data []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			httpx.BadRequest(w, r, "File is required")
+			return
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+		if err != nil {
+			httpx.BadRequest(w, r, "Could not read uploaded file")
+			return
+		}
+	} else {
+		data, err = io.ReadAll(r.Body)
+		if err != nil {
+			httpx.BadRequest(w, r, "Backup too large or unreadable")
+			return
+		}
+	}
+
+	archive, err := backup.Read(data)
+	if err != nil {
+		httpx.BadRequest(w, r, "Invalid backup: "+err.Error())
+		return
+	}
+
+	result, err := backup.Restore(h.DB, archive, strategy)
+	if err != nil {
+		if errors.Is(err, backup.ErrConflict) {
+			httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "Restore aborted: "+err.Error())
+			return
+		}
+		h.Logger.Error("Failed to restore backup", zap.Error(err))
+		httpx.Internal(w, r, "Failed to restore backup")
+		return
+	}
+
+	// Archives from before portfolios existed have no owners: users get a personal
+	// portfolio and the admin restoring the archive takes the rows
+	if err := auth.CreatePersonalPortfolios(h.DB); err != nil {
+		h.Logger.Warnw("Failed to create personal portfolios", "error", err)
+	}
+	if membership, err := auth.DefaultMembership(h.DB, auth.UserID(r)); err == nil {
+		if err := auth.ClaimOrphans(h.DB, membership.PortfolioID, membership.UserID); err != nil {
+			h.Logger.Warnw("Failed to assign restored rows", "error", err)
+		}
+	}
+
+	h.Logger.Infow("Backup restored", "strategy", strategy, "tables", result.Tables)
+
+	httpx.JSON(w, http.StatusOK, result)
+}
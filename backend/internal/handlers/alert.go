@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// defaultCooldown is the cooldown of rules created without one: a day
+const defaultCooldown = 24 * 60
+
+type AlertHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+}
+
+func NewAlertHandler(db *gorm.DB, logger *zap.SugaredLogger) *AlertHandler {
+	return &AlertHandler{DB: db, Logger: logger}
+}
+
+// AlertRuleRequest is the body of POST /alerts and PUT /alerts/{id}
+type AlertRuleRequest struct {
+	Type            models.AlertType `json:"type" validate:"required,oneof=price_above price_below day_change drawdown goal_drift"`
+	Symbol          string           `json:"symbol"`
+	Category        string           `json:"category"`
+	Threshold       decimal.Decimal  `json:"threshold" validate:"required,gt=0"`
+	CooldownMinutes *int             `json:"cooldown_minutes" validate:"gte=0"` // default: a day
+	Enabled         *bool            `json:"enabled"`                           // default: true
+}
+
+// ListAlerts handles GET /alerts
+func (h *AlertHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	rules := []models.AlertRule{}
+	if err := h.DB.Scopes(auth.InPortfolio(r)).Order("id").Find(&rules).Error; err != nil {
+		h.Logger.Error("Failed to fetch alert rules", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, rules)
+}
+
+// CreateAlert handles POST /alerts (editors and owners)
+func (h *AlertHandler) CreateAlert(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
+	rule := models.AlertRule{
+		PortfolioID:     auth.PortfolioID(r),
+		CreatedByID:     auth.UserID(r),
+		CooldownMinutes: defaultCooldown,
+		Enabled:         true,
+	}
+	if !h.decodeRule(w, r, &rule) {
+		return
+	}
+
+	if err := h.DB.Create(&rule).Error; err != nil {
+		h.Logger.Error("Failed to create alert rule", zap.Error(err))
+		httpx.Internal(w, r, "Failed to create alert rule")
+		return
+	}
+	httpx.JSON(w, http.StatusCreated, rule)
+}
+
+// UpdateAlert handles PUT /alerts/{id} (editors and owners)
+// The rule is replaced; fields left out get their defaults back.
+func (h *AlertHandler) UpdateAlert(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
+	var rule models.AlertRule
+	if err := h.DB.Scopes(auth.InPortfolio(r)).First(&rule, "id = ?", chi.URLParam(r, "id")).Error; err != nil {
+		httpx.NotFound(w, r, "Alert rule not found")
+		return
+	}
+	rule.CooldownMinutes = defaultCooldown
+	rule.Enabled = true
+	if !h.decodeRule(w, r, &rule) {
+		return
+	}
+
+	if err := h.DB.Save(&rule).Error; err != nil {
+		h.Logger.Error("Failed to update alert rule", zap.Error(err))
+		httpx.Internal(w, r, "Failed to update alert rule")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, rule)
+}
+
+// DeleteAlert handles DELETE /alerts/{id} (editors and owners)
+// The history of the rule is kept.
+func (h *AlertHandler) DeleteAlert(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
+	result := h.DB.Scopes(auth.InPortfolio(r)).Where("id = ?", chi.URLParam(r, "id")).Delete(&models.AlertRule{})
+	if result.Error != nil {
+		h.Logger.Error("Failed to delete alert rule", zap.Error(result.Error))
+		httpx.Internal(w, r, "Failed to delete alert rule")
+		return
+	}
+	if result.RowsAffected == 0 {
+		httpx.NotFound(w, r, "Alert rule not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAlertEvents handles GET /alerts/events
+// It returns the triggered alerts of the portfolio, newest first.
+// Query parameters: rule_id, before (an event ID, for paging) and limit (default 50).
+func (h *AlertHandler) ListAlertEvents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query := h.DB.Scopes(auth.InPortfolio(r))
+	for _, param := range []string{"rule_id", "before"} {
+		raw := q.Get(param)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			httpx.BadRequest(w, r, "Invalid "+param+" '"+raw+"'")
+			return
+		}
+		if param == "rule_id" {
+			query = query.Where("rule_id = ?", id)
+		} else {
+			query = query.Where("id < ?", id)
+		}
+	}
+
+	events := []models.AlertEvent{}
+	if err := query.Order("id desc").Limit(limit).Find(&events).Error; err != nil {
+		h.Logger.Error("Failed to fetch alert history", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, events)
+}
+
+// decodeRule reads and checks an AlertRuleRequest into rule
+func (h *AlertHandler) decodeRule(w http.ResponseWriter, r *http.Request, rule *models.AlertRule) bool {
+	var req AlertRuleRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return false
+	}
+	req.Type = models.AlertType(strings.ToLower(strings.TrimSpace(string(req.Type))))
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	req.Category = strings.TrimSpace(req.Category)
+	if !httpx.Validate(w, r, &req) {
+		return false
+	}
+
+	if req.Type.SymbolAlert() {
+		if req.Symbol == "" {
+			httpx.Invalid(w, r, []validate.FieldError{{Field: "symbol", Message: "is required"}})
+			return false
+		}
+		if err := h.DB.First(&models.Ticker{}, "symbol = ?", req.Symbol).Error; err != nil {
+			httpx.Invalid(w, r, []validate.FieldError{{Field: "symbol", Message: "unknown symbol '" + req.Symbol + "'"}})
+			return false
+		}
+		req.Category = ""
+	} else {
+		req.Symbol = ""
+	}
+
+	rule.Type = req.Type
+	rule.Symbol = req.Symbol
+	rule.Category = req.Category
+	rule.Threshold = req.Threshold
+	if req.CooldownMinutes != nil {
+		rule.CooldownMinutes = *req.CooldownMinutes
+	}
+	if req.Enabled != nil {
+		rule.Enabled = *req.Enabled
+	}
+	return true
+}
+
+// evaluateAlerts checks the enabled rules of a portfolio against the cached
+// prices and records the alerts that trigger. It runs after every price refresh.
+func evaluateAlerts(db *gorm.DB, logger *zap.SugaredLogger, portfolioID uint) ([]models.AlertEvent, error) {
+	var rules []models.AlertRule
+	if err := db.Where("portfolio_id = ? AND enabled = ?", portfolioID, true).Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	var tickers []models.Ticker
+	if err := db.Find(&tickers).Error; err != nil {
+		return nil, err
+	}
+	tickerMap := make(map[string]models.Ticker, len(tickers))
+	for _, t := range tickers {
+		tickerMap[t.Symbol] = t
+	}
+	valuation, err := services.LoadValuation(db, baseCurrency())
+	if err != nil {
+		return nil, err
+	}
+	in, err := alertInput(db, portfolioID, tickers, valuation)
+	if err != nil {
+		return nil, err
+	}
+	in.Tickers = tickerMap
+
+	now := time.Now()
+	var triggered []models.AlertEvent
+	for _, rule := range rules {
+		if rule.CoolingDown(now) {
+			continue
+		}
+
+		events := services.EvaluateAlert(rule, *in)
+		if len(events) == 0 {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+			return tx.Model(&rule).Update("last_triggered_at", now).Error
+		})
+		if err != nil {
+			return triggered, err
+		}
+		for _, e := range events {
+			logger.Infof("Alert %d triggered: %s", rule.ID, e.Message)
+		}
+		triggered = append(triggered, events...)
+	}
+	return triggered, nil
+}
+
+// alertInput loads the positions and goal of a portfolio
+func alertInput(db *gorm.DB, portfolioID uint, tickers []models.Ticker, valuation services.Valuation) (*services.AlertInput, error) {
+	var transactions []models.Transaction
+	if err := db.Where("portfolio_id = ?", portfolioID).Find(&transactions).Error; err != nil {
+		return nil, err
+	}
+	goal, err := currentGoal(db, portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	positions, _ := services.ValuePositions(transactions, tickers, valuation)
+	return &services.AlertInput{Positions: positions, Goal: goal}, nil
+}
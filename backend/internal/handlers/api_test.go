@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// apiTest serves handlers behind the real authentication and portfolio
+// middleware, on a migrated database. No market data provider is configured:
+// new symbols are accepted without a lookup and priced by hand.
+type apiTest struct {
+	t       *testing.T
+	db      *gorm.DB
+	logger  *zap.SugaredLogger
+	bus     *events.Bus
+	finance *services.FinanceService
+	symbols *services.SymbolService
+	router  chi.Router
+}
+
+func newAPITest(t *testing.T, db *gorm.DB) *apiTest {
+	t.Helper()
+	logger := zap.NewNop().Sugar()
+	if _, err := migrations.Up(db, logger, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ALPHA_API_KEY", "")
+	finance := services.NewFinanceService(logger)
+	router := chi.NewRouter()
+	router.Use(auth.Middleware(db, logger), auth.PortfolioMiddleware(db, logger))
+	return &apiTest{t: t, db: db, logger: logger, bus: events.NewBus(), finance: finance,
+		symbols: services.NewSymbolService(db, logger, finance), router: router}
+}
+
+// handle routes method and pattern (e.g. "/audit/{id}/revert") to h
+func (a *apiTest) handle(method, pattern string, h http.HandlerFunc) {
+	a.router.Method(method, pattern, h)
+}
+
+// transactions routes the transaction handler
+func (a *apiTest) transactions() *TransactionHandler {
+	h := NewTransactionHandler(a.db, a.logger, a.finance, a.symbols, a.bus)
+	a.handle(http.MethodPost, "/transactions", h.Create)
+	a.handle(http.MethodGet, "/transactions", h.GetAll)
+	a.handle(http.MethodPut, "/transactions/{id}", h.Update)
+	a.handle(http.MethodDelete, "/transactions/{id}", h.Delete)
+	return h
+}
+
+// prices routes the ticker listing and edits
+func (a *apiTest) prices() *PriceHandler {
+	h := NewPriceHandler(a.db, a.logger, a.finance, a.symbols, a.bus, services.NewJobService(a.db, a.logger))
+	a.handle(http.MethodGet, "/prices", h.GetAll)
+	a.handle(http.MethodPut, "/prices", h.UpdatePrice)
+	return h
+}
+
+// member adds the user with email to a portfolio
+func (a *apiTest) member(portfolioID uint, email string, role models.Role) {
+	a.t.Helper()
+	var user models.User
+	if err := a.db.First(&user, "email = ?", email).Error; err != nil {
+		a.t.Fatal(err)
+	}
+	if err := a.db.Create(&models.PortfolioMember{PortfolioID: portfolioID, UserID: user.ID, Role: role}).Error; err != nil {
+		a.t.Fatal(err)
+	}
+}
+
+// user creates a user with a personal portfolio and returns a session token
+func (a *apiTest) user(email string, admin bool) string {
+	a.t.Helper()
+	user := models.User{Email: email, Name: email, PasswordHash: "-", IsAdmin: admin}
+	if err := a.db.Create(&user).Error; err != nil {
+		a.t.Fatal(err)
+	}
+	if _, err := auth.CreatePortfolio(a.db, "Personal", user.ID); err != nil {
+		a.t.Fatal(err)
+	}
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	session := models.Session{TokenHash: hash, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := a.db.Create(&session).Error; err != nil {
+		a.t.Fatal(err)
+	}
+	return token
+}
+
+// do sends a request as the owner of token; body is encoded as JSON unless it is nil
+func (a *apiTest) do(token, method, target string, body any) *httptest.ResponseRecorder {
+	a.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			a.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, r)
+	return w
+}
+
+// ok fails the test unless the response has the status, and decodes its body into out (if not nil)
+func (a *apiTest) ok(w *httptest.ResponseRecorder, status int, out any) {
+	a.t.Helper()
+	if w.Code != status {
+		a.t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			a.t.Fatalf("decoding %s: %v", w.Body, err)
+		}
+	}
+}
+
+// addTransaction creates a transaction through the API and returns it
+func (a *apiTest) addTransaction(token string, tx models.Transaction) models.Transaction {
+	a.t.Helper()
+	var created models.Transaction
+	a.ok(a.do(token, http.MethodPost, "/transactions?unlisted=true", tx), http.StatusCreated, &created)
+	return created
+}
+
+// trade is a transaction payload; amounts are decimal strings and date is YYYY-MM-DD
+func trade(symbol string, typ models.TransactionType, quantity, price, fee, currency, date string) models.Transaction {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	return models.Transaction{
+		Symbol: symbol, Type: typ, Currency: currency, Date: d,
+		Quantity: decimal.RequireFromString(quantity), Price: decimal.RequireFromString(price), Fee: decimal.RequireFromString(fee),
+	}
+}
+
+func itoa(n uint) string {
+	return strconv.FormatUint(uint64(n), 10)
+}
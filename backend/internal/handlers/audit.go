@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AuditHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+}
+
+func NewAuditHandler(db *gorm.DB, logger *zap.SugaredLogger) *AuditHandler {
+	return &AuditHandler{DB: db, Logger: logger}
+}
+
+// revertRoles is the role needed to revert a change of each portfolio entity,
+// the same one needed to make the change
+var revertRoles = map[string]models.Role{
+	models.EntityTransaction: models.RoleEditor,
+	models.EntityGoal:        models.RoleOwner,
+}
+
+// sharedEntities are the market data shared by every portfolio. Their changes
+// are logged without a portfolio (portfolio_id 0), and only admins see and
+// revert them.
+var sharedEntities = map[string]bool{
+	models.EntityTicker:       true,
+	models.EntityExchangeRate: true,
+}
+
+// inAuditLog scopes the audit log to the request's portfolio, plus the shared
+// market data for admins
+func inAuditLog(r *http.Request) func(*gorm.DB) *gorm.DB {
+	if !auth.IsAdmin(r) {
+		return auth.InPortfolio(r)
+	}
+	portfolios := []uint{auth.PortfolioID(r), 0}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("portfolio_id IN ?", portfolios)
+	}
+}
+
+// Errors of revert
+var (
+	errChanged   = errors.New("changed since")     // a later change would be overwritten
+	errUnchanged = errors.New("nothing to revert") // the record is already gone
+)
+
+// ListAudit handles GET /audit
+// It returns the changes of the portfolio, and for admins of the shared market data, newest first.
+// Query parameters: entity, entity_id, before (an entry ID, for paging) and limit (default 50).
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query := h.DB.Scopes(inAuditLog(r))
+	if entity := strings.TrimSpace(q.Get("entity")); entity != "" {
+		if _, ok := revertRoles[entity]; !ok && !sharedEntities[entity] {
+			httpx.BadRequest(w, r, "Unknown entity '"+entity+"'")
+			return
+		}
+		query = query.Where("entity = ?", entity)
+	}
+	if id := strings.TrimSpace(q.Get("entity_id")); id != "" {
+		query = query.Where("entity_id = ?", id)
+	}
+	if raw := q.Get("before"); raw != "" {
+		before, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			httpx.BadRequest(w, r, "Invalid before '"+raw+"'. Must be an entry ID")
+			return
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	entries := []models.AuditEntry{}
+	if err := query.Order("id desc").Limit(limit).Find(&entries).Error; err != nil {
+		h.Logger.Errorw("failed to fetch audit log", "error", err)
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, entries)
+}
+
+// Revert handles POST /audit/{id}/revert
+// It puts the record back in the state it had before the change: a create is
+// undone by deleting, a delete by restoring. The revert is itself logged.
+// If the record changed again afterwards the request fails with 409, unless ?force=true.
+func (h *AuditHandler) Revert(w http.ResponseWriter, r *http.Request) {
+	var entry models.AuditEntry
+	err := h.DB.Scopes(inAuditLog(r)).First(&entry, "id = ?", chi.URLParam(r, "id")).Error
+	if err != nil {
+		httpx.NotFound(w, r, "Audit entry not found")
+		return
+	}
+	// Shared market data is only visible to admins, which is enough to revert it
+	if entry.PortfolioID != 0 && !auth.Can(w, r, revertRoles[entry.Entity]) {
+		return
+	}
+	force := r.URL.Query().Get("force") == "true"
+
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if !force {
+			var later int64
+			err := tx.Model(&models.AuditEntry{}).
+				Where("entity = ? AND entity_id = ? AND id > ?", entry.Entity, entry.EntityID, entry.ID).
+				Count(&later).Error
+			if err != nil {
+				return err
+			}
+			if later > 0 {
+				return errChanged
+			}
+		}
+		return revert(tx, r, entry)
+	})
+	if errors.Is(err, errChanged) {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict,
+			"The "+entry.Entity+" changed after this entry. Revert the later changes first, or use force=true.")
+		return
+	}
+	if errors.Is(err, errUnchanged) {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "The "+entry.Entity+" no longer exists")
+		return
+	}
+	if err != nil {
+		h.Logger.Errorw("failed to revert change", "entry", entry.ID, "error", err)
+		httpx.Internal(w, r, "Failed to revert change")
+		return
+	}
+
+	h.Logger.Infow("Change reverted", "entry", entry.ID, "entity", entry.Entity, "entity_id", entry.EntityID)
+
+	var result models.AuditEntry
+	h.DB.Where("revert_of_id = ?", entry.ID).Order("id desc").First(&result)
+	httpx.JSON(w, http.StatusOK, result)
+}
+
+// revert restores the record of an entry to its Before state and logs it
+func revert(tx *gorm.DB, r *http.Request, entry models.AuditEntry) error {
+	restore := len(entry.Before) > 0 && string(entry.Before) != "null"
+	revertOf := &entry.ID
+
+	switch entry.Entity {
+	case models.EntityTransaction:
+		var current *models.Transaction
+		var existing models.Transaction
+		if err := tx.First(&existing, "id = ?", entry.EntityID).Error; err == nil {
+			current = &existing
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		change := transactionChange(current, nil)
+		change.RevertOf = revertOf
+		if !restore {
+			if current == nil {
+				return errUnchanged
+			}
+			if err := tx.Where("id = ?", entry.EntityID).Delete(&models.Transaction{}).Error; err != nil {
+				return err
+			}
+			return audit.Record(tx, r, change)
+		}
+
+		var t models.Transaction
+		if err := json.Unmarshal(entry.Before, &t); err != nil {
+			return err
+		}
+		// Also brings back a deleted transaction
+		t.DeletedAt = gorm.DeletedAt{}
+		t.UpdatedByID = auth.UserID(r)
+		if err := tx.Unscoped().Save(&t).Error; err != nil {
+			return err
+		}
+		change.EntityID, change.PortfolioID, change.After = t.ID, t.PortfolioID, t
+		return audit.Record(tx, r, change)
+
+	case models.EntityTicker:
+		var t models.Ticker
+		return revertRow(tx, r, entry, map[string]any{"symbol": entry.EntityID}, &t)
+
+	case models.EntityExchangeRate:
+		base, quote, _ := strings.Cut(entry.EntityID, "/")
+		var e models.ExchangeRate
+		return revertRow(tx, r, entry, map[string]any{"base": base, "quote": quote}, &e)
+
+	case models.EntityGoal:
+		goal := models.PortfolioGoal{PortfolioID: entry.PortfolioID, UpdatedByID: auth.UserID(r)}
+		if restore {
+			var before models.PortfolioGoal
+			if err := json.Unmarshal(entry.Before, &before); err != nil {
+				return err
+			}
+			goal.GoalTotal = before.GoalTotal
+			for _, a := range before.Allocations {
+				goal.Allocations = append(goal.Allocations, models.GoalAllocation{Category: a.Category, Percentage: a.Percentage})
+			}
+		}
+		return replaceGoal(tx, r, &goal, revertOf)
+	}
+	return errors.New("unknown entity " + entry.Entity)
+}
+
+// revertRow reverts a change of a market data row (tickers and exchange rates),
+// which are found by their key columns and deleted for good
+func revertRow[T any](tx *gorm.DB, r *http.Request, entry models.AuditEntry, key map[string]any, row *T) error {
+	change := audit.Change{Entity: entry.Entity, EntityID: entry.EntityID, RevertOf: &entry.ID}
+
+	var current T
+	if err := tx.Where(key).First(&current).Error; err == nil {
+		change.Before = current
+	} else if err != gorm.ErrRecordNotFound {
+		return err
+	}
+
+	if len(entry.Before) == 0 || string(entry.Before) == "null" {
+		if change.Before == nil {
+			return errUnchanged
+		}
+		if err := tx.Where(key).Delete(row).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, r, change)
+	}
+
+	if err := json.Unmarshal(entry.Before, row); err != nil {
+		return err
+	}
+	if err := tx.Save(row).Error; err != nil {
+		return err
+	}
+	change.After = *row
+	return audit.Record(tx, r, change)
+}
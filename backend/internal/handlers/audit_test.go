@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// auditTest routes the audit log next to the transaction and ticker handlers
+func auditTest(t *testing.T, db *gorm.DB) *apiTest {
+	a := newAPITest(t, db)
+	a.transactions()
+	a.prices()
+	h := NewAuditHandler(db, a.logger)
+	a.handle(http.MethodGet, "/audit", h.ListAudit)
+	a.handle(http.MethodPost, "/audit/{id}/revert", h.Revert)
+	return a
+}
+
+// latestEntry returns the newest audit entry about an entity
+func (a *apiTest) latestEntry(token, entity, entityID string) models.AuditEntry {
+	a.t.Helper()
+	var entries []models.AuditEntry
+	a.ok(a.do(token, http.MethodGet, "/audit?entity="+entity+"&entity_id="+entityID, nil), http.StatusOK, &entries)
+	if len(entries) == 0 {
+		a.t.Fatalf("no audit entry for %s %s", entity, entityID)
+	}
+	return entries[0]
+}
+
+func TestRevertTransaction(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := auditTest(t, db)
+		token := a.user("ann@example.com", false)
+		buy := trade("AAPL", models.Buy, "10", "100", "0", "USD", "2024-01-10")
+
+		// stored returns the transaction as stored, and whether it is deleted
+		stored := func(id string) (models.Transaction, bool) {
+			var tx models.Transaction
+			if err := db.Unscoped().First(&tx, "id = ?", id).Error; err != nil {
+				t.Fatal(err)
+			}
+			return tx, tx.DeletedAt.Valid
+		}
+		revert := func(entry models.AuditEntry, query string) models.AuditEntry {
+			var result models.AuditEntry
+			a.ok(a.do(token, http.MethodPost, "/audit/"+itoa(entry.ID)+"/revert"+query, nil), http.StatusOK, &result)
+			if result.RevertOfID == nil || *result.RevertOfID != entry.ID {
+				t.Errorf("revert entry = %+v, want it to point at entry %d", result, entry.ID)
+			}
+			return result
+		}
+
+		t.Run("create", func(t *testing.T) {
+			created := a.addTransaction(token, buy)
+			revert(a.latestEntry(token, models.EntityTransaction, created.ID), "")
+			if _, deleted := stored(created.ID); !deleted {
+				t.Error("reverting the create did not delete the transaction")
+			}
+		})
+
+		t.Run("update", func(t *testing.T) {
+			created := a.addTransaction(token, buy)
+			changed := trade("AAPL", models.Buy, "10", "120", "0", "USD", "2024-01-10")
+			changed.Note = "typo"
+			a.ok(a.do(token, http.MethodPut, "/transactions/"+created.ID, changed), http.StatusOK, nil)
+
+			revert(a.latestEntry(token, models.EntityTransaction, created.ID), "")
+			tx, deleted := stored(created.ID)
+			if deleted || !tx.Price.Equal(buy.Price) || tx.Note != "" {
+				t.Errorf("after the revert: %+v, deleted %v; want the price and note from before the update", tx, deleted)
+			}
+		})
+
+		t.Run("delete", func(t *testing.T) {
+			created := a.addTransaction(token, buy)
+			a.ok(a.do(token, http.MethodDelete, "/transactions/"+created.ID, nil), http.StatusNoContent, nil)
+
+			revert(a.latestEntry(token, models.EntityTransaction, created.ID), "")
+			if tx, deleted := stored(created.ID); deleted || !tx.Quantity.Equal(buy.Quantity) {
+				t.Errorf("after the revert: %+v, deleted %v; want the transaction back", tx, deleted)
+			}
+		})
+
+		t.Run("changed since", func(t *testing.T) {
+			created := a.addTransaction(token, buy)
+			createEntry := a.latestEntry(token, models.EntityTransaction, created.ID)
+			changed := buy
+			changed.Note = "later change"
+			a.ok(a.do(token, http.MethodPut, "/transactions/"+created.ID, changed), http.StatusOK, nil)
+
+			w := a.do(token, http.MethodPost, "/audit/"+itoa(createEntry.ID)+"/revert", nil)
+			if w.Code != http.StatusConflict {
+				t.Errorf("status %d, want 409: %s", w.Code, w.Body)
+			}
+			if tx, deleted := stored(created.ID); deleted || tx.Note != "later change" {
+				t.Errorf("refused revert changed the transaction: %+v, deleted %v", tx, deleted)
+			}
+
+			revert(createEntry, "?force=true")
+			if _, deleted := stored(created.ID); !deleted {
+				t.Error("forced revert of the create did not delete the transaction")
+			}
+		})
+
+		t.Run("gone", func(t *testing.T) {
+			created := a.addTransaction(token, buy)
+			entry := a.latestEntry(token, models.EntityTransaction, created.ID)
+			if err := db.Unscoped().Delete(&models.Transaction{}, "id = ?", created.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if w := a.do(token, http.MethodPost, "/audit/"+itoa(entry.ID)+"/revert", nil); w.Code != http.StatusConflict {
+				t.Errorf("status %d, want 409: %s", w.Code, w.Body)
+			}
+		})
+	})
+}
+
+func TestRevertPermissions(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := auditTest(t, db)
+		ann := a.user("ann@example.com", false)
+		bob := a.user("bob@example.com", false)
+		eve := a.user("eve@example.com", false)
+		a.member(1, "eve@example.com", models.RoleViewer)
+		admin := a.user("admin@example.com", true)
+
+		created := a.addTransaction(ann, trade("AAPL", models.Buy, "10", "100", "0", "USD", "2024-01-10"))
+		entry := a.latestEntry(ann, models.EntityTransaction, created.ID)
+		a.ok(a.do(ann, http.MethodPut, "/prices", map[string]any{"symbol": "AAPL", "category": "tech"}), http.StatusOK, nil)
+		tickerEntry := a.latestEntry(admin, models.EntityTicker, "AAPL")
+
+		tests := []struct {
+			name   string
+			token  string
+			target string
+			status int
+		}{
+			{"viewer", eve, "/audit/" + itoa(entry.ID) + "/revert?portfolio=1", http.StatusForbidden},
+			{"another portfolio", bob, "/audit/" + itoa(entry.ID) + "/revert", http.StatusNotFound},
+			{"shared ticker, not an admin", ann, "/audit/" + itoa(tickerEntry.ID) + "/revert", http.StatusNotFound},
+			{"shared ticker, admin", admin, "/audit/" + itoa(tickerEntry.ID) + "/revert", http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if w := a.do(tt.token, http.MethodPost, tt.target, nil); w.Code != tt.status {
+					t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+			})
+		}
+
+		var aapl models.Ticker
+		if err := db.First(&aapl, "symbol = ?", "AAPL").Error; err != nil || aapl.Category != "" {
+			t.Errorf("AAPL = %+v (%v), want the category change reverted", aapl, err)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AuthHandler struct {
+	DB           *gorm.DB
+	Logger       *zap.SugaredLogger
+	Registration auth.Registration
+}
+
+func NewAuthHandler(db *gorm.DB, logger *zap.SugaredLogger, registration auth.Registration) *AuthHandler {
+	return &AuthHandler{DB: db, Logger: logger, Registration: registration}
+}
+
+// RegisterRequest is the body of POST /auth/register
+type RegisterRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=8,max=72"` // bcrypt ignores bytes past 72
+	Name     string `json:"name" validate:"max=100"`
+}
+
+// LoginRequest is the body of POST /auth/login
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// AuthResponse is returned by register and login. Send the token as "Authorization: Bearer <token>".
+type AuthResponse struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	User      models.User `json:"user"`
+}
+
+// dummyHash is compared against when the e-mail is unknown, so both cases take as long
+var dummyHash, _ = auth.HashPassword("not-a-real-password")
+
+// Register handles POST /auth/register
+// Every user gets a personal portfolio. The user registering with ADMIN_EMAIL
+// becomes an admin and takes over the data created before accounts existed.
+// When registration is closed only that user may register.
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req RegisterRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Name = strings.TrimSpace(req.Name)
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+	isAdmin := h.Registration.IsAdminEmail(req.Email)
+	if !h.Registration.Open && !isAdmin {
+		httpx.Forbidden(w, r, "Registration is closed; ask an admin for an account")
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password)
+	if err != nil {
+		h.Logger.Errorw("failed to hash password", "error", err)
+		httpx.Internal(w, r, "Failed to create user")
+		return
+	}
+
+	user := models.User{Email: req.Email, Name: req.Name, PasswordHash: hash, IsAdmin: isAdmin}
+	conflict := false
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("email = ?", user.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			conflict = true
+			return nil
+		}
+
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+
+		// Everyone starts with a personal portfolio
+		portfolio, err := auth.CreatePortfolio(tx, "Personal", user.ID)
+		if err != nil {
+			return err
+		}
+		if !user.IsAdmin {
+			return nil
+		}
+		return auth.ClaimOrphans(tx, portfolio.ID, user.ID)
+	})
+	if err != nil {
+		h.Logger.Errorw("failed to create user", "error", err)
+		httpx.Internal(w, r, "Failed to create user")
+		return
+	}
+	if conflict {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "Email already registered")
+		return
+	}
+
+	h.Logger.Infow("User registered", "user_id", user.ID, "admin", user.IsAdmin)
+	h.startSession(w, r, http.StatusCreated, user)
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req LoginRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+
+	var user models.User
+	err := h.DB.Where("email = ?", strings.ToLower(strings.TrimSpace(req.Email))).First(&user).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		h.Logger.Errorw("failed to fetch user", "error", err)
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	hash := user.PasswordHash
+	if err != nil {
+		hash = dummyHash
+	}
+	if !auth.CheckPassword(hash, req.Password) || err != nil {
+		httpx.Unauthorized(w, r, "Invalid email or password")
+		return
+	}
+
+	// Housekeeping: expired sessions are never used again
+	h.DB.Where("expires_at <= ?", time.Now()).Delete(&models.Session{})
+
+	h.startSession(w, r, http.StatusOK, user)
+}
+
+func (h *AuthHandler) startSession(w http.ResponseWriter, r *http.Request, status int, user models.User) {
+	token, hash, err := auth.NewToken()
+	if err != nil {
+		h.Logger.Errorw("failed to generate token", "error", err)
+		httpx.Internal(w, r, "Failed to start session")
+		return
+	}
+
+	session := models.Session{
+		TokenHash: hash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(auth.SessionTTL),
+	}
+	if err := h.DB.Create(&session).Error; err != nil {
+		h.Logger.Errorw("failed to create session", "error", err)
+		httpx.Internal(w, r, "Failed to start session")
+		return
+	}
+
+	httpx.JSON(w, status, AuthResponse{Token: token, ExpiresAt: session.ExpiresAt, User: user})
+}
+
+// Logout handles POST /auth/logout and revokes the token used for the request
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if session := auth.SessionFrom(r.Context()); session != nil {
+		if err := h.DB.Delete(session).Error; err != nil {
+			h.Logger.Errorw("failed to delete session", "error", err)
+			httpx.Internal(w, r, "Failed to log out")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Me handles GET /auth/me
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	httpx.JSON(w, http.StatusOK, auth.UserFrom(r.Context()))
+}
@@ -1,12 +1,19 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
 	"github.com/Felipalds/gemini-stocks/internal/models"
 	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -25,67 +32,359 @@ func NewCurrencyHandler(db *gorm.DB, logger *zap.SugaredLogger, finance *service
 	}
 }
 
+// CurrencyList is the response of GET /currencies
+type CurrencyList struct {
+	Base      string                `json:"base"` // reporting currency (BASE_CURRENCY)
+	Supported []string              `json:"supported"`
+	Rates     []models.ExchangeRate `json:"rates"`
+}
+
+// SetRateRequest is the body of PUT /currencies/rates
+type SetRateRequest struct {
+	Base  string          `json:"base" validate:"required,currency"`
+	Quote string          `json:"quote" validate:"required,currency"`
+	Rate  decimal.Decimal `json:"rate" validate:"required,gt=0"`
+}
+
 // GetCurrencies handles GET /currencies
 func (h *CurrencyHandler) GetCurrencies(w http.ResponseWriter, r *http.Request) {
-	var currencies []models.Currency
-	if err := h.DB.Find(&currencies).Error; err != nil {
-		h.Logger.Error("Failed to fetch currencies", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	var rates []models.ExchangeRate
+	if err := h.DB.Order("base, quote").Find(&rates).Error; err != nil {
+		h.Logger.Error("Failed to fetch exchange rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(currencies)
+	list := CurrencyList{Base: baseCurrency(), Rates: rates}
+	for code := range models.SupportedCurrencies {
+		list.Supported = append(list.Supported, code)
+	}
+	sort.Strings(list.Supported)
+	httpx.JSON(w, http.StatusOK, list)
 }
 
-// GetUSDRate handles GET /currencies/usd
-func (h *CurrencyHandler) GetUSDRate(w http.ResponseWriter, r *http.Request) {
-	var currency models.Currency
-	if err := h.DB.First(&currency, "code = ?", "USD").Error; err != nil {
-		// If not found, return default rate
-		if err == gorm.ErrRecordNotFound {
-			currency = models.Currency{
-				Code:      "USD",
-				Rate:      5.50, // Default fallback
-				UpdatedAt: time.Now(),
-			}
-		} else {
-			h.Logger.Error("Failed to fetch USD rate", zap.Error(err))
-			http.Error(w, "Database error", http.StatusInternalServerError)
+// GetRate handles GET /currencies/rate?from=EUR&to=BRL (to defaults to the base currency)
+// The rate may be the inverse of a stored pair or a cross rate through other currencies.
+func (h *CurrencyHandler) GetRate(w http.ResponseWriter, r *http.Request) {
+	from := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("from")))
+	if from == "" {
+		httpx.BadRequest(w, r, "from is required")
+		return
+	}
+	to, ok := reportCurrency(w, r, "to")
+	if !ok {
+		return
+	}
+
+	rates, err := services.LoadRates(h.DB)
+	if err != nil {
+		h.Logger.Error("Failed to fetch exchange rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	quote, err := rates.Rate(from, to)
+	if errors.Is(err, services.ErrNoRate) {
+		httpx.NotFound(w, r, "No exchange rate from "+from+" to "+to)
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, quote)
+}
+
+// SetRate handles PUT /currencies/rates (admins)
+func (h *CurrencyHandler) SetRate(w http.ResponseWriter, r *http.Request) {
+	if !canEditMarketData(w, r) {
+		return
+	}
+
+	var req SetRateRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Base = strings.ToUpper(strings.TrimSpace(req.Base))
+	req.Quote = strings.ToUpper(strings.TrimSpace(req.Quote))
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+	if req.Base == req.Quote {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "quote", Message: "must differ from base"}})
+		return
+	}
+
+	rate, err := saveRate(h.DB, r, req.Base, req.Quote, req.Rate)
+	if err != nil {
+		h.Logger.Error("Failed to save exchange rate", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, rate)
+}
+
+// RefreshRates handles POST /currencies/refresh (editors and owners)
+// It fetches the rate of every currency in use to the base currency, plus the stored pairs.
+func (h *CurrencyHandler) RefreshRates(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
+	result, err := refreshRates(h.DB, r, h.Finance, h.Logger)
+	if err != nil {
+		h.Logger.Error("Failed to refresh exchange rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	if result.Total > 0 && result.Updated == 0 {
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Failed to fetch exchange rates")
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, result)
+}
+
+// BackfillRequest is the body of POST /currencies/history/backfill
+type BackfillRequest struct {
+	Base  string `json:"base" validate:"required,currency"`
+	Quote string `json:"quote" validate:"currency"` // default: the base currency
+	Full  bool   `json:"full"`                      // the whole series instead of the last 100 days
+}
+
+// HistoryResult is the response of the historical rates imports
+type HistoryResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// GetHistory handles GET /currencies/history?base=USD&quote=BRL&start=2024-01-01&end=2024-12-31
+// It lists the stored daily rates, newest first
+func (h *CurrencyHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query := h.DB.Order("date DESC, base, quote").Limit(limit)
+	if base := strings.ToUpper(strings.TrimSpace(q.Get("base"))); base != "" {
+		query = query.Where("base = ?", base)
+	}
+	if quote := strings.ToUpper(strings.TrimSpace(q.Get("quote"))); quote != "" {
+		query = query.Where("quote = ?", quote)
+	}
+	for param, op := range map[string]string{"start": ">=", "end": "<="} {
+		v := q.Get(param)
+		if v == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			httpx.BadRequest(w, r, "Invalid "+param+" date. Use YYYY-MM-DD")
 			return
 		}
+		query = query.Where("date "+op+" ?", date)
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(currency)
+	rates := []models.HistoricalRate{}
+	if err := query.Find(&rates).Error; err != nil {
+		h.Logger.Error("Failed to fetch historical rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, rates)
 }
 
-// RefreshUSDRate handles POST /currencies/refresh
-func (h *CurrencyHandler) RefreshUSDRate(w http.ResponseWriter, r *http.Request) {
-	h.Logger.Info("Fetching USD/BRL exchange rate...")
+// BackfillHistory handles POST /currencies/history/backfill (editors and owners)
+// It fetches the daily rates of a pair from the provider
+func (h *CurrencyHandler) BackfillHistory(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
+	var req BackfillRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Base = strings.ToUpper(strings.TrimSpace(req.Base))
+	req.Quote = strings.ToUpper(strings.TrimSpace(req.Quote))
+	if req.Quote == "" {
+		req.Quote = baseCurrency()
+	}
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+	if req.Base == req.Quote {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "quote", Message: "must differ from base"}})
+		return
+	}
 
-	rate, err := h.Finance.GetExchangeRate("USD", "BRL")
+	rates, err := h.Finance.GetDailyRates(r.Context(), req.Base, req.Quote, req.Full)
 	if err != nil {
-		h.Logger.Error("Failed to fetch USD/BRL rate", zap.Error(err))
-		http.Error(w, "Failed to fetch exchange rate: "+err.Error(), http.StatusInternalServerError)
+		h.Logger.Warnf("Failed to fetch %s/%s daily rates: %v", req.Base, req.Quote, err)
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Failed to fetch daily rates: "+err.Error())
+		return
+	}
+	if err := saveHistory(h.DB, rates); err != nil {
+		h.Logger.Error("Failed to save historical rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
 		return
 	}
 
-	// Upsert the USD currency
-	currency := models.Currency{
-		Code:      "USD",
-		Rate:      rate,
-		UpdatedAt: time.Now(),
+	h.Logger.Infof("Backfilled %d %s/%s daily rates", len(rates), req.Base, req.Quote)
+	httpx.JSON(w, http.StatusOK, HistoryResult{Imported: len(rates), Errors: []ImportRowError{}})
+}
+
+// ImportHistory handles POST /currencies/history/import (admins)
+// It loads a PTAX CSV from Banco Central do Brasil (see services.ParsePTAX);
+// rate=buy uses the buy rates instead of the sell ones.
+func (h *CurrencyHandler) ImportHistory(w http.ResponseWriter, r *http.Request) {
+	if !canEditMarketData(w, r) {
+		return
 	}
 
-	if err := h.DB.Save(&currency).Error; err != nil {
-		h.Logger.Error("Failed to save USD rate", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		httpx.BadRequest(w, r, "File too large or invalid form")
 		return
 	}
+	side := strings.ToLower(strings.TrimSpace(r.FormValue("rate")))
+	if side != "" && side != "sell" && side != "buy" {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "rate", Message: "must be sell or buy"}})
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		httpx.BadRequest(w, r, "File is required")
+		return
+	}
+	defer file.Close()
+
+	rates, lineErrors, err := services.ParsePTAX(file, side == "buy")
+	if err != nil {
+		httpx.BadRequest(w, r, "Could not read file")
+		return
+	}
+	if err := saveHistory(h.DB, rates); err != nil {
+		h.Logger.Error("Failed to save historical rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	result := HistoryResult{Imported: len(rates), Failed: len(lineErrors), Errors: []ImportRowError{}}
+	for _, e := range lineErrors {
+		result.Errors = append(result.Errors, ImportRowError{Row: e.Line, Message: e.Message})
+	}
+	h.Logger.Infof("Imported %d PTAX rates (%d failed)", result.Imported, result.Failed)
+	httpx.JSON(w, http.StatusOK, result)
+}
+
+// saveHistory upserts daily rates; a day already stored is overwritten
+func saveHistory(db *gorm.DB, rates []models.HistoricalRate) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i := range rates {
+			rates[i].Date = services.Day(rates[i].Date)
+			if err := tx.Save(&rates[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// refreshRates fetches the pairs linking every currency in use (tickers and
+// transactions) to the base currency, and every pair stored by hand
+func refreshRates(db *gorm.DB, r *http.Request, finance *services.FinanceService, logger *zap.SugaredLogger) (RefreshResult, error) {
+	base := baseCurrency()
+
+	var stored []models.ExchangeRate
+	if err := db.Find(&stored).Error; err != nil {
+		return RefreshResult{}, err
+	}
+	var used []string
+	err := db.Raw("SELECT currency FROM tickers UNION SELECT currency FROM transactions WHERE deleted_at IS NULL").
+		Scan(&used).Error
+	if err != nil {
+		return RefreshResult{}, err
+	}
+
+	pairs := make(map[string]models.ExchangeRate)
+	for _, code := range used {
+		if code != "" && code != base {
+			pair := models.ExchangeRate{Base: code, Quote: base}
+			pairs[pair.Pair()] = pair
+		}
+	}
+	for _, pair := range stored {
+		pairs[pair.Pair()] = pair
+	}
+
+	result := RefreshResult{Total: len(pairs)}
+	for _, pair := range pairs {
+		logger.Infof("Fetching %s exchange rate...", pair.Pair())
+		rate, err := finance.GetExchangeRate(r.Context(), pair.Base, pair.Quote)
+		if err != nil {
+			logger.Warnf("Failed to fetch %s rate: %v", pair.Pair(), err)
+			result.Failed++
+			continue
+		}
+		if _, err := saveRate(db, r, pair.Base, pair.Quote, rate); err != nil {
+			logger.Warnf("Failed to save %s rate: %v", pair.Pair(), err)
+			result.Failed++
+			continue
+		}
+		logger.Infof("%s rate updated: %s", pair.Pair(), rate)
+		result.Updated++
+	}
+	return result, nil
+}
 
-	h.Logger.Infof("USD/BRL rate updated: %.4f", rate)
+// saveRate upserts an exchange rate and records the change in the audit log
+func saveRate(db *gorm.DB, r *http.Request, base, quote string, value decimal.Decimal) (models.ExchangeRate, error) {
+	rate := models.ExchangeRate{
+		Base:      base,
+		Quote:     quote,
+		Rate:      value,
+		UpdatedAt: time.Now(),
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var before *models.ExchangeRate
+		var existing models.ExchangeRate
+		if err := tx.First(&existing, "base = ? AND quote = ?", base, quote).Error; err == nil {
+			before = &existing
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(currency)
+		if err := tx.Save(&rate).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, r, audit.Change{Entity: models.EntityExchangeRate, EntityID: rate.Pair(), Before: before, After: rate})
+	})
+	return rate, err
+}
+
+// baseCurrency is the configured reporting currency. BASE_CURRENCY is checked
+// at startup, so an invalid value cannot get here.
+func baseCurrency() string {
+	base, err := services.BaseCurrency()
+	if err != nil {
+		return services.DefaultBaseCurrency
+	}
+	return base
+}
+
+// reportCurrency reads a currency query parameter, defaulting to the base currency
+func reportCurrency(w http.ResponseWriter, r *http.Request, param string) (string, bool) {
+	code := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get(param)))
+	if code == "" {
+		return baseCurrency(), true
+	}
+	if !models.SupportedCurrencies[code] {
+		httpx.BadRequest(w, r, "Unknown currency '"+code+"'")
+		return "", false
+	}
+	return code, true
 }
@@ -2,7 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"slices"
 
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -19,11 +24,66 @@ func NewDataHandler(db *gorm.DB, logger *zap.SugaredLogger) *DataHandler {
 	}
 }
 
+// GetSummary handles GET /data/summary?currency=EUR
+// It values every open position in one currency (default: the base currency)
+// and totals cost, market value and PnL. Costs are converted at the rate of
+// each purchase date, so PnL splits into asset and currency return.
 func (dh *DataHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
-	dh.logger.Info("Getting data summary")
-	// Implement logic to fetch and return data summary
-	// get all transactions
-	// compare all with the prices
-	// give me total, profit, percentage of win or loss
+	currency, ok := reportCurrency(w, r, "currency")
+	if !ok {
+		return
+	}
+
+	positions, missing, ok := dh.valuePositions(w, r, currency)
+	if !ok {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, services.Summarize(positions, missing, currency))
+}
+
+// GetAllocation handles GET /data/allocation?by=sector&currency=EUR
+// It splits the market value of the open positions by category (default),
+// sector, industry, country, exchange, asset type or currency.
+func (dh *DataHandler) GetAllocation(w http.ResponseWriter, r *http.Request) {
+	currency, ok := reportCurrency(w, r, "currency")
+	if !ok {
+		return
+	}
+	by := r.URL.Query().Get("by")
+	if by == "" {
+		by = "category"
+	}
+	if !slices.Contains(services.AllocationGroups, by) {
+		httpx.BadRequest(w, r, "Unknown grouping '"+by+"'")
+		return
+	}
+
+	positions, missing, ok := dh.valuePositions(w, r, currency)
+	if !ok {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, services.Allocate(positions, missing, by, currency))
+}
+
+// valuePositions values the open positions of the portfolio in currency
+func (dh *DataHandler) valuePositions(w http.ResponseWriter, r *http.Request, currency string) ([]services.Position, []string, bool) {
+	var transactions []models.Transaction
+	if err := dh.db.Scopes(auth.InPortfolio(r)).Find(&transactions).Error; err != nil {
+		dh.logger.Error("Failed to fetch transactions", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return nil, nil, false
+	}
+	var tickers []models.Ticker
+	if err := dh.db.Find(&tickers).Error; err != nil {
+		dh.logger.Warn("Failed to fetch stock prices", zap.Error(err))
+	}
+	valuation, err := services.LoadValuation(dh.db, currency)
+	if err != nil {
+		dh.logger.Error("Failed to fetch exchange rates", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return nil, nil, false
+	}
 
+	positions, missing := services.ValuePositions(transactions, tickers, valuation)
+	return positions, missing, true
 }
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// streamHeartbeat is how often an idle stream gets a comment, so proxies keep it open
+const streamHeartbeat = 25 * time.Second
+
+// streamBuffer is how many events a slow client can fall behind before it is
+// disconnected. It reconnects with Last-Event-ID and catches up.
+const streamBuffer = 64
+
+// streamRetry is the reconnection delay suggested to clients, in milliseconds
+const streamRetry = 3000
+
+// EventHandler streams portfolio events to live clients
+type EventHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+	Events *events.Bus
+}
+
+func NewEventHandler(db *gorm.DB, logger *zap.SugaredLogger, bus *events.Bus) *EventHandler {
+	return &EventHandler{DB: db, Logger: logger, Events: bus}
+}
+
+// StreamEvents handles GET /events
+// It sends the events of the request's portfolio, and the shared market data
+// ones, as Server-Sent Events until the client goes away. A Last-Event-ID
+// header replays the recent events the client missed.
+func (h *EventHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	portfolioID := auth.PortfolioID(r)
+
+	// Subscribe before reading the history, so nothing falls in between
+	queue := make(chan events.Event, streamBuffer)
+	overflow := make(chan struct{})
+	var closeOverflow sync.Once
+	unsubscribe := h.Events.Subscribe(func(e events.Event) {
+		if !e.ForPortfolio(portfolioID) {
+			return
+		}
+		select {
+		case queue <- e:
+		default:
+			closeOverflow.Do(func() { close(overflow) })
+		}
+	})
+	defer unsubscribe()
+
+	var missed []events.Event
+	if last := r.Header.Get("Last-Event-ID"); last != "" {
+		missed, _ = h.Events.Since(last)
+	}
+
+	// Streams outlive any server write timeout
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		httpx.Internal(w, r, "Streaming not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+
+	sent := make(map[string]bool, len(missed))
+	for _, e := range missed {
+		if !e.ForPortfolio(portfolioID) {
+			continue
+		}
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+		sent[e.ID] = true
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-overflow:
+			h.Logger.Infow("Closing slow event stream", "user_id", auth.UserID(r), "portfolio_id", portfolioID)
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e := <-queue:
+			if sent[e.ID] {
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				h.Logger.Warnw("Failed to write event", "event", e.Type, "error", err)
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one event in the text/event-stream format
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
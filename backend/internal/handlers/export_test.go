@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+func TestCSVSheet(t *testing.T) {
+	single := []exportSheet{{Name: "Positions"}}
+	tax := []exportSheet{{Name: "Monthly Summary", Key: "months"}, {Name: "Sales", Key: "sales"}}
+
+	tests := []struct {
+		name   string
+		sheets []exportSheet
+		query  string
+		want   string // sheet name, empty when the request must fail
+	}{
+		{"single sheet needs no choice", single, "", "Positions"},
+		{"several sheets need a choice", tax, "", ""},
+		{"picks the first sheet", tax, "?sheet=months", "Monthly Summary"},
+		{"picks another sheet", tax, "?sheet=Sales", "Sales"},
+		{"unknown sheet", tax, "?sheet=details", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/export/tax"+tt.query, nil)
+
+			sheet, ok := csvSheet(w, r, tt.sheets)
+			if tt.want == "" {
+				if ok || w.Code != http.StatusBadRequest {
+					t.Fatalf("got ok=%v status %d, want a 400", ok, w.Code)
+				}
+				return
+			}
+			if !ok || sheet.Name != tt.want {
+				t.Fatalf("got %q (ok=%v), want %q", sheet.Name, ok, tt.want)
+			}
+		})
+	}
+}
+
+// exportTest is a portfolio with a few years of trades, behind the export routes
+func exportTest(t *testing.T, db *gorm.DB) (*apiTest, string) {
+	a := newAPITest(t, db)
+	a.transactions()
+	h := NewExportHandler(db, a.logger)
+	a.handle(http.MethodGet, "/export/transactions", h.ExportTransactions)
+	a.handle(http.MethodGet, "/export/positions", h.ExportPositions)
+	a.handle(http.MethodGet, "/export/tax", h.ExportTaxReport)
+	a.handle(http.MethodGet, "/export/income", h.ExportIncome)
+
+	token := a.user("ann@example.com", false)
+	for _, tx := range []models.Transaction{
+		trade("AAPL", models.Buy, "10", "100", "1", "USD", "2024-01-10"),
+		trade("AAPL", models.Sell, "4", "150", "2", "USD", "2024-02-15"),
+		trade("PETR4", models.Buy, "100", "30", "0", "BRL", "2024-03-01"),
+		trade("AAPL", models.Sell, "6", "90", "0", "USD", "2024-05-20"),
+		trade("AAPL", models.Buy, "5", "80", "0", "USD", "2024-06-01"),
+		trade("PETR4", models.Sell, "50", "40", "0", "BRL", "2025-01-10"),
+	} {
+		a.addTransaction(token, tx)
+	}
+	return a, token
+}
+
+// csvRows reads a CSV export into its header and rows
+func csvRows(t *testing.T, w *httptest.ResponseRecorder) ([]string, [][]string) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil || len(records) == 0 {
+		t.Fatalf("invalid CSV (%v): %s", err, w.Body)
+	}
+	return records[0], records[1:]
+}
+
+func TestExports(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a, token := exportTest(t, db)
+
+		tests := []struct {
+			name   string
+			target string
+			header []string
+			rows   [][]string // every column but the transaction IDs
+		}{
+			{
+				name:   "transactions",
+				target: "/export/transactions?format=csv&symbol=PETR4",
+				header: []string{"ID", "Date", "Symbol", "Type", "Quantity", "Price", "Fee", "Total", "Currency", "Note"},
+				rows: [][]string{
+					{"", "2024-03-01", "PETR4", "BUY", "100", "30", "0", "3000", "BRL", ""},
+					{"", "2025-01-10", "PETR4", "SELL", "50", "40", "0", "2000", "BRL", ""},
+				},
+			},
+			{
+				name:   "positions",
+				target: "/export/positions?format=csv",
+				header: []string{"Symbol", "Category", "Currency", "Quantity", "Average Cost", "Cost Basis", "Current Price", "Market Value", "PnL", "PnL %"},
+				rows: [][]string{
+					{"AAPL", "", "USD", "5", "80", "400", "100", "500", "100", "25"},
+					{"PETR4", "", "BRL", "50", "30", "1500", "30", "1500", "0", "0"},
+				},
+			},
+			{
+				name:   "tax months",
+				target: "/export/tax?format=csv&year=2024&sheet=months",
+				header: []string{"Month", "Currency", "Sales", "Cost Basis", "Fees", "Realized Gain"},
+				rows: [][]string{
+					{"2024-02", "USD", "600", "400.4", "2", "197.6"},
+					{"2024-05", "USD", "540", "600.6", "0", "-60.6"},
+				},
+			},
+			{
+				name:   "tax sales",
+				target: "/export/tax?format=csv&year=2025&sheet=sales",
+				header: []string{"Date", "Symbol", "Currency", "Quantity", "Proceeds", "Cost Basis", "Fee", "Realized Gain", "Transaction ID"},
+				rows: [][]string{
+					{"2025-01-10", "PETR4", "BRL", "50", "2000", "1500", "0", "500", ""},
+				},
+			},
+			{
+				name:   "income by month",
+				target: "/export/income?format=csv&year=2024",
+				header: []string{"Period", "Currency", "Sales", "Proceeds", "Cost Basis", "Fees", "Realized Gain"},
+				rows: [][]string{
+					{"2024-02", "USD", "1", "600", "400.4", "2", "197.6"},
+					{"2024-05", "USD", "1", "540", "600.6", "0", "-60.6"},
+				},
+			},
+			{
+				name:   "income by quarter",
+				target: "/export/income?format=csv&period=quarter",
+				header: []string{"Period", "Currency", "Sales", "Proceeds", "Cost Basis", "Fees", "Realized Gain"},
+				rows: [][]string{
+					{"2024-Q1", "USD", "1", "600", "400.4", "2", "197.6"},
+					{"2024-Q2", "USD", "1", "540", "600.6", "0", "-60.6"},
+					{"2025-Q1", "BRL", "1", "2000", "1500", "0", "500"},
+				},
+			},
+			{
+				name:   "income by year",
+				target: "/export/income?format=csv&period=year",
+				header: []string{"Period", "Currency", "Sales", "Proceeds", "Cost Basis", "Fees", "Realized Gain"},
+				rows: [][]string{
+					{"2024", "USD", "2", "1140", "1001", "2", "137"},
+					{"2025", "BRL", "1", "2000", "1500", "0", "500"},
+				},
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				header, rows := csvRows(t, a.do(token, http.MethodGet, tt.target, nil))
+				if !slices.Equal(header, tt.header) {
+					t.Errorf("header = %q, want %q", header, tt.header)
+				}
+				if len(rows) != len(tt.rows) {
+					t.Fatalf("rows = %q, want %q", rows, tt.rows)
+				}
+				for i, row := range rows {
+					for j, cell := range row {
+						if header[j] == "ID" || header[j] == "Transaction ID" {
+							if cell == "" {
+								t.Errorf("row %d has no %s", i, header[j])
+							}
+							continue
+						}
+						if cell != tt.rows[i][j] {
+							t.Errorf("row %d, %s = %q, want %q", i, header[j], cell, tt.rows[i][j])
+						}
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestExportFormats(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a, token := exportTest(t, db)
+
+		t.Run("json", func(t *testing.T) {
+			var report services.IncomeReport
+			a.ok(a.do(token, http.MethodGet, "/export/income?format=json&period=year&year=2024", nil), http.StatusOK, &report)
+			if report.Period != "year" || report.Year != 2024 || len(report.Periods) != 1 ||
+				report.Periods[0].Count != 2 || !report.Periods[0].Gain.Equal(decimal.NewFromInt(137)) {
+				t.Errorf("report = %+v", report)
+			}
+		})
+
+		t.Run("xlsx", func(t *testing.T) {
+			w := a.do(token, http.MethodGet, "/export/tax?year=2024", nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+			f, err := excelize.OpenReader(w.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if sheets := f.GetSheetList(); !slices.Equal(sheets, []string{"Monthly Summary", "Sales"}) {
+				t.Errorf("sheets = %q", sheets)
+			}
+			rows, err := f.GetRows("Sales")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 3 || rows[0][0] != "Date" || rows[1][1] != "AAPL" || rows[2][7] != "-60.60" {
+				t.Errorf("Sales rows = %q", rows)
+			}
+		})
+
+		t.Run("errors", func(t *testing.T) {
+			for _, target := range []string{
+				"/export/income?format=pdf",
+				"/export/income?period=week",
+				"/export/income?year=abc",
+				"/export/income?currency=XYZ",
+				"/export/tax?format=csv",
+			} {
+				if w := a.do(token, http.MethodGet, target, nil); w.Code != http.StatusBadRequest {
+					t.Errorf("%s: status %d, want 400", target, w.Code)
+				}
+			}
+		})
+	})
+}
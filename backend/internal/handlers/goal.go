@@ -1,10 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
+	"strconv"
 
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
 	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -12,90 +17,128 @@ import (
 type GoalHandler struct {
 	DB     *gorm.DB
 	Logger *zap.SugaredLogger
+	Events *events.Bus
 }
 
-func NewGoalHandler(db *gorm.DB, logger *zap.SugaredLogger) *GoalHandler {
-	return &GoalHandler{DB: db, Logger: logger}
+func NewGoalHandler(db *gorm.DB, logger *zap.SugaredLogger, bus *events.Bus) *GoalHandler {
+	return &GoalHandler{DB: db, Logger: logger, Events: bus}
 }
 
+// GetGoal handles GET /goal
 func (h *GoalHandler) GetGoal(w http.ResponseWriter, r *http.Request) {
 	var goal models.PortfolioGoal
-	result := h.DB.Preload("Allocations").Order("created_at desc").First(&goal)
+	result := h.DB.Scopes(auth.InPortfolio(r)).Preload("Allocations").Order("created_at desc").First(&goal)
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]string{"error": "no goal found"})
+			httpx.NotFound(w, r, "No goal found")
 			return
 		}
 		h.Logger.Errorw("failed to fetch goal", "error", result.Error)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Failed to fetch goal")
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(goal)
+	httpx.JSON(w, http.StatusOK, goal)
 }
 
-type saveGoalRequest struct {
-	GoalTotal   float64 `json:"goal_total"`
+// SaveGoalRequest is the body of POST /goal
+type SaveGoalRequest struct {
+	GoalTotal   decimal.Decimal `json:"goal_total" validate:"required,gt=0"`
 	Allocations []struct {
-		Category   string  `json:"category"`
-		Percentage float64 `json:"percentage"`
+		Category   string  `json:"category" validate:"required"`
+		Percentage float64 `json:"percentage" validate:"gte=0,lte=100"`
 	} `json:"allocations"`
 }
 
+// SaveGoal handles POST /goal and replaces the goal of the portfolio (owners only)
 func (h *GoalHandler) SaveGoal(w http.ResponseWriter, r *http.Request) {
-	var req saveGoalRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	if !auth.Can(w, r, models.RoleOwner) {
 		return
 	}
 
-	if req.GoalTotal <= 0 {
-		http.Error(w, "goal_total must be positive", http.StatusBadRequest)
+	var req SaveGoalRequest
+	if !httpx.Decode(w, r, &req) {
 		return
 	}
 
-	tx := h.DB.Begin()
-
-	// Delete existing allocations and goals
-	tx.Where("1 = 1").Delete(&models.GoalAllocation{})
-	tx.Where("1 = 1").Delete(&models.PortfolioGoal{})
-
 	goal := models.PortfolioGoal{
-		GoalTotal: req.GoalTotal,
+		PortfolioID: auth.PortfolioID(r),
+		UpdatedByID: auth.UserID(r),
+		GoalTotal:   req.GoalTotal,
 	}
-
-	if err := tx.Create(&goal).Error; err != nil {
-		tx.Rollback()
-		h.Logger.Errorw("failed to create goal", "error", err)
-		http.Error(w, "failed to save goal", http.StatusInternalServerError)
-		return
-	}
-
 	for _, a := range req.Allocations {
 		if a.Percentage <= 0 {
 			continue
 		}
-		alloc := models.GoalAllocation{
-			PortfolioGoalID: goal.ID,
-			Category:        a.Category,
-			Percentage:      a.Percentage,
-		}
-		if err := tx.Create(&alloc).Error; err != nil {
-			tx.Rollback()
-			h.Logger.Errorw("failed to create allocation", "error", err)
-			http.Error(w, "failed to save allocation", http.StatusInternalServerError)
-			return
-		}
+		goal.Allocations = append(goal.Allocations, models.GoalAllocation{
+			Category:   a.Category,
+			Percentage: a.Percentage,
+		})
 	}
 
-	tx.Commit()
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceGoal(tx, r, &goal, nil)
+	})
+	if err != nil {
+		h.Logger.Errorw("failed to save goal", "error", err)
+		httpx.Internal(w, r, "Failed to save goal")
+		return
+	}
 
 	// Reload with allocations
 	h.DB.Preload("Allocations").First(&goal, goal.ID)
+	h.Events.Publish(events.GoalUpdated, goal.PortfolioID, goal)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(goal)
+	httpx.JSON(w, http.StatusCreated, goal)
+}
+
+// currentGoal loads the goal of a portfolio with its allocations, or nil if it has none
+func currentGoal(tx *gorm.DB, portfolioID uint) (*models.PortfolioGoal, error) {
+	var goal models.PortfolioGoal
+	err := tx.Preload("Allocations").Where("portfolio_id = ?", portfolioID).Order("created_at desc").First(&goal).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+// replaceGoal deletes the goal of goal.PortfolioID and creates goal in its
+// place, recording the change in the audit log. It must run in a transaction.
+// A goal with a zero GoalTotal only deletes the current one.
+func replaceGoal(tx *gorm.DB, r *http.Request, goal *models.PortfolioGoal, revertOf *uint) error {
+	before, err := currentGoal(tx, goal.PortfolioID)
+	if err != nil {
+		return err
+	}
+	if before == nil && goal.GoalTotal.IsZero() {
+		return errUnchanged
+	}
+
+	// Delete the portfolio's existing allocations and goals
+	owned := tx.Model(&models.PortfolioGoal{}).Where("portfolio_id = ?", goal.PortfolioID).Select("id")
+	if err := tx.Where("portfolio_goal_id IN (?)", owned).Delete(&models.GoalAllocation{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("portfolio_id = ?", goal.PortfolioID).Delete(&models.PortfolioGoal{}).Error; err != nil {
+		return err
+	}
+
+	change := audit.Change{
+		Entity:      models.EntityGoal,
+		EntityID:    strconv.FormatUint(uint64(goal.PortfolioID), 10),
+		PortfolioID: goal.PortfolioID,
+		Before:      before,
+		RevertOf:    revertOf,
+	}
+	if goal.GoalTotal.IsPositive() {
+		// Allocations are created along with the goal
+		if err := tx.Create(goal).Error; err != nil {
+			return err
+		}
+		change.After = goal
+	}
+	return audit.Record(tx, r, change)
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// JobHandler reports on the background jobs started from a portfolio
+type JobHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+	Jobs   *services.JobService
+}
+
+func NewJobHandler(db *gorm.DB, logger *zap.SugaredLogger, jobs *services.JobService) *JobHandler {
+	return &JobHandler{DB: db, Logger: logger, Jobs: jobs}
+}
+
+// ListJobs handles GET /jobs
+// It returns the jobs of the portfolio, newest first.
+// Query parameters: kind, status, before (a job ID, for paging) and limit (default 50).
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query := h.DB.Scopes(auth.InPortfolio(r))
+	if kind := strings.TrimSpace(q.Get("kind")); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if status := strings.TrimSpace(q.Get("status")); status != "" {
+		switch status {
+		case models.JobRunning, models.JobCompleted, models.JobFailed, models.JobCancelled:
+		default:
+			httpx.BadRequest(w, r, "Unknown status '"+status+"'")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	if raw := q.Get("before"); raw != "" {
+		before, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			httpx.BadRequest(w, r, "Invalid before '"+raw+"'. Must be a job ID")
+			return
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	jobs := []models.Job{}
+	if err := query.Order("id desc").Limit(limit).Find(&jobs).Error; err != nil {
+		h.Logger.Error("Failed to fetch jobs", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, jobs)
+}
+
+// GetJob handles GET /jobs/{id}
+func (h *JobHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+	httpx.JSON(w, http.StatusOK, job)
+}
+
+// CancelJob handles POST /jobs/{id}/cancel (editors and owners)
+// The job stops after the item in progress and is returned as it was then.
+func (h *JobHandler) CancelJob(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+	job, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Jobs.Cancel(job.ID); err != nil {
+		if errors.Is(err, services.ErrJobNotRunning) {
+			httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "Job is not running")
+			return
+		}
+		h.Logger.Error("Failed to cancel job", zap.Error(err))
+		httpx.Internal(w, r, "Failed to cancel job")
+		return
+	}
+	h.Logger.Infow("Job cancelled", "job_id", job.ID, "user_id", auth.UserID(r))
+	httpx.JSON(w, http.StatusAccepted, job)
+}
+
+// find loads the job of the {id} URL parameter in the request's portfolio
+func (h *JobHandler) find(w http.ResponseWriter, r *http.Request) (models.Job, bool) {
+	var job models.Job
+	if err := h.DB.Scopes(auth.InPortfolio(r)).First(&job, "id = ?", chi.URLParam(r, "id")).Error; err != nil {
+		httpx.NotFound(w, r, "Job not found")
+		return job, false
+	}
+	return job, true
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/notify"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NotificationHandler manages the notification preferences of the signed-in user
+type NotificationHandler struct {
+	DB            *gorm.DB
+	Logger        *zap.SugaredLogger
+	Notifications *notify.Service
+}
+
+func NewNotificationHandler(db *gorm.DB, logger *zap.SugaredLogger, notifications *notify.Service) *NotificationHandler {
+	return &NotificationHandler{DB: db, Logger: logger, Notifications: notifications}
+}
+
+// NotificationPreferenceRequest is the body of PUT /notifications/{channel}
+type NotificationPreferenceRequest struct {
+	Address string   `json:"address" validate:"max=200"` // e-mail address (default: the account's) or Telegram chat ID
+	Topics  []string `json:"topics"`                     // empty for every topic
+	Enabled *bool    `json:"enabled"`                    // default: true
+}
+
+// NotificationSettings is the response of GET /notifications
+type NotificationSettings struct {
+	Channels    []string                        `json:"channels"` // configured on this server
+	Topics      []string                        `json:"topics"`
+	Preferences []models.NotificationPreference `json:"preferences"`
+}
+
+// TaxSummaryResult is the response of POST /notifications/{channel}/tax-summary
+type TaxSummaryResult struct {
+	Month string `json:"month"`
+	Sent  bool   `json:"sent"` // false when the month had no sales
+}
+
+// ListNotifications handles GET /notifications
+func (h *NotificationHandler) ListNotifications(w http.ResponseWriter, r *http.Request) {
+	prefs := []models.NotificationPreference{}
+	if err := h.DB.Scopes(auth.OwnedByUser(r)).Order("channel").Find(&prefs).Error; err != nil {
+		h.Logger.Error("Failed to fetch notification preferences", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, NotificationSettings{
+		Channels:    notify.Channels(h.Notifications.Notifiers),
+		Topics:      models.NotificationTopics,
+		Preferences: prefs,
+	})
+}
+
+// SaveNotification handles PUT /notifications/{channel}
+// It creates or replaces the user's preference for a configured channel.
+func (h *NotificationHandler) SaveNotification(w http.ResponseWriter, r *http.Request) {
+	channel := chi.URLParam(r, "channel")
+	if h.Notifications.Notifiers[channel] == nil {
+		httpx.NotFound(w, r, "Channel '"+channel+"' is not configured")
+		return
+	}
+
+	var req NotificationPreferenceRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Address = strings.TrimSpace(req.Address)
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+
+	var fields []validate.FieldError
+	switch channel {
+	case models.ChannelEmail:
+		if addr, err := mail.ParseAddress(req.Address); req.Address != "" && (err != nil || addr.Address != req.Address) {
+			fields = append(fields, validate.FieldError{Field: "address", Message: "must be a valid e-mail address"})
+		}
+	case models.ChannelTelegram:
+		if req.Address == "" {
+			fields = append(fields, validate.FieldError{Field: "address", Message: "is required: the chat ID to message"})
+		}
+	}
+	topics := []string{}
+	for i, topic := range req.Topics {
+		switch {
+		case !slices.Contains(models.NotificationTopics, topic):
+			fields = append(fields, validate.FieldError{
+				Field: "topics[" + strconv.Itoa(i) + "]", Message: "must be one of " + strings.Join(models.NotificationTopics, ", "),
+			})
+		case !slices.Contains(topics, topic):
+			topics = append(topics, topic)
+		}
+	}
+	if len(fields) > 0 {
+		httpx.Invalid(w, r, fields)
+		return
+	}
+
+	pref := models.NotificationPreference{UserID: auth.UserID(r), Channel: channel}
+	err := h.DB.FirstOrInit(&pref, "user_id = ? AND channel = ?", pref.UserID, channel).Error
+	if err != nil {
+		h.Logger.Error("Failed to fetch notification preference", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	if pref.CreatedAt.IsZero() {
+		// The summary of the month that just ended is not sent to new preferences
+		pref.TaxSummarySentFor = notify.PreviousMonth(time.Now())
+	}
+	pref.Address = req.Address
+	pref.Topics = topics
+	pref.Enabled = req.Enabled == nil || *req.Enabled
+
+	if err := h.DB.Save(&pref).Error; err != nil {
+		h.Logger.Error("Failed to save notification preference", zap.Error(err))
+		httpx.Internal(w, r, "Failed to save notification preference")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, pref)
+}
+
+// DeleteNotification handles DELETE /notifications/{channel}
+func (h *NotificationHandler) DeleteNotification(w http.ResponseWriter, r *http.Request) {
+	result := h.DB.Scopes(auth.OwnedByUser(r)).Where("channel = ?", chi.URLParam(r, "channel")).
+		Delete(&models.NotificationPreference{})
+	if result.Error != nil {
+		h.Logger.Error("Failed to delete notification preference", zap.Error(result.Error))
+		httpx.Internal(w, r, "Failed to delete notification preference")
+		return
+	}
+	if result.RowsAffected == 0 {
+		httpx.NotFound(w, r, "Notification preference not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TestNotification handles POST /notifications/{channel}/test
+// It sends a test message right away, even if the preference is disabled.
+func (h *NotificationHandler) TestNotification(w http.ResponseWriter, r *http.Request) {
+	pref, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Notifications.SendTest(r.Context(), *auth.UserFrom(r.Context()), pref); err != nil {
+		h.Logger.Warnw("Test notification failed", "channel", pref.Channel, "error", err)
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Could not send the test message: "+err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SendTaxSummary handles POST /notifications/{channel}/tax-summary
+// It sends the tax summary of a month (?month=YYYY-MM, default: the previous
+// one) right away, without changing what the monthly summary sends.
+func (h *NotificationHandler) SendTaxSummary(w http.ResponseWriter, r *http.Request) {
+	pref, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+	month := r.URL.Query().Get("month")
+	if month == "" {
+		month = notify.PreviousMonth(time.Now())
+	} else if _, err := time.Parse("2006-01", month); err != nil {
+		httpx.BadRequest(w, r, "Invalid month '"+month+"'. Use YYYY-MM")
+		return
+	}
+
+	err := h.Notifications.SendTaxSummary(r.Context(), *auth.UserFrom(r.Context()), pref, month)
+	switch {
+	case errors.Is(err, notify.ErrNoSales):
+		httpx.JSON(w, http.StatusOK, TaxSummaryResult{Month: month, Sent: false})
+	case err != nil:
+		h.Logger.Warnw("Tax summary notification failed", "channel", pref.Channel, "month", month, "error", err)
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Could not send the tax summary: "+err.Error())
+	default:
+		httpx.JSON(w, http.StatusOK, TaxSummaryResult{Month: month, Sent: true})
+	}
+}
+
+// find loads the user's preference for the {channel} URL parameter
+func (h *NotificationHandler) find(w http.ResponseWriter, r *http.Request) (models.NotificationPreference, bool) {
+	var pref models.NotificationPreference
+	if err := h.DB.Scopes(auth.OwnedByUser(r)).First(&pref, "channel = ?", chi.URLParam(r, "channel")).Error; err != nil {
+		httpx.NotFound(w, r, "Notification preference not found")
+		return pref, false
+	}
+	return pref, true
+}
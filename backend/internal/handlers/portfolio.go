@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PortfolioHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+}
+
+func NewPortfolioHandler(db *gorm.DB, logger *zap.SugaredLogger) *PortfolioHandler {
+	return &PortfolioHandler{DB: db, Logger: logger}
+}
+
+// PortfolioResponse is a portfolio with the caller's role in it
+type PortfolioResponse struct {
+	models.Portfolio
+	Role models.Role `json:"role"`
+}
+
+// SavePortfolioRequest is the body of POST /portfolios and PUT /portfolios/{id}
+type SavePortfolioRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// MemberResponse is a membership with the member's name and e-mail
+type MemberResponse struct {
+	models.PortfolioMember
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// AddMemberRequest is the body of POST /portfolios/{id}/members. The user must already have an account.
+type AddMemberRequest struct {
+	Email string      `json:"email" validate:"required,email"`
+	Role  models.Role `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+// UpdateMemberRequest is the body of PUT /portfolios/{id}/members/{userID}
+type UpdateMemberRequest struct {
+	Role models.Role `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+// ListPortfolios handles GET /portfolios
+func (h *PortfolioHandler) ListPortfolios(w http.ResponseWriter, r *http.Request) {
+	response := []PortfolioResponse{}
+	err := h.DB.Model(&models.Portfolio{}).
+		Select("portfolios.*, portfolio_members.role").
+		Joins("JOIN portfolio_members ON portfolio_members.portfolio_id = portfolios.id").
+		Where("portfolio_members.user_id = ?", auth.UserID(r)).
+		Order("portfolios.id").
+		Scan(&response).Error
+	if err != nil {
+		h.Logger.Errorw("failed to fetch portfolios", "error", err)
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, response)
+}
+
+// CreatePortfolio handles POST /portfolios. The caller becomes its owner.
+func (h *PortfolioHandler) CreatePortfolio(w http.ResponseWriter, r *http.Request) {
+	var req SavePortfolioRequest
+	if !h.decodeName(w, r, &req) {
+		return
+	}
+
+	var portfolio models.Portfolio
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		portfolio, err = auth.CreatePortfolio(tx, req.Name, auth.UserID(r))
+		return err
+	})
+	if err != nil {
+		h.Logger.Errorw("failed to create portfolio", "error", err)
+		httpx.Internal(w, r, "Failed to create portfolio")
+		return
+	}
+
+	h.Logger.Infow("Portfolio created", "portfolio_id", portfolio.ID, "user_id", auth.UserID(r))
+	httpx.JSON(w, http.StatusCreated, PortfolioResponse{Portfolio: portfolio, Role: models.RoleOwner})
+}
+
+// UpdatePortfolio handles PUT /portfolios/{id} (owners only)
+func (h *PortfolioHandler) UpdatePortfolio(w http.ResponseWriter, r *http.Request) {
+	me, ok := h.membership(w, r, models.RoleOwner)
+	if !ok {
+		return
+	}
+	var req SavePortfolioRequest
+	if !h.decodeName(w, r, &req) {
+		return
+	}
+
+	var portfolio models.Portfolio
+	if err := h.DB.First(&portfolio, me.PortfolioID).Error; err != nil {
+		httpx.NotFound(w, r, "Portfolio not found")
+		return
+	}
+	portfolio.Name = req.Name
+	if err := h.DB.Save(&portfolio).Error; err != nil {
+		h.Logger.Errorw("failed to update portfolio", "error", err)
+		httpx.Internal(w, r, "Failed to update portfolio")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, PortfolioResponse{Portfolio: portfolio, Role: me.Role})
+}
+
+// ListMembers handles GET /portfolios/{id}/members
+func (h *PortfolioHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	me, ok := h.membership(w, r, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	members := []MemberResponse{}
+	err := h.DB.Model(&models.PortfolioMember{}).
+		Select("portfolio_members.*, users.email, users.name").
+		Joins("JOIN users ON users.id = portfolio_members.user_id").
+		Where("portfolio_members.portfolio_id = ?", me.PortfolioID).
+		Order("portfolio_members.created_at").
+		Scan(&members).Error
+	if err != nil {
+		h.Logger.Errorw("failed to fetch members", "error", err)
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, members)
+}
+
+// AddMember handles POST /portfolios/{id}/members (owners only)
+func (h *PortfolioHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	me, ok := h.membership(w, r, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var req AddMemberRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+
+	var user models.User
+	if err := h.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		httpx.NotFound(w, r, "No user with this email")
+		return
+	}
+
+	var count int64
+	h.DB.Model(&models.PortfolioMember{}).Where("portfolio_id = ? AND user_id = ?", me.PortfolioID, user.ID).Count(&count)
+	if count > 0 {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "User is already a member")
+		return
+	}
+
+	member := models.PortfolioMember{
+		PortfolioID: me.PortfolioID,
+		UserID:      user.ID,
+		Role:        req.Role,
+		AddedByID:   me.UserID,
+	}
+	if err := h.DB.Create(&member).Error; err != nil {
+		h.Logger.Errorw("failed to add member", "error", err)
+		httpx.Internal(w, r, "Failed to add member")
+		return
+	}
+
+	h.Logger.Infow("Member added", "portfolio_id", me.PortfolioID, "user_id", user.ID, "role", req.Role, "by", me.UserID)
+	httpx.JSON(w, http.StatusCreated, MemberResponse{PortfolioMember: member, Email: user.Email, Name: user.Name})
+}
+
+// UpdateMember handles PUT /portfolios/{id}/members/{userID} (owners only)
+func (h *PortfolioHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	me, ok := h.membership(w, r, models.RoleOwner)
+	if !ok {
+		return
+	}
+	member, ok := h.member(w, r, me.PortfolioID)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if !httpx.Decode(w, r, &req) {
+		return
+	}
+	if member.Role == models.RoleOwner && req.Role != models.RoleOwner && !h.hasOtherOwner(member) {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "A portfolio needs at least one owner")
+		return
+	}
+
+	member.Role = req.Role
+	if err := h.DB.Save(&member).Error; err != nil {
+		h.Logger.Errorw("failed to update member", "error", err)
+		httpx.Internal(w, r, "Failed to update member")
+		return
+	}
+
+	h.Logger.Infow("Member role changed", "portfolio_id", me.PortfolioID, "user_id", member.UserID, "role", req.Role, "by", me.UserID)
+	httpx.JSON(w, http.StatusOK, member)
+}
+
+// RemoveMember handles DELETE /portfolios/{id}/members/{userID}.
+// Owners remove anyone; other members can only leave.
+func (h *PortfolioHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	me, ok := h.membership(w, r, models.RoleViewer)
+	if !ok {
+		return
+	}
+	member, ok := h.member(w, r, me.PortfolioID)
+	if !ok {
+		return
+	}
+
+	if member.UserID != me.UserID && me.Role != models.RoleOwner {
+		httpx.Forbidden(w, r, "This requires the owner role in the portfolio")
+		return
+	}
+	if member.Role == models.RoleOwner && !h.hasOtherOwner(member) {
+		httpx.Error(w, r, http.StatusConflict, httpx.CodeConflict, "A portfolio needs at least one owner")
+		return
+	}
+
+	if err := h.DB.Delete(&member).Error; err != nil {
+		h.Logger.Errorw("failed to remove member", "error", err)
+		httpx.Internal(w, r, "Failed to remove member")
+		return
+	}
+
+	h.Logger.Infow("Member removed", "portfolio_id", me.PortfolioID, "user_id", member.UserID, "by", me.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *PortfolioHandler) decodeName(w http.ResponseWriter, r *http.Request, req *SavePortfolioRequest) bool {
+	if !httpx.DecodeOnly(w, r, req) {
+		return false
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	return httpx.Validate(w, r, req)
+}
+
+// membership returns the caller's membership in the {id} portfolio, requiring at least min.
+// Portfolios the caller does not belong to are not found.
+func (h *PortfolioHandler) membership(w http.ResponseWriter, r *http.Request, min models.Role) (models.PortfolioMember, bool) {
+	var me models.PortfolioMember
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		httpx.NotFound(w, r, "Portfolio not found")
+		return me, false
+	}
+	if err := h.DB.Where("portfolio_id = ? AND user_id = ?", id, auth.UserID(r)).First(&me).Error; err != nil {
+		httpx.NotFound(w, r, "Portfolio not found")
+		return me, false
+	}
+	if !me.Role.AtLeast(min) {
+		httpx.Forbidden(w, r, "This requires the "+string(min)+" role in the portfolio")
+		return me, false
+	}
+	return me, true
+}
+
+// member returns the {userID} membership of the portfolio
+func (h *PortfolioHandler) member(w http.ResponseWriter, r *http.Request, portfolioID uint) (models.PortfolioMember, bool) {
+	var member models.PortfolioMember
+	err := h.DB.Where("portfolio_id = ? AND user_id = ?", portfolioID, chi.URLParam(r, "userID")).First(&member).Error
+	if err != nil {
+		httpx.NotFound(w, r, "Member not found")
+		return member, false
+	}
+	return member, true
+}
+
+func (h *PortfolioHandler) hasOtherOwner(member models.PortfolioMember) bool {
+	var count int64
+	h.DB.Model(&models.PortfolioMember{}).
+		Where("portfolio_id = ? AND user_id <> ? AND role = ?", member.PortfolioID, member.UserID, models.RoleOwner).
+		Count(&count)
+	return count > 0
+}
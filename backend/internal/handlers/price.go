@@ -1,13 +1,20 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
 	"github.com/Felipalds/gemini-stocks/internal/models"
 	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
@@ -16,114 +23,291 @@ type PriceHandler struct {
 	DB      *gorm.DB
 	Logger  *zap.SugaredLogger
 	Finance *services.FinanceService
+	Symbols *services.SymbolService
+	Events  *events.Bus
+	Jobs    *services.JobService
 }
 
-func NewPriceHandler(db *gorm.DB, logger *zap.SugaredLogger, finance *services.FinanceService) *PriceHandler {
+func NewPriceHandler(db *gorm.DB, logger *zap.SugaredLogger, finance *services.FinanceService, symbols *services.SymbolService, bus *events.Bus, jobs *services.JobService) *PriceHandler {
 	return &PriceHandler{
 		DB:      db,
 		Logger:  logger,
 		Finance: finance,
+		Symbols: symbols,
+		Events:  bus,
+		Jobs:    jobs,
 	}
 }
 
-// RefreshPrices handles POST /prices/refresh
-// It iterates over all known stocks and updates their prices from the API
-// Also updates the USD/BRL exchange rate
-func (h *PriceHandler) RefreshPrices(w http.ResponseWriter, r *http.Request) {
-	h.Logger.Info("Starting manual price update...")
+// RefreshResult counts the outcome of a refresh. It is the response of POST
+// /currencies/refresh and the data of prices.refreshed events.
+type RefreshResult struct {
+	JobID   uint `json:"job_id,omitempty"` // the price refresh job
+	Total   int  `json:"total"`
+	Updated int  `json:"updated"`
+	Failed  int  `json:"failed"`
+	Alerts  int  `json:"alerts,omitempty"` // alerts triggered by the new prices
+}
 
-	// 1. First, update USD/BRL exchange rate
-	h.Logger.Info("Fetching USD/BRL exchange rate...")
-	usdRate, err := h.Finance.GetExchangeRate("USD", "BRL")
-	if err != nil {
-		h.Logger.Warnf("Failed to fetch USD/BRL rate: %v", err)
-	} else {
-		currency := models.Currency{
-			Code:      "USD",
-			Rate:      usdRate,
-			UpdatedAt: time.Now(),
-		}
-		if err := h.DB.Save(&currency).Error; err != nil {
-			h.Logger.Warnf("Failed to save USD rate: %v", err)
-		} else {
-			h.Logger.Infof("USD/BRL rate updated: %.4f", usdRate)
-		}
+// RefreshProgress is the data of prices.refresh_progress events, sent after every ticker
+type RefreshProgress struct {
+	JobID   uint   `json:"job_id"`
+	Symbol  string `json:"symbol"`
+	Status  string `json:"status"` // of the symbol, see models.JobItem
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+	Updated int    `json:"updated"`
+	Failed  int    `json:"failed"`
+}
+
+// RefreshPrices handles POST /prices/refresh (editors and owners)
+// It starts a job that updates the exchange rates to the base currency, then
+// the price of every ticker the portfolio holds or watches with an alert from
+// the API, then checks the portfolio's alert rules against the new prices. Prices still fresh in the
+// quote cache are kept unless force=true. The job is returned right away; while
+// one of the portfolio is running, it is returned instead of starting another.
+func (h *PriceHandler) RefreshPrices(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
 	}
+	force := r.URL.Query().Get("force") == "true"
 
-	// 2. Get all unique stocks from the StockPrice table
+	held := h.DB.Model(&models.Transaction{}).Scopes(auth.InPortfolio(r)).Select("symbol")
+	watched := h.DB.Model(&models.AlertRule{}).Scopes(auth.InPortfolio(r)).Where("enabled = ? AND symbol <> ''", true).Select("symbol")
 	var stocks []models.Ticker
-	if err := h.DB.Find(&stocks).Error; err != nil {
+	if err := h.DB.Where("symbol IN (?) OR symbol IN (?)", held, watched).Order("symbol").Find(&stocks).Error; err != nil {
 		h.Logger.Error("Failed to fetch stocks", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Database error")
 		return
 	}
 
-	// 3. Iterate and Update
-	updatedCount := 0
+	job := models.Job{
+		Kind:        models.JobRefreshPrices,
+		PortfolioID: auth.PortfolioID(r),
+		CreatedByID: auth.UserID(r),
+		Total:       len(stocks),
+		Items:       make([]models.JobItem, len(stocks)),
+	}
+	now := time.Now()
+	for i, stock := range stocks {
+		job.Items[i] = models.JobItem{Symbol: stock.Symbol, Status: models.JobItemPending}
+		if !force && h.Finance.Cache.Fresh(stock, now) {
+			job.Items[i].Status = models.JobItemCached
+			job.Items[i].Price = &stock.Price
+			job.Done++
+		}
+	}
+
+	started, err := h.Jobs.Start(r, &job, h.refresh)
+	if err != nil {
+		h.Logger.Error("Failed to start price refresh", zap.Error(err))
+		httpx.Internal(w, r, "Failed to start price refresh")
+		return
+	}
+	if started {
+		h.Logger.Infow("Price refresh started", "job_id", job.ID, "tickers", job.Total)
+	}
+	w.Header().Set("Location", "/jobs/"+strconv.FormatUint(uint64(job.ID), 10))
+	httpx.JSON(w, http.StatusAccepted, job)
+}
+
+// refresh is the price refresh job. Quotes are fetched concurrently within the
+// provider's quota, and saved one at a time as they arrive. Once cancelled, the
+// remaining tickers are skipped but alerts are still checked against the
+// prices already updated.
+func (h *PriceHandler) refresh(r *http.Request, job *models.Job) error {
+	if _, err := refreshRates(h.DB, r, h.Finance, h.Logger); err != nil {
+		h.Logger.Warnf("Failed to refresh exchange rates: %v", err)
+	}
+
+	items := make(map[string]*models.JobItem, len(job.Items))
+	var symbols []string
+	for i := range job.Items {
+		if job.Items[i].Status == models.JobItemPending {
+			items[job.Items[i].Symbol] = &job.Items[i]
+			symbols = append(symbols, job.Items[i].Symbol)
+		}
+	}
+	// Read again, so tickers deleted since the job started are not recreated
+	var stocks []models.Ticker
+	if err := h.DB.Where("symbol IN ?", symbols).Order("symbol").Find(&stocks).Error; err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(stocks))
 	for _, stock := range stocks {
-		// Call your existing finance service
-		newTicker, err := h.Finance.UpdateTickerFromAPI(stock.Symbol, stock.Currency)
-		if err != nil {
-			h.Logger.Warnf("Failed to update %s: %v", stock.Symbol, err)
-			continue
+		found[stock.Symbol] = true
+	}
+	for _, symbol := range symbols {
+		if !found[symbol] {
+			item := items[symbol]
+			item.Status, item.Error = models.JobItemError, "ticker no longer exists"
+			if err := h.progress(job, item); err != nil {
+				return err
+			}
+		}
+	}
+
+	for result := range h.Finance.FetchQuotes(r.Context(), stocks) {
+		item := items[result.Ticker.Symbol]
+		h.saveQuote(r, item, result)
+		if err := h.progress(job, item); err != nil {
+			return err
 		}
+	}
+	for i := range job.Items {
+		if job.Items[i].Status == models.JobItemPending {
+			job.Items[i].Status = models.JobItemSkipped
+		}
+	}
+	h.Logger.Infow("Price refresh finished", "job_id", job.ID, "updated", job.Updated, "failed", job.Failed)
+
+	triggered, err := evaluateAlerts(h.DB, h.Logger, job.PortfolioID)
+	if err != nil {
+		h.Logger.Warnf("Failed to evaluate alerts: %v", err)
+	}
+	job.Alerts = len(triggered)
 
-		// Update DB
-		stock.Price = newTicker.Price
-		stock.DayChangePercent = newTicker.DayChangePercent
-		h.DB.Save(&stock)
-		updatedCount++
+	// Other portfolios holding the same tickers hear about the new prices
+	// through ticker.updated; the refresh itself is the portfolio's
+	h.Events.Publish(events.PricesRefreshed, job.PortfolioID, RefreshResult{
+		JobID: job.ID, Total: job.Total, Updated: job.Updated, Failed: job.Failed, Alerts: job.Alerts,
+	})
+	for _, e := range triggered {
+		h.Events.Publish(events.AlertTriggered, e.PortfolioID, e)
 	}
+	return nil
+}
+
+// progress counts a finished item, saves the job and publishes its progress
+func (h *PriceHandler) progress(job *models.Job, item *models.JobItem) error {
+	job.Done++
+	if item.Status == models.JobItemUpdated {
+		job.Updated++
+	} else {
+		job.Failed++
+	}
+	if err := h.Jobs.Save(job); err != nil {
+		return err
+	}
+	h.Events.Publish(events.RefreshProgress, job.PortfolioID, RefreshProgress{
+		JobID: job.ID, Symbol: item.Symbol, Status: item.Status,
+		Done: job.Done, Total: job.Total, Updated: job.Updated, Failed: job.Failed,
+	})
+	return nil
+}
 
-	h.Logger.Infof("Updated %d stocks successfully", updatedCount)
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Prices updated successfulssssssy"))
+// saveQuote saves the price fetched for one ticker, recording the outcome in
+// item and publishing the change
+func (h *PriceHandler) saveQuote(r *http.Request, item *models.JobItem, result services.QuoteResult) {
+	if err := result.Err; err != nil {
+		h.Logger.Warnf("Failed to update %s: %v", item.Symbol, err)
+		item.Error = err.Error()
+		switch {
+		case errors.Is(err, services.ErrRateLimited):
+			item.Status = models.JobItemRateLimited
+		case errors.Is(err, services.ErrNotFound):
+			item.Status = models.JobItemNotFound
+		default:
+			item.Status = models.JobItemError
+		}
+		return
+	}
+
+	// Read again, so changes made while the quote was fetched are kept
+	var stock models.Ticker
+	if err := h.DB.First(&stock, "symbol = ?", item.Symbol).Error; err != nil {
+		item.Status, item.Error = models.JobItemError, "ticker no longer exists"
+		return
+	}
+	before := stock
+	stock.Price = result.Quote.Price
+	stock.DayChangePercent = result.Quote.DayChangePercent
+	stock.Source = result.Quote.Source
+	stock.FetchedAt = result.Quote.FetchedAt
+	if err := saveTicker(h.DB, r, before, &stock); err != nil {
+		h.Logger.Warnf("Failed to save %s: %v", stock.Symbol, err)
+		item.Status, item.Error = models.JobItemError, "failed to save the price"
+		return
+	}
+	item.Status = models.JobItemUpdated
+	item.Price = &stock.Price
+	h.Finance.Cache.Mark(&stock)
+	h.Events.Publish(events.TickerUpdated, 0, stock)
 }
 
-// GetAll handles GET /prices
+// GetAll handles GET /prices: the tickers the portfolio has transactions in
 func (h *PriceHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	held := h.DB.Model(&models.Transaction{}).Scopes(auth.InPortfolio(r)).Select("symbol")
 	var prices []models.Ticker
-	if err := h.DB.Find(&prices).Error; err != nil {
+	if err := h.DB.Where("symbol IN (?)", held).Order("symbol").Find(&prices).Error; err != nil {
 		h.Logger.Error("Failed to fetch stock prices", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Database error")
 		return
 	}
+	for i := range prices {
+		h.Finance.Cache.Mark(&prices[i])
+	}
+
+	httpx.JSON(w, http.StatusOK, prices)
+}
+
+// UpdatePriceRequest is the body of PUT /prices. Only the fields sent are changed.
+type UpdatePriceRequest struct {
+	Symbol   string           `json:"symbol" validate:"required"`
+	Price    *decimal.Decimal `json:"price" validate:"gt=0"`
+	Tags     *string          `json:"tags"`
+	Category *string          `json:"category"`
+	Currency *string          `json:"currency" validate:"currency"`
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(prices)
+	Name      *string `json:"name" validate:"max=200"`
+	Exchange  *string `json:"exchange" validate:"max=50"`
+	Country   *string `json:"country" validate:"max=100"`
+	Sector    *string `json:"sector" validate:"max=100"`
+	Industry  *string `json:"industry" validate:"max=100"`
+	AssetType *string `json:"asset_type" validate:"oneof=stock fii etf bdr crypto bond"`
+	ISIN      *string `json:"isin" validate:"isin"`
+	LogoURL   *string `json:"logo_url" validate:"url"`
 }
 
-// UpdatePrice handles PUT /prices
+// classifiesOnly reports whether the request sets nothing but the category and tags
+func (b UpdatePriceRequest) classifiesOnly() bool {
+	return b.Price == nil && b.Currency == nil && b.Name == nil && b.Exchange == nil && b.Country == nil &&
+		b.Sector == nil && b.Industry == nil && b.AssetType == nil && b.ISIN == nil && b.LogoURL == nil
+}
+
+// UpdatePrice handles PUT /prices (admins, or editors setting the category and tags of a ticker the portfolio holds)
 func (h *PriceHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
-	var body struct {
-		Symbol   string   `json:"symbol"`
-		Price    *float64 `json:"price"`
-		Tags     *string  `json:"tags"`
-		Category *string  `json:"category"`
-		Currency *string  `json:"currency"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	var body UpdatePriceRequest
+	if !httpx.Decode(w, r, &body) {
 		return
 	}
 
-	if body.Symbol == "" {
-		http.Error(w, "Symbol is required", http.StatusBadRequest)
+	// Category and tags are how owners organize their holdings (e.g. for goals),
+	// so editors may set them; the price, currency and metadata stay with admins
+	if !body.classifiesOnly() && !canEditMarketData(w, r) {
+		return
+	}
+	if !auth.IsAdmin(r) && !auth.Can(w, r, models.RoleEditor) {
 		return
 	}
 
 	var stock models.Ticker
-	if err := h.DB.First(&stock, "symbol = ?", body.Symbol).Error; err != nil {
-		http.Error(w, "Stock not found", http.StatusNotFound)
+	query := h.DB
+	if !auth.IsAdmin(r) {
+		query = query.Where("symbol IN (?)", h.DB.Model(&models.Transaction{}).Scopes(auth.InPortfolio(r)).Select("symbol"))
+	}
+	if err := query.First(&stock, "symbol = ?", body.Symbol).Error; err != nil {
+		httpx.NotFound(w, r, "Stock not found")
 		return
 	}
 
+	before := stock
 	if body.Tags != nil {
 		tags := strings.TrimSpace(*body.Tags)
 		if tags != "" {
 			parts := strings.Split(tags, ",")
 			if len(parts) > 5 {
-				http.Error(w, "Maximum of 5 tags allowed", http.StatusBadRequest)
+				httpx.Invalid(w, r, []validate.FieldError{{Field: "tags", Message: "must have at most 5 tags"}})
 				return
 			}
 		}
@@ -131,7 +315,10 @@ func (h *PriceHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if body.Price != nil {
+		now := time.Now()
 		stock.Price = *body.Price
+		stock.Source = models.QuoteSourceManual
+		stock.FetchedAt = &now
 	}
 
 	if body.Category != nil {
@@ -139,17 +326,111 @@ func (h *PriceHandler) UpdatePrice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if body.Currency != nil {
-		stock.Currency = strings.TrimSpace(*body.Currency)
+		stock.Currency = strings.ToUpper(strings.TrimSpace(*body.Currency))
+	}
+
+	// Metadata; an empty string clears the field
+	setMetadata := func(field *string, value *string) {
+		if value != nil {
+			*field = strings.TrimSpace(*value)
+		}
 	}
+	setMetadata(&stock.Name, body.Name)
+	setMetadata(&stock.Exchange, body.Exchange)
+	setMetadata(&stock.Country, body.Country)
+	setMetadata(&stock.Sector, body.Sector)
+	setMetadata(&stock.Industry, body.Industry)
+	setMetadata(&stock.AssetType, body.AssetType)
+	setMetadata(&stock.ISIN, body.ISIN)
+	setMetadata(&stock.LogoURL, body.LogoURL)
 
-	if err := h.DB.Save(&stock).Error; err != nil {
+	if err := saveTicker(h.DB, r, before, &stock); err != nil {
 		h.Logger.Error("Failed to update stock price", zap.Error(err))
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Internal Server Error")
 		return
 	}
 
-	h.Logger.Infof("Stock %s updated: price=%.2f, tags=%s", stock.Symbol, stock.Price, stock.Tags)
+	h.Logger.Infof("Stock %s updated: price=%s, tags=%s", stock.Symbol, stock.Price, stock.Tags)
+	h.Finance.Cache.Mark(&stock)
+	h.Events.Publish(events.TickerUpdated, 0, stock)
+
+	httpx.JSON(w, http.StatusOK, stock)
+}
+
+// RefreshMetadataRequest is the body of POST /prices/metadata
+type RefreshMetadataRequest struct {
+	Symbol    string `json:"symbol" validate:"required"`
+	Overwrite bool   `json:"overwrite"` // replace the fields that are already set too
+}
+
+// RefreshMetadata handles POST /prices/metadata (admins). It fills the metadata
+// of a ticker from the provider's company overview, or from its listing where
+// there is no overview (e.g. B3). Only empty fields are filled unless overwrite is set.
+func (h *PriceHandler) RefreshMetadata(w http.ResponseWriter, r *http.Request) {
+	if !canEditMarketData(w, r) {
+		return
+	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stock)
+	var body RefreshMetadataRequest
+	if !httpx.Decode(w, r, &body) {
+		return
+	}
+
+	var stock models.Ticker
+	if err := h.DB.First(&stock, "symbol = ?", body.Symbol).Error; err != nil {
+		httpx.NotFound(w, r, "Stock not found")
+		return
+	}
+
+	metadata, err := h.Finance.Overview(r.Context(), stock.Symbol, stock.Currency)
+	if errors.Is(err, services.ErrNotFound) {
+		var listing *models.SymbolMatch
+		if listing, _, err = h.Symbols.Lookup(r.Context(), stock.Symbol, stock.Currency); err == nil {
+			if listing == nil {
+				httpx.NotFound(w, r, "The provider has no data on "+stock.Symbol)
+				return
+			}
+			metadata = services.ListingMetadata(stock.Symbol, listing)
+		}
+	}
+	if err != nil {
+		h.Logger.Warnf("Failed to fetch metadata for %s: %v", stock.Symbol, err)
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Failed to fetch metadata: "+err.Error())
+		return
+	}
+
+	before := stock
+	services.ApplyMetadata(&stock, metadata, body.Overwrite)
+	now := time.Now()
+	stock.MetadataFetchedAt = &now
+	if err := saveTicker(h.DB, r, before, &stock); err != nil {
+		h.Logger.Error("Failed to save stock metadata", zap.Error(err))
+		httpx.Internal(w, r, "Internal Server Error")
+		return
+	}
+
+	h.Finance.Cache.Mark(&stock)
+	h.Events.Publish(events.TickerUpdated, 0, stock)
+	httpx.JSON(w, http.StatusOK, stock)
+}
+
+// canEditMarketData only lets admins set tickers and exchange rates by hand:
+// they are shared by every portfolio, so a portfolio role is not enough.
+// Otherwise it writes a 403.
+func canEditMarketData(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.IsAdmin(r) {
+		httpx.Forbidden(w, r, "Market data is shared by every portfolio; only admins can change it")
+		return false
+	}
+	return true
+}
+
+// saveTicker saves a changed ticker and records the change in the audit log
+func saveTicker(db *gorm.DB, r *http.Request, before models.Ticker, after *models.Ticker) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(after).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, r, audit.Change{Entity: models.EntityTicker, EntityID: after.Symbol, Before: before, After: *after})
+	})
 }
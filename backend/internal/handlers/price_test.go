@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestUpdatePricePermissions(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := newAPITest(t, db)
+		a.transactions()
+		a.prices()
+
+		ann := a.user("ann@example.com", false) // owner of portfolio 1, which holds AAPL
+		bob := a.user("bob@example.com", false) // owner of portfolio 2, which holds MSFT
+		eve := a.user("eve@example.com", false)
+		a.member(1, "eve@example.com", models.RoleViewer)
+		admin := a.user("admin@example.com", true)
+		a.addTransaction(ann, trade("AAPL", models.Buy, "1", "100", "0", "USD", "2024-01-10"))
+		a.addTransaction(bob, trade("MSFT", models.Buy, "1", "300", "0", "USD", "2024-01-10"))
+
+		tests := []struct {
+			name   string
+			token  string
+			target string
+			body   map[string]any
+			status int
+		}{
+			{"owner sets category and tags", ann, "/prices", map[string]any{"symbol": "AAPL", "category": "tech", "tags": "us,growth"}, http.StatusOK},
+			{"owner cannot set the price", ann, "/prices", map[string]any{"symbol": "AAPL", "price": "1"}, http.StatusForbidden},
+			{"owner cannot set metadata", ann, "/prices", map[string]any{"symbol": "AAPL", "category": "tech", "sector": "Energy"}, http.StatusForbidden},
+			{"owner cannot classify a ticker the portfolio does not hold", ann, "/prices", map[string]any{"symbol": "MSFT", "category": "tech"}, http.StatusNotFound},
+			{"viewer cannot classify", eve, "/prices?portfolio=1", map[string]any{"symbol": "AAPL", "category": "other"}, http.StatusForbidden},
+			{"admin sets the price", admin, "/prices", map[string]any{"symbol": "MSFT", "price": "310"}, http.StatusOK},
+			{"admin classifies any ticker", admin, "/prices", map[string]any{"symbol": "MSFT", "category": "software"}, http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := a.do(tt.token, http.MethodPut, tt.target, tt.body)
+				if w.Code != tt.status {
+					t.Errorf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+				}
+			})
+		}
+
+		var aapl, msft models.Ticker
+		db.First(&aapl, "symbol = ?", "AAPL")
+		db.First(&msft, "symbol = ?", "MSFT")
+		if aapl.Category != "tech" || aapl.Tags != "us,growth" || aapl.Price.String() != "100" || aapl.Sector != "" {
+			t.Errorf("AAPL = %+v, want only the category and tags set", aapl)
+		}
+		if msft.Category != "software" || msft.Price.String() != "310" {
+			t.Errorf("MSFT = %+v, want the admin's category and price", msft)
+		}
+		var entries int64
+		db.Model(&models.AuditEntry{}).Where("entity = ? AND entity_id = ? AND action = ?", models.EntityTicker, "AAPL", models.AuditUpdate).Count(&entries)
+		if entries != 1 {
+			t.Errorf("%d updates of AAPL in the audit log, want 1", entries)
+		}
+	})
+}
+
+// fixedQuotes prices every ticker at 500
+type fixedQuotes struct{}
+
+func (fixedQuotes) Quote(ctx context.Context, ticker models.Ticker) (models.Ticker, error) {
+	now := time.Now()
+	ticker.Price, ticker.Source, ticker.FetchedAt = decimal.NewFromInt(500), models.QuoteSourceAlphaVantage, &now
+	return ticker, nil
+}
+
+func TestRefreshPricesOfPortfolio(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := newAPITest(t, db)
+		a.transactions()
+		a.handle(http.MethodPost, "/prices/refresh", a.prices().RefreshPrices)
+		a.finance.Quotes = fixedQuotes{}
+
+		ann := a.user("ann@example.com", false) // portfolio 1 holds AAPL and watches NVDA
+		bob := a.user("bob@example.com", false) // portfolio 2 holds MSFT
+		a.addTransaction(ann, trade("AAPL", models.Buy, "1", "100", "0", "USD", "2024-01-10"))
+		a.addTransaction(bob, trade("MSFT", models.Buy, "1", "300", "0", "USD", "2024-01-10"))
+		for _, row := range []any{
+			&models.Ticker{Symbol: "NVDA", Currency: "USD", Price: decimal.NewFromInt(100)},
+			&models.Ticker{Symbol: "GOOG", Currency: "USD", Price: decimal.NewFromInt(100)},
+			&models.AlertRule{PortfolioID: 1, Type: models.AlertPriceAbove, Symbol: "NVDA", Threshold: decimal.NewFromInt(1), Enabled: true},
+			&models.AlertRule{PortfolioID: 1, Type: models.AlertPriceAbove, Symbol: "GOOG", Threshold: decimal.NewFromInt(1)},
+			&models.AlertRule{PortfolioID: 2, Type: models.AlertPriceAbove, Symbol: "MSFT", Threshold: decimal.NewFromInt(1), Enabled: true},
+		} {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		var mu sync.Mutex
+		var published []events.Event
+		defer a.bus.Subscribe(func(e events.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			published = append(published, e)
+		})()
+
+		var job models.Job
+		a.ok(a.do(ann, http.MethodPost, "/prices/refresh?force=true", nil), http.StatusAccepted, &job)
+		deadline := time.Now().Add(5 * time.Second)
+		for job.Status == models.JobRunning && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+			if err := db.First(&job, job.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		if job.Status != models.JobCompleted || job.Updated != 2 || job.Alerts != 1 {
+			t.Fatalf("job = %+v, want completed with 2 prices updated and 1 alert", job)
+		}
+
+		var symbols []string
+		for _, item := range job.Items {
+			symbols = append(symbols, item.Symbol)
+		}
+		slices.Sort(symbols)
+		if !slices.Equal(symbols, []string{"AAPL", "NVDA"}) {
+			t.Errorf("job tickers = %q, want the held and the watched one", symbols)
+		}
+		for symbol, price := range map[string]string{"AAPL": "500", "NVDA": "500", "MSFT": "300", "GOOG": "100"} {
+			var ticker models.Ticker
+			if err := db.First(&ticker, "symbol = ?", symbol).Error; err != nil || ticker.Price.String() != price {
+				t.Errorf("%s price = %s (%v), want %s", symbol, ticker.Price, err, price)
+			}
+		}
+
+		var alerts []models.AlertEvent
+		if err := db.Find(&alerts).Error; err != nil {
+			t.Fatal(err)
+		}
+		if len(alerts) != 1 || alerts[0].PortfolioID != 1 || alerts[0].Symbol != "NVDA" {
+			t.Errorf("alerts = %+v, want only portfolio 1's NVDA rule", alerts)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range published {
+			want := uint(1)
+			if e.Type == events.TickerUpdated {
+				want = 0 // prices are shared
+			}
+			if e.PortfolioID != want {
+				t.Errorf("%s event for portfolio %d, want %d", e.Type, e.PortfolioID, want)
+			}
+		}
+	})
+}
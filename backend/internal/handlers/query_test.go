@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// TestListQueries runs the list endpoints with their filters and paging on
+// every engine. Ann (portfolio 1) and Bob (portfolio 2) have rows of every
+// kind; Ann's lists must never show Bob's.
+func TestListQueries(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := newAPITest(t, db)
+		a.transactions()
+		a.prices()
+		portfolios := NewPortfolioHandler(db, a.logger)
+		a.handle(http.MethodGet, "/portfolios", portfolios.ListPortfolios)
+		a.handle(http.MethodGet, "/portfolios/{id}/members", portfolios.ListMembers)
+		a.handle(http.MethodGet, "/transactions/deleted", NewTransactionHandler(db, a.logger, a.finance, a.symbols, a.bus).ListDeleted)
+		a.handle(http.MethodGet, "/jobs", NewJobHandler(db, a.logger, services.NewJobService(db, a.logger)).ListJobs)
+		a.handle(http.MethodGet, "/alerts/events", NewAlertHandler(db, a.logger).ListAlertEvents)
+		a.handle(http.MethodGet, "/webhooks/{id}/deliveries", NewWebhookHandler(db, a.logger, services.NewWebhookService(db, a.logger)).ListDeliveries)
+		currencies := NewCurrencyHandler(db, a.logger, a.finance)
+		a.handle(http.MethodGet, "/currencies/history", currencies.GetHistory)
+		audit := NewAuditHandler(db, a.logger)
+		a.handle(http.MethodGet, "/audit", audit.ListAudit)
+
+		ann := a.user("ann@example.com", false)
+		bob := a.user("bob@example.com", false)
+		eve := a.user("eve@example.com", false)
+		a.member(1, "eve@example.com", models.RoleViewer)
+
+		var annIDs []string
+		for _, tx := range []models.Transaction{
+			trade("AAPL", models.Buy, "10", "100", "0", "USD", "2024-01-10"),
+			trade("MSFT", models.Buy, "5", "300", "0", "USD", "2024-01-11"),
+			trade("PETR4", models.Buy, "100", "30", "0", "BRL", "2024-01-12"),
+		} {
+			annIDs = append(annIDs, a.addTransaction(ann, tx).ID)
+		}
+		bobTx := a.addTransaction(bob, trade("VALE3", models.Buy, "1", "60", "0", "BRL", "2024-01-10"))
+		a.ok(a.do(ann, http.MethodDelete, "/transactions/"+annIDs[0], nil), http.StatusNoContent, nil)
+		a.ok(a.do(ann, http.MethodDelete, "/transactions/"+annIDs[2], nil), http.StatusNoContent, nil)
+		a.ok(a.do(ann, http.MethodDelete, "/transactions/"+bobTx.ID, nil), http.StatusNotFound, nil)
+		a.ok(a.do(bob, http.MethodDelete, "/transactions/"+bobTx.ID, nil), http.StatusNoContent, nil)
+
+		day := func(d int) time.Time { return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC) }
+		hook := models.Webhook{PortfolioID: 1, URL: "https://example.com/hook", Secret: "s", Enabled: true}
+		rows := []any{
+			&models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices, Status: models.JobCompleted},
+			&models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices, Status: models.JobFailed},
+			&models.Job{PortfolioID: 2, Kind: models.JobRefreshPrices, Status: models.JobCompleted},
+			&models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices, Status: models.JobCompleted},
+			&models.AlertEvent{RuleID: 1, PortfolioID: 1, Symbol: "AAPL"},
+			&models.AlertEvent{RuleID: 2, PortfolioID: 1, Symbol: "MSFT"},
+			&models.AlertEvent{RuleID: 3, PortfolioID: 2, Symbol: "VALE3"},
+			&models.AlertEvent{RuleID: 1, PortfolioID: 1, Symbol: "AAPL"},
+			&hook,
+			&models.HistoricalRate{Base: "USD", Quote: "BRL", Date: day(10), Rate: decimal.RequireFromString("4.9")},
+			&models.HistoricalRate{Base: "USD", Quote: "BRL", Date: day(11), Rate: decimal.RequireFromString("4.95")},
+			&models.HistoricalRate{Base: "USD", Quote: "BRL", Date: day(12), Rate: decimal.RequireFromString("5")},
+			&models.HistoricalRate{Base: "EUR", Quote: "BRL", Date: day(11), Rate: decimal.RequireFromString("5.4")},
+		}
+		for _, row := range rows {
+			if err := db.Create(row).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, status := range []string{models.DeliveryDelivered, models.DeliveryFailed, models.DeliveryDelivered} {
+			if err := db.Create(&models.WebhookDelivery{WebhookID: hook.ID, Event: "ping", Status: status}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			name   string
+			token  string
+			target string
+			field  string // of each item, listed in order
+			want   []string
+		}{
+			{"portfolios", ann, "/portfolios", "name", []string{"Personal"}},
+			{"portfolio roles", eve, "/portfolios", "role", []string{"owner", "viewer"}},
+			{"members", ann, "/portfolios/1/members", "email", []string{"ann@example.com", "eve@example.com"}},
+			{"held tickers", ann, "/prices", "symbol", []string{"MSFT"}}, // the others were deleted
+			{"recycle bin", ann, "/transactions/deleted", "ID", []string{annIDs[2], annIDs[0]}},
+			{"jobs", ann, "/jobs", "id", []string{"4", "2", "1"}},
+			{"jobs by status", ann, "/jobs?status=completed", "id", []string{"4", "1"}},
+			{"jobs page", ann, "/jobs?before=4&limit=1", "id", []string{"2"}},
+			{"alert events", ann, "/alerts/events", "id", []string{"4", "2", "1"}},
+			{"alert events of a rule", ann, "/alerts/events?rule_id=1&before=4", "id", []string{"1"}},
+			{"deliveries", ann, "/webhooks/1/deliveries", "id", []string{"3", "2", "1"}},
+			{"deliveries by status", ann, "/webhooks/1/deliveries?status=delivered&limit=1", "id", []string{"3"}},
+			{"rate history", ann, "/currencies/history?base=usd", "rate", []string{"5", "4.95", "4.9"}},
+			{"rate history range", ann, "/currencies/history?start=2024-01-11&end=2024-01-11", "base", []string{"EUR", "USD"}},
+			{"audit", ann, "/audit?entity=transaction&entity_id=" + annIDs[0], "action", []string{"delete", "create"}},
+			{"audit page", ann, "/audit?limit=2", "entity", []string{"transaction", "transaction"}},
+			{"other portfolio's audit", bob, "/audit?entity=transaction", "entity_id", []string{bobTx.ID, bobTx.ID}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var items []map[string]any
+				a.ok(a.do(tt.token, http.MethodGet, tt.target, nil), http.StatusOK, &items)
+				var got []string
+				for _, item := range items {
+					got = append(got, fmt.Sprint(item[tt.field]))
+				}
+				if tt.field == "role" {
+					slices.Sort(got)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("%s = %q, want %q", tt.field, got, tt.want)
+				}
+			})
+		}
+
+		// Another portfolio's webhook is not found
+		if w := a.do(bob, http.MethodGet, "/webhooks/1/deliveries", nil); w.Code != http.StatusNotFound {
+			t.Errorf("Bob's view of Ann's deliveries: status %d, want 404", w.Code)
+		}
+		var history []models.HistoricalRate
+		a.ok(a.do(ann, http.MethodGet, "/currencies/history?base=USD&limit=1", nil), http.StatusOK, &history)
+		if len(history) != 1 || !history[0].Date.Equal(day(12)) {
+			t.Errorf("latest USD rate = %+v, want the one of 2024-01-12", history)
+		}
+	})
+}
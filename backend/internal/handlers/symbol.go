@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SymbolHandler searches the listings of the market data provider
+type SymbolHandler struct {
+	DB      *gorm.DB
+	Logger  *zap.SugaredLogger
+	Symbols *services.SymbolService
+}
+
+func NewSymbolHandler(db *gorm.DB, logger *zap.SugaredLogger, symbols *services.SymbolService) *SymbolHandler {
+	return &SymbolHandler{DB: db, Logger: logger, Symbols: symbols}
+}
+
+// SearchSymbols handles GET /symbols/search?q=
+// It returns the listings matching a symbol or company name, best first.
+// Results are cached, so repeated searches do not spend the provider's quota.
+func (h *SymbolHandler) SearchSymbols(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q == "" {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "q", Message: "is required"}})
+		return
+	}
+
+	matches, err := h.Symbols.Search(r.Context(), q)
+	if err != nil {
+		h.Logger.Warnf("Failed to search symbols for %q: %v", q, err)
+		httpx.Error(w, r, http.StatusBadGateway, httpx.CodeUpstream, "Failed to search symbols: "+err.Error())
+		return
+	}
+	httpx.JSON(w, http.StatusOK, matches)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type TokenHandler struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+}
+
+func NewTokenHandler(db *gorm.DB, logger *zap.SugaredLogger) *TokenHandler {
+	return &TokenHandler{DB: db, Logger: logger}
+}
+
+// CreateTokenRequest is the body of POST /tokens
+type CreateTokenRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // null for a token that never expires
+}
+
+// CreateTokenResponse is returned once, when the token is created. The token cannot be read again.
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+	models.APIToken
+}
+
+// ListTokens handles GET /tokens
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens := []models.APIToken{}
+	if err := h.DB.Scopes(auth.OwnedByUser(r)).Order("created_at desc").Find(&tokens).Error; err != nil {
+		h.Logger.Errorw("failed to fetch tokens", "error", err)
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, tokens)
+}
+
+// CreateToken handles POST /tokens
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req CreateTokenRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if !httpx.Validate(w, r, &req) {
+		return
+	}
+
+	var fields []validate.FieldError
+	scopes := make([]string, 0, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		field := fmt.Sprintf("scopes[%d]", i)
+		switch {
+		case !slices.Contains(auth.Scopes, scope):
+			fields = append(fields, validate.FieldError{Field: field, Message: "must be one of " + strings.Join(auth.Scopes, ", ")})
+		case scope == auth.ScopeAdmin && !auth.UserFrom(r.Context()).IsAdmin:
+			fields = append(fields, validate.FieldError{Field: field, Message: "only admins can grant the admin scope"})
+		case !slices.Contains(scopes, scope):
+			scopes = append(scopes, scope)
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		fields = append(fields, validate.FieldError{Field: "expires_at", Message: "must be in the future"})
+	}
+	if len(fields) > 0 {
+		httpx.Invalid(w, r, fields)
+		return
+	}
+
+	token, hash, err := auth.NewAPIToken()
+	if err != nil {
+		h.Logger.Errorw("failed to generate token", "error", err)
+		httpx.Internal(w, r, "Failed to create token")
+		return
+	}
+
+	apiToken := models.APIToken{
+		UserID:    auth.UserID(r),
+		Name:      req.Name,
+		Prefix:    token[:len(auth.APITokenPrefix)+6],
+		TokenHash: hash,
+		Scopes:    scopes,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := h.DB.Create(&apiToken).Error; err != nil {
+		h.Logger.Errorw("failed to create token", "error", err)
+		httpx.Internal(w, r, "Failed to create token")
+		return
+	}
+
+	h.Logger.Infow("API token created", "user_id", apiToken.UserID, "token_id", apiToken.ID, "scopes", scopes)
+	httpx.JSON(w, http.StatusCreated, CreateTokenResponse{Token: token, APIToken: apiToken})
+}
+
+// RevokeToken handles DELETE /tokens/{id}
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	// Hard delete: a revoked token must never come back
+	result := h.DB.Unscoped().Scopes(auth.OwnedByUser(r)).Where("id = ?", id).Delete(&models.APIToken{})
+	if result.Error != nil {
+		h.Logger.Errorw("failed to revoke token", "error", result.Error)
+		httpx.Internal(w, r, "Failed to revoke token")
+		return
+	}
+	if result.RowsAffected == 0 {
+		httpx.NotFound(w, r, "Token not found")
+		return
+	}
+
+	h.Logger.Infow("API token revoked", "user_id", auth.UserID(r), "token_id", id)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
 	"github.com/Felipalds/gemini-stocks/internal/models"
 	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
 	"github.com/go-chi/chi/v5"
+	"github.com/shopspring/decimal"
 	"github.com/xuri/excelize/v2"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -18,6 +24,7 @@ import (
 
 type ImportRowError struct {
 	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
 	Message string `json:"message"`
 }
 
@@ -32,35 +39,98 @@ type TransactionHandler struct {
 	DB      *gorm.DB
 	Logger  *zap.SugaredLogger
 	Finance *services.FinanceService
+	Symbols *services.SymbolService
+	Events  *events.Bus
 }
 
 type TransactionResponse struct {
 	models.Transaction
-	CurrentPrice float64 `json:"current_price"` // Explicit JSON tag
-	MarketValue  float64 `json:"market_value"`
-	PnL          float64 `json:"pnl"`
-	PnLPercent   float64 `json:"pnl_percent"`
+	CurrentPrice decimal.Decimal `json:"current_price"` // Explicit JSON tag
+	MarketValue  decimal.Decimal `json:"market_value"`
+	PnL          decimal.Decimal `json:"pnl"`
+	PnLPercent   float64         `json:"pnl_percent"`
 }
 
 // NewTransactionHandler is the constructor
-func NewTransactionHandler(db *gorm.DB, logger *zap.SugaredLogger, financeService *services.FinanceService) *TransactionHandler {
+func NewTransactionHandler(db *gorm.DB, logger *zap.SugaredLogger, financeService *services.FinanceService, symbols *services.SymbolService, bus *events.Bus) *TransactionHandler {
 	return &TransactionHandler{
 		DB:      db,
 		Logger:  logger,
 		Finance: financeService,
+		Symbols: symbols,
+		Events:  bus,
+	}
+}
+
+// lookupSymbol checks that a symbol without a ticker yet is listed by the
+// market data provider, in currency when currencyGiven. It returns the
+// listing, or nil for known tickers, for unlisted=true (assets priced by hand)
+// and when the provider cannot be asked. Unknown symbols get a 422 and ok is false.
+func (h *TransactionHandler) lookupSymbol(w http.ResponseWriter, r *http.Request, symbol, currency string, currencyGiven bool) (listing *models.SymbolMatch, ok bool) {
+	var count int64
+	if err := h.DB.Model(&models.Ticker{}).Where("symbol = ?", symbol).Count(&count).Error; err == nil && count > 0 {
+		return nil, true
+	}
+	if r.URL.Query().Get("unlisted") == "true" {
+		return nil, true
+	}
+
+	want := currency
+	if !currencyGiven {
+		want = ""
+	}
+	listing, matches, err := h.Symbols.Lookup(r.Context(), symbol, want)
+	if err != nil {
+		h.Logger.Warnf("Could not look up symbol %s, accepting it: %v", symbol, err)
+		return nil, true
+	}
+	if listing == nil {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "symbol", Message: unknownSymbolMessage(symbol, currency, matches)}})
+		return nil, false
+	}
+	if !models.SupportedCurrencies[listing.Currency] {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "symbol", Message: fmt.Sprintf(
+			"%s is quoted in %s, which is not a supported currency", symbol, listing.Currency)}})
+		return nil, false
+	}
+	return listing, true
+}
+
+// unknownSymbolMessage explains why a symbol was not found, suggesting the listings found instead
+func unknownSymbolMessage(symbol, currency string, matches []models.SymbolMatch) string {
+	var listedIn, similar []string
+	for _, m := range matches {
+		if strings.EqualFold(m.Symbol, symbol) {
+			listedIn = append(listedIn, m.Currency)
+		} else if len(similar) < 3 {
+			similar = append(similar, m.Symbol)
+		}
+	}
+	switch {
+	case len(listedIn) > 0:
+		return fmt.Sprintf("%s is listed in %s, not %s", symbol, strings.Join(listedIn, ", "), currency)
+	case len(similar) > 0:
+		return fmt.Sprintf("unknown symbol %s, did you mean %s? Send unlisted=true for assets priced by hand", symbol, strings.Join(similar, ", "))
+	default:
+		return fmt.Sprintf("unknown symbol %s; send unlisted=true for assets priced by hand", symbol)
 	}
 }
 
 // ensureStockExists checks if a ticker exists in stock_prices; if not, creates it and fetches the price.
-func (h *TransactionHandler) ensureStockExists(symbol, currency string) {
+// Its name, exchange, country and asset type come from listing, when known. If the price cannot be fetched,
+// price stands in for it until the next refresh.
+func (h *TransactionHandler) ensureStockExists(r *http.Request, symbol, currency string, listing *models.SymbolMatch, price decimal.Decimal) {
 	var ticker models.Ticker
 	if err := h.DB.First(&ticker, "symbol = ?", symbol).Error; err != nil {
 		h.Logger.Infof("New stock symbol detected: %s. Fetching initial price...", symbol)
 
-		ticker, err := h.Finance.UpdateTickerFromAPI(symbol, currency)
+		ticker, err := h.Finance.UpdateTickerFromAPI(r.Context(), symbol, currency)
 		if err != nil {
 			h.Logger.Warn("Could not fetch initial price from API", zap.Error(err))
-			ticker.Price = 0
+			ticker = models.Ticker{Price: price}
+			if price.IsPositive() {
+				ticker.Source = models.QuoteSourceTransaction
+			}
 		}
 
 		newStock := models.Ticker{
@@ -68,42 +138,71 @@ func (h *TransactionHandler) ensureStockExists(symbol, currency string) {
 			Price:            ticker.Price,
 			DayChangePercent: ticker.DayChangePercent,
 			Currency:         currency,
+			Source:           ticker.Source,
+			FetchedAt:        ticker.FetchedAt,
 		}
-		if err := h.DB.Create(&newStock).Error; err != nil {
+		services.ApplyMetadata(&newStock, services.ListingMetadata(symbol, listing), false)
+		err = h.DB.Transaction(func(db *gorm.DB) error {
+			if err := db.Create(&newStock).Error; err != nil {
+				return err
+			}
+			return audit.Record(db, r, audit.Change{Entity: models.EntityTicker, EntityID: symbol, After: newStock})
+		})
+		if err != nil {
 			h.Logger.Error("Failed to save new stock ticker", zap.Error(err))
 		}
 	}
 }
 
-// Create handles POST /transactions
+// Create handles POST /transactions (editors and owners)
 func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
 	var tx models.Transaction
 
 	// 1. Decode JSON Body
-	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
-		h.Logger.Error("Failed to decode JSON body", zap.Error(err))
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !httpx.DecodeOnly(w, r, &tx) {
 		return
 	}
+	// The ID and timestamps are the server's, whatever the body says
+	tx.ID, tx.CreatedAt, tx.UpdatedAt, tx.DeletedAt = "", time.Time{}, time.Time{}, gorm.DeletedAt{}
 
-	// 2. Basic Validation
-	if tx.Symbol == "" || tx.Quantity <= 0 || tx.Price <= 0 {
-		http.Error(w, "Symbol, quantity, and price are required", http.StatusBadRequest)
+	// 2. Defaults and Validation (see the validate tags on models.Transaction)
+	currencyGiven := strings.TrimSpace(tx.Currency) != ""
+	normalizeTransaction(&tx)
+	if !httpx.Validate(w, r, &tx) {
 		return
 	}
 
-	// Default currency to USD if not provided
-	if tx.Currency == "" {
-		tx.Currency = "USD"
+	// 3. New symbols must be listed, and set the currency when none was given
+	listing, ok := h.lookupSymbol(w, r, tx.Symbol, tx.Currency, currencyGiven)
+	if !ok {
+		return
+	}
+	if listing != nil && !currencyGiven {
+		tx.Currency = listing.Currency
+		tx.Fee = models.RoundMoney(tx.Fee, tx.Currency)
 	}
 
-	// 3. Ensure the Stock Ticker exists in our Price Cache table
-	h.ensureStockExists(tx.Symbol, tx.Currency)
+	tx.PortfolioID = auth.PortfolioID(r)
+	tx.CreatedByID = auth.UserID(r)
+	tx.UpdatedByID = tx.CreatedByID
 
-	// 4. Save Transaction to Database
-	if err := h.DB.Create(&tx).Error; err != nil {
+	// 4. Ensure the Stock Ticker exists in our Price Cache table
+	h.ensureStockExists(r, tx.Symbol, tx.Currency, listing, tx.Price)
+
+	// 5. Save Transaction to Database, along with its audit entry
+	err := h.DB.Transaction(func(db *gorm.DB) error {
+		if err := db.Create(&tx).Error; err != nil {
+			return err
+		}
+		return audit.Record(db, r, transactionChange(nil, &tx))
+	})
+	if err != nil {
 		h.Logger.Error("Failed to create transaction in DB", zap.Error(err))
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Failed to save transaction")
 		return
 	}
 
@@ -112,59 +211,144 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		"type", tx.Type,
 		"id", tx.ID,
 	)
+	h.Events.Publish(events.TransactionCreated, tx.PortfolioID, tx)
+
+	// 6. Return Response
+	httpx.JSON(w, http.StatusCreated, tx)
+}
+
+// normalizeTransaction fills in the defaults of a transaction payload before validation
+func normalizeTransaction(tx *models.Transaction) {
+	tx.Symbol = strings.TrimSpace(tx.Symbol)
+	tx.Type = models.TransactionType(strings.ToUpper(string(tx.Type)))
+	tx.Currency = strings.ToUpper(strings.TrimSpace(tx.Currency))
+
+	// Default currency to USD if not provided
+	if tx.Currency == "" {
+		tx.Currency = "USD"
+	}
+	tx.Fee = models.RoundMoney(tx.Fee, tx.Currency)
+}
 
-	// 5. Return Response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(tx)
+// transactionChange describes a change for the audit log; before is nil for
+// creates and after is nil for deletes
+func transactionChange(before, after *models.Transaction) audit.Change {
+	c := audit.Change{Entity: models.EntityTransaction}
+	if before != nil {
+		c.EntityID, c.PortfolioID, c.Before = before.ID, before.PortfolioID, *before
+	}
+	if after != nil {
+		c.EntityID, c.PortfolioID, c.After = after.ID, after.PortfolioID, *after
+	}
+	return c
 }
 
 // GetAll handles GET /transactions
-// Complexity: O(N) - Linear time, very fast.
+// Query parameters:
+//   - filters: symbol, type, currency, category, q (note search), from, to (YYYY-MM-DD)
+//   - sort: date, symbol, type, quantity, price or created_at, prefixed with - for descending (default: -date)
+//   - limit and cursor: keyset pagination. Without them every matching transaction is returned.
+//
+// The total number of matches is sent in X-Total-Count and the next page in X-Next-Cursor and Link.
 func (h *TransactionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
-	// 1. Fetch ALL transactions (Query #1)
+	q := r.URL.Query()
+
+	filter, err := parseTransactionFilter(q)
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	sort, err := parseTransactionSort(q.Get("sort"), "-date")
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	cursor := q.Get("cursor")
+	if cursor != "" && limit == 0 {
+		limit = defaultPageSize
+	}
+
+	// 1. Count every match, regardless of the page
+	var total int64
+	if err := filter.apply(h.DB.Model(&models.Transaction{}).Scopes(auth.InPortfolio(r))).Count(&total).Error; err != nil {
+		h.Logger.Error("Failed to count transactions", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	// 2. Fetch the page. One extra row tells us whether there is a next page.
+	query := sort.apply(filter.apply(h.DB.Scopes(auth.InPortfolio(r))))
+	if cursor != "" {
+		query, err = applyTransactionCursor(query, sort, cursor)
+		if err != nil {
+			httpx.BadRequest(w, r, err.Error())
+			return
+		}
+	}
+	if limit > 0 {
+		query = query.Limit(limit + 1)
+	}
+
 	var transactions []models.Transaction
-	if err := h.DB.Find(&transactions).Error; err != nil {
+	if err := query.Find(&transactions).Error; err != nil {
 		h.Logger.Error("Failed to fetch transactions", zap.Error(err))
-		http.Error(w, "Database error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Database error")
 		return
 	}
 
-	// 2. Fetch ALL cached prices (Query #2)
-	// We get everything in one shot instead of querying inside the loop
+	nextCursor := ""
+	if limit > 0 && len(transactions) > limit {
+		transactions = transactions[:limit]
+		nextCursor = newTransactionCursor(sort, transactions[len(transactions)-1])
+	}
+
+	// 3. Fetch the cached prices of the symbols on this page in one shot
+	// instead of querying inside the loop
+	symbols := make([]string, 0, len(transactions))
+	seen := make(map[string]bool)
+	for _, t := range transactions {
+		if !seen[t.Symbol] {
+			seen[t.Symbol] = true
+			symbols = append(symbols, t.Symbol)
+		}
+	}
+
 	var stockPrices []models.Ticker
-	if err := h.DB.Find(&stockPrices).Error; err != nil {
-		h.Logger.Warn("Failed to fetch stock prices", zap.Error(err))
-		// We continue; we just won't have price data
+	if len(symbols) > 0 {
+		if err := h.DB.Where("symbol IN ?", symbols).Find(&stockPrices).Error; err != nil {
+			h.Logger.Warn("Failed to fetch stock prices", zap.Error(err))
+			// We continue; we just won't have price data
+		}
 	}
 
-	// 3. Create a Lookup Map (O(M))
+	// 4. Create a Lookup Map (O(M))
 	// This allows O(1) access time later
-	priceMap := make(map[string]float64)
+	priceMap := make(map[string]decimal.Decimal)
 	for _, s := range stockPrices {
 		priceMap[s.Symbol] = s.Price
 	}
 
-	// 4. Merge Data (O(N))
-	var response []TransactionResponse
+	// 5. Merge Data (O(N))
+	response := make([]TransactionResponse, 0, len(transactions))
 	for _, t := range transactions {
 		// O(1) Lookup
 		currentPrice := priceMap[t.Symbol]
 
-		// Calculate PnL
-		marketValue := 0.0
-		pnl := 0.0
+		// Calculate PnL, rounded to the transaction's currency
+		var marketValue, pnl decimal.Decimal
 		pnlPercent := 0.0
 
 		// Only calculate if we have a valid price > 0
-		if currentPrice > 0 {
-			marketValue = currentPrice * float64(t.Quantity)
-			costBasis := t.Price * float64(t.Quantity)
-			pnl = marketValue - costBasis - t.Fee
-
-			if costBasis > 0 {
-				pnlPercent = (pnl / costBasis) * 100
-			}
+		if currentPrice.IsPositive() {
+			marketValue = models.RoundMoney(currentPrice.Mul(t.Quantity), t.Currency)
+			costBasis := models.RoundMoney(t.Price.Mul(t.Quantity), t.Currency)
+			pnl = marketValue.Sub(costBasis).Sub(t.Fee)
+			pnlPercent = services.PercentOf(pnl, costBasis)
 		}
 
 		response = append(response, TransactionResponse{
@@ -176,31 +360,61 @@ func (h *TransactionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
-	// 5. Return JSON
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	// 6. Return JSON
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if nextCursor != "" {
+		next := *r.URL
+		nq := next.Query()
+		nq.Set("cursor", nextCursor)
+		nq.Set("limit", strconv.Itoa(limit))
+		next.RawQuery = nq.Encode()
+
+		w.Header().Set("X-Next-Cursor", nextCursor)
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+	}
+	httpx.JSON(w, http.StatusOK, response)
 }
 
-// Update handles PUT /transactions/{id}
+// Update handles PUT /transactions/{id} (editors and owners)
 func (h *TransactionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
 	id := chi.URLParam(r, "id")
 
 	// 1. Find existing transaction
 	var existing models.Transaction
-	if err := h.DB.First(&existing, "id = ?", id).Error; err != nil {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	if err := h.DB.Scopes(auth.InPortfolio(r)).First(&existing, "id = ?", id).Error; err != nil {
+		httpx.NotFound(w, r, "Transaction not found")
 		return
 	}
 
-	// 2. Decode request body
+	// 2. Decode and validate request body
 	var body models.Transaction
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		h.Logger.Error("Failed to decode JSON body", zap.Error(err))
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !httpx.DecodeOnly(w, r, &body) {
+		return
+	}
+	currencyGiven := strings.TrimSpace(body.Currency) != ""
+	normalizeTransaction(&body)
+	if !httpx.Validate(w, r, &body) {
 		return
 	}
 
-	// 3. Update fields
+	// 3. A new symbol or currency is checked like on create, and gets its ticker
+	if body.Symbol != existing.Symbol || body.Currency != existing.Currency {
+		listing, ok := h.lookupSymbol(w, r, body.Symbol, body.Currency, currencyGiven)
+		if !ok {
+			return
+		}
+		if listing != nil && !currencyGiven {
+			body.Currency = listing.Currency
+			body.Fee = models.RoundMoney(body.Fee, body.Currency)
+		}
+		h.ensureStockExists(r, body.Symbol, body.Currency, listing, body.Price)
+	}
+
+	// 4. Update fields, keeping the previous values for the audit log
+	before := existing
 	existing.Symbol = body.Symbol
 	existing.Type = body.Type
 	existing.Quantity = body.Quantity
@@ -209,46 +423,65 @@ func (h *TransactionHandler) Update(w http.ResponseWriter, r *http.Request) {
 	existing.Currency = body.Currency
 	existing.Date = body.Date
 	existing.Note = body.Note
+	existing.UpdatedByID = auth.UserID(r)
 
-	if existing.Currency == "" {
-		existing.Currency = "USD"
-	}
-
-	// 4. Save
-	if err := h.DB.Save(&existing).Error; err != nil {
+	// 5. Save
+	err := h.DB.Transaction(func(db *gorm.DB) error {
+		if err := db.Save(&existing).Error; err != nil {
+			return err
+		}
+		return audit.Record(db, r, transactionChange(&before, &existing))
+	})
+	if err != nil {
 		h.Logger.Error("Failed to update transaction", zap.Error(err))
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		httpx.Internal(w, r, "Failed to update transaction")
 		return
 	}
 
 	h.Logger.Infof("Transaction %s updated successfully", id)
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(existing)
+	h.Events.Publish(events.TransactionUpdated, existing.PortfolioID, existing)
+	httpx.JSON(w, http.StatusOK, existing)
 }
 
-// ImportExcel handles POST /transactions/import
+// ImportExcel handles POST /transactions/import (editors and owners)
 func (h *TransactionHandler) ImportExcel(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+
 	// 1. Parse multipart form (10MB limit)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
-		http.Error(w, "File too large or invalid form", http.StatusBadRequest)
+		httpx.BadRequest(w, r, "File too large or invalid form")
 		return
 	}
 
 	symbol := strings.TrimSpace(strings.ToUpper(r.FormValue("symbol")))
 	currency := strings.TrimSpace(strings.ToUpper(r.FormValue("currency")))
+	currencyGiven := currency != ""
 
 	if symbol == "" {
-		http.Error(w, "Symbol is required", http.StatusBadRequest)
+		httpx.BadRequest(w, r, "Symbol is required")
 		return
 	}
 	if currency == "" {
 		currency = "USD"
 	}
+	if !models.SupportedCurrencies[currency] {
+		httpx.Invalid(w, r, []validate.FieldError{{Field: "currency", Message: fmt.Sprintf("unknown currency '%s'", currency)}})
+		return
+	}
+	listing, ok := h.lookupSymbol(w, r, symbol, currency, currencyGiven)
+	if !ok {
+		return
+	}
+	if listing != nil && !currencyGiven {
+		currency = listing.Currency
+	}
 
 	// 2. Get the uploaded file
 	file, _, err := r.FormFile("file")
 	if err != nil {
-		http.Error(w, "File is required", http.StatusBadRequest)
+		httpx.BadRequest(w, r, "File is required")
 		return
 	}
 	defer file.Close()
@@ -256,19 +489,19 @@ func (h *TransactionHandler) ImportExcel(w http.ResponseWriter, r *http.Request)
 	// 3. Open with excelize
 	f, err := excelize.OpenReader(file)
 	if err != nil {
-		http.Error(w, "Invalid Excel file", http.StatusBadRequest)
+		httpx.BadRequest(w, r, "Invalid Excel file")
 		return
 	}
 	defer f.Close()
 
 	// 4. Ensure ticker exists (reuse shared logic)
-	h.ensureStockExists(symbol, currency)
+	h.ensureStockExists(r, symbol, currency, listing, decimal.Zero)
 
 	// 5. Read rows from the first sheet
 	sheetName := f.GetSheetName(0)
 	rows, err := f.GetRows(sheetName)
 	if err != nil {
-		http.Error(w, "Could not read spreadsheet", http.StatusBadRequest)
+		httpx.BadRequest(w, r, "Could not read spreadsheet")
 		return
 	}
 
@@ -290,61 +523,25 @@ func (h *TransactionHandler) ImportExcel(w http.ResponseWriter, r *http.Request)
 			continue
 		}
 
-		// Parse Date (YYYY-MM-DD)
-		date, err := time.Parse("2006-01-02", strings.TrimSpace(row[0]))
-		if err != nil {
-			result.Failed++
-			result.Errors = append(result.Errors, ImportRowError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("Invalid date '%s'. Expected format: YYYY-MM-DD", row[0]),
-			})
-			continue
-		}
-
-		// Parse Quantity (float)
-		quantity, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 32)
-		if err != nil || quantity <= 0 {
-			result.Failed++
-			result.Errors = append(result.Errors, ImportRowError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("Invalid quantity '%s'. Must be a positive number", row[1]),
-			})
-			continue
-		}
-
-		// Parse Price (float)
-		price, err := strconv.ParseFloat(strings.TrimSpace(row[2]), 64)
-		if err != nil || price <= 0 {
-			result.Failed++
-			result.Errors = append(result.Errors, ImportRowError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("Invalid price '%s'. Must be a positive number", row[2]),
-			})
-			continue
-		}
-
-		// Parse Fee (float)
-		fee, err := strconv.ParseFloat(strings.TrimSpace(row[3]), 64)
-		if err != nil || fee < 0 {
+		tx, fields := parseImportRow(row, symbol, currency)
+		if len(fields) > 0 {
 			result.Failed++
-			result.Errors = append(result.Errors, ImportRowError{
-				Row:     rowNum,
-				Message: fmt.Sprintf("Invalid fee '%s'. Must be a non-negative number", row[3]),
-			})
+			for _, f := range fields {
+				result.Errors = append(result.Errors, ImportRowError{Row: rowNum, Field: f.Field, Message: f.Message})
+			}
 			continue
 		}
+		tx.PortfolioID = auth.PortfolioID(r)
+		tx.CreatedByID = auth.UserID(r)
+		tx.UpdatedByID = tx.CreatedByID
 
-		tx := models.Transaction{
-			Symbol:   symbol,
-			Type:     models.Buy,
-			Quantity: float32(quantity),
-			Price:    price,
-			Fee:      fee,
-			Currency: currency,
-			Date:     date,
-		}
-
-		if err := h.DB.Create(&tx).Error; err != nil {
+		err := h.DB.Transaction(func(db *gorm.DB) error {
+			if err := db.Create(&tx).Error; err != nil {
+				return err
+			}
+			return audit.Record(db, r, transactionChange(nil, &tx))
+		})
+		if err != nil {
 			result.Failed++
 			result.Errors = append(result.Errors, ImportRowError{
 				Row:     rowNum,
@@ -354,36 +551,82 @@ func (h *TransactionHandler) ImportExcel(w http.ResponseWriter, r *http.Request)
 		}
 
 		result.Imported++
+		h.Events.Publish(events.TransactionCreated, tx.PortfolioID, tx)
 	}
 
 	h.Logger.Infof("Excel import for %s: %d imported, %d failed", symbol, result.Imported, result.Failed)
 
-	w.Header().Set("Content-Type", "application/json")
+	status := http.StatusOK
 	if result.Imported == 0 && result.Failed > 0 {
-		w.WriteHeader(http.StatusBadRequest)
+		status = http.StatusBadRequest
+	}
+	httpx.JSON(w, status, result)
+}
+
+// parseImportRow reads a Date, Quantity, Price, Fee row of an import into a
+// purchase and checks it like a transaction sent as JSON. Cells that can't be
+// parsed are reported along with the rules the others break.
+func parseImportRow(row []string, symbol, currency string) (models.Transaction, []validate.FieldError) {
+	var fields []validate.FieldError
+	parseDecimal := func(field, cell string) decimal.Decimal {
+		d, err := decimal.NewFromString(strings.TrimSpace(cell))
+		if err != nil {
+			fields = append(fields, validate.FieldError{Field: field, Message: fmt.Sprintf("'%s' is not a number", cell)})
+		}
+		return d
+	}
+
+	tx := models.Transaction{
+		Symbol:   symbol,
+		Type:     models.Buy,
+		Quantity: parseDecimal("quantity", row[1]),
+		Price:    parseDecimal("price", row[2]),
+		Fee:      parseDecimal("fee", row[3]),
+		Currency: currency,
+	}
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(row[0]))
+	if err != nil {
+		fields = append(fields, validate.FieldError{Field: "date", Message: fmt.Sprintf("'%s' is not a date; expected YYYY-MM-DD", row[0])})
 	}
-	json.NewEncoder(w).Encode(result)
+	tx.Date = date
+	normalizeTransaction(&tx)
+
+	for _, f := range validate.Struct(&tx) {
+		if !slices.ContainsFunc(fields, func(e validate.FieldError) bool { return e.Field == f.Field }) {
+			fields = append(fields, f)
+		}
+	}
+	return tx, fields
 }
 
-// Delete handles DELETE /transactions/{id}
+// Delete handles DELETE /transactions/{id} (editors and owners)
 func (h *TransactionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
 	id := chi.URLParam(r, "id")
 
-	// Correção: Usar .Where("id = ?", id) explicitamente
-	// Isso garante que o GORM nunca tente rodar um DELETE sem cláusula WHERE
-	result := h.DB.Where("id = ?", id).Delete(&models.Transaction{})
-
-	if result.Error != nil {
-		h.Logger.Error("Failed to delete transaction", zap.Error(result.Error))
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	var existing models.Transaction
+	if err := h.DB.Scopes(auth.InPortfolio(r)).First(&existing, "id = ?", id).Error; err != nil {
+		httpx.NotFound(w, r, "Transaction not found")
 		return
 	}
 
-	if result.RowsAffected == 0 {
-		http.Error(w, "Transaction not found", http.StatusNotFound)
+	// Correção: Usar .Where("id = ?", id) explicitamente
+	// Isso garante que o GORM nunca tente rodar um DELETE sem cláusula WHERE
+	err := h.DB.Transaction(func(db *gorm.DB) error {
+		if err := db.Where("id = ?", existing.ID).Delete(&models.Transaction{}).Error; err != nil {
+			return err
+		}
+		return audit.Record(db, r, transactionChange(&existing, nil))
+	})
+	if err != nil {
+		h.Logger.Error("Failed to delete transaction", zap.Error(err))
+		httpx.Internal(w, r, "Failed to delete transaction")
 		return
 	}
 
 	h.Logger.Infof("Transaction %s deleted successfully", id)
+	h.Events.Publish(events.TransactionDeleted, existing.PortfolioID, existing)
 	w.WriteHeader(http.StatusNoContent) // 204 No Content
 }
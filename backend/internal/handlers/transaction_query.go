@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/database"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Pagination limits for GET /transactions
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// transactionSortColumns maps the ?sort= keys to their database columns
+var transactionSortColumns = map[string]string{
+	"date":       "date",
+	"symbol":     "symbol",
+	"type":       "type",
+	"quantity":   "quantity",
+	"price":      "price",
+	"created_at": "created_at",
+}
+
+// transactionFilter holds the query parameters shared by the transaction list and export:
+// symbol, type, currency, category, q (note search), from and to (YYYY-MM-DD, inclusive)
+type transactionFilter struct {
+	Symbol   string
+	Type     string
+	Currency string
+	Category string
+	Search   string
+	From     *time.Time
+	To       *time.Time
+}
+
+func parseTransactionFilter(q url.Values) (transactionFilter, error) {
+	f := transactionFilter{
+		Symbol:   strings.TrimSpace(strings.ToUpper(q.Get("symbol"))),
+		Type:     strings.TrimSpace(strings.ToUpper(q.Get("type"))),
+		Currency: strings.TrimSpace(strings.ToUpper(q.Get("currency"))),
+		Category: strings.TrimSpace(q.Get("category")),
+		Search:   strings.TrimSpace(q.Get("q")),
+	}
+
+	if f.Type != "" && f.Type != string(models.Buy) && f.Type != string(models.Sell) {
+		return f, fmt.Errorf("invalid type '%s'. Use BUY or SELL", f.Type)
+	}
+
+	if from := q.Get("from"); from != "" {
+		date, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return f, fmt.Errorf("invalid 'from' date. Expected format: YYYY-MM-DD")
+		}
+		f.From = &date
+	}
+	if to := q.Get("to"); to != "" {
+		date, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return f, fmt.Errorf("invalid 'to' date. Expected format: YYYY-MM-DD")
+		}
+		f.To = &date
+	}
+
+	return f, nil
+}
+
+// apply adds the filter conditions to the query
+func (f transactionFilter) apply(query *gorm.DB) *gorm.DB {
+	if f.Symbol != "" {
+		query = query.Where("symbol = ?", f.Symbol)
+	}
+	if f.Type != "" {
+		query = query.Where("type = ?", f.Type)
+	}
+	if f.Currency != "" {
+		query = query.Where("currency = ?", f.Currency)
+	}
+	if f.Category != "" {
+		query = query.Where("symbol IN (?)", query.Session(&gorm.Session{NewDB: true}).
+			Model(&models.Ticker{}).Select("symbol").Where("category = ?", f.Category))
+	}
+	if f.Search != "" {
+		query = query.Where("LOWER(note) LIKE ? ESCAPE '\\'", "%"+escapeLike(strings.ToLower(f.Search))+"%")
+	}
+	if f.From != nil {
+		query = query.Where("date >= ?", *f.From)
+	}
+	if f.To != nil {
+		// Inclusive: everything before the start of the next day
+		query = query.Where("date < ?", f.To.AddDate(0, 0, 1))
+	}
+	return query
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(s)
+}
+
+// transactionSort is a parsed ?sort= value, e.g. "-date" (descending) or "symbol"
+type transactionSort struct {
+	Key  string
+	Desc bool
+}
+
+func parseTransactionSort(s string, fallback string) (transactionSort, error) {
+	if s == "" {
+		s = fallback
+	}
+	sort := transactionSort{Key: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	if _, ok := transactionSortColumns[sort.Key]; !ok {
+		return sort, fmt.Errorf("invalid sort '%s'. Use date, symbol, type, quantity, price or created_at, prefixed with - for descending", s)
+	}
+	return sort, nil
+}
+
+func (s transactionSort) String() string {
+	if s.Desc {
+		return "-" + s.Key
+	}
+	return s.Key
+}
+
+// column returns the expression the query is ordered by. SQLite stores
+// decimals as text (see migration 14), so they are compared as numbers there.
+func (s transactionSort) column(query *gorm.DB) string {
+	column := transactionSortColumns[s.Key]
+	if s.numeric() && !database.IsPostgres(query) {
+		return "CAST(" + column + " AS NUMERIC)"
+	}
+	return column
+}
+
+func (s transactionSort) numeric() bool {
+	return s.Key == "quantity" || s.Key == "price"
+}
+
+// apply orders the query by the sort column, with the ID as a tie-breaker
+// so that the order (and the cursor) is stable
+func (s transactionSort) apply(query *gorm.DB) *gorm.DB {
+	dir := "ASC"
+	if s.Desc {
+		dir = "DESC"
+	}
+	return query.Order(fmt.Sprintf("%s %s, id %s", s.column(query), dir, dir))
+}
+
+// transactionCursor points right after the last row of a page (keyset pagination).
+// It is sent to clients as an opaque base64 string.
+type transactionCursor struct {
+	Sort  string `json:"s"`
+	Value any    `json:"v"`
+	ID    string `json:"id"`
+}
+
+func newTransactionCursor(sort transactionSort, last models.Transaction) string {
+	var value any
+	switch sort.Key {
+	case "date":
+		value = last.Date
+	case "created_at":
+		value = last.CreatedAt
+	case "symbol":
+		value = last.Symbol
+	case "type":
+		value = string(last.Type)
+	case "quantity":
+		value = last.Quantity
+	case "price":
+		value = last.Price
+	}
+
+	raw, _ := json.Marshal(transactionCursor{Sort: sort.String(), Value: value, ID: last.ID})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// applyTransactionCursor restricts the query to the rows after the cursor
+func applyTransactionCursor(query *gorm.DB, sort transactionSort, encoded string) (*gorm.DB, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	var c struct {
+		Sort  string          `json:"s"`
+		Value json.RawMessage `json:"v"`
+		ID    string          `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sort.String() {
+		return nil, fmt.Errorf("cursor was created with sort '%s', not '%s'", c.Sort, sort.String())
+	}
+
+	// Decode the value back to the column type so the comparison matches the stored data
+	var value any
+	switch sort.Key {
+	case "date", "created_at":
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		value = t
+	case "quantity", "price":
+		var n decimal.Decimal
+		err = json.Unmarshal(c.Value, &n)
+		value = n
+	default:
+		var s string
+		err = json.Unmarshal(c.Value, &s)
+		value = s
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	op := ">"
+	if sort.Desc {
+		op = "<"
+	}
+	column, param := sort.column(query), "?"
+	if sort.numeric() && !database.IsPostgres(query) {
+		param = "CAST(? AS NUMERIC)" // the cursor value is bound as text too
+	}
+	return query.Where(
+		fmt.Sprintf("(%s %s %s) OR (%s = %s AND id %s ?)", column, op, param, column, param, op),
+		value, value, c.ID,
+	), nil
+}
+
+// parseLimit reads ?limit=. Zero means no limit was requested.
+func parseLimit(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit '%s'. Must be a positive number", s)
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, nil
+}
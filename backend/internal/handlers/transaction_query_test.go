@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"cmp"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// queryTest is a portfolio of four transactions, plus one in another user's portfolio
+func queryTest(t *testing.T, db *gorm.DB) (*apiTest, string) {
+	a := newAPITest(t, db)
+	a.transactions()
+	token := a.user("ann@example.com", false)
+
+	withNote := func(tx models.Transaction, note string) models.Transaction {
+		tx.Note = note
+		return tx
+	}
+	for _, tx := range []models.Transaction{
+		withNote(trade("AAPL", models.Buy, "10", "100", "0", "USD", "2024-01-10"), "first buy"),
+		withNote(trade("AAPL", models.Sell, "50", "150", "0", "USD", "2024-02-15"), "took 50%_profit"),
+		trade("PETR4", models.Buy, "20", "120", "0", "BRL", "2024-03-01"),
+		withNote(trade("MSFT", models.Buy, "30", "300", "0", "USD", "2024-03-05"), "Index rebalance"),
+	} {
+		a.addTransaction(token, tx)
+	}
+	a.addTransaction(a.user("bob@example.com", false), trade("AAPL", models.Buy, "1", "1", "0", "USD", "2024-01-01"))
+
+	if err := db.Model(&models.Ticker{}).Where("symbol = ?", "AAPL").Update("category", "tech").Error; err != nil {
+		t.Fatal(err)
+	}
+	return a, token
+}
+
+// label names a transaction by its symbol and date, e.g. "AAPL 2024-01-10"
+func label(tx TransactionResponse) string {
+	return tx.Symbol + " " + tx.Date.Format("2006-01-02")
+}
+
+func TestTransactionFilters(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a, token := queryTest(t, db)
+
+		tests := []struct {
+			query string
+			want  []string
+		}{
+			{"", []string{"AAPL 2024-01-10", "AAPL 2024-02-15", "MSFT 2024-03-05", "PETR4 2024-03-01"}},
+			{"symbol=aapl", []string{"AAPL 2024-01-10", "AAPL 2024-02-15"}},
+			{"type=sell", []string{"AAPL 2024-02-15"}},
+			{"currency=brl", []string{"PETR4 2024-03-01"}},
+			{"category=tech", []string{"AAPL 2024-01-10", "AAPL 2024-02-15"}},
+			{"category=energy", nil},
+			{"q=INDEX", []string{"MSFT 2024-03-05"}},
+			{"q=" + url.QueryEscape("%"), []string{"AAPL 2024-02-15"}},
+			{"q=_", []string{"AAPL 2024-02-15"}},
+			{"from=2024-02-15", []string{"AAPL 2024-02-15", "MSFT 2024-03-05", "PETR4 2024-03-01"}},
+			{"to=2024-02-15", []string{"AAPL 2024-01-10", "AAPL 2024-02-15"}},
+			{"from=2024-02-16&to=2024-03-01", []string{"PETR4 2024-03-01"}},
+			{"symbol=AAPL&type=BUY&q=first", []string{"AAPL 2024-01-10"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				var page []TransactionResponse
+				w := a.do(token, http.MethodGet, "/transactions?"+tt.query, nil)
+				a.ok(w, http.StatusOK, &page)
+				var got []string
+				for _, tx := range page {
+					got = append(got, label(tx))
+				}
+				slices.Sort(got)
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+				if total := w.Header().Get("X-Total-Count"); total != itoa(uint(len(tt.want))) {
+					t.Errorf("X-Total-Count = %s, want %d", total, len(tt.want))
+				}
+			})
+		}
+	})
+}
+
+func TestTransactionSortAndCursor(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a, token := queryTest(t, db)
+
+		var all []TransactionResponse
+		a.ok(a.do(token, http.MethodGet, "/transactions", nil), http.StatusOK, &all)
+
+		keys := map[string]func(x, y TransactionResponse) int{
+			"date":       func(x, y TransactionResponse) int { return x.Date.Compare(y.Date) },
+			"created_at": func(x, y TransactionResponse) int { return x.CreatedAt.Compare(y.CreatedAt) },
+			"symbol":     func(x, y TransactionResponse) int { return cmp.Compare(x.Symbol, y.Symbol) },
+			"type":       func(x, y TransactionResponse) int { return cmp.Compare(x.Type, y.Type) },
+			"quantity":   func(x, y TransactionResponse) int { return x.Quantity.Cmp(y.Quantity) },
+			"price":      func(x, y TransactionResponse) int { return x.Price.Cmp(y.Price) },
+		}
+		for key, compare := range keys {
+			for _, sort := range []string{key, "-" + key} {
+				t.Run(sort, func(t *testing.T) {
+					// The expected order, with the ID as the tie-breaker
+					want := slices.Clone(all)
+					slices.SortStableFunc(want, func(x, y TransactionResponse) int {
+						c := cmp.Or(compare(x, y), cmp.Compare(x.ID, y.ID))
+						if strings.HasPrefix(sort, "-") {
+							return -c
+						}
+						return c
+					})
+
+					// One row per page, following the cursor to the end
+					var got []TransactionResponse
+					target := "/transactions?limit=1&sort=" + sort
+					for range len(all) + 1 {
+						var page []TransactionResponse
+						w := a.do(token, http.MethodGet, target, nil)
+						a.ok(w, http.StatusOK, &page)
+						got = append(got, page...)
+						cursor := w.Header().Get("X-Next-Cursor")
+						if cursor == "" {
+							break
+						}
+						if link := w.Header().Get("Link"); !strings.Contains(link, "cursor="+cursor) {
+							t.Errorf("Link = %q, want the next cursor", link)
+						}
+						target = "/transactions?limit=1&sort=" + url.QueryEscape(sort) + "&cursor=" + cursor
+					}
+
+					if len(got) != len(want) {
+						t.Fatalf("paged through %d transactions, want %d", len(got), len(want))
+					}
+					for i := range got {
+						if got[i].ID != want[i].ID {
+							t.Errorf("row %d = %s, want %s", i, label(got[i]), label(want[i]))
+						}
+					}
+				})
+			}
+		}
+	})
+}
+
+func TestTransactionQueryErrors(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a, token := queryTest(t, db)
+
+		w := a.do(token, http.MethodGet, "/transactions?limit=1&sort=date", nil)
+		a.ok(w, http.StatusOK, nil)
+		dateCursor := w.Header().Get("X-Next-Cursor")
+
+		tests := []struct {
+			query string
+			err   string
+		}{
+			{"type=HOLD", "invalid type"},
+			{"from=01/02/2024", "invalid 'from' date"},
+			{"to=2024-13-01", "invalid 'to' date"},
+			{"sort=note", "invalid sort"},
+			{"limit=0", "invalid limit"},
+			{"limit=ten", "invalid limit"},
+			{"cursor=not-base64!", "invalid cursor"},
+			{"cursor=bm90IGpzb24", "invalid cursor"}, // "not json"
+			{"sort=-date&cursor=" + dateCursor, "cursor was created with sort 'date', not '-date'"},
+		}
+		for _, tt := range tests {
+			t.Run(tt.query, func(t *testing.T) {
+				w := a.do(token, http.MethodGet, "/transactions?"+tt.query, nil)
+				if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), tt.err) {
+					t.Errorf("status %d: %s, want 400 with %q", w.Code, w.Body, tt.err)
+				}
+			})
+		}
+	})
+}
+
+// Quantities and prices are stored as text on SQLite, where "100" < "9"
+func TestTransactionNumericSort(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := newAPITest(t, db)
+		a.transactions()
+		token := a.user("ann@example.com", false)
+		for _, amount := range []string{"10", "100", "9", "9.5"} {
+			a.addTransaction(token, trade("AAPL", models.Buy, amount, amount, "0", "USD", "2024-01-10"))
+		}
+
+		for _, tt := range []struct {
+			sort string
+			want []string
+		}{
+			{"quantity", []string{"9", "9.5", "10", "100"}},
+			{"-quantity", []string{"100", "10", "9.5", "9"}},
+			{"price", []string{"9", "9.5", "10", "100"}},
+			{"-price", []string{"100", "10", "9.5", "9"}},
+		} {
+			t.Run(tt.sort, func(t *testing.T) {
+				var got []string
+				target := "/transactions?limit=1&sort=" + url.QueryEscape(tt.sort)
+				for range len(tt.want) + 1 {
+					var page []TransactionResponse
+					w := a.do(token, http.MethodGet, target, nil)
+					a.ok(w, http.StatusOK, &page)
+					for _, tx := range page {
+						got = append(got, tx.Quantity.String())
+					}
+					cursor := w.Header().Get("X-Next-Cursor")
+					if cursor == "" {
+						break
+					}
+					target = "/transactions?limit=1&sort=" + url.QueryEscape(tt.sort) + "&cursor=" + cursor
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("paged through %q, want %q", got, tt.want)
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestParseImportRow(t *testing.T) {
+	future := time.Now().AddDate(0, 0, 3).Format("2006-01-02")
+
+	tests := []struct {
+		name   string
+		row    []string
+		fields []string // the fields reported, none when the row is valid
+	}{
+		{"valid row", []string{"2024-01-15", "10", "25.50", "1.99"}, nil},
+		{"zero fee", []string{"2024-01-15", "10", "25.50", "0"}, nil},
+		{"date in the future", []string{future, "10", "25.50", "0"}, []string{"date"}},
+		{"bad date", []string{"15/01/2024", "10", "25.50", "0"}, []string{"date"}},
+		{"zero quantity", []string{"2024-01-15", "0", "25.50", "0"}, []string{"quantity"}},
+		{"quantity not a number", []string{"2024-01-15", "ten", "25.50", "0"}, []string{"quantity"}},
+		{"negative price and fee", []string{"2024-01-15", "10", "-1", "-2"}, []string{"price", "fee"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx, errs := parseImportRow(tt.row, "AAPL", "USD")
+			var fields []string
+			for _, e := range errs {
+				fields = append(fields, e.Field)
+			}
+			slices.Sort(fields)
+			want := slices.Sorted(slices.Values(tt.fields))
+			if !slices.Equal(fields, want) {
+				t.Fatalf("fields = %v (%v), want %v", fields, errs, want)
+			}
+			if len(errs) == 0 && (tx.Symbol != "AAPL" || tx.Currency != "USD" || tx.Date.IsZero()) {
+				t.Errorf("row parsed into %+v", tx)
+			}
+		})
+	}
+}
+
+func TestCreateTransactionServerFields(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		a := newAPITest(t, db)
+		a.transactions()
+		token := a.user("ann@example.com", false)
+
+		// The client's ID, timestamps and deletion are ignored
+		body := map[string]any{
+			"ID": "chosen", "CreatedAt": "2000-01-01T00:00:00Z", "DeletedAt": "2000-01-02T00:00:00Z",
+			"symbol": "AAPL", "type": "BUY", "quantity": "1", "price": "100", "date": "2024-01-10T00:00:00Z",
+		}
+		var created models.Transaction
+		a.ok(a.do(token, http.MethodPost, "/transactions?unlisted=true", body), http.StatusCreated, &created)
+		if created.ID == "chosen" || created.CreatedAt.Year() < 2025 || created.DeletedAt.Valid {
+			t.Errorf("created %+v, want a new ID, created now and not deleted", created)
+		}
+		var stored models.Transaction
+		if err := db.First(&stored, "id = ?", created.ID).Error; err != nil {
+			t.Errorf("created transaction is not listed: %v", err)
+		}
+
+		// The date is required
+		delete(body, "date")
+		w := a.do(token, http.MethodPost, "/transactions?unlisted=true", body)
+		if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `"field":"date"`) {
+			t.Errorf("no date: status %d: %s, want 422 on the date", w.Code, w.Body)
+		}
+	})
+}
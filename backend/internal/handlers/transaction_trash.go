@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Felipalds/gemini-stocks/internal/audit"
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PurgeResult is the response of DELETE /transactions/deleted
+type PurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// deleted scopes a query to the soft-deleted transactions of the request's portfolio
+func deleted(r *http.Request) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Scopes(auth.InPortfolio(r)).Where("deleted_at IS NOT NULL")
+	}
+}
+
+// ListDeleted handles GET /transactions/deleted, most recently deleted first
+func (h *TransactionHandler) ListDeleted(w http.ResponseWriter, r *http.Request) {
+	transactions := []models.Transaction{}
+	if err := h.DB.Scopes(deleted(r)).Order("deleted_at desc").Find(&transactions).Error; err != nil {
+		h.Logger.Error("Failed to fetch deleted transactions", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	httpx.JSON(w, http.StatusOK, transactions)
+}
+
+// Restore handles POST /transactions/deleted/{id}/restore (editors and owners)
+func (h *TransactionHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleEditor) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+
+	var tx models.Transaction
+	if err := h.DB.Scopes(deleted(r)).First(&tx, "id = ?", id).Error; err != nil {
+		httpx.NotFound(w, r, "Deleted transaction not found")
+		return
+	}
+
+	tx.DeletedAt = gorm.DeletedAt{}
+	tx.UpdatedByID = auth.UserID(r)
+	err := h.DB.Transaction(func(db *gorm.DB) error {
+		if err := db.Unscoped().Save(&tx).Error; err != nil {
+			return err
+		}
+		return audit.Record(db, r, transactionChange(nil, &tx))
+	})
+	if err != nil {
+		h.Logger.Error("Failed to restore transaction", zap.Error(err))
+		httpx.Internal(w, r, "Failed to restore transaction")
+		return
+	}
+
+	h.Logger.Infof("Transaction %s restored", id)
+	httpx.JSON(w, http.StatusOK, tx)
+}
+
+// Purge handles DELETE /transactions/deleted/{id} and deletes a transaction
+// for good (owners only)
+func (h *TransactionHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+	id := chi.URLParam(r, "id")
+
+	var tx models.Transaction
+	if err := h.DB.Scopes(deleted(r)).First(&tx, "id = ?", id).Error; err != nil {
+		httpx.NotFound(w, r, "Deleted transaction not found")
+		return
+	}
+
+	if err := h.purge(r, []models.Transaction{tx}); err != nil {
+		h.Logger.Error("Failed to purge transaction", zap.Error(err))
+		httpx.Internal(w, r, "Failed to purge transaction")
+		return
+	}
+
+	h.Logger.Infof("Transaction %s purged", id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// EmptyTrash handles DELETE /transactions/deleted and purges every deleted
+// transaction of the portfolio (owners only)
+func (h *TransactionHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	var transactions []models.Transaction
+	if err := h.DB.Scopes(deleted(r)).Find(&transactions).Error; err != nil {
+		h.Logger.Error("Failed to fetch deleted transactions", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+
+	if err := h.purge(r, transactions); err != nil {
+		h.Logger.Error("Failed to empty the recycle bin", zap.Error(err))
+		httpx.Internal(w, r, "Failed to purge transactions")
+		return
+	}
+
+	h.Logger.Infof("Purged %d deleted transactions", len(transactions))
+	httpx.JSON(w, http.StatusOK, PurgeResult{Purged: len(transactions)})
+}
+
+// purge hard-deletes soft-deleted transactions, logging each one
+func (h *TransactionHandler) purge(r *http.Request, transactions []models.Transaction) error {
+	return h.DB.Transaction(func(db *gorm.DB) error {
+		for i := range transactions {
+			if err := db.Unscoped().Where("id = ?", transactions[i].ID).Delete(&models.Transaction{}).Error; err != nil {
+				return err
+			}
+			if err := audit.Record(db, r, transactionChange(&transactions[i], nil)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
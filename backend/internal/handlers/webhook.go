@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// webhookSecretPrefix marks webhook secrets, so they are recognizable in configs
+const webhookSecretPrefix = "whsec_"
+
+// WebhookHandler manages the webhooks of a portfolio. They send portfolio
+// data to other services, so every endpoint is for owners only.
+type WebhookHandler struct {
+	DB       *gorm.DB
+	Logger   *zap.SugaredLogger
+	Webhooks *services.WebhookService
+}
+
+func NewWebhookHandler(db *gorm.DB, logger *zap.SugaredLogger, webhooks *services.WebhookService) *WebhookHandler {
+	return &WebhookHandler{DB: db, Logger: logger, Webhooks: webhooks}
+}
+
+// WebhookRequest is the body of POST /webhooks and PUT /webhooks/{id}
+type WebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2000"`
+	Description string   `json:"description" validate:"max=200"`
+	Events      []string `json:"events"`  // empty for every event
+	Enabled     *bool    `json:"enabled"` // default: true
+}
+
+// CreateWebhookResponse is returned once, when the webhook is created. The secret cannot be read again.
+type CreateWebhookResponse struct {
+	Secret string `json:"secret"`
+	models.Webhook
+}
+
+// ListWebhooks handles GET /webhooks
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	hooks := []models.Webhook{}
+	if err := h.DB.Scopes(auth.InPortfolio(r)).Order("id").Find(&hooks).Error; err != nil {
+		h.Logger.Error("Failed to fetch webhooks", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, hooks)
+}
+
+// CreateWebhook handles POST /webhooks
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	secret, _, err := auth.NewToken()
+	if err != nil {
+		h.Logger.Error("Failed to generate webhook secret", zap.Error(err))
+		httpx.Internal(w, r, "Failed to create webhook")
+		return
+	}
+	hook := models.Webhook{
+		PortfolioID: auth.PortfolioID(r),
+		CreatedByID: auth.UserID(r),
+		Secret:      webhookSecretPrefix + secret,
+		Enabled:     true,
+	}
+	if !decodeWebhook(w, r, &hook) {
+		return
+	}
+
+	if err := h.DB.Create(&hook).Error; err != nil {
+		h.Logger.Error("Failed to create webhook", zap.Error(err))
+		httpx.Internal(w, r, "Failed to create webhook")
+		return
+	}
+
+	h.Logger.Infow("Webhook created", "webhook_id", hook.ID, "portfolio_id", hook.PortfolioID)
+	httpx.JSON(w, http.StatusCreated, CreateWebhookResponse{Secret: hook.Secret, Webhook: hook})
+}
+
+// UpdateWebhook handles PUT /webhooks/{id}
+// The webhook is replaced, except for its secret.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	hook, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+	hook.Enabled = true
+	if !decodeWebhook(w, r, &hook) {
+		return
+	}
+
+	if err := h.DB.Save(&hook).Error; err != nil {
+		h.Logger.Error("Failed to update webhook", zap.Error(err))
+		httpx.Internal(w, r, "Failed to update webhook")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook handles DELETE /webhooks/{id}
+// Pending deliveries fail on their next attempt.
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	result := h.DB.Scopes(auth.InPortfolio(r)).Where("id = ?", chi.URLParam(r, "id")).Delete(&models.Webhook{})
+	if result.Error != nil {
+		h.Logger.Error("Failed to delete webhook", zap.Error(result.Error))
+		httpx.Internal(w, r, "Failed to delete webhook")
+		return
+	}
+	if result.RowsAffected == 0 {
+		httpx.NotFound(w, r, "Webhook not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PingWebhook handles POST /webhooks/{id}/ping
+// It queues a ping event, even if the webhook is disabled, and returns the delivery.
+func (h *WebhookHandler) PingWebhook(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+
+	hook, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+	delivery, err := h.Webhooks.Queue(hook, events.New(events.Ping, hook.PortfolioID, hook))
+	if err != nil {
+		h.Logger.Error("Failed to queue webhook ping", zap.Error(err))
+		httpx.Internal(w, r, "Failed to queue ping")
+		return
+	}
+	httpx.JSON(w, http.StatusAccepted, delivery)
+}
+
+// ListDeliveries handles GET /webhooks/{id}/deliveries
+// It returns the delivery log of a webhook, newest first.
+// Query parameters: status, before (a delivery ID, for paging) and limit (default 50).
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+	hook, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := parseLimit(q.Get("limit"))
+	if err != nil {
+		httpx.BadRequest(w, r, err.Error())
+		return
+	}
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+
+	query := h.DB.Where("webhook_id = ?", hook.ID)
+	if status := strings.TrimSpace(q.Get("status")); status != "" {
+		if status != models.DeliveryPending && status != models.DeliveryDelivered && status != models.DeliveryFailed {
+			httpx.BadRequest(w, r, "Unknown status '"+status+"'")
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+	if raw := q.Get("before"); raw != "" {
+		before, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			httpx.BadRequest(w, r, "Invalid before '"+raw+"'. Must be a delivery ID")
+			return
+		}
+		query = query.Where("id < ?", before)
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := query.Order("id desc").Limit(limit).Find(&deliveries).Error; err != nil {
+		h.Logger.Error("Failed to fetch webhook deliveries", zap.Error(err))
+		httpx.Internal(w, r, "Database error")
+		return
+	}
+	httpx.JSON(w, http.StatusOK, deliveries)
+}
+
+// Redeliver handles POST /webhooks/{id}/deliveries/{deliveryID}/redeliver
+// It sends the payload of a past delivery again, as a new delivery.
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	if !auth.Can(w, r, models.RoleOwner) {
+		return
+	}
+	hook, ok := h.find(w, r)
+	if !ok {
+		return
+	}
+
+	var past models.WebhookDelivery
+	if err := h.DB.Where("webhook_id = ?", hook.ID).First(&past, "id = ?", chi.URLParam(r, "deliveryID")).Error; err != nil {
+		httpx.NotFound(w, r, "Delivery not found")
+		return
+	}
+	delivery, err := h.Webhooks.Redeliver(past)
+	if err != nil {
+		h.Logger.Error("Failed to queue webhook delivery", zap.Error(err))
+		httpx.Internal(w, r, "Failed to queue delivery")
+		return
+	}
+	httpx.JSON(w, http.StatusAccepted, delivery)
+}
+
+// find loads the webhook of the {id} URL parameter in the request's portfolio
+func (h *WebhookHandler) find(w http.ResponseWriter, r *http.Request) (models.Webhook, bool) {
+	var hook models.Webhook
+	if err := h.DB.Scopes(auth.InPortfolio(r)).First(&hook, "id = ?", chi.URLParam(r, "id")).Error; err != nil {
+		httpx.NotFound(w, r, "Webhook not found")
+		return hook, false
+	}
+	return hook, true
+}
+
+// decodeWebhook reads and checks a WebhookRequest into hook
+func decodeWebhook(w http.ResponseWriter, r *http.Request, hook *models.Webhook) bool {
+	var req WebhookRequest
+	if !httpx.DecodeOnly(w, r, &req) {
+		return false
+	}
+	req.URL = strings.TrimSpace(req.URL)
+	req.Description = strings.TrimSpace(req.Description)
+	if !httpx.Validate(w, r, &req) {
+		return false
+	}
+
+	subscribed := []string{}
+	for i, e := range req.Events {
+		if !slices.Contains(events.Types, e) {
+			httpx.Invalid(w, r, []validate.FieldError{{
+				Field: "events[" + strconv.Itoa(i) + "]", Message: "must be one of " + strings.Join(events.Types, ", "),
+			}})
+			return false
+		}
+		if !slices.Contains(subscribed, e) {
+			subscribed = append(subscribed, e)
+		}
+	}
+
+	hook.URL = req.URL
+	hook.Description = req.Description
+	hook.Events = subscribed
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+// Package httpx holds the JSON response helpers shared by every handler.
+//
+// Errors are always sent as an envelope:
+//
+//	{"error": {"code": "validation_failed", "message": "...", "fields": [...], "request_id": "..."}}
+package httpx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Error codes
+const (
+	CodeBadRequest   = "bad_request"
+	CodeValidation   = "validation_failed"
+	CodeNotFound     = "not_found"
+	CodeMethod       = "method_not_allowed"
+	CodeConflict     = "conflict"
+	CodeTooLarge     = "payload_too_large"
+	CodeInternal     = "internal_error"
+	CodeUpstream     = "upstream_error"
+	CodeUnauthorized = "unauthorized"
+	CodeForbidden    = "forbidden"
+)
+
+// ErrorBody is the content of the error envelope
+type ErrorBody struct {
+	Code      string                `json:"code"`
+	Message   string                `json:"message"`
+	Fields    []validate.FieldError `json:"fields,omitempty"`
+	RequestID string                `json:"request_id,omitempty"`
+}
+
+// ErrorResponse is the error envelope
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// JSON writes v with the given status
+func JSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Error writes the error envelope, tagged with the chi request ID
+func Error(w http.ResponseWriter, r *http.Request, status int, code, message string, fields ...validate.FieldError) {
+	JSON(w, status, ErrorResponse{Error: ErrorBody{
+		Code:      code,
+		Message:   message,
+		Fields:    fields,
+		RequestID: middleware.GetReqID(r.Context()),
+	}})
+}
+
+// BadRequest writes a 400 bad_request error
+func BadRequest(w http.ResponseWriter, r *http.Request, message string) {
+	Error(w, r, http.StatusBadRequest, CodeBadRequest, message)
+}
+
+// NotFound writes a 404 not_found error
+func NotFound(w http.ResponseWriter, r *http.Request, message string) {
+	Error(w, r, http.StatusNotFound, CodeNotFound, message)
+}
+
+// Unauthorized writes a 401 unauthorized error
+func Unauthorized(w http.ResponseWriter, r *http.Request, message string) {
+	Error(w, r, http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden writes a 403 forbidden error
+func Forbidden(w http.ResponseWriter, r *http.Request, message string) {
+	Error(w, r, http.StatusForbidden, CodeForbidden, message)
+}
+
+// Internal writes a 500 internal_error. The cause is logged by the handler, never sent.
+func Internal(w http.ResponseWriter, r *http.Request, message string) {
+	Error(w, r, http.StatusInternalServerError, CodeInternal, message)
+}
+
+// Invalid writes a 422 validation_failed error with the field errors
+func Invalid(w http.ResponseWriter, r *http.Request, fields []validate.FieldError) {
+	Error(w, r, http.StatusUnprocessableEntity, CodeValidation, "Request validation failed", fields...)
+}
+
+// Decode reads a JSON body into v and validates it.
+// On failure it writes the error response and returns false.
+func Decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if !DecodeOnly(w, r, v) {
+		return false
+	}
+	return Validate(w, r, v)
+}
+
+// DecodeOnly reads a JSON body into v without validating it, for handlers that
+// need to fill in defaults first. On failure it writes the error response and returns false.
+func DecodeOnly(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			Invalid(w, r, []validate.FieldError{{
+				Field:   typeErr.Field,
+				Message: fmt.Sprintf("must be a %s", jsonTypeName(typeErr.Type.Kind().String())),
+			}})
+		case errors.As(err, &maxErr):
+			Error(w, r, http.StatusRequestEntityTooLarge, CodeTooLarge, "Request body too large")
+		case errors.Is(err, io.EOF):
+			BadRequest(w, r, "Request body is empty")
+		default:
+			BadRequest(w, r, "Invalid request payload")
+		}
+		return false
+	}
+	return true
+}
+
+// Validate runs the declarative rules of v. On failure it writes a 422 and returns false.
+func Validate(w http.ResponseWriter, r *http.Request, v any) bool {
+	if fields := validate.Struct(v); len(fields) > 0 {
+		Invalid(w, r, fields)
+		return false
+	}
+	return true
+}
+
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "float"), strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "number"
+	case kind == "struct":
+		return "valid value"
+	}
+	return kind
+}
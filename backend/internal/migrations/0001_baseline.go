@@ -0,0 +1,170 @@
+package migrations
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baseline creates the schema as it was before versioned migrations. On a
+// database created by AutoMigrate it only fills in what is missing, so both
+// new and existing databases end up at version 1.
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		// The price cache used to be called stock_prices
+		if tx.Migrator().HasTable("stock_prices") && !tx.Migrator().HasTable("tickers") {
+			if err := tx.Migrator().RenameTable("stock_prices", "tickers"); err != nil {
+				return err
+			}
+		}
+		return tx.AutoMigrate(baselineTables...)
+	},
+	Down: func(tx *gorm.DB) error {
+		for i := len(baselineTables) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(baselineTables[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// baselineTables lists the tables of version 1, parents before children
+var baselineTables = []any{
+	&v1User{}, &v1Session{}, &v1APIToken{},
+	&v1Portfolio{}, &v1PortfolioMember{},
+	&v1Ticker{}, &v1Currency{},
+	&v1Transaction{},
+	&v1PortfolioGoal{}, &v1GoalAllocation{},
+	&v1AuditEntry{},
+}
+
+type v1User struct {
+	gorm.Model
+	Email        string `gorm:"uniqueIndex"`
+	Name         string
+	PasswordHash string
+	IsAdmin      bool
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Session struct {
+	TokenHash string `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (v1Session) TableName() string { return "sessions" }
+
+type v1APIToken struct {
+	gorm.Model
+	UserID     uint `gorm:"index"`
+	Name       string
+	Prefix     string
+	TokenHash  string   `gorm:"uniqueIndex"`
+	Scopes     []string `gorm:"serializer:json"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+func (v1APIToken) TableName() string { return "api_tokens" }
+
+type v1Portfolio struct {
+	gorm.Model
+	Name string
+}
+
+func (v1Portfolio) TableName() string { return "portfolios" }
+
+type v1PortfolioMember struct {
+	PortfolioID uint `gorm:"primaryKey"`
+	UserID      uint `gorm:"primaryKey;index"`
+	Role        string
+	AddedByID   uint
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (v1PortfolioMember) TableName() string { return "portfolio_members" }
+
+type v1Ticker struct {
+	Symbol           string `gorm:"primaryKey"`
+	Price            float64
+	DayChangePercent float64
+	Tags             string
+	Category         string
+	Currency         string `gorm:"default:USD"`
+	UpdatedAt        time.Time
+}
+
+func (v1Ticker) TableName() string { return "tickers" }
+
+type v1Currency struct {
+	Code      string `gorm:"primaryKey"`
+	Rate      float64
+	UpdatedAt time.Time
+}
+
+func (v1Currency) TableName() string { return "currencies" }
+
+// v1Transaction has the text ID that models.Transaction declared next to the
+// uint ID of gorm.Model; the text one was always the column.
+type v1Transaction struct {
+	ID          string `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Symbol      string         `gorm:"index"`
+	Type        string
+	Quantity    float32
+	Price       float64
+	Currency    string  `gorm:"default:USD"`
+	Fee         float64 `gorm:"default:0"`
+	Date        time.Time
+	Note        string
+	PortfolioID uint `gorm:"index"`
+	CreatedByID uint
+	UpdatedByID uint
+}
+
+func (v1Transaction) TableName() string { return "transactions" }
+
+type v1PortfolioGoal struct {
+	gorm.Model
+	PortfolioID uint `gorm:"index"`
+	UpdatedByID uint
+	GoalTotal   float64
+	Allocations []v1GoalAllocation `gorm:"foreignKey:PortfolioGoalID;constraint:OnDelete:CASCADE"`
+}
+
+func (v1PortfolioGoal) TableName() string { return "portfolio_goals" }
+
+type v1GoalAllocation struct {
+	gorm.Model
+	PortfolioGoalID uint
+	Category        string
+	Percentage      float64
+}
+
+func (v1GoalAllocation) TableName() string { return "goal_allocations" }
+
+type v1AuditEntry struct {
+	ID          uint      `gorm:"primaryKey"`
+	CreatedAt   time.Time `gorm:"index"`
+	Entity      string    `gorm:"index:idx_audit_entity"`
+	EntityID    string    `gorm:"index:idx_audit_entity"`
+	Action      string
+	Before      json.RawMessage `gorm:"type:text"`
+	After       json.RawMessage `gorm:"type:text"`
+	PortfolioID uint            `gorm:"index"`
+	ActorID     uint
+	RequestID   string
+	RevertOfID  *uint
+}
+
+func (v1AuditEntry) TableName() string { return "audit_entries" }
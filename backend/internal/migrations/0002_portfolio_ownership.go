@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// portfolioOwnership moves the rows of databases from before portfolios
+// (a user_id column on transactions and goals) into a personal portfolio per
+// user, and drops user_id. The owners cannot be put back, so there is no down step.
+var portfolioOwnership = Migration{
+	Version: 2,
+	Name:    "portfolio_ownership",
+	Up: func(tx *gorm.DB) error {
+		var users []v1User
+		err := tx.Where("id NOT IN (?)", tx.Model(&v1PortfolioMember{}).Select("user_id")).Find(&users).Error
+		if err != nil {
+			return err
+		}
+
+		m := tx.Migrator()
+		legacy := []any{&v2LegacyTransaction{}, &v2LegacyPortfolioGoal{}}
+		for _, user := range users {
+			portfolio := v1Portfolio{Name: "Personal"}
+			if err := tx.Create(&portfolio).Error; err != nil {
+				return err
+			}
+			member := v1PortfolioMember{PortfolioID: portfolio.ID, UserID: user.ID, Role: "owner", AddedByID: user.ID}
+			if err := tx.Create(&member).Error; err != nil {
+				return err
+			}
+
+			for _, model := range legacy {
+				if !m.HasColumn(model, "UserID") {
+					continue
+				}
+				err := tx.Unscoped().Model(model).
+					Where("user_id = ? AND (portfolio_id = 0 OR portfolio_id IS NULL)", user.ID).
+					Update("portfolio_id", portfolio.ID).Error
+				if err != nil {
+					return err
+				}
+			}
+			if m.HasColumn(&v2LegacyTransaction{}, "UserID") {
+				err := tx.Unscoped().Model(&v2LegacyTransaction{}).
+					Where("user_id = ? AND (created_by_id = 0 OR created_by_id IS NULL)", user.ID).
+					Updates(map[string]any{"created_by_id": user.ID, "updated_by_id": user.ID}).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, model := range legacy {
+			if m.HasColumn(model, "UserID") {
+				if err := m.DropColumn(model, "UserID"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// v2LegacyTransaction is the part of a transaction from before portfolios that
+// this migration moves
+type v2LegacyTransaction struct {
+	ID          string `gorm:"primaryKey"`
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt
+	UserID      uint
+	PortfolioID uint
+	CreatedByID uint
+	UpdatedByID uint
+}
+
+func (v2LegacyTransaction) TableName() string { return "transactions" }
+
+type v2LegacyPortfolioGoal struct {
+	ID          uint `gorm:"primarykey"`
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt
+	UserID      uint
+	PortfolioID uint
+}
+
+func (v2LegacyPortfolioGoal) TableName() string { return "portfolio_goals" }
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/database"
+)
+
+// transactionID rebuilds the transactions table around a single, required
+// text ID. models.Transaction used to embed gorm.Model next to its own string
+// ID, and databases created before that have integer or missing IDs. Integer
+// IDs are kept as text and missing ones get a new UUID.
+var transactionID = Migration{
+	Version: 3,
+	Name:    "transaction_id",
+	Up: func(tx *gorm.DB) error {
+		m := tx.Migrator()
+		if err := m.RenameTable("transactions", "transactions_old"); err != nil {
+			return err
+		}
+		// PostgreSQL keeps the primary key constraint name too
+		if database.IsPostgres(tx) {
+			if err := tx.Exec("ALTER TABLE transactions_old RENAME CONSTRAINT transactions_pkey TO transactions_old_pkey").Error; err != nil {
+				return err
+			}
+		}
+		// Indexes keep their names when the table is renamed
+		for _, index := range []string{"idx_transactions_deleted_at", "idx_transactions_symbol", "idx_transactions_portfolio_id"} {
+			if err := tx.Exec("DROP INDEX IF EXISTS " + index).Error; err != nil {
+				return err
+			}
+		}
+		if err := m.CreateTable(&v3Transaction{}); err != nil {
+			return err
+		}
+
+		columns := strings.Join(v3TransactionColumns, ", ")
+		err := tx.Exec("INSERT INTO transactions (id, " + columns + ") " +
+			"SELECT CAST(id AS TEXT), " + columns + " FROM transactions_old " +
+			"WHERE id IS NOT NULL AND CAST(id AS TEXT) <> ''").Error
+		if err != nil {
+			return err
+		}
+
+		var missing []map[string]any
+		err = tx.Table("transactions_old").Select(columns).Where("id IS NULL OR CAST(id AS TEXT) = ''").Find(&missing).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range missing {
+			row["id"] = uuid.New().String()
+			if err := tx.Table("transactions").Create(row).Error; err != nil {
+				return err
+			}
+		}
+
+		return m.DropTable("transactions_old")
+	},
+	// The rebuilt table works with the code of version 2 as it is
+	Down: func(tx *gorm.DB) error { return nil },
+}
+
+// v3TransactionColumns are the columns copied besides id
+var v3TransactionColumns = []string{
+	"created_at", "updated_at", "deleted_at", "symbol", "type", "quantity", "price",
+	"currency", "fee", "date", "note", "portfolio_id", "created_by_id", "updated_by_id",
+}
+
+type v3Transaction struct {
+	ID          string `gorm:"primaryKey;not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	Symbol      string         `gorm:"index"`
+	Type        string
+	Quantity    float32
+	Price       float64
+	Currency    string  `gorm:"default:USD"`
+	Fee         float64 `gorm:"default:0"`
+	Date        time.Time
+	Note        string
+	PortfolioID uint `gorm:"index"`
+	CreatedByID uint
+	UpdatedByID uint
+}
+
+func (v3Transaction) TableName() string { return "transactions" }
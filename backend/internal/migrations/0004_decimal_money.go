@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/database"
+)
+
+// decimalMoney turns quantities, prices, fees, rates and goal totals into
+// NUMERIC columns. Quantities were float32, so 0.1 was stored as
+// 0.10000000149011612: they are rewritten with the shortest decimal that reads
+// back as the same float32, which is what the user typed. Fees are rounded to
+// the minor unit of their currency.
+var decimalMoney = Migration{
+	Version: 4,
+	Name:    "decimal_money",
+	Up: func(tx *gorm.DB) error {
+		if err := alterColumns(tx, v4Columns); err != nil {
+			return err
+		}
+
+		var rows []struct {
+			ID       string
+			Quantity float64
+			Fee      float64
+			Currency string
+		}
+		if err := tx.Table("transactions").Select("id, quantity, fee, currency").Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			quantity, err := decimal.NewFromString(strconv.FormatFloat(float64(float32(row.Quantity)), 'f', -1, 32))
+			if err != nil {
+				return err
+			}
+			fee := decimal.NewFromFloat(row.Fee).Round(v4MoneyPlaces(row.Currency))
+			err = tx.Table("transactions").Where("id = ?", row.ID).
+				Updates(map[string]any{"quantity": quantity, "fee": fee}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// Values are converted back to floating point, losing the exact digits
+	Down: func(tx *gorm.DB) error {
+		return alterColumns(tx, v3Columns)
+	},
+}
+
+// v4MoneyPlaces is the number of decimal places fees were rounded to, as
+// models.MoneyPlaces had it at this version
+func v4MoneyPlaces(currency string) int32 {
+	switch currency {
+	case "JPY":
+		return 0
+	case "BTC", "ETH":
+		return 8
+	}
+	return 2
+}
+
+// columnChange is a table snapshot and the fields whose column type changes
+type columnChange struct {
+	table  string
+	model  any
+	fields []string
+}
+
+// alterColumns changes the column types in place. SQLite cannot do that, so
+// gorm rebuilds the table there, which drops its indexes: they are recreated
+// from their saved definitions.
+func alterColumns(tx *gorm.DB, changes []columnChange) error {
+	m := tx.Migrator()
+	for _, c := range changes {
+		var indexes []string
+		if !database.IsPostgres(tx) {
+			err := tx.Raw("SELECT sql FROM sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", c.table).
+				Scan(&indexes).Error
+			if err != nil {
+				return err
+			}
+		}
+		for _, field := range c.fields {
+			if err := m.AlterColumn(c.model, field); err != nil {
+				return err
+			}
+		}
+		for _, index := range indexes {
+			if err := tx.Exec(index).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var v4Columns = []columnChange{
+	{"transactions", &v4Transaction{}, []string{"Quantity", "Price", "Fee"}},
+	{"tickers", &v4Ticker{}, []string{"Price"}},
+	{"currencies", &v4Currency{}, []string{"Rate"}},
+	{"portfolio_goals", &v4PortfolioGoal{}, []string{"GoalTotal"}},
+}
+
+var v3Columns = []columnChange{
+	{"transactions", &v3Transaction{}, []string{"Quantity", "Price", "Fee"}},
+	{"tickers", &v1Ticker{}, []string{"Price"}},
+	{"currencies", &v1Currency{}, []string{"Rate"}},
+	{"portfolio_goals", &v1PortfolioGoal{}, []string{"GoalTotal"}},
+}
+
+type v4Transaction struct {
+	ID       string          `gorm:"primaryKey;not null"`
+	Quantity decimal.Decimal `gorm:"type:numeric"`
+	Price    decimal.Decimal `gorm:"type:numeric"`
+	Fee      decimal.Decimal `gorm:"type:numeric;default:0"`
+}
+
+func (v4Transaction) TableName() string { return "transactions" }
+
+type v4Ticker struct {
+	Symbol string          `gorm:"primaryKey"`
+	Price  decimal.Decimal `gorm:"type:numeric"`
+}
+
+func (v4Ticker) TableName() string { return "tickers" }
+
+type v4Currency struct {
+	Code      string          `gorm:"primaryKey"`
+	Rate      decimal.Decimal `gorm:"type:numeric"`
+	UpdatedAt time.Time
+}
+
+func (v4Currency) TableName() string { return "currencies" }
+
+type v4PortfolioGoal struct {
+	ID        uint            `gorm:"primarykey"`
+	GoalTotal decimal.Decimal `gorm:"type:numeric"`
+}
+
+func (v4PortfolioGoal) TableName() string { return "portfolio_goals" }
@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// exchangeRates replaces the currencies table, which only held rates to BRL,
+// with exchange_rates pairs. Every CODE row becomes the CODE/BRL pair, and
+// the audit entries of currencies are rewritten as entries of those pairs.
+var exchangeRates = Migration{
+	Version: 5,
+	Name:    "exchange_rates",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&v5ExchangeRate{}); err != nil {
+			return err
+		}
+		err := tx.Exec("INSERT INTO exchange_rates (base, quote, rate, updated_at) " +
+			"SELECT code, 'BRL', rate, updated_at FROM currencies WHERE code <> 'BRL'").Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropTable("currencies"); err != nil {
+			return err
+		}
+
+		// Read as strings: the snapshots are text columns
+		var rows []struct {
+			ID       uint
+			EntityID string
+			Before   *string
+			After    *string
+		}
+		err = tx.Table("audit_entries").Select("id, entity_id, before, after").
+			Where("entity = ?", "currency").Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			before, err := v5UpgradeSnapshot(row.Before)
+			if err != nil {
+				return err
+			}
+			after, err := v5UpgradeSnapshot(row.After)
+			if err != nil {
+				return err
+			}
+			err = tx.Table("audit_entries").Where("id = ?", row.ID).Updates(map[string]any{
+				"entity": "exchange_rate", "entity_id": row.EntityID + "/BRL", "before": before, "after": after,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// Only the pairs quoted in BRL fit the old table. Audit entries keep
+	// their exchange_rate form.
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&v4Currency{}); err != nil {
+			return err
+		}
+		err := tx.Exec("INSERT INTO currencies (code, rate, updated_at) " +
+			"SELECT base, rate, updated_at FROM exchange_rates WHERE quote = 'BRL'").Error
+		if err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("exchange_rates")
+	},
+}
+
+// v5UpgradeSnapshot rewrites the audit snapshot of a currencies row as the
+// CODE/BRL exchange rate
+func v5UpgradeSnapshot(raw *string) (*string, error) {
+	if raw == nil || *raw == "" || *raw == "null" {
+		return raw, nil
+	}
+	var c struct {
+		Code      string          `json:"code"`
+		Rate      decimal.Decimal `json:"rate"`
+		UpdatedAt time.Time       `json:"updated_at"`
+	}
+	if err := json.Unmarshal([]byte(*raw), &c); err != nil {
+		return nil, err
+	}
+	// The rate is written as a JSON number, as models.ExchangeRate had it
+	upgraded, err := json.Marshal(struct {
+		Base      string      `json:"base"`
+		Quote     string      `json:"quote"`
+		Rate      json.Number `json:"rate"`
+		UpdatedAt time.Time   `json:"updated_at"`
+	}{c.Code, "BRL", json.Number(c.Rate.String()), c.UpdatedAt})
+	if err != nil {
+		return nil, err
+	}
+	s := string(upgraded)
+	return &s, nil
+}
+
+type v5ExchangeRate struct {
+	Base      string          `gorm:"primaryKey"`
+	Quote     string          `gorm:"primaryKey"`
+	Rate      decimal.Decimal `gorm:"type:numeric"`
+	UpdatedAt time.Time
+}
+
+func (v5ExchangeRate) TableName() string { return "exchange_rates" }
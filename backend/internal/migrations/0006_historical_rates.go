@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// historicalRates adds the daily rates past transactions are valued with
+var historicalRates = Migration{
+	Version: 6,
+	Name:    "historical_rates",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&v6HistoricalRate{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("historical_rates")
+	},
+}
+
+type v6HistoricalRate struct {
+	Base   string          `gorm:"primaryKey"`
+	Quote  string          `gorm:"primaryKey"`
+	Date   time.Time       `gorm:"primaryKey;type:date"`
+	Rate   decimal.Decimal `gorm:"type:numeric"`
+	Source string
+}
+
+func (v6HistoricalRate) TableName() string { return "historical_rates" }
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// alerts adds price alert rules and the history of triggered alerts
+var alerts = Migration{
+	Version: 7,
+	Name:    "alerts",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&v7AlertRule{}, &v7AlertEvent{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("alert_events", "alert_rules")
+	},
+}
+
+type v7AlertRule struct {
+	gorm.Model
+	PortfolioID     uint `gorm:"index"`
+	CreatedByID     uint
+	Type            string
+	Symbol          string
+	Category        string
+	Threshold       decimal.Decimal `gorm:"type:numeric"`
+	CooldownMinutes int
+	Enabled         bool
+	LastTriggeredAt *time.Time
+}
+
+func (v7AlertRule) TableName() string { return "alert_rules" }
+
+type v7AlertEvent struct {
+	ID          uint      `gorm:"primaryKey"`
+	CreatedAt   time.Time `gorm:"index"`
+	RuleID      uint      `gorm:"index"`
+	PortfolioID uint      `gorm:"index"`
+	Type        string
+	Symbol      string
+	Category    string
+	Value       decimal.Decimal `gorm:"type:numeric"`
+	Threshold   decimal.Decimal `gorm:"type:numeric"`
+	Message     string
+}
+
+func (v7AlertEvent) TableName() string { return "alert_events" }
@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// webhooks adds webhook subscriptions and their delivery log
+var webhooks = Migration{
+	Version: 8,
+	Name:    "webhooks",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&v8Webhook{}, &v8WebhookDelivery{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("webhook_deliveries", "webhooks")
+	},
+}
+
+type v8Webhook struct {
+	gorm.Model
+	PortfolioID uint `gorm:"index"`
+	CreatedByID uint
+	URL         string
+	Description string
+	Events      []string `gorm:"serializer:json"`
+	Secret      string
+	Enabled     bool
+}
+
+func (v8Webhook) TableName() string { return "webhooks" }
+
+type v8WebhookDelivery struct {
+	ID            uint      `gorm:"primaryKey"`
+	CreatedAt     time.Time `gorm:"index"`
+	UpdatedAt     time.Time
+	WebhookID     uint `gorm:"index"`
+	EventID       string
+	Event         string
+	Payload       string
+	Status        string `gorm:"index"`
+	Attempts      int
+	NextAttemptAt *time.Time
+	StatusCode    int
+	Error         string
+	DeliveredAt   *time.Time
+}
+
+func (v8WebhookDelivery) TableName() string { return "webhook_deliveries" }
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// notificationPreferences adds the channels users are notified on
+var notificationPreferences = Migration{
+	Version: 9,
+	Name:    "notification_preferences",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&v9NotificationPreference{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("notification_preferences")
+	},
+}
+
+type v9NotificationPreference struct {
+	UserID            uint   `gorm:"primaryKey;autoIncrement:false"`
+	Channel           string `gorm:"primaryKey"`
+	Address           string
+	Topics            []string `gorm:"serializer:json"`
+	Enabled           bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	TaxSummarySentFor string
+}
+
+func (v9NotificationPreference) TableName() string { return "notification_preferences" }
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// jobs adds the background jobs, starting with price refreshes
+var jobs = Migration{
+	Version: 10,
+	Name:    "jobs",
+	Up: func(tx *gorm.DB) error {
+		return tx.Migrator().CreateTable(&v10Job{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("jobs")
+	},
+}
+
+type v10Job struct {
+	ID          uint `gorm:"primaryKey"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	PortfolioID uint `gorm:"index"`
+	CreatedByID uint
+	Kind        string `gorm:"index"`
+	Status      string `gorm:"index"`
+	Total       int
+	Done        int
+	Updated     int
+	Failed      int
+	Alerts      int
+	Items       string // JSON
+	Error       string
+	FinishedAt  *time.Time
+}
+
+func (v10Job) TableName() string { return "jobs" }
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// quoteMetadata records where and when every ticker price was fetched. Prices
+// from before are taken as fetched at their last update, from an unknown
+// source; tickers without a price yet are left unfetched.
+var quoteMetadata = Migration{
+	Version: 11,
+	Name:    "quote_metadata",
+	Up: func(tx *gorm.DB) error {
+		for _, field := range []string{"Source", "FetchedAt"} {
+			if err := tx.Migrator().AddColumn(&v11Ticker{}, field); err != nil {
+				return err
+			}
+		}
+		return tx.Exec("UPDATE tickers SET fetched_at = updated_at WHERE price > 0").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, field := range []string{"FetchedAt", "Source"} {
+			if err := tx.Migrator().DropColumn(&v11Ticker{}, field); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+type v11Ticker struct {
+	Symbol           string          `gorm:"primaryKey"`
+	Price            decimal.Decimal `gorm:"type:numeric"`
+	DayChangePercent float64
+	Tags             string
+	Category         string
+	Currency         string `gorm:"default:USD"`
+	UpdatedAt        time.Time
+	Source           string
+	FetchedAt        *time.Time
+}
+
+func (v11Ticker) TableName() string { return "tickers" }
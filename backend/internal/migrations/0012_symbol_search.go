@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// symbolSearch adds the cache of symbol searches and the exchange of tickers
+var symbolSearch = Migration{
+	Version: 12,
+	Name:    "symbol_search",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&v12SymbolSearch{}); err != nil {
+			return err
+		}
+		return tx.Migrator().AddColumn(&v12Ticker{}, "Exchange")
+	},
+	Down: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&v12Ticker{}, "Exchange"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("symbol_searches")
+	},
+}
+
+type v12SymbolSearch struct {
+	Query      string `gorm:"primaryKey"`
+	Matches    string // JSON
+	SearchedAt time.Time
+}
+
+func (v12SymbolSearch) TableName() string { return "symbol_searches" }
+
+type v12Ticker struct {
+	Symbol           string          `gorm:"primaryKey"`
+	Price            decimal.Decimal `gorm:"type:numeric"`
+	DayChangePercent float64
+	Tags             string
+	Category         string
+	Currency         string `gorm:"default:USD"`
+	Exchange         string
+	UpdatedAt        time.Time
+	Source           string
+	FetchedAt        *time.Time
+}
+
+func (v12Ticker) TableName() string { return "tickers" }
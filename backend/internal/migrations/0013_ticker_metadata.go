@@ -0,0 +1,57 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// tickerMetadataFields are the columns added by tickerMetadata, in order
+var tickerMetadataFields = []string{"Name", "Country", "Sector", "Industry", "AssetType", "ISIN", "LogoURL", "MetadataFetchedAt"}
+
+// tickerMetadata adds descriptive metadata to tickers. Crypto pairs, the only
+// type known from the symbol alone, are typed right away.
+var tickerMetadata = Migration{
+	Version: 13,
+	Name:    "ticker_metadata",
+	Up: func(tx *gorm.DB) error {
+		for _, field := range tickerMetadataFields {
+			if err := tx.Migrator().AddColumn(&v13Ticker{}, field); err != nil {
+				return err
+			}
+		}
+		return tx.Exec("UPDATE tickers SET asset_type = 'crypto' WHERE symbol LIKE '%/%'").Error
+	},
+	Down: func(tx *gorm.DB) error {
+		for i := len(tickerMetadataFields) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropColumn(&v13Ticker{}, tickerMetadataFields[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+type v13Ticker struct {
+	Symbol            string          `gorm:"primaryKey"`
+	Price             decimal.Decimal `gorm:"type:numeric"`
+	DayChangePercent  float64
+	Tags              string
+	Category          string
+	Currency          string `gorm:"default:USD"`
+	Exchange          string
+	UpdatedAt         time.Time
+	Source            string
+	FetchedAt         *time.Time
+	Name              string
+	Country           string
+	Sector            string
+	Industry          string
+	AssetType         string
+	ISIN              string `gorm:"column:isin"`
+	LogoURL           string
+	MetadataFetchedAt *time.Time
+}
+
+func (v13Ticker) TableName() string { return "tickers" }
@@ -0,0 +1,106 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/database"
+)
+
+// decimalText stores decimals as TEXT on SQLite. A NUMERIC column there has
+// REAL affinity, so every value was rounded to a float64 and lost the digits
+// past the 15th. What was stored is kept as it reads now. PostgreSQL's NUMERIC
+// is exact and stays.
+var decimalText = Migration{
+	Version: 14,
+	Name:    "decimal_text",
+	Up: func(tx *gorm.DB) error {
+		if database.IsPostgres(tx) {
+			return nil
+		}
+		return alterColumns(tx, v14Columns)
+	},
+	Down: func(tx *gorm.DB) error {
+		if database.IsPostgres(tx) {
+			return nil
+		}
+		return alterColumns(tx, v13Columns)
+	},
+}
+
+var v14Columns = []columnChange{
+	{"transactions", &v14Transaction{}, []string{"Quantity", "Price", "Fee"}},
+	{"tickers", &v14Ticker{}, []string{"Price"}},
+	{"exchange_rates", &v14ExchangeRate{}, []string{"Rate"}},
+	{"historical_rates", &v14HistoricalRate{}, []string{"Rate"}},
+	{"portfolio_goals", &v14PortfolioGoal{}, []string{"GoalTotal"}},
+	{"alert_rules", &v14AlertRule{}, []string{"Threshold"}},
+	{"alert_events", &v14AlertEvent{}, []string{"Value", "Threshold"}},
+}
+
+var v13Columns = []columnChange{
+	{"transactions", &v4Transaction{}, []string{"Quantity", "Price", "Fee"}},
+	{"tickers", &v4Ticker{}, []string{"Price"}},
+	{"exchange_rates", &v5ExchangeRate{}, []string{"Rate"}},
+	{"historical_rates", &v6HistoricalRate{}, []string{"Rate"}},
+	{"portfolio_goals", &v4PortfolioGoal{}, []string{"GoalTotal"}},
+	{"alert_rules", &v7AlertRule{}, []string{"Threshold"}},
+	{"alert_events", &v7AlertEvent{}, []string{"Value", "Threshold"}},
+}
+
+type v14Transaction struct {
+	ID       string          `gorm:"primaryKey;not null"`
+	Quantity decimal.Decimal `gorm:"type:text"`
+	Price    decimal.Decimal `gorm:"type:text"`
+	Fee      decimal.Decimal `gorm:"type:text;default:0"`
+}
+
+func (v14Transaction) TableName() string { return "transactions" }
+
+type v14Ticker struct {
+	Symbol string          `gorm:"primaryKey"`
+	Price  decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14Ticker) TableName() string { return "tickers" }
+
+type v14ExchangeRate struct {
+	Base  string          `gorm:"primaryKey"`
+	Quote string          `gorm:"primaryKey"`
+	Rate  decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14ExchangeRate) TableName() string { return "exchange_rates" }
+
+type v14HistoricalRate struct {
+	Base  string          `gorm:"primaryKey"`
+	Quote string          `gorm:"primaryKey"`
+	Date  time.Time       `gorm:"primaryKey;type:date"`
+	Rate  decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14HistoricalRate) TableName() string { return "historical_rates" }
+
+type v14PortfolioGoal struct {
+	ID        uint            `gorm:"primarykey"`
+	GoalTotal decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14PortfolioGoal) TableName() string { return "portfolio_goals" }
+
+type v14AlertRule struct {
+	ID        uint            `gorm:"primarykey"`
+	Threshold decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14AlertRule) TableName() string { return "alert_rules" }
+
+type v14AlertEvent struct {
+	ID        uint            `gorm:"primaryKey"`
+	Value     decimal.Decimal `gorm:"type:text"`
+	Threshold decimal.Decimal `gorm:"type:text"`
+}
+
+func (v14AlertEvent) TableName() string { return "alert_events" }
@@ -0,0 +1,198 @@
+// Package migrations versions the database schema. Every migration has a
+// number, an up step and, when it can be undone, a down step. Applied
+// migrations are recorded in the schema_version table.
+//
+// Migrations describe tables with their own copies of the models, so later
+// changes to internal/models never rewrite an old migration. To change the
+// schema, add a file with the next number and append it to all.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error // nil when the change cannot be undone
+}
+
+// all lists every migration, in version order
+var all = []Migration{
+	baseline,
+	portfolioOwnership,
+	transactionID,
+	decimalMoney,
+	exchangeRates,
+	historicalRates,
+	alerts,
+	webhooks,
+	notificationPreferences,
+	jobs,
+	quoteMetadata,
+	symbolSearch,
+	tickerMetadata,
+	decimalText,
+}
+
+// SchemaVersion is a row of schema_version: one applied migration
+type SchemaVersion struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (SchemaVersion) TableName() string { return "schema_version" }
+
+// Status describes a migration and when it was applied (nil while pending)
+type Status struct {
+	Version    int
+	Name       string
+	Reversible bool
+	AppliedAt  *time.Time
+}
+
+// ErrIrreversible is returned by Down for a migration without a down step
+var ErrIrreversible = errors.New("migration cannot be undone")
+
+// Latest is the version of the newest migration
+func Latest() int {
+	return all[len(all)-1].Version
+}
+
+// Current returns the version of the newest applied migration, 0 for an empty database
+func Current(db *gorm.DB) (int, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for v := range applied {
+		current = max(current, v)
+	}
+	return current, nil
+}
+
+// List reports every migration known to this build, oldest first
+func List(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Status, 0, len(all))
+	for _, m := range all {
+		s := Status{Version: m.Version, Name: m.Name, Reversible: m.Down != nil}
+		if row, ok := applied[m.Version]; ok {
+			s.AppliedAt = &row.AppliedAt
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// Up applies the pending migrations up to and including target (0: all of
+// them), each in its own transaction. It returns how many were applied.
+func Up(db *gorm.DB, logger *zap.SugaredLogger, target int) (int, error) {
+	if target == 0 {
+		target = Latest()
+	}
+	if target < 0 || target > Latest() {
+		return 0, fmt.Errorf("unknown version %d (latest is %d)", target, Latest())
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+	for v := range applied {
+		if v > Latest() {
+			return 0, fmt.Errorf("the database is at version %d, newer than this build (%d)", v, Latest())
+		}
+	}
+
+	count := 0
+	for _, m := range all {
+		if m.Version > target {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		logger.Infow("Applied migration", "version", m.Version, "name", m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down undoes the applied migrations newer than target, newest first.
+// It stops at the first migration that cannot be undone.
+func Down(db *gorm.DB, logger *zap.SugaredLogger, target int) (int, error) {
+	if target < 0 || target > Latest() {
+		return 0, fmt.Errorf("unknown version %d (latest is %d)", target, Latest())
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= target {
+			break
+		}
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if m.Down == nil {
+			return count, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, ErrIrreversible)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaVersion{}, m.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		logger.Infow("Reverted migration", "version", m.Version, "name", m.Name)
+		count++
+	}
+	return count, nil
+}
+
+// appliedVersions reads schema_version, creating it on first use
+func appliedVersions(db *gorm.DB) (map[int]SchemaVersion, error) {
+	if err := db.AutoMigrate(&SchemaVersion{}); err != nil {
+		return nil, err
+	}
+	var rows []SchemaVersion
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]SchemaVersion, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
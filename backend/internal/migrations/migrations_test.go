@@ -0,0 +1,170 @@
+package migrations
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+)
+
+// TestUpDown applies every migration, reverts them all and applies them again
+func TestUpDown(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		logger := zap.NewNop().Sugar()
+		if _, err := Up(db, logger, 0); err != nil {
+			t.Fatal(err)
+		}
+		// Irreversible migrations stop the way down
+		lowest := 0
+		for _, m := range all {
+			if m.Down == nil {
+				lowest = m.Version
+			}
+		}
+		if _, err := Down(db, logger, lowest); err != nil {
+			t.Fatal(err)
+		}
+		if current, err := Current(db); err != nil || current != lowest {
+			t.Fatalf("Current = %d, %v after reverting, want %d", current, err, lowest)
+		}
+		if _, err := Up(db, logger, 0); err != nil {
+			t.Fatal(err)
+		}
+		if current, err := Current(db); err != nil || current != Latest() {
+			t.Fatalf("Current = %d, %v, want %d", current, err, Latest())
+		}
+	})
+}
+
+// TestPortfolioOwnership upgrades a database from before portfolios, where
+// transactions and goals had a user_id
+func TestPortfolioOwnership(t *testing.T) {
+	dbtest.Each(t, testPortfolioOwnership)
+}
+
+func testPortfolioOwnership(t *testing.T, db *gorm.DB) {
+	logger := zap.NewNop().Sugar()
+	if _, err := Up(db, logger, 1); err != nil {
+		t.Fatal(err)
+	}
+	// Quoted like gorm created it: the SQLite driver only drops quoted columns
+	for _, table := range []string{"transactions", "portfolio_goals"} {
+		if err := db.Exec("ALTER TABLE " + table + ` ADD COLUMN "user_id" integer`).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, stmt := range []string{
+		"INSERT INTO users (id, email) VALUES (1, 'a@example.com'), (2, 'b@example.com')",
+		"INSERT INTO transactions (id, symbol, user_id) VALUES ('t1', 'AAPL', 1), ('t2', 'MSFT', 2)",
+		"INSERT INTO portfolio_goals (id, goal_total, user_id) VALUES (1, 1000, 2)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Up(db, logger, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var members []v1PortfolioMember
+	if err := db.Order("user_id").Find(&members).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 || members[0].UserID != 1 || members[1].UserID != 2 || members[0].Role != "owner" {
+		t.Fatalf("members = %+v, want an owner per user", members)
+	}
+	portfolios := map[uint]uint{members[0].UserID: members[0].PortfolioID, members[1].UserID: members[1].PortfolioID}
+
+	var transactions []v1Transaction
+	if err := db.Order("id").Find(&transactions).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i, userID := range []uint{1, 2} {
+		tx := transactions[i]
+		if tx.PortfolioID != portfolios[userID] || tx.CreatedByID != userID {
+			t.Errorf("transaction %s is in portfolio %d by %d, want %d by %d", tx.ID, tx.PortfolioID, tx.CreatedByID, portfolios[userID], userID)
+		}
+	}
+	var goal v1PortfolioGoal
+	if err := db.First(&goal).Error; err != nil {
+		t.Fatal(err)
+	}
+	if goal.PortfolioID != portfolios[2] {
+		t.Errorf("goal is in portfolio %d, want %d", goal.PortfolioID, portfolios[2])
+	}
+
+	for _, model := range []any{&v2LegacyTransaction{}, &v2LegacyPortfolioGoal{}} {
+		if db.Migrator().HasColumn(model, "UserID") {
+			t.Errorf("%T still has user_id", model)
+		}
+	}
+}
+
+func TestV4MoneyPlaces(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     int32
+	}{
+		{"USD", 2},
+		{"BRL", 2},
+		{"JPY", 0},
+		{"BTC", 8},
+		{"ETH", 8},
+	}
+	for _, tt := range tests {
+		if got := v4MoneyPlaces(tt.currency); got != tt.want {
+			t.Errorf("v4MoneyPlaces(%s) = %d, want %d", tt.currency, got, tt.want)
+		}
+	}
+}
+
+// TestExchangeRates turns the currencies table and its audit entries into
+// CODE/BRL exchange rates
+func TestExchangeRates(t *testing.T) {
+	dbtest.Each(t, testExchangeRates)
+}
+
+func testExchangeRates(t *testing.T, db *gorm.DB) {
+	logger := zap.NewNop().Sugar()
+	if _, err := Up(db, logger, 4); err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"INSERT INTO currencies (code, rate, updated_at) VALUES ('USD', '5.12', '2024-01-02 00:00:00')",
+		`INSERT INTO audit_entries (id, entity, entity_id, before, after) VALUES
+			(1, 'currency', 'USD', NULL, '{"code":"USD","rate":5.12,"updated_at":"2024-01-02T00:00:00Z"}')`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Up(db, logger, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	var rate v5ExchangeRate
+	if err := db.First(&rate).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rate.Base != "USD" || rate.Quote != "BRL" || rate.Rate.String() != "5.12" {
+		t.Errorf("rate = %+v, want USD/BRL 5.12", rate)
+	}
+
+	var entry struct {
+		Entity   string
+		EntityID string
+		Before   *string
+		After    string
+	}
+	if err := db.Table("audit_entries").First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := `{"base":"USD","quote":"BRL","rate":5.12,"updated_at":"2024-01-02T00:00:00Z"}`
+	if entry.Entity != "exchange_rate" || entry.EntityID != "USD/BRL" || entry.Before != nil || entry.After != want {
+		t.Errorf("entry = %+v, want exchange_rate USD/BRL after %s", entry, want)
+	}
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// AlertType is the condition an alert rule watches
+type AlertType string
+
+const (
+	AlertPriceAbove AlertType = "price_above" // price at or above Threshold, in the ticker's currency
+	AlertPriceBelow AlertType = "price_below" // price at or below Threshold
+	AlertDayChange  AlertType = "day_change"  // day change of at least Threshold percent, up or down
+	AlertDrawdown   AlertType = "drawdown"    // price at least Threshold percent below the average cost
+	AlertGoalDrift  AlertType = "goal_drift"  // a category's share differs from the goal by at least Threshold points
+)
+
+// AlertTypes lists every alert type
+var AlertTypes = []AlertType{AlertPriceAbove, AlertPriceBelow, AlertDayChange, AlertDrawdown, AlertGoalDrift}
+
+// SymbolAlert reports whether rules of the type watch a single symbol
+func (t AlertType) SymbolAlert() bool {
+	return t != AlertGoalDrift
+}
+
+// AlertRule is checked after every price refresh. Once it triggers, it stays
+// quiet for CooldownMinutes even if the condition still holds.
+type AlertRule struct {
+	gorm.Model
+	PortfolioID     uint            `json:"portfolio_id" gorm:"index"`
+	CreatedByID     uint            `json:"created_by_id"`
+	Type            AlertType       `json:"type"`
+	Symbol          string          `json:"symbol"`   // every type but goal_drift
+	Category        string          `json:"category"` // goal_drift only; empty for every category of the goal
+	Threshold       decimal.Decimal `json:"threshold"`
+	CooldownMinutes int             `json:"cooldown_minutes"`
+	Enabled         bool            `json:"enabled"`
+	LastTriggeredAt *time.Time      `json:"last_triggered_at"`
+}
+
+// CoolingDown reports whether the rule triggered less than its cooldown before now
+func (a AlertRule) CoolingDown(now time.Time) bool {
+	return a.LastTriggeredAt != nil && now.Sub(*a.LastTriggeredAt) < time.Duration(a.CooldownMinutes)*time.Minute
+}
+
+// AlertEvent records a rule that triggered. Value is what was observed (a
+// price or a percentage), so the history survives later edits of the rule.
+type AlertEvent struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time       `gorm:"index" json:"created_at"`
+	RuleID      uint            `gorm:"index" json:"rule_id"`
+	PortfolioID uint            `gorm:"index" json:"portfolio_id"`
+	Type        AlertType       `json:"type"`
+	Symbol      string          `json:"symbol"`
+	Category    string          `json:"category"`
+	Value       decimal.Decimal `json:"value"`
+	Threshold   decimal.Decimal `json:"threshold"`
+	Message     string          `json:"message"`
+}
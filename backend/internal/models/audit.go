@@ -0,0 +1,40 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditAction is what a change did to a record
+type AuditAction string
+
+const (
+	AuditCreate AuditAction = "create"
+	AuditUpdate AuditAction = "update"
+	AuditDelete AuditAction = "delete"
+)
+
+// Audited entities
+const (
+	EntityTransaction  = "transaction"
+	EntityTicker       = "ticker"
+	EntityGoal         = "goal"          // one per portfolio; EntityID is the portfolio ID
+	EntityExchangeRate = "exchange_rate" // EntityID is the pair, e.g. USD/BRL
+	EntityCurrency     = "currency"      // before exchange rates were pairs, see audit.UpgradeLegacy
+)
+
+// AuditEntry records one change. Entries are append-only: reverting a change
+// adds a new entry pointing at the reverted one.
+type AuditEntry struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time       `gorm:"index" json:"created_at"`
+	Entity      string          `gorm:"index:idx_audit_entity" json:"entity"`
+	EntityID    string          `gorm:"index:idx_audit_entity" json:"entity_id"`
+	Action      AuditAction     `json:"action"`
+	Before      json.RawMessage `gorm:"type:text" json:"before"`   // null for creates
+	After       json.RawMessage `gorm:"type:text" json:"after"`    // null for deletes
+	PortfolioID uint            `gorm:"index" json:"portfolio_id"` // 0 for market data (tickers and currencies)
+	ActorID     uint            `json:"actor_id"`                  // 0 for changes the server made on its own
+	RequestID   string          `json:"request_id"`
+	RevertOfID  *uint           `json:"revert_of_id,omitempty"`
+}
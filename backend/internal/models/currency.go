@@ -2,11 +2,73 @@ package models
 
 import (
 	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate is the price of one unit of Base in Quote (e.g., USD/BRL 5.50
+// means 1 USD = 5.50 BRL). Inverse and cross rates are derived from the stored
+// pairs (see services.RateTable).
+type ExchangeRate struct {
+	Base      string          `gorm:"primaryKey" json:"base"`
+	Quote     string          `gorm:"primaryKey" json:"quote"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Pair returns the pair as BASE/QUOTE, which is also its audit log ID
+func (e ExchangeRate) Pair() string {
+	return e.Base + "/" + e.Quote
+}
+
+// Sources of historical rates
+const (
+	RateSourceAlphaVantage = "alphavantage" // FX_DAILY
+	RateSourcePTAX         = "ptax"         // Banco Central do Brasil CSV
+	RateSourceManual       = "manual"
 )
 
-// Currency stores exchange rates (e.g., USD to BRL)
-type Currency struct {
-	Code      string    `gorm:"primaryKey" json:"code"` // e.g., "USD"
-	Rate      float64   `json:"rate"`                   // Rate to BRL (e.g., 5.50 means 1 USD = 5.50 BRL)
-	UpdatedAt time.Time `json:"updated_at"`
+// HistoricalRate is the closing rate of a pair on a day, used to value past
+// transactions at the rate of their date. Date is midnight UTC.
+type HistoricalRate struct {
+	Base   string          `gorm:"primaryKey" json:"base"`
+	Quote  string          `gorm:"primaryKey" json:"quote"`
+	Date   time.Time       `gorm:"primaryKey;type:date" json:"date"`
+	Rate   decimal.Decimal `json:"rate"`
+	Source string          `json:"source"`
+}
+
+// Pair returns the pair as BASE/QUOTE
+func (h HistoricalRate) Pair() string {
+	return h.Base + "/" + h.Quote
+}
+
+// LegacyCurrency is a row of the currencies table, which held rates to BRL
+// before rates became pairs. Old backups and audit entries still have them.
+type LegacyCurrency struct {
+	Code      string          `json:"code"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// ExchangeRate returns the row as the CODE/BRL pair
+func (c LegacyCurrency) ExchangeRate() ExchangeRate {
+	return ExchangeRate{Base: c.Code, Quote: "BRL", Rate: c.Rate, UpdatedAt: c.UpdatedAt}
+}
+
+// SupportedCurrencies lists the currency codes accepted for transactions and tickers
+var SupportedCurrencies = map[string]bool{
+	"BRL": true,
+	"USD": true,
+	"EUR": true,
+	"GBP": true,
+	"JPY": true,
+	"CHF": true,
+	"CAD": true,
+	"AUD": true,
+	"CNY": true,
+	"ARS": true,
+	"MXN": true,
+	"BTC": true,
+	"ETH": true,
 }
@@ -1,10 +1,15 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
 
 type PortfolioGoal struct {
 	gorm.Model
-	GoalTotal   float64          `json:"goal_total"`
+	PortfolioID uint             `json:"portfolio_id" gorm:"index"`
+	UpdatedByID uint             `json:"updated_by_id"`
+	GoalTotal   decimal.Decimal  `json:"goal_total"`
 	Allocations []GoalAllocation `json:"allocations" gorm:"foreignKey:PortfolioGoalID;constraint:OnDelete:CASCADE"`
 }
 
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Job kinds
+const (
+	JobRefreshPrices = "prices.refresh"
+)
+
+// Job states
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job item states
+const (
+	JobItemPending     = "pending"
+	JobItemUpdated     = "updated"
+	JobItemCached      = "cached" // the stored price was still fresh
+	JobItemRateLimited = "rate_limited"
+	JobItemNotFound    = "not_found"
+	JobItemError       = "error"
+	JobItemSkipped     = "skipped" // the job was cancelled before reaching it
+)
+
+// Job is work that runs in the background after the request that started it,
+// e.g. a price refresh. Items report on each unit of work, e.g. each symbol.
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	PortfolioID uint       `gorm:"index" json:"portfolio_id"`
+	CreatedByID uint       `json:"created_by_id"`
+	Kind        string     `gorm:"index" json:"kind"`
+	Status      string     `gorm:"index" json:"status"`
+	Total       int        `json:"total"`
+	Done        int        `json:"done"`
+	Updated     int        `json:"updated"`
+	Failed      int        `json:"failed"`
+	Alerts      int        `json:"alerts"`                       // alerts triggered once the job finished
+	Items       []JobItem  `gorm:"serializer:json" json:"items"` // in processing order
+	Error       string     `json:"error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// JobItem is the outcome of one unit of work of a job
+type JobItem struct {
+	Symbol string           `json:"symbol"`
+	Status string           `json:"status"`
+	Price  *decimal.Decimal `json:"price,omitempty"` // the new price, once updated
+	Error  string           `json:"error,omitempty"`
+}
+
+// Finished reports whether the job is no longer running
+func (j Job) Finished() bool {
+	return j.Status != JobRunning
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Quantities, prices, fees and rates are exact decimals, stored in NUMERIC
+// columns on PostgreSQL and TEXT ones on SQLite, whose NUMERIC would round them
+// to a float64 (see the migrations). They are still sent as JSON numbers, with
+// every digit, so clients need no changes.
+func init() {
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// minorUnits lists the currencies whose amounts are not rounded to cents
+var minorUnits = map[string]int32{
+	"JPY": 0,
+	"BTC": 8,
+	"ETH": 8,
+}
+
+// MoneyPlaces returns the number of decimal places amounts in a currency are rounded to
+func MoneyPlaces(currency string) int32 {
+	if places, ok := minorUnits[currency]; ok {
+		return places
+	}
+	return 2
+}
+
+// RoundMoney rounds a computed amount (totals, fees, PnL) to the currency's minor
+// unit, half away from zero. Unit prices, rates and quantities are kept as entered.
+func RoundMoney(amount decimal.Decimal, currency string) decimal.Decimal {
+	return amount.Round(MoneyPlaces(currency))
+}
+
+// Money is an amount in a currency, rounded to the currency's minor unit
+type Money struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// NewMoney rounds amount to the minor unit of currency
+func NewMoney(amount decimal.Decimal, currency string) Money {
+	return Money{Amount: RoundMoney(amount, currency), Currency: currency}
+}
+
+// Add sums two amounts of the same currency. Adding different currencies is a
+// bug (convert first), so it panics.
+func (m Money) Add(other Money) Money {
+	if m.Currency != other.Currency {
+		panic(fmt.Sprintf("models: adding %s to %s", other.Currency, m.Currency))
+	}
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}
+}
+
+// Sub subtracts an amount of the same currency, with the same rules as Add
+func (m Money) Sub(other Money) Money {
+	return m.Add(Money{Amount: other.Amount.Neg(), Currency: other.Currency})
+}
+
+func (m Money) String() string {
+	return m.Amount.StringFixed(MoneyPlaces(m.Currency)) + " " + m.Currency
+}
@@ -0,0 +1,110 @@
+package models_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// TestDecimalRoundTrip stores decimals with more significant digits than a
+// float64 holds and checks every digit comes back
+func TestDecimalRoundTrip(t *testing.T) {
+	long := decimal.RequireFromString("12345678901.123456789012")
+	tiny := decimal.RequireFromString("0.000000012345678901234567")
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name string
+			row  any
+			read func() ([]decimal.Decimal, error)
+			want []decimal.Decimal
+		}{
+			{
+				"transaction",
+				&models.Transaction{ID: "t1", Symbol: "BTC/USD", Type: models.Buy, Quantity: tiny, Price: long, Fee: long, Date: day},
+				func() ([]decimal.Decimal, error) {
+					var tx models.Transaction
+					err := db.First(&tx, "id = ?", "t1").Error
+					return []decimal.Decimal{tx.Quantity, tx.Price, tx.Fee}, err
+				},
+				[]decimal.Decimal{tiny, long, long},
+			},
+			{
+				"ticker",
+				&models.Ticker{Symbol: "AAPL", Price: long},
+				func() ([]decimal.Decimal, error) {
+					var ticker models.Ticker
+					err := db.First(&ticker, "symbol = ?", "AAPL").Error
+					return []decimal.Decimal{ticker.Price}, err
+				},
+				[]decimal.Decimal{long},
+			},
+			{
+				"exchange rate",
+				&models.ExchangeRate{Base: "BTC", Quote: "BRL", Rate: long},
+				func() ([]decimal.Decimal, error) {
+					var rate models.ExchangeRate
+					err := db.First(&rate, "base = ? AND quote = ?", "BTC", "BRL").Error
+					return []decimal.Decimal{rate.Rate}, err
+				},
+				[]decimal.Decimal{long},
+			},
+			{
+				"historical rate",
+				&models.HistoricalRate{Base: "USD", Quote: "BRL", Date: day, Rate: tiny},
+				func() ([]decimal.Decimal, error) {
+					var rate models.HistoricalRate
+					err := db.First(&rate, "base = ? AND quote = ?", "USD", "BRL").Error
+					return []decimal.Decimal{rate.Rate}, err
+				},
+				[]decimal.Decimal{tiny},
+			},
+			{
+				"goal",
+				&models.PortfolioGoal{PortfolioID: 1, GoalTotal: long},
+				func() ([]decimal.Decimal, error) {
+					var goal models.PortfolioGoal
+					err := db.First(&goal, "portfolio_id = ?", 1).Error
+					return []decimal.Decimal{goal.GoalTotal}, err
+				},
+				[]decimal.Decimal{long},
+			},
+			{
+				"alert event",
+				&models.AlertEvent{RuleID: 1, Value: long, Threshold: tiny},
+				func() ([]decimal.Decimal, error) {
+					var event models.AlertEvent
+					err := db.First(&event, "rule_id = ?", 1).Error
+					return []decimal.Decimal{event.Value, event.Threshold}, err
+				},
+				[]decimal.Decimal{long, tiny},
+			},
+		}
+		for _, tt := range tests {
+			if err := db.Create(tt.row).Error; err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			got, err := tt.read()
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("%s: read back %s, stored %s", tt.name, got[i], tt.want[i])
+				}
+			}
+		}
+	})
+}
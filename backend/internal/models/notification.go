@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// Notification channels
+const (
+	ChannelEmail    = "email"
+	ChannelTelegram = "telegram"
+)
+
+// Notification topics
+const (
+	TopicAlerts     = "alert.triggered" // every triggered alert of the user's portfolios
+	TopicTaxSummary = "tax.monthly"     // the realized gains of the previous month, on the 1st
+)
+
+// NotificationTopics lists every topic users can subscribe to
+var NotificationTopics = []string{TopicAlerts, TopicTaxSummary}
+
+// NotificationPreference is how a user wants to be told about things on one channel
+type NotificationPreference struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Channel   string    `gorm:"primaryKey" json:"channel"`
+	Address   string    `json:"address"`                       // e-mail address or Telegram chat ID
+	Topics    []string  `gorm:"serializer:json" json:"topics"` // empty for every topic
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// YYYY-MM of the last monthly tax summary sent, so it goes out once
+	TaxSummarySentFor string `json:"tax_summary_sent_for"`
+}
+
+// Wants reports whether the preference subscribes to a topic
+func (p NotificationPreference) Wants(topic string) bool {
+	if !p.Enabled {
+		return false
+	}
+	if len(p.Topics) == 0 {
+		return true
+	}
+	for _, t := range p.Topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
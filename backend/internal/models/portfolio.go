@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Role is the access level of a member in a portfolio
+type Role string
+
+const (
+	RoleViewer Role = "viewer" // read only
+	RoleEditor Role = "editor" // also creates, edits and deletes transactions
+	RoleOwner  Role = "owner"  // also manages the goal, ticker settings and members
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// AtLeast reports whether r grants everything min grants
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min] && roleRank[min] > 0
+}
+
+// Portfolio owns transactions and a goal. Users reach it through memberships.
+type Portfolio struct {
+	gorm.Model
+	Name string `json:"name"`
+}
+
+// PortfolioMember gives a user a role in a portfolio
+type PortfolioMember struct {
+	PortfolioID uint      `gorm:"primaryKey" json:"portfolio_id"`
+	UserID      uint      `gorm:"primaryKey;index" json:"user_id"`
+	Role        Role      `json:"role"`
+	AddedByID   uint      `json:"added_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// SymbolMatch is a listing found by a symbol search
+type SymbolMatch struct {
+	Symbol     string  `json:"symbol"` // as used in tickers, e.g. PETR4 for PETR4.SAO
+	Name       string  `json:"name"`
+	Exchange   string  `json:"exchange"`
+	Currency   string  `json:"currency"`
+	AssetType  string  `json:"asset_type"` // as named by the provider, e.g. Equity or ETF
+	Region     string  `json:"region"`
+	MatchScore float64 `json:"match_score"` // from 0 to 1
+}
+
+// SymbolSearch caches the matches of a symbol search, so the provider is only
+// asked once for every query
+type SymbolSearch struct {
+	Query      string        `gorm:"primaryKey" json:"query"` // trimmed, upper case
+	Matches    []SymbolMatch `gorm:"serializer:json" json:"matches"`
+	SearchedAt time.Time     `json:"searched_at"`
+}
@@ -1,16 +1,96 @@
 package models
 
 import (
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Sources of ticker prices
+const (
+	QuoteSourceAlphaVantage = "alphavantage"
+	QuoteSourceManual       = "manual"
+	QuoteSourceTransaction  = "transaction" // the price paid in the first transaction, until one is fetched
+)
+
+// Asset classes, which set how long a price stays fresh
+const (
+	AssetEquity = "equity"
+	AssetCrypto = "crypto"
+)
+
+// Asset types of a ticker, set from the provider or by hand
+const (
+	AssetTypeStock  = "stock"
+	AssetTypeFII    = "fii" // Brazilian real estate fund
+	AssetTypeETF    = "etf"
+	AssetTypeBDR    = "bdr" // Brazilian depositary receipt of a foreign stock
+	AssetTypeCrypto = "crypto"
+	AssetTypeBond   = "bond"
 )
 
 // Ticker acts as a cache for the latest market price
 type Ticker struct {
-	Symbol           string    `gorm:"primaryKey" json:"symbol"`
-	Price            float64   `json:"price"`
-	DayChangePercent float64   `json:"day_change_percent"`
-	Tags             string    `json:"tags"`
-	Category         string    `json:"category"`
-	Currency         string    `json:"currency" gorm:"default:USD"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	Symbol           string          `gorm:"primaryKey" json:"symbol"`
+	Price            decimal.Decimal `json:"price"`
+	DayChangePercent float64         `json:"day_change_percent"`
+	Tags             string          `json:"tags"`
+	Category         string          `json:"category"`
+	Currency         string          `json:"currency" gorm:"default:USD"`
+	Exchange         string          `json:"exchange"` // where it is listed, e.g. B3; empty when unknown
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Source           string          `json:"source"`     // where Price came from; empty before it was tracked
+	FetchedAt        *time.Time      `json:"fetched_at"` // when Price was fetched or set by hand
+	Stale            bool            `json:"stale" gorm:"-"`
+
+	// Metadata, filled from the provider when the ticker is created or refreshed and editable by hand
+	Name              string     `json:"name"`
+	Country           string     `json:"country"`
+	Sector            string     `json:"sector"`
+	Industry          string     `json:"industry"`
+	AssetType         string     `json:"asset_type"`
+	ISIN              string     `json:"isin" gorm:"column:isin"`
+	LogoURL           string     `json:"logo_url"`
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at"` // when the provider's overview was last applied
+}
+
+// AssetClass tells crypto pairs (e.g. BTC/USD) from equities
+func (t Ticker) AssetClass() string {
+	if t.AssetType == AssetTypeCrypto || strings.Contains(t.Symbol, "/") {
+		return AssetCrypto
+	}
+	return AssetEquity
+}
+
+// ValidISIN checks the format and check digit of an ISIN (e.g. US0378331005)
+func ValidISIN(isin string) bool {
+	if len(isin) != 12 {
+		return false
+	}
+	// Letters count as two digits (A=10 ... Z=35), then the Luhn check applies
+	var digits []int
+	for i, c := range isin {
+		switch {
+		case c >= '0' && c <= '9' && i >= 2:
+			digits = append(digits, int(c-'0'))
+		case c >= 'A' && c <= 'Z' && i < 11:
+			n := int(c-'A') + 10
+			digits = append(digits, n/10, n%10)
+		default:
+			return false
+		}
+	}
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
 }
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIToken is a personal access token for scripts and dashboards.
+// Only the SHA-256 of the token is stored; Prefix identifies it in listings.
+type APIToken struct {
+	gorm.Model
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	TokenHash  string     `gorm:"uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -15,16 +16,24 @@ const (
 )
 
 type Transaction struct {
-	gorm.Model
-	ID       string          `gorm:"primaryKey" json:"ID"`
-	Symbol   string          `json:"symbol" gorm:"index"`
-	Type     TransactionType `json:"type"`
-	Quantity float32         `json:"quantity"`
-	Price    float64         `json:"price"`
-	Currency string          `json:"currency" gorm:"default:USD"`
-	Fee      float64         `json:"fee" gorm:"default:0"`
-	Date     time.Time       `json:"date"`
+	ID        string         `gorm:"primaryKey;not null" json:"ID"`
+	CreatedAt time.Time      `json:"CreatedAt"`
+	UpdatedAt time.Time      `json:"UpdatedAt"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"DeletedAt"`
+
+	Symbol   string          `json:"symbol" gorm:"index" validate:"required"`
+	Type     TransactionType `json:"type" validate:"required,oneof=BUY SELL"`
+	Quantity decimal.Decimal `json:"quantity" validate:"required,gt=0"`
+	Price    decimal.Decimal `json:"price" validate:"required,gt=0"`
+	Currency string          `json:"currency" gorm:"default:USD" validate:"currency"`
+	Fee      decimal.Decimal `json:"fee" gorm:"default:0" validate:"gte=0"`
+	Date     time.Time       `json:"date" validate:"required,notfuture"`
 	Note     string          `json:"note"`
+
+	// Set by the server: the portfolio and who created and last changed the record
+	PortfolioID uint `json:"portfolio_id" gorm:"index"`
+	CreatedByID uint `json:"created_by_id"`
+	UpdatedByID uint `json:"updated_by_id"`
 }
 
 func (t *Transaction) BeforeCreate(tx *gorm.DB) (err error) {
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// User owns transactions and goals. Admins are set up with ADMIN_EMAIL or `api create-admin`.
+type User struct {
+	gorm.Model
+	Email        string `gorm:"uniqueIndex" json:"email"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"-"`
+	IsAdmin      bool   `json:"is_admin"`
+}
+
+// Session is a login. Only the SHA-256 of the bearer token is stored.
+type Session struct {
+	TokenHash string    `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
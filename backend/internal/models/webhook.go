@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook sends the events of a portfolio to a URL, signed with Secret
+// (see services.WebhookService)
+type Webhook struct {
+	gorm.Model
+	PortfolioID uint     `json:"portfolio_id" gorm:"index"`
+	CreatedByID uint     `json:"created_by_id"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events" gorm:"serializer:json"` // empty for every event
+	Secret      string   `json:"-"`
+	Enabled     bool     `json:"enabled"`
+}
+
+// Wants reports whether the webhook subscribes to an event type
+func (w Webhook) Wants(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery states
+const (
+	DeliveryPending   = "pending" // waiting for its first or next attempt
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // gave up after the last attempt
+)
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// last attempt
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	WebhookID     uint       `gorm:"index" json:"webhook_id"`
+	EventID       string     `json:"event_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"` // the JSON body sent
+	Status        string     `gorm:"index" json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	StatusCode    int        `json:"status_code"` // of the last attempt; 0 if no response
+	Error         string     `json:"error"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
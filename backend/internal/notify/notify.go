@@ -0,0 +1,80 @@
+// Package notify tells people about alerts and tax summaries over e-mail and
+// Telegram. Channels implement Notifier and are configured from the
+// environment; users choose theirs in their notification preferences.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// Message is a rendered notification
+type Message struct {
+	Subject string
+	Text    string
+}
+
+// Notifier sends messages over one channel
+type Notifier interface {
+	// Channel is the name users pick in their preferences, e.g. email
+	Channel() string
+	// Send delivers msg to an address of the channel (an e-mail address, a chat ID)
+	Send(ctx context.Context, to string, msg Message) error
+}
+
+// FromEnv returns the channels configured in the environment, by name:
+//
+//	SMTP_HOST, SMTP_PORT (default 587), SMTP_USERNAME, SMTP_PASSWORD, SMTP_FROM
+//	TELEGRAM_BOT_TOKEN, TELEGRAM_API_URL (default https://api.telegram.org)
+//
+// A channel without its host or token is left out.
+func FromEnv() (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier)
+
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := 587
+		if raw := os.Getenv("SMTP_PORT"); raw != "" {
+			p, err := strconv.Atoi(raw)
+			if err != nil || p <= 0 || p > 65535 {
+				return nil, fmt.Errorf("invalid SMTP_PORT %q", raw)
+			}
+			port = p
+		}
+		from := os.Getenv("SMTP_FROM")
+		if from == "" {
+			return nil, fmt.Errorf("SMTP_FROM is required with SMTP_HOST")
+		}
+		if _, err := mail.ParseAddress(from); err != nil {
+			return nil, fmt.Errorf("invalid SMTP_FROM %q: %w", from, err)
+		}
+		notifiers[models.ChannelEmail] = &SMTPNotifier{
+			Host:     host,
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	}
+
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		notifiers[models.ChannelTelegram] = NewTelegramNotifier(os.Getenv("TELEGRAM_API_URL"), token)
+	}
+
+	return notifiers, nil
+}
+
+// Channels returns the names of the notifiers, sorted
+func Channels(notifiers map[string]Notifier) []string {
+	names := make([]string, 0, len(notifiers))
+	for name := range notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
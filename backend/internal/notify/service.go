@@ -0,0 +1,242 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// sendTimeout bounds a single delivery
+const sendTimeout = 30 * time.Second
+
+// monthlyCheck is how often RunMonthly looks for tax summaries to send
+const monthlyCheck = time.Hour
+
+// ErrNoChannel is returned when a preference names a channel that is not configured
+var ErrNoChannel = errors.New("channel not configured")
+
+// ErrNoSales is returned when a month has no sales to summarize
+var ErrNoSales = errors.New("no sales in the month")
+
+// Service sends notifications to users on the channels they chose
+type Service struct {
+	DB        *gorm.DB
+	Logger    *zap.SugaredLogger
+	Notifiers map[string]Notifier
+	Templates *Templates
+}
+
+func NewService(db *gorm.DB, logger *zap.SugaredLogger, notifiers map[string]Notifier, templates *Templates) *Service {
+	return &Service{DB: db, Logger: logger, Notifiers: notifiers, Templates: templates}
+}
+
+// Handle notifies the members of a portfolio of its triggered alerts.
+// It is meant to be subscribed to the events bus; sending happens in the
+// background so slow channels do not hold up the request that triggered it.
+func (s *Service) Handle(e events.Event) {
+	if e.Type != events.AlertTriggered || len(s.Notifiers) == 0 {
+		return
+	}
+	alert, ok := e.Data.(models.AlertEvent)
+	if !ok {
+		return
+	}
+
+	var prefs []models.NotificationPreference
+	err := s.DB.Where("enabled = ? AND user_id IN (?)", true,
+		s.DB.Model(&models.PortfolioMember{}).Select("user_id").Where("portfolio_id = ?", e.PortfolioID)).
+		Find(&prefs).Error
+	if err != nil {
+		s.Logger.Errorw("failed to fetch notification preferences", "event", e.Type, "error", err)
+		return
+	}
+
+	var portfolio models.Portfolio
+	if err := s.DB.First(&portfolio, e.PortfolioID).Error; err != nil {
+		s.Logger.Errorw("failed to fetch portfolio", "portfolio_id", e.PortfolioID, "error", err)
+		return
+	}
+
+	for _, pref := range prefs {
+		if !pref.Wants(models.TopicAlerts) {
+			continue
+		}
+		go func(pref models.NotificationPreference) {
+			var user models.User
+			if err := s.DB.First(&user, pref.UserID).Error; err != nil {
+				s.Logger.Errorw("failed to fetch user", "user_id", pref.UserID, "error", err)
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			defer cancel()
+			data := AlertData{User: user, Portfolio: portfolio, Alert: alert}
+			if err := s.send(ctx, user, pref, KindAlert, data); err != nil {
+				s.Logger.Errorw("failed to send alert notification",
+					"user_id", pref.UserID, "channel", pref.Channel, "alert_event", alert.ID, "error", err)
+			}
+		}(pref)
+	}
+}
+
+// SendTest sends a test message to check a preference's address
+func (s *Service) SendTest(ctx context.Context, user models.User, pref models.NotificationPreference) error {
+	return s.send(ctx, user, pref, KindTest, TestData{User: user, Channel: pref.Channel})
+}
+
+// SendTaxSummary sends the realized gains of a month (YYYY-MM), one message
+// per portfolio of the user that had sales in it. Gains are reported in the
+// base currency at the rates of each sale. It returns ErrNoSales when there
+// was nothing to send.
+func (s *Service) SendTaxSummary(ctx context.Context, user models.User, pref models.NotificationPreference, month string) error {
+	start, err := time.Parse("2006-01", month)
+	if err != nil {
+		return fmt.Errorf("invalid month %q", month)
+	}
+	currency, err := services.BaseCurrency()
+	if err != nil {
+		return err
+	}
+	valuation, err := services.LoadValuation(s.DB, currency)
+	if err != nil {
+		return err
+	}
+
+	var portfolios []models.Portfolio
+	err = s.DB.Where("id IN (?)", s.DB.Model(&models.PortfolioMember{}).Select("portfolio_id").Where("user_id = ?", user.ID)).
+		Order("id").Find(&portfolios).Error
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, portfolio := range portfolios {
+		var transactions []models.Transaction
+		if err := s.DB.Where("portfolio_id = ?", portfolio.ID).Find(&transactions).Error; err != nil {
+			return err
+		}
+
+		report := services.BuildTaxReport(transactions, start.Year(), &valuation)
+		data := TaxSummaryData{
+			User:      user,
+			Portfolio: portfolio,
+			Month:     month,
+			Currency:  currency,
+			Summary:   services.MonthlyTaxSummary{Month: month, Currency: currency},
+		}
+		for _, m := range report.Months {
+			if m.Month == month {
+				data.Summary = m
+			}
+		}
+		for _, sale := range report.Details {
+			if !strings.HasPrefix(sale.Date, month) {
+				continue
+			}
+			data.Sales = append(data.Sales, sale)
+			if sale.Report == nil && !slices.Contains(data.MissingRates, sale.Currency) {
+				data.MissingRates = append(data.MissingRates, sale.Currency)
+			}
+		}
+		if len(data.Sales) == 0 {
+			continue
+		}
+
+		if err := s.send(ctx, user, pref, KindTaxSummary, data); err != nil {
+			return err
+		}
+		sent++
+	}
+	if sent == 0 {
+		return ErrNoSales
+	}
+	return nil
+}
+
+// RunMonthly sends the tax summary of the previous month to every preference
+// subscribed to it that has not had it yet, checking every hour until ctx is done.
+func (s *Service) RunMonthly(ctx context.Context) {
+	ticker := time.NewTicker(monthlyCheck)
+	defer ticker.Stop()
+
+	for {
+		s.sendMonthly(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) sendMonthly(ctx context.Context) {
+	month := PreviousMonth(time.Now())
+
+	var prefs []models.NotificationPreference
+	err := s.DB.Where("enabled = ? AND tax_summary_sent_for <> ?", true, month).Find(&prefs).Error
+	if err != nil {
+		s.Logger.Errorw("failed to fetch notification preferences", "error", err)
+		return
+	}
+
+	for _, pref := range prefs {
+		if !pref.Wants(models.TopicTaxSummary) || s.Notifiers[pref.Channel] == nil {
+			continue
+		}
+		var user models.User
+		if err := s.DB.First(&user, pref.UserID).Error; err != nil {
+			s.Logger.Errorw("failed to fetch user", "user_id", pref.UserID, "error", err)
+			continue
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := s.SendTaxSummary(sendCtx, user, pref, month)
+		cancel()
+		if err != nil && !errors.Is(err, ErrNoSales) {
+			// Left unmarked, so it is tried again at the next check
+			s.Logger.Errorw("failed to send tax summary",
+				"user_id", pref.UserID, "channel", pref.Channel, "month", month, "error", err)
+			continue
+		}
+
+		err = s.DB.Model(&pref).Update("tax_summary_sent_for", month).Error
+		if err != nil {
+			s.Logger.Errorw("failed to mark tax summary as sent", "user_id", pref.UserID, "error", err)
+		}
+	}
+}
+
+// PreviousMonth returns the month before t's, as YYYY-MM
+func PreviousMonth(t time.Time) string {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, -1, 0).Format("2006-01")
+}
+
+// Address returns where a preference's messages go: its address, or the
+// user's e-mail when an e-mail preference has none
+func Address(user models.User, pref models.NotificationPreference) string {
+	if pref.Address == "" && pref.Channel == models.ChannelEmail {
+		return user.Email
+	}
+	return pref.Address
+}
+
+func (s *Service) send(ctx context.Context, user models.User, pref models.NotificationPreference, kind string, data any) error {
+	notifier, ok := s.Notifiers[pref.Channel]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNoChannel, pref.Channel)
+	}
+	msg, err := s.Templates.Render(kind, data)
+	if err != nil {
+		return err
+	}
+	return notifier.Send(ctx, Address(user, pref), msg)
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// SMTPNotifier sends plain text e-mails. Without a username it sends without
+// authentication, which is what local sinks like MailHog expect.
+type SMTPNotifier struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (n *SMTPNotifier) Channel() string { return models.ChannelEmail }
+
+// Send delivers msg to an e-mail address. net/smtp has no context support,
+// so ctx only stops a send that has not started.
+func (n *SMTPNotifier) Send(ctx context.Context, to string, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", n.From)
+	fmt.Fprintf(&body, "To: %s\r\n", to)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(msg.Text)
+
+	// The envelope takes the bare address of From, e.g. of "Stocks <stocks@example.com>"
+	sender := n.From
+	if addr, err := mail.ParseAddress(n.From); err == nil {
+		sender = addr.Address
+	}
+
+	addr := net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+	return smtp.SendMail(addr, auth, sender, []string{to}, body.Bytes())
+}
@@ -0,0 +1,86 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// defaultTelegramURL is the Bot API; TELEGRAM_API_URL points elsewhere, e.g. at a mock
+const defaultTelegramURL = "https://api.telegram.org"
+
+// TelegramNotifier sends messages through a Telegram bot. The address is the chat ID.
+type TelegramNotifier struct {
+	BaseURL string
+	Token   string
+	Client  *http.Client
+}
+
+func NewTelegramNotifier(baseURL, token string) *TelegramNotifier {
+	if baseURL == "" {
+		baseURL = defaultTelegramURL
+	}
+	return &TelegramNotifier{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		Token:   token,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *TelegramNotifier) Channel() string { return models.ChannelTelegram }
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Send calls sendMessage with the subject as the first line
+func (n *TelegramNotifier) Send(ctx context.Context, to string, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"chat_id": to,
+		"text":    msg.Subject + "\n\n" + msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := n.BaseURL + "/bot" + n.Token + "/sendMessage"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		// The URL has the token; keep it out of logs
+		return fmt.Errorf("telegram request failed: %w", unwrapURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var data telegramResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+	}
+	if !data.OK {
+		return fmt.Errorf("telegram returned status %d: %s", resp.StatusCode, data.Description)
+	}
+	return nil
+}
+
+// unwrapURLError drops the URL from an *url.Error
+func unwrapURLError(err error) error {
+	var urlErr *neturl.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// Kinds of messages, each with its template
+const (
+	KindAlert      = models.TopicAlerts
+	KindTaxSummary = models.TopicTaxSummary
+	KindTest       = "test"
+)
+
+// defaultTemplates are used for the kinds without a file in the templates
+// directory. Each defines a "subject" and a "body" template.
+var defaultTemplates = map[string]string{
+	KindAlert: `{{define "subject"}}Alert: {{.Alert.Message}}{{end}}
+{{define "body"}}{{.Alert.Message}}
+
+Portfolio: {{.Portfolio.Name}}
+Rule: {{.Alert.Type}}, threshold {{.Alert.Threshold}}
+Triggered at {{.Alert.CreatedAt.Format "2006-01-02 15:04 MST"}}
+{{end}}`,
+
+	KindTaxSummary: `{{define "subject"}}Tax summary for {{.Month}}: {{.Portfolio.Name}}{{end}}
+{{define "body"}}Realized gains of {{.Portfolio.Name}} in {{.Month}}, in {{.Currency}}:
+
+Sales:      {{.Summary.Sales}}
+Cost basis: {{.Summary.CostBasis}}
+Fees:       {{.Summary.Fees}}
+Gain:       {{.Summary.Gain}}
+{{range .Sales}}
+{{.Date}} sold {{.Quantity}} {{.Symbol}}{{with .Report}}: gain {{.Gain.Amount}}{{if .Estimated}} (estimated rate){{end}}{{end}}{{end}}
+{{if .MissingRates}}
+Sales in {{join .MissingRates ", "}} are left out: there is no exchange rate to {{.Currency}}.
+{{end}}{{end}}`,
+
+	KindTest: `{{define "subject"}}Test notification{{end}}
+{{define "body"}}Hi{{with .User.Name}} {{.}}{{end}}, {{.Channel}} notifications are working.
+{{end}}`,
+}
+
+// AlertData is what the alert template is rendered with
+type AlertData struct {
+	User      models.User
+	Portfolio models.Portfolio
+	Alert     models.AlertEvent
+}
+
+// TaxSummaryData is what the monthly tax summary template is rendered with.
+// Summary totals the sales of Month converted to Currency.
+type TaxSummaryData struct {
+	User         models.User
+	Portfolio    models.Portfolio
+	Month        string // YYYY-MM
+	Currency     string
+	Summary      services.MonthlyTaxSummary
+	Sales        []services.RealizedGain
+	MissingRates []string
+}
+
+// TestData is what the test template is rendered with
+type TestData struct {
+	User    models.User
+	Channel string
+}
+
+// Templates renders messages. Every kind has a template; LoadTemplates
+// replaces the defaults with the files of a directory.
+type Templates struct {
+	byKind map[string]*template.Template
+}
+
+var funcs = template.FuncMap{"join": strings.Join}
+
+// LoadTemplates parses the default templates, replacing each with the file
+// <kind>.tmpl of dir when it exists (e.g. dir/alert.triggered.tmpl).
+// An empty dir keeps every default.
+func LoadTemplates(dir string) (*Templates, error) {
+	t := &Templates{byKind: make(map[string]*template.Template)}
+	for kind, text := range defaultTemplates {
+		source := "default"
+		if dir != "" {
+			path := filepath.Join(dir, kind+".tmpl")
+			raw, err := os.ReadFile(path)
+			switch {
+			case err == nil:
+				text, source = string(raw), path
+			case !errors.Is(err, fs.ErrNotExist):
+				return nil, err
+			}
+		}
+
+		tmpl, err := template.New(kind).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s template (%s): %w", kind, source, err)
+		}
+		for _, name := range []string{"subject", "body"} {
+			if tmpl.Lookup(name) == nil {
+				return nil, fmt.Errorf("%s template (%s) does not define %q", kind, source, name)
+			}
+		}
+		t.byKind[kind] = tmpl
+	}
+	return t, nil
+}
+
+// Render builds the message of a kind
+func (t *Templates) Render(kind string, data any) (Message, error) {
+	tmpl, ok := t.byKind[kind]
+	if !ok {
+		return Message{}, fmt.Errorf("no template for %s", kind)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Message{}, err
+	}
+	return Message{Subject: strings.TrimSpace(subject.String()), Text: body.String()}, nil
+}
@@ -0,0 +1,375 @@
+// Package openapi describes the HTTP API as an OpenAPI 3 document.
+//
+// The document is built from the route table in routes.go; request and response
+// schemas are derived from the Go types by reflection, so they follow the models.
+// Verify compares the table with the chi router so the two cannot drift apart.
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Document is the root of an OpenAPI 3 document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers,omitempty"`
+	Tags       []Tag                           `json:"tags,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+	Security   []map[string][]string           `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+	// Security overrides the document default; an empty list makes the operation public
+	Security *[]map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // path, query or header
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is the subset of JSON Schema used by the API
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// Version is the version of the API described by the document
+const Version = "1.0.0"
+
+var (
+	specOnce sync.Once
+	specJSON []byte
+)
+
+// Spec builds the OpenAPI document from the route table
+func Spec() *Document {
+	g := newGenerator()
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:       "Gemini Stocks API",
+			Description: "Stock portfolio manager: transactions, cached prices, goals, currencies, exports and backups.",
+			Version:     Version,
+		},
+		Servers:  []Server{{URL: "http://localhost:8080"}},
+		Paths:    make(map[string]map[string]Operation),
+		Security: []map[string][]string{{"bearerAuth": {}}},
+	}
+
+	tags := make(map[string]bool)
+	for _, rt := range routes {
+		if doc.Paths[rt.Path] == nil {
+			doc.Paths[rt.Path] = make(map[string]Operation)
+		}
+		doc.Paths[rt.Path][strings.ToLower(rt.Method)] = g.operation(rt)
+		tags[rt.Tag] = true
+	}
+
+	for name := range tags {
+		doc.Tags = append(doc.Tags, Tag{Name: name})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+
+	doc.Components.Schemas = g.schemas
+	doc.Components.SecuritySchemes = map[string]SecurityScheme{
+		"bearerAuth": {Type: "http", Scheme: "bearer", Description: "Session token from POST /auth/login, or a personal API token (gsk_...) from POST /tokens"},
+	}
+	return doc
+}
+
+// Handler serves the document as JSON (GET /openapi.json)
+func Handler(w http.ResponseWriter, r *http.Request) {
+	specOnce.Do(func() {
+		specJSON, _ = json.MarshalIndent(Spec(), "", "  ")
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(specJSON)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// generator turns routes into operations, collecting named schemas as components
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) operation(rt route) Operation {
+	op := Operation{
+		OperationID: rt.ID,
+		Summary:     rt.Summary,
+		Description: rt.Description,
+		Tags:        []string{rt.Tag},
+		Responses:   make(map[string]Response),
+	}
+	if rt.Public {
+		op.Security = &[]map[string][]string{}
+	}
+
+	for _, m := range pathParamPattern.FindAllStringSubmatch(rt.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	if inPortfolio(rt.Path) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: "X-Portfolio-ID", In: "header",
+			Description: "Portfolio to work on (default: the oldest one you own). The portfolio query parameter works too.",
+			Schema:      &Schema{Type: "integer"},
+		})
+	}
+	op.Parameters = append(op.Parameters, rt.Query...)
+
+	if rt.Body != nil || rt.BodySchema != nil {
+		contentType := rt.BodyType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		schema := rt.BodySchema
+		if schema == nil {
+			schema = g.schemaFor(reflect.TypeOf(rt.Body))
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{contentType: {Schema: schema}},
+		}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := Response{Description: http.StatusText(status), Headers: rt.Headers}
+	if rt.Response != nil || rt.ResponseSchema != nil {
+		contentType := rt.ResponseType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		schema := rt.ResponseSchema
+		if schema == nil {
+			schema = g.schemaFor(reflect.TypeOf(rt.Response))
+		}
+		success.Content = map[string]MediaType{contentType: {Schema: schema}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	op.Responses["default"] = Response{
+		Description: "Error envelope. Validation failures (422) list the invalid fields.",
+		Content:     map[string]MediaType{"application/json": {Schema: g.schemaFor(reflect.TypeOf(httpx.ErrorResponse{}))}},
+	}
+
+	return op
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	decimalType   = reflect.TypeOf(decimal.Decimal{})
+)
+
+// schemaFor returns the schema of a Go type, as a $ref for named structs
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case t == rawJSONType:
+		return &Schema{}
+	case t == decimalType:
+		// Exact decimals are sent as plain JSON numbers
+		return &Schema{Type: "number"}
+	}
+
+	if values, ok := enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		s := g.schemaFor(t.Elem())
+		if s.Ref != "" {
+			return s
+		}
+		s.Nullable = true
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.ref(t)
+	}
+	return &Schema{}
+}
+
+// ref registers a named struct as a component and returns a reference to it
+func (g *generator) ref(t reflect.Type) *Schema {
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		// Same name in another package, e.g. backup.Result
+		pkg := path.Base(t.PkgPath())
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	g.names[t] = name
+	g.schemas[name] = &Schema{} // placeholder for recursive types
+	*g.schemas[name] = *g.structSchema(t)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema lists the JSON fields of a struct, following encoding/json rules:
+// embedded structs are flattened and shallower fields win over embedded ones
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	queue := []reflect.Type{t}
+	for len(queue) > 0 {
+		var next []reflect.Type
+		seenAtDepth := make(map[string]bool)
+		for _, st := range queue {
+			for i := 0; i < st.NumField(); i++ {
+				f := st.Field(i)
+				tag := f.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name := strings.Split(tag, ",")[0]
+
+				if f.Anonymous && name == "" {
+					ft := f.Type
+					if ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, ft)
+						continue
+					}
+				}
+				if !f.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = f.Name
+				}
+				if _, exists := s.Properties[name]; exists || seenAtDepth[name] {
+					continue
+				}
+				seenAtDepth[name] = true
+				s.Properties[name] = g.schemaFor(f.Type)
+			}
+		}
+		queue = next
+	}
+
+	return s
+}
@@ -0,0 +1,641 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/backup"
+	"github.com/Felipalds/gemini-stocks/internal/handlers"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+)
+
+// route documents one endpoint. Body and Response are zero values of the Go
+// types sent and received; their schemas are derived by reflection.
+// BodySchema and ResponseSchema are used instead for non-JSON payloads.
+type route struct {
+	Method      string
+	Path        string
+	ID          string
+	Tag         string
+	Summary     string
+	Description string
+	Query       []Parameter
+	Public      bool // no session token needed
+
+	Body       any
+	BodyType   string // default: application/json
+	BodySchema *Schema
+
+	Status         int // default: 200
+	Response       any
+	ResponseType   string // default: application/json
+	ResponseSchema *Schema
+	Headers        map[string]Header
+}
+
+// portfolioScoped lists the path prefixes behind the portfolio middleware
+var portfolioScoped = []string{"/transactions", "/data/", "/prices", "/goal", "/jobs", "/alerts", "/webhooks", "/events", "/currencies", "/audit", "/export/"}
+
+func inPortfolio(path string) bool {
+	for _, prefix := range portfolioScoped {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// enums lists the allowed values of named string types
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(models.Buy):             {string(models.Buy), string(models.Sell)},
+	reflect.TypeOf(models.RoleViewer):      {string(models.RoleViewer), string(models.RoleEditor), string(models.RoleOwner)},
+	reflect.TypeOf(models.AlertPriceAbove): {string(models.AlertPriceAbove), string(models.AlertPriceBelow), string(models.AlertDayChange), string(models.AlertDrawdown), string(models.AlertGoalDrift)},
+	reflect.TypeOf(models.AuditCreate):     {string(models.AuditCreate), string(models.AuditUpdate), string(models.AuditDelete)},
+	reflect.TypeOf(backup.StrategySkip):    {string(backup.StrategySkip), string(backup.StrategyOverwrite), string(backup.StrategyReplace), string(backup.StrategyFail)},
+}
+
+func query(name, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string"}}
+}
+
+func queryEnum(name, description string, values ...string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: "string", Enum: values}}
+}
+
+var (
+	binary = &Schema{Type: "string", Format: "binary"}
+
+	transactionFilters = []Parameter{
+		query("symbol", "Only transactions of this symbol"),
+		queryEnum("type", "Only buys or sells", "BUY", "SELL"),
+		query("currency", "Only transactions in this currency"),
+		query("category", "Only symbols of this ticker category"),
+		query("q", "Case-insensitive search in the note"),
+		query("from", "First date, inclusive (YYYY-MM-DD)"),
+		query("to", "Last date, inclusive (YYYY-MM-DD)"),
+		query("sort", "date, symbol, type, quantity, price or created_at, prefixed with - for descending"),
+	}
+
+	exportFormat = queryEnum("format", "File format (default: xlsx)", "xlsx", "csv", "json")
+)
+
+// routes must list every route registered on the router (see Verify)
+var routes = []route{
+	{
+		Method: http.MethodGet, Path: "/health", ID: "health", Tag: "system",
+		Summary:  "Health check",
+		Response: "", ResponseType: "text/plain",
+		Public: true,
+	},
+	{
+		Method: http.MethodGet, Path: "/openapi.json", ID: "getOpenAPI", Tag: "system",
+		Summary:        "This OpenAPI document",
+		ResponseSchema: &Schema{Type: "object"},
+		Public:         true,
+	},
+
+	// Accounts
+	{
+		Method: http.MethodPost, Path: "/auth/register", ID: "register", Tag: "auth",
+		Summary:     "Create an account and log in",
+		Description: "The user registering with ADMIN_EMAIL becomes an admin and owns the data created before accounts existed. With REGISTRATION_OPEN=false only that user may register; everyone else gets 403.",
+		Body:        handlers.RegisterRequest{},
+		Status:      http.StatusCreated, Response: handlers.AuthResponse{},
+		Public: true,
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/login", ID: "login", Tag: "auth",
+		Summary:  "Log in and get a session token",
+		Body:     handlers.LoginRequest{},
+		Response: handlers.AuthResponse{},
+		Public:   true,
+	},
+	{
+		Method: http.MethodPost, Path: "/auth/logout", ID: "logout", Tag: "auth",
+		Summary: "Revoke the session token of the request",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodGet, Path: "/auth/me", ID: "getCurrentUser", Tag: "auth",
+		Summary:  "The authenticated user",
+		Response: models.User{},
+	},
+
+	// Personal API tokens (login session only)
+	{
+		Method: http.MethodGet, Path: "/tokens", ID: "listTokens", Tag: "tokens",
+		Summary:  "List your personal API tokens",
+		Response: []models.APIToken{},
+	},
+	{
+		Method: http.MethodPost, Path: "/tokens", ID: "createToken", Tag: "tokens",
+		Summary:     "Create a personal API token",
+		Description: "Scopes: read:portfolio (read everything but /admin), write:transactions (change the portfolio, except its members and webhooks, which need a login session) and admin (/admin, admins only). The token is only returned here.",
+		Body:        handlers.CreateTokenRequest{},
+		Status:      http.StatusCreated, Response: handlers.CreateTokenResponse{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/tokens/{id}", ID: "revokeToken", Tag: "tokens",
+		Summary: "Revoke a personal API token",
+		Status:  http.StatusNoContent,
+	},
+
+	// Notification preferences of the signed-in user (login session only)
+	{
+		Method: http.MethodGet, Path: "/notifications", ID: "listNotifications", Tag: "notifications",
+		Summary:  "List the configured channels, the topics and your notification preferences",
+		Response: handlers.NotificationSettings{},
+	},
+	{
+		Method: http.MethodPut, Path: "/notifications/{channel}", ID: "saveNotification", Tag: "notifications",
+		Summary: "Create or replace your preference for a channel (email or telegram)",
+		Description: "Topics: alert.triggered (the alerts of your portfolios) and tax.monthly (the realized gains of the previous month, " +
+			"sent early each month in the base currency). An empty list subscribes to every topic. " +
+			"E-mails go to your account's address unless another is given; Telegram needs the chat ID.",
+		Body:     handlers.NotificationPreferenceRequest{},
+		Response: models.NotificationPreference{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/notifications/{channel}", ID: "deleteNotification", Tag: "notifications",
+		Summary: "Delete your preference for a channel",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/notifications/{channel}/test", ID: "testNotification", Tag: "notifications",
+		Summary: "Send a test message on a channel, even if the preference is disabled",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/notifications/{channel}/tax-summary", ID: "sendTaxSummary", Tag: "notifications",
+		Summary:  "Send the tax summary of a month on a channel now",
+		Query:    []Parameter{query("month", "Month to summarize (YYYY-MM, default: the previous one)")},
+		Response: handlers.TaxSummaryResult{},
+	},
+
+	// Portfolios and members (changes need a login session)
+	{
+		Method: http.MethodGet, Path: "/portfolios", ID: "listPortfolios", Tag: "portfolios",
+		Summary:  "List the portfolios you belong to, with your role",
+		Response: []handlers.PortfolioResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/portfolios", ID: "createPortfolio", Tag: "portfolios",
+		Summary: "Create a portfolio you own",
+		Body:    handlers.SavePortfolioRequest{},
+		Status:  http.StatusCreated, Response: handlers.PortfolioResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/portfolios/{id}", ID: "updatePortfolio", Tag: "portfolios",
+		Summary:  "Rename a portfolio (owners)",
+		Body:     handlers.SavePortfolioRequest{},
+		Response: handlers.PortfolioResponse{},
+	},
+	{
+		Method: http.MethodGet, Path: "/portfolios/{id}/members", ID: "listMembers", Tag: "portfolios",
+		Summary:  "List the members of a portfolio",
+		Response: []handlers.MemberResponse{},
+	},
+	{
+		Method: http.MethodPost, Path: "/portfolios/{id}/members", ID: "addMember", Tag: "portfolios",
+		Summary:     "Add a user to a portfolio (owners)",
+		Description: "Viewers can read, editors can also change transactions and refresh prices, owners also manage the goal, ticker settings and members.",
+		Body:        handlers.AddMemberRequest{},
+		Status:      http.StatusCreated, Response: handlers.MemberResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/portfolios/{id}/members/{userID}", ID: "updateMember", Tag: "portfolios",
+		Summary:  "Change the role of a member (owners)",
+		Body:     handlers.UpdateMemberRequest{},
+		Response: models.PortfolioMember{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/portfolios/{id}/members/{userID}", ID: "removeMember", Tag: "portfolios",
+		Summary:     "Remove a member (owners) or leave a portfolio",
+		Description: "The last owner cannot leave or be removed.",
+		Status:      http.StatusNoContent,
+	},
+
+	// Symbols
+	{
+		Method: http.MethodGet, Path: "/symbols/search", ID: "searchSymbols", Tag: "symbols",
+		Summary:     "Search the market data provider's listings by symbol or name, best first",
+		Description: "Results are cached for a week (a day when nothing was found), so repeated searches do not spend the provider's quota.",
+		Query:       []Parameter{query("q", "A symbol or company name, e.g. PETR4 or Petrobras")},
+		Response:    []models.SymbolMatch{},
+	},
+
+	// Transactions
+	{
+		Method: http.MethodPost, Path: "/transactions", ID: "createTransaction", Tag: "transactions",
+		Summary: "Create a transaction",
+		Description: "Creates the ticker (and fetches its price) if the symbol is new. A new symbol must be listed by the market data provider, " +
+			"or the request fails with 422, unless unlisted=true; its exchange is recorded, and its currency is used when none is given. " +
+			"Otherwise currency defaults to USD.",
+		Query:  []Parameter{queryEnum("unlisted", "Accept a new symbol the provider does not list, for assets priced by hand", "true", "false")},
+		Body:   models.Transaction{},
+		Status: http.StatusCreated, Response: models.Transaction{},
+	},
+	{
+		Method: http.MethodGet, Path: "/transactions", ID: "listTransactions", Tag: "transactions",
+		Summary:     "List transactions with their current PnL",
+		Description: "Without limit every matching transaction is returned. With limit, follow X-Next-Cursor (or the Link header) to get the next page.",
+		Query: append(append([]Parameter{}, transactionFilters...),
+			query("limit", "Page size (max 500)"),
+			query("cursor", "Opaque cursor from X-Next-Cursor"),
+		),
+		Response: []handlers.TransactionResponse{},
+		Headers: map[string]Header{
+			"X-Total-Count": {Description: "Number of transactions matching the filters", Schema: &Schema{Type: "integer"}},
+			"X-Next-Cursor": {Description: "Cursor of the next page, absent on the last page", Schema: &Schema{Type: "string"}},
+			"Link":          {Description: "URL of the next page (rel=\"next\")", Schema: &Schema{Type: "string"}},
+		},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions/import", ID: "importTransactions", Tag: "transactions",
+		Summary: "Import BUY transactions from an Excel file",
+		Description: "The first sheet must have the columns Date (YYYY-MM-DD), Quantity, Price and Fee, with a header row. " +
+			"A new symbol is checked as in createTransaction.",
+		Query:    []Parameter{queryEnum("unlisted", "Accept a new symbol the provider does not list, for assets priced by hand", "true", "false")},
+		BodyType: "multipart/form-data",
+		BodySchema: &Schema{Type: "object", Required: []string{"file", "symbol"}, Properties: map[string]*Schema{
+			"file":     binary,
+			"symbol":   {Type: "string"},
+			"currency": {Type: "string", Description: "Default: the listing's currency for new symbols, otherwise USD"},
+		}},
+		Response: handlers.ImportResult{},
+	},
+	{
+		Method: http.MethodPut, Path: "/transactions/{id}", ID: "updateTransaction", Tag: "transactions",
+		Summary: "Update a transaction",
+		Description: "A changed symbol or currency is checked as on create: a new symbol must be listed unless unlisted=true, " +
+			"and gets its ticker.",
+		Query:    []Parameter{queryEnum("unlisted", "Accept a new symbol the provider does not list, for assets priced by hand", "true", "false")},
+		Body:     models.Transaction{},
+		Response: models.Transaction{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/transactions/{id}", ID: "deleteTransaction", Tag: "transactions",
+		Summary:     "Delete a transaction",
+		Description: "The transaction goes to the recycle bin and is purged after TRASH_RETENTION_DAYS (default 30).",
+		Status:      http.StatusNoContent,
+	},
+
+	// Recycle bin
+	{
+		Method: http.MethodGet, Path: "/transactions/deleted", ID: "listDeletedTransactions", Tag: "transactions",
+		Summary:  "List the deleted transactions, most recently deleted first",
+		Response: []models.Transaction{},
+	},
+	{
+		Method: http.MethodPost, Path: "/transactions/deleted/{id}/restore", ID: "restoreTransaction", Tag: "transactions",
+		Summary:  "Restore a deleted transaction",
+		Response: models.Transaction{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/transactions/deleted/{id}", ID: "purgeTransaction", Tag: "transactions",
+		Summary: "Delete a deleted transaction for good (owners)",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodDelete, Path: "/transactions/deleted", ID: "emptyRecycleBin", Tag: "transactions",
+		Summary:  "Delete every deleted transaction for good (owners)",
+		Response: handlers.PurgeResult{},
+	},
+
+	// Data
+	{
+		Method: http.MethodGet, Path: "/data/summary", ID: "getSummary", Tag: "data",
+		Summary:     "Value the open positions and total them in one currency",
+		Description: "Market values are converted at the current rates and costs at the rates of the purchase dates (the current rate when no daily rate is stored, flagged estimated_cost), so PnL splits into asset_return and currency_return. Rates are derived through inverse and cross rates when needed. Positions in currencies without a rate are left out of the totals and listed in missing_rates.",
+		Query:       []Parameter{query("currency", "Report currency (default: the base currency)")},
+		Response:    services.Summary{},
+	},
+	{
+		Method: http.MethodGet, Path: "/data/allocation", ID: "getAllocation", Tag: "data",
+		Summary:     "Split the market value of the open positions by a ticker field",
+		Description: "Values are converted to one currency like in getSummary, and groups come largest first. Positions whose ticker has no value for the field are grouped under an empty key.",
+		Query: []Parameter{
+			queryEnum("by", "The field to group by (default: category)", services.AllocationGroups...),
+			query("currency", "Report currency (default: the base currency)"),
+		},
+		Response: services.Allocation{},
+	},
+
+	// Prices
+	{
+		Method: http.MethodGet, Path: "/prices", ID: "listPrices", Tag: "prices",
+		Summary:     "List the tickers the portfolio has transactions in",
+		Description: "Tickers whose price is older than the quote cache allows for their asset class are flagged stale.",
+		Response:    []models.Ticker{},
+	},
+	{
+		Method: http.MethodPost, Path: "/prices/refresh", ID: "refreshPrices", Tag: "prices",
+		Summary: "Start a job fetching the latest prices of the portfolio's tickers and the exchange rates (editors)",
+		Description: "Returns the job right away; follow it with GET /jobs/{id} or the prices.refresh_progress events. " +
+			"Only the tickers the portfolio holds or watches with an enabled alert rule are fetched. " +
+			"Prices still fresh in the quote cache are kept, as cached items, unless force=true. " +
+			"Quotes are fetched in batches where the provider supports it, and otherwise a few at a time " +
+			"within its quota, so items finish out of order. " +
+			"Once the tickers are done, the portfolio's alert rules are checked against the new prices. " +
+			"While a refresh of the portfolio is running, it is returned instead of starting another.",
+		Query:  []Parameter{queryEnum("force", "Fetch every price, even those still fresh", "true", "false")},
+		Status: http.StatusAccepted, Response: models.Job{},
+		Headers: map[string]Header{"Location": {Description: "The job's URL", Schema: &Schema{Type: "string"}}},
+	},
+	{
+		Method: http.MethodPut, Path: "/prices", ID: "updatePrice", Tag: "prices",
+		Summary: "Edit a ticker's price, tags, category, currency or metadata (admins; editors for tags and category)",
+		Description: "Tickers are shared by every portfolio, so only admins can change their price, currency and metadata. " +
+			"Editors may set the tags and category of the tickers their portfolio holds. " +
+			"Only the fields sent are changed; an empty string clears a metadata field. asset_type is one of stock, fii, etf, bdr, crypto or bond.",
+		Body:     handlers.UpdatePriceRequest{},
+		Response: models.Ticker{},
+	},
+	{
+		Method: http.MethodPost, Path: "/prices/metadata", ID: "refreshTickerMetadata", Tag: "prices",
+		Summary: "Fill a ticker's metadata from the market data provider (admins)",
+		Description: "Uses the provider's company overview (name, exchange, country, sector, industry and asset type), " +
+			"or its symbol listing where there is no overview, e.g. for B3 tickers. " +
+			"Only empty fields are filled, so values edited by hand are kept, unless overwrite is true. " +
+			"The provider has no ISIN or logo; set those with updatePrice.",
+		Body:     handlers.RefreshMetadataRequest{},
+		Response: models.Ticker{},
+	},
+
+	// Jobs
+	{
+		Method: http.MethodGet, Path: "/jobs", ID: "listJobs", Tag: "jobs",
+		Summary: "List the background jobs started from the portfolio, newest first",
+		Query: []Parameter{
+			query("kind", "Only jobs of this kind, e.g. prices.refresh"),
+			queryEnum("status", "Only jobs in this state", models.JobRunning, models.JobCompleted, models.JobFailed, models.JobCancelled),
+			query("before", "Only jobs older than this job ID, for paging"),
+			query("limit", "Page size (default 50, max 500)"),
+		},
+		Response: []models.Job{},
+	},
+	{
+		Method: http.MethodGet, Path: "/jobs/{id}", ID: "getJob", Tag: "jobs",
+		Summary: "Get a job and the status of each of its items",
+		Description: "Item states: pending, updated, cached, rate_limited, not_found, error, and skipped when the job was cancelled first. " +
+			"Jobs still running when the server stopped are failed at the next start.",
+		Response: models.Job{},
+	},
+	{
+		Method: http.MethodPost, Path: "/jobs/{id}/cancel", ID: "cancelJob", Tag: "jobs",
+		Summary: "Cancel a running job (editors)",
+		Description: "The job stops after the item in progress; work already done is kept. " +
+			"Responds 409 when the job is not running.",
+		Status: http.StatusAccepted, Response: models.Job{},
+	},
+
+	// Goal
+	{
+		Method: http.MethodGet, Path: "/goal", ID: "getGoal", Tag: "goal",
+		Summary:  "Get the portfolio goal and its allocations",
+		Response: models.PortfolioGoal{},
+	},
+	{
+		Method: http.MethodPost, Path: "/goal", ID: "saveGoal", Tag: "goal",
+		Summary: "Replace the portfolio goal",
+		Body:    handlers.SaveGoalRequest{},
+		Status:  http.StatusCreated, Response: models.PortfolioGoal{},
+	},
+
+	// Alerts
+	{
+		Method: http.MethodGet, Path: "/alerts", ID: "listAlerts", Tag: "alerts",
+		Summary:  "List the alert rules of the portfolio",
+		Response: []models.AlertRule{},
+	},
+	{
+		Method: http.MethodPost, Path: "/alerts", ID: "createAlert", Tag: "alerts",
+		Summary: "Create an alert rule",
+		Description: "Rules are checked after every price refresh of the portfolio. price_above and price_below compare the price with threshold, " +
+			"day_change the absolute day change in percent, drawdown how far the price is below the average cost in percent, " +
+			"and goal_drift how many points a goal category's share of the portfolio is off (every category when category is empty). " +
+			"After triggering, a rule stays quiet for cooldown_minutes (default: a day).",
+		Body:   handlers.AlertRuleRequest{},
+		Status: http.StatusCreated, Response: models.AlertRule{},
+	},
+	{
+		Method: http.MethodGet, Path: "/alerts/events", ID: "listAlertEvents", Tag: "alerts",
+		Summary: "List the triggered alerts, newest first",
+		Query: []Parameter{
+			query("rule_id", "Only alerts of this rule"),
+			query("before", "Only alerts older than this alert ID, for paging"),
+			query("limit", "Page size (default 50, max 500)"),
+		},
+		Response: []models.AlertEvent{},
+	},
+	{
+		Method: http.MethodPut, Path: "/alerts/{id}", ID: "updateAlert", Tag: "alerts",
+		Summary:  "Replace an alert rule",
+		Body:     handlers.AlertRuleRequest{},
+		Response: models.AlertRule{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/alerts/{id}", ID: "deleteAlert", Tag: "alerts",
+		Summary: "Delete an alert rule; its triggered alerts are kept",
+		Status:  http.StatusNoContent,
+	},
+
+	// Webhooks (login session only)
+	{
+		Method: http.MethodGet, Path: "/webhooks", ID: "listWebhooks", Tag: "webhooks",
+		Summary:  "List the webhooks of the portfolio (owners)",
+		Response: []models.Webhook{},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks", ID: "createWebhook", Tag: "webhooks",
+		Summary: "Subscribe a URL to portfolio events (owners)",
+		Description: "Every event is POSTed as JSON with the headers X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and " +
+			"X-Webhook-Signature: sha256= and the hex HMAC-SHA256 of \"timestamp.body\" keyed with the secret, which is only returned here. " +
+			"Responses outside 2xx are retried with exponential backoff, starting at a minute, up to 6 attempts. " +
+			"ticker.updated is sent to the webhooks of every portfolio.",
+		Body:   handlers.WebhookRequest{},
+		Status: http.StatusCreated, Response: handlers.CreateWebhookResponse{},
+	},
+	{
+		Method: http.MethodPut, Path: "/webhooks/{id}", ID: "updateWebhook", Tag: "webhooks",
+		Summary:  "Replace a webhook, keeping its secret (owners)",
+		Body:     handlers.WebhookRequest{},
+		Response: models.Webhook{},
+	},
+	{
+		Method: http.MethodDelete, Path: "/webhooks/{id}", ID: "deleteWebhook", Tag: "webhooks",
+		Summary: "Delete a webhook (owners)",
+		Status:  http.StatusNoContent,
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks/{id}/ping", ID: "pingWebhook", Tag: "webhooks",
+		Summary: "Send a ping event to a webhook (owners)",
+		Status:  http.StatusAccepted, Response: models.WebhookDelivery{},
+	},
+	{
+		Method: http.MethodGet, Path: "/webhooks/{id}/deliveries", ID: "listWebhookDeliveries", Tag: "webhooks",
+		Summary: "List the deliveries of a webhook, newest first (owners)",
+		Query: []Parameter{
+			queryEnum("status", "Only deliveries in this state", models.DeliveryPending, models.DeliveryDelivered, models.DeliveryFailed),
+			query("before", "Only deliveries older than this delivery ID, for paging"),
+			query("limit", "Page size (default 50, max 500)"),
+		},
+		Response: []models.WebhookDelivery{},
+	},
+	{
+		Method: http.MethodPost, Path: "/webhooks/{id}/deliveries/{deliveryID}/redeliver", ID: "redeliverWebhook", Tag: "webhooks",
+		Summary: "Send the payload of a past delivery again, as a new delivery (owners)",
+		Status:  http.StatusAccepted, Response: models.WebhookDelivery{},
+	},
+
+	// Live updates
+	{
+		Method: http.MethodGet, Path: "/events", ID: "streamEvents", Tag: "events",
+		Summary: "Stream the events of the portfolio as Server-Sent Events",
+		Description: "Each message has the event ID, its type as the event name and the event as JSON data. " +
+			"Besides the webhook event types, prices.refresh_progress follows every ticker of a price refresh. " +
+			"Send Last-Event-ID to replay the recent events missed while disconnected. Idle streams get a comment every 25 seconds.",
+		ResponseType:   "text/event-stream",
+		ResponseSchema: &Schema{Type: "string"},
+	},
+
+	// Currencies
+	{
+		Method: http.MethodGet, Path: "/currencies", ID: "listCurrencies", Tag: "currencies",
+		Summary:  "List the base currency, the supported currencies and the stored exchange rates",
+		Response: handlers.CurrencyList{},
+	},
+	{
+		Method: http.MethodGet, Path: "/currencies/rate", ID: "getRate", Tag: "currencies",
+		Summary:     "Get the rate between two currencies",
+		Description: "Uses the stored pair, the inverse of the opposite pair or a cross rate through other currencies, with the fewest conversions. 404 if no chain of rates links them.",
+		Query: []Parameter{
+			query("from", "Currency to convert from (required)"),
+			query("to", "Currency to convert to (default: the base currency)"),
+		},
+		Response: services.Quote{},
+	},
+	{
+		Method: http.MethodPut, Path: "/currencies/rates", ID: "setRate", Tag: "currencies",
+		Summary:     "Set an exchange rate by hand (admins)",
+		Description: "Exchange rates are shared by every portfolio, so only admins can set them.",
+		Body:        handlers.SetRateRequest{},
+		Response:    models.ExchangeRate{},
+	},
+	{
+		Method: http.MethodPost, Path: "/currencies/refresh", ID: "refreshRates", Tag: "currencies",
+		Summary:     "Fetch the latest exchange rates",
+		Description: "Fetches the rate of every currency used by tickers and transactions to the base currency, and every stored pair.",
+		Response:    handlers.RefreshResult{},
+	},
+	{
+		Method: http.MethodGet, Path: "/currencies/history", ID: "listRateHistory", Tag: "currencies",
+		Summary:     "List the stored daily exchange rates, newest first",
+		Description: "Past transactions are valued at the rate of their date: the latest rate of the pair on or before it, up to 10 days back, derived through inverse and cross rates like the current ones.",
+		Query: []Parameter{
+			query("base", "Only rates of this base currency"),
+			query("quote", "Only rates in this quote currency"),
+			query("start", "First date, inclusive (YYYY-MM-DD)"),
+			query("end", "Last date, inclusive (YYYY-MM-DD)"),
+			query("limit", "Page size (default 50, max 500)"),
+		},
+		Response: []models.HistoricalRate{},
+	},
+	{
+		Method: http.MethodPost, Path: "/currencies/history/backfill", ID: "backfillRateHistory", Tag: "currencies",
+		Summary:     "Fetch the daily rates of a pair from the provider",
+		Description: "Uses Alpha Vantage FX_DAILY, which only has physical currencies. Days already stored are overwritten.",
+		Body:        handlers.BackfillRequest{},
+		Response:    handlers.HistoryResult{},
+	},
+	{
+		Method: http.MethodPost, Path: "/currencies/history/import", ID: "importRateHistory", Tag: "currencies",
+		Summary: "Import daily rates to BRL from a Banco Central do Brasil PTAX CSV (admins)",
+		Description: "Lines are DDMMYYYY;code;type;symbol;buy;sell;parity;parity with decimal commas. Days already stored are overwritten. " +
+			"The history is shared by every portfolio, so only admins can import it.",
+		BodyType: "multipart/form-data",
+		BodySchema: &Schema{Type: "object", Required: []string{"file"}, Properties: map[string]*Schema{
+			"file": binary,
+			"rate": {Type: "string", Enum: []string{"sell", "buy"}, Description: "Default: sell"},
+		}},
+		Response: handlers.HistoryResult{},
+	},
+
+	// Audit log
+	{
+		Method: http.MethodGet, Path: "/audit", ID: "listAudit", Tag: "audit",
+		Summary: "List the changes of the portfolio (and, for admins, of tickers and exchange rates), newest first",
+		Description: "Each entry has the record before and after the change (null for creates and deletes), who made it and the request ID. " +
+			"Changes of tickers and exchange rates are shared by every portfolio and only listed for admins.",
+		Query: []Parameter{
+			queryEnum("entity", "Only changes of this kind of record",
+				models.EntityTransaction, models.EntityTicker, models.EntityGoal, models.EntityExchangeRate),
+			query("entity_id", "Only changes of this record (the portfolio ID for goals, BASE/QUOTE for exchange rates)"),
+			query("before", "Only entries older than this entry ID, for paging"),
+			query("limit", "Page size (default 50, max 500)"),
+		},
+		Response: []models.AuditEntry{},
+	},
+	{
+		Method: http.MethodPost, Path: "/audit/{id}/revert", ID: "revertChange", Tag: "audit",
+		Summary:     "Undo a change",
+		Description: "Puts the record back as it was before the change; reverting a create deletes it. Needs the role the change needed; changes of tickers and exchange rates are reverted by admins. Fails with 409 if the record changed again since, unless force=true.",
+		Query:       []Parameter{queryEnum("force", "Revert even if the record changed since", "true", "false")},
+		Response:    models.AuditEntry{},
+	},
+
+	// Exports
+	{
+		Method: http.MethodGet, Path: "/export/transactions", ID: "exportTransactions", Tag: "export",
+		Summary:      "Export transactions",
+		Query:        append([]Parameter{exportFormat}, transactionFilters...),
+		ResponseType: "application/octet-stream", ResponseSchema: binary,
+	},
+	{
+		Method: http.MethodGet, Path: "/export/positions", ID: "exportPositions", Tag: "export",
+		Summary:      "Export the current positions",
+		Query:        []Parameter{exportFormat, query("currency", "Also value the positions in this currency")},
+		ResponseType: "application/octet-stream", ResponseSchema: binary,
+	},
+	{
+		Method: http.MethodGet, Path: "/export/tax", ID: "exportTaxReport", Tag: "export",
+		Summary:     "Export the realized gains of a year",
+		Description: "XLSX and JSON exports hold both the monthly summary and the sales. A CSV file holds one of them, chosen with sheet; without it the request fails with 400.",
+		Query: []Parameter{
+			exportFormat, query("year", "Default: current year"),
+			query("currency", "Also convert the sales to this currency, at the rates of their dates"),
+			queryEnum("sheet", "The sheet of a CSV export", "months", "sales"),
+		},
+		ResponseType: "application/octet-stream", ResponseSchema: binary,
+	},
+	{
+		Method: http.MethodGet, Path: "/export/income", ID: "exportIncome", Tag: "export",
+		Summary:     "Export the realized gains by period",
+		Description: "Sales are totalled by month, quarter or year and currency. Without year, every year with sales is included.",
+		Query: []Parameter{
+			exportFormat, queryEnum("period", "Default: month", "month", "quarter", "year"),
+			query("year", "Only this year's sales"),
+			query("currency", "Also convert the sales to this currency, at the rates of their dates"),
+		},
+		ResponseType: "application/octet-stream", ResponseSchema: binary,
+	},
+
+	// Admin
+	{
+		Method: http.MethodGet, Path: "/admin/backup", ID: "backup", Tag: "admin",
+		Summary:      "Download a backup of every table (admins only)",
+		Query:        []Parameter{queryEnum("format", "Archive format (default: zip)", backup.FormatZIP, backup.FormatJSON)},
+		ResponseType: "application/octet-stream", ResponseSchema: binary,
+	},
+	{
+		Method: http.MethodPost, Path: "/admin/restore", ID: "restore", Tag: "admin",
+		Summary:     "Load a backup archive (admins only)",
+		Description: "The archive is sent as the raw body or as the \"file\" field of a multipart form.",
+		Query: []Parameter{queryEnum("strategy", "What to do with rows that already exist (default: skip)",
+			string(backup.StrategySkip), string(backup.StrategyOverwrite), string(backup.StrategyReplace), string(backup.StrategyFail))},
+		BodyType: "application/octet-stream", BodySchema: binary,
+		Response: backup.Result{},
+	},
+}
@@ -0,0 +1,47 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Verify compares the documented routes with the routes registered on the router.
+// It returns one message per route that is missing on either side.
+func Verify(router chi.Routes) ([]string, error) {
+	registered := make(map[string]bool)
+	err := chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// r.Route("/x", ...) + r.Get("/", ...) registers "/x/"
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		registered[method+" "+route] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	documented := make(map[string]bool)
+	for _, rt := range routes {
+		documented[rt.Method+" "+rt.Path] = true
+	}
+
+	var problems []string
+	for key := range registered {
+		if !documented[key] {
+			problems = append(problems, fmt.Sprintf("%s is registered but not documented", key))
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			problems = append(problems, fmt.Sprintf("%s is documented but not registered", key))
+		}
+	}
+
+	sort.Strings(problems)
+	return problems, nil
+}
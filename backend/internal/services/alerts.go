@@ -0,0 +1,125 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// AlertInput is what the rules of one portfolio are checked against
+type AlertInput struct {
+	Tickers   map[string]models.Ticker
+	Positions []Position            // valued in one currency, see ValuePositions
+	Goal      *models.PortfolioGoal // nil when the portfolio has no goal
+}
+
+// EvaluateAlert checks a rule and returns the events it triggers: at most one,
+// except for goal_drift rules on every category, which report each drifting one.
+// Cooldowns are left to the caller.
+func EvaluateAlert(rule models.AlertRule, in AlertInput) []models.AlertEvent {
+	event := func(symbol, category string, value decimal.Decimal, message string) models.AlertEvent {
+		return models.AlertEvent{
+			RuleID: rule.ID, PortfolioID: rule.PortfolioID, Type: rule.Type,
+			Symbol: symbol, Category: category, Value: value, Threshold: rule.Threshold, Message: message,
+		}
+	}
+
+	if rule.Type == models.AlertGoalDrift {
+		var events []models.AlertEvent
+		for _, d := range GoalDrift(in.Positions, in.Goal) {
+			if rule.Category != "" && d.Category != rule.Category {
+				continue
+			}
+			if d.Drift.Abs().GreaterThanOrEqual(rule.Threshold) {
+				events = append(events, event("", d.Category, d.Drift, fmt.Sprintf(
+					"%s is %s%% of the portfolio, goal is %s%%", d.Category, d.Actual.StringFixed(2), d.Target.StringFixed(2))))
+			}
+		}
+		return events
+	}
+
+	ticker, ok := in.Tickers[rule.Symbol]
+	if !ok || !ticker.Price.IsPositive() {
+		return nil
+	}
+
+	switch rule.Type {
+	case models.AlertPriceAbove:
+		if ticker.Price.GreaterThanOrEqual(rule.Threshold) {
+			return []models.AlertEvent{event(rule.Symbol, "", ticker.Price, fmt.Sprintf(
+				"%s is at %s %s, above %s", rule.Symbol, ticker.Price, ticker.Currency, rule.Threshold))}
+		}
+
+	case models.AlertPriceBelow:
+		if ticker.Price.LessThanOrEqual(rule.Threshold) {
+			return []models.AlertEvent{event(rule.Symbol, "", ticker.Price, fmt.Sprintf(
+				"%s is at %s %s, below %s", rule.Symbol, ticker.Price, ticker.Currency, rule.Threshold))}
+		}
+
+	case models.AlertDayChange:
+		change := decimal.NewFromFloat(ticker.DayChangePercent)
+		if change.Abs().GreaterThanOrEqual(rule.Threshold) {
+			return []models.AlertEvent{event(rule.Symbol, "", change, fmt.Sprintf(
+				"%s moved %s%% today", rule.Symbol, change.StringFixed(2)))}
+		}
+
+	case models.AlertDrawdown:
+		for _, p := range in.Positions {
+			if p.Symbol != rule.Symbol || !p.AverageCost.IsPositive() {
+				continue
+			}
+			drawdown := p.AverageCost.Sub(ticker.Price).Mul(decimal.NewFromInt(100)).DivRound(p.AverageCost, 2)
+			if drawdown.GreaterThanOrEqual(rule.Threshold) {
+				return []models.AlertEvent{event(rule.Symbol, "", drawdown, fmt.Sprintf(
+					"%s is at %s %s, %s%% below the average cost of %s", rule.Symbol, ticker.Price, ticker.Currency,
+					drawdown.StringFixed(2), p.AverageCost))}
+			}
+		}
+	}
+	return nil
+}
+
+// CategoryDrift is how far a category's share of the portfolio is from the goal, in percentage points
+type CategoryDrift struct {
+	Category string          `json:"category"`
+	Target   decimal.Decimal `json:"target"`
+	Actual   decimal.Decimal `json:"actual"`
+	Drift    decimal.Decimal `json:"drift"` // Actual - Target
+}
+
+// GoalDrift compares the share of each goal category in the market value of
+// the positions (in their report currency) with its goal percentage.
+// Positions without a Report are left out.
+func GoalDrift(positions []Position, goal *models.PortfolioGoal) []CategoryDrift {
+	if goal == nil || len(goal.Allocations) == 0 {
+		return nil
+	}
+
+	total := decimal.Zero
+	byCategory := make(map[string]decimal.Decimal)
+	for _, p := range positions {
+		if p.Report == nil {
+			continue
+		}
+		total = total.Add(p.Report.MarketValue.Amount)
+		byCategory[p.Category] = byCategory[p.Category].Add(p.Report.MarketValue.Amount)
+	}
+	if !total.IsPositive() {
+		return nil
+	}
+
+	drifts := make([]CategoryDrift, 0, len(goal.Allocations))
+	for _, a := range goal.Allocations {
+		d := CategoryDrift{
+			Category: a.Category,
+			Target:   decimal.NewFromFloat(a.Percentage),
+			Actual:   byCategory[a.Category].Mul(decimal.NewFromInt(100)).DivRound(total, 2),
+		}
+		d.Drift = d.Actual.Sub(d.Target)
+		drifts = append(drifts, d)
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Category < drifts[j].Category })
+	return drifts
+}
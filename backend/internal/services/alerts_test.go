@@ -0,0 +1,154 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func position(symbol, category, averageCost, marketValue string) Position {
+	p := Position{Symbol: symbol, Category: category, AverageCost: decimal.RequireFromString(averageCost)}
+	if marketValue != "" {
+		p.Report = &PositionReport{MarketValue: models.Money{Amount: decimal.RequireFromString(marketValue), Currency: "USD"}}
+	}
+	return p
+}
+
+func goal(allocations map[string]float64) *models.PortfolioGoal {
+	g := &models.PortfolioGoal{}
+	for category, percentage := range allocations {
+		g.Allocations = append(g.Allocations, models.GoalAllocation{Category: category, Percentage: percentage})
+	}
+	return g
+}
+
+func TestEvaluateAlert(t *testing.T) {
+	in := AlertInput{
+		Tickers: map[string]models.Ticker{
+			"AAPL": {Symbol: "AAPL", Currency: "USD", Price: decimal.RequireFromString("150"), DayChangePercent: -4.5},
+			"ZERO": {Symbol: "ZERO", Currency: "USD"},
+		},
+		Positions: []Position{
+			position("AAPL", "Stocks", "200", "750"),
+			position("BND", "Bonds", "100", "250"),
+		},
+		Goal: goal(map[string]float64{"Stocks": 60, "Bonds": 40}),
+	}
+	rule := func(typ models.AlertType, symbol, category, threshold string) models.AlertRule {
+		return models.AlertRule{Type: typ, Symbol: symbol, Category: category, Threshold: decimal.RequireFromString(threshold)}
+	}
+
+	tests := []struct {
+		name   string
+		rule   models.AlertRule
+		values []string // the Value of each event, in order
+	}{
+		{"price above, reached", rule(models.AlertPriceAbove, "AAPL", "", "150"), []string{"150"}},
+		{"price above, not reached", rule(models.AlertPriceAbove, "AAPL", "", "151"), nil},
+		{"price below, reached", rule(models.AlertPriceBelow, "AAPL", "", "160"), []string{"150"}},
+		{"price below, not reached", rule(models.AlertPriceBelow, "AAPL", "", "149"), nil},
+		{"day change counts drops", rule(models.AlertDayChange, "AAPL", "", "4"), []string{"-4.5"}},
+		{"day change too small", rule(models.AlertDayChange, "AAPL", "", "5"), nil},
+		{"drawdown from average cost", rule(models.AlertDrawdown, "AAPL", "", "25"), []string{"25"}},
+		{"drawdown too small", rule(models.AlertDrawdown, "AAPL", "", "26"), nil},
+		{"drawdown without a position", rule(models.AlertDrawdown, "BND", "", "1"), nil},
+		{"unknown symbol", rule(models.AlertPriceBelow, "MSFT", "", "1000"), nil},
+		{"ticker without a price", rule(models.AlertPriceBelow, "ZERO", "", "1000"), nil},
+		{"goal drift, every category", rule(models.AlertGoalDrift, "", "", "15"), []string{"-15", "15"}},
+		{"goal drift, one category", rule(models.AlertGoalDrift, "", "Stocks", "15"), []string{"15"}},
+		{"goal drift too small", rule(models.AlertGoalDrift, "", "", "16"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := EvaluateAlert(tt.rule, in)
+			if len(events) != len(tt.values) {
+				t.Fatalf("got %d events %+v, want %d", len(events), events, len(tt.values))
+			}
+			for i, e := range events {
+				if !e.Value.Equal(decimal.RequireFromString(tt.values[i])) {
+					t.Errorf("event %d: value %s, want %s", i, e.Value, tt.values[i])
+				}
+				if !e.Threshold.Equal(tt.rule.Threshold) || e.Type != tt.rule.Type || e.Message == "" {
+					t.Errorf("event %d does not describe the rule: %+v", i, e)
+				}
+			}
+		})
+	}
+}
+
+func TestGoalDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		positions []Position
+		goal      *models.PortfolioGoal
+		want      map[string]string // category -> drift
+	}{
+		{
+			name:      "no goal",
+			positions: []Position{position("AAPL", "Stocks", "1", "100")},
+		},
+		{
+			name:      "empty portfolio",
+			positions: nil,
+			goal:      goal(map[string]float64{"Stocks": 100}),
+		},
+		{
+			name:      "on target",
+			positions: []Position{position("AAPL", "Stocks", "1", "60"), position("BND", "Bonds", "1", "40")},
+			goal:      goal(map[string]float64{"Stocks": 60, "Bonds": 40}),
+			want:      map[string]string{"Bonds": "0", "Stocks": "0"},
+		},
+		{
+			name: "categories add up across positions",
+			positions: []Position{
+				position("AAPL", "Stocks", "1", "50"), position("MSFT", "Stocks", "1", "30"), position("BND", "Bonds", "1", "20"),
+			},
+			goal: goal(map[string]float64{"Stocks": 60, "Bonds": 40}),
+			want: map[string]string{"Bonds": "-20", "Stocks": "20"},
+		},
+		{
+			name:      "category missing from the portfolio",
+			positions: []Position{position("AAPL", "Stocks", "1", "100")},
+			goal:      goal(map[string]float64{"Stocks": 70, "Crypto": 30}),
+			want:      map[string]string{"Crypto": "-30", "Stocks": "30"},
+		},
+		{
+			name:      "positions without a report are left out",
+			positions: []Position{position("AAPL", "Stocks", "1", "100"), position("BTC", "Crypto", "1", "")},
+			goal:      goal(map[string]float64{"Stocks": 50, "Crypto": 50}),
+			want:      map[string]string{"Crypto": "-50", "Stocks": "50"},
+		},
+		{
+			name:      "shares are rounded to two places",
+			positions: []Position{position("A", "Stocks", "1", "1"), position("B", "Bonds", "1", "2")},
+			goal:      goal(map[string]float64{"Stocks": 33, "Bonds": 67}),
+			want:      map[string]string{"Bonds": "-0.33", "Stocks": "0.33"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drifts := GoalDrift(tt.positions, tt.goal)
+			if len(drifts) != len(tt.want) {
+				t.Fatalf("got %+v, want %v", drifts, tt.want)
+			}
+			for i, d := range drifts {
+				if i > 0 && drifts[i-1].Category >= d.Category {
+					t.Errorf("drifts are not sorted by category: %+v", drifts)
+				}
+				want, ok := tt.want[d.Category]
+				if !ok {
+					t.Errorf("unexpected category %q", d.Category)
+					continue
+				}
+				if !d.Drift.Equal(decimal.RequireFromString(want)) {
+					t.Errorf("%s: drift %s, want %s", d.Category, d.Drift, want)
+				}
+				if !d.Drift.Equal(d.Actual.Sub(d.Target)) {
+					t.Errorf("%s: drift %s is not actual %s - target %s", d.Category, d.Drift, d.Actual, d.Target)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"sort"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// AllocationGroups are the position fields an allocation can be grouped by
+var AllocationGroups = []string{"category", "sector", "industry", "country", "exchange", "asset_type", "currency"}
+
+// AllocationGroup is the share of the portfolio of the positions with the same
+// value of the grouped field. Key is empty for positions without one.
+type AllocationGroup struct {
+	Key         string       `json:"key"`
+	MarketValue models.Money `json:"market_value"`
+	Percent     float64      `json:"percent"`
+	Symbols     []string     `json:"symbols"`
+}
+
+// Allocation splits the market value of a portfolio by one position field
+type Allocation struct {
+	Currency    string       `json:"currency"`
+	By          string       `json:"by"`
+	MarketValue models.Money `json:"market_value"`
+	// Currencies with no rate to the report currency; their positions are left out
+	MissingRates []string          `json:"missing_rates"`
+	Groups       []AllocationGroup `json:"groups"`
+}
+
+// Allocate groups positions valued by ValuePositions by one of AllocationGroups,
+// largest group first
+func Allocate(positions []Position, missing []string, by, currency string) Allocation {
+	allocation := Allocation{
+		Currency: currency, By: by, MarketValue: models.NewMoney(decimal.Zero, currency),
+		MissingRates: missing, Groups: []AllocationGroup{},
+	}
+
+	index := make(map[string]int)
+	for _, p := range positions {
+		if p.Report == nil {
+			continue
+		}
+		key := allocationKey(p, by)
+		i, ok := index[key]
+		if !ok {
+			i = len(allocation.Groups)
+			index[key] = i
+			allocation.Groups = append(allocation.Groups, AllocationGroup{Key: key, MarketValue: models.NewMoney(decimal.Zero, currency)})
+		}
+		g := &allocation.Groups[i]
+		g.MarketValue = g.MarketValue.Add(p.Report.MarketValue)
+		g.Symbols = append(g.Symbols, p.Symbol)
+		allocation.MarketValue = allocation.MarketValue.Add(p.Report.MarketValue)
+	}
+
+	for i := range allocation.Groups {
+		allocation.Groups[i].Percent = PercentOf(allocation.Groups[i].MarketValue.Amount, allocation.MarketValue.Amount)
+	}
+	sort.SliceStable(allocation.Groups, func(i, j int) bool {
+		return allocation.Groups[i].MarketValue.Amount.GreaterThan(allocation.Groups[j].MarketValue.Amount)
+	})
+	return allocation
+}
+
+func allocationKey(p Position, by string) string {
+	switch by {
+	case "sector":
+		return p.Sector
+	case "industry":
+		return p.Industry
+	case "country":
+		return p.Country
+	case "exchange":
+		return p.Exchange
+	case "asset_type":
+		return p.AssetType
+	case "currency":
+		return p.Currency
+	}
+	return p.Category
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// NewHTTPClient returns the client shared by the market data providers:
+// bounded timeouts at every step and idle connections kept for reuse, since
+// a refresh sends many requests to the same host.
+func NewHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.ResponseHeaderTimeout = 15 * time.Second
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 16
+	transport.IdleConnTimeout = 90 * time.Second
+	return &http.Client{Timeout: 30 * time.Second, Transport: transport}
+}
+
+// TokenBucket limits requests to a provider's quota: it refills at a steady
+// rate and holds up to burst tokens, one per request.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket allows perMinute requests a minute, up to burst at once. It starts full.
+func NewTokenBucket(perMinute, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// StatusError is a response outside 2xx
+type StatusError struct {
+	StatusCode int
+	RetryAfter time.Duration // from the Retry-After header, if any
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned status %d", e.StatusCode)
+}
+
+// Fetcher sends the requests of one provider through a shared client and its
+// rate limiter. Server errors, 429s and network errors are retried up to
+// Retries times, waiting Backoff and then twice as long after every failure.
+type Fetcher struct {
+	Client  *http.Client
+	Limiter *TokenBucket
+	Retries int
+	Backoff time.Duration
+}
+
+// GetJSON fetches a URL and decodes its JSON body into out
+func (f *Fetcher) GetJSON(ctx context.Context, rawURL string, out any) error {
+	for attempt := 0; ; attempt++ {
+		if err := f.Limiter.Wait(ctx); err != nil {
+			return err
+		}
+		err := f.getJSON(ctx, rawURL, out)
+		if err == nil || !retryable(err) || attempt >= f.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		// Exponential backoff with jitter, so workers that failed together do not retry together
+		delay := f.Backoff<<attempt + rand.N(f.Backoff)
+		var status *StatusError
+		if errors.As(err, &status) && status.RetryAfter > delay {
+			delay = status.RetryAfter
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func (f *Fetcher) getJSON(ctx context.Context, rawURL string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return withoutURL(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // so the connection is reused
+		e := &StatusError{StatusCode: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			e.RetryAfter = time.Duration(seconds) * time.Second
+		}
+		return e
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", withoutURL(err))
+	}
+	return nil
+}
+
+// retryable reports whether a request might succeed if sent again. Client
+// timeouts are; the caller's own cancellation is checked by GetJSON.
+func retryable(err error) bool {
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.StatusCode >= 500 || status.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// withoutURL drops the URL from the errors of the HTTP client: it carries the
+// API key, and these errors end up in logs and job reports
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s request failed: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	tests := []struct {
+		name             string
+		perMinute, burst int
+		requests         int
+		min, max         time.Duration
+	}{
+		{"within the burst", 60, 3, 3, 0, 20 * time.Millisecond},
+		{"past the burst waits for refills", 6000, 2, 5, 30 * time.Millisecond, 200 * time.Millisecond},
+		{"burst of one spaces every request", 6000, 1, 4, 30 * time.Millisecond, 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewTokenBucket(tt.perMinute, tt.burst)
+			start := time.Now()
+			for i := 0; i < tt.requests; i++ {
+				if err := b.Wait(context.Background()); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed < tt.min || elapsed > tt.max {
+				t.Errorf("%d requests took %s, want %s to %s", tt.requests, elapsed, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestTokenBucketCancel(t *testing.T) {
+	b := NewTokenBucket(1, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait on an empty bucket = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestFetcherGetJSON(t *testing.T) {
+	type reply struct {
+		status     int
+		body       string
+		retryAfter string
+	}
+	ok := reply{http.StatusOK, `{"price":"1.5"}`, ""}
+	tests := []struct {
+		name     string
+		replies  []reply // the last one repeats
+		requests int32
+		status   int // of the StatusError returned, 0 for none
+		wantErr  bool
+		min      time.Duration
+	}{
+		{"first try", []reply{ok}, 1, 0, false, 0},
+		{"server error is retried", []reply{{status: 500}, ok}, 2, 0, false, 0},
+		{"too many requests is retried", []reply{{status: 429}, {status: 503}, ok}, 3, 0, false, 0},
+		{"Retry-After is respected", []reply{{status: 429, retryAfter: "1"}, ok}, 2, 0, false, time.Second},
+		{"client error is not retried", []reply{{status: 404}, ok}, 1, 404, true, 0},
+		{"gives up after the retries", []reply{{status: 502}}, 3, 502, true, 0},
+		{"invalid JSON is not retried", []reply{{status: 200, body: "<html>"}, ok}, 1, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(requests.Add(1)) - 1
+				rp := tt.replies[min(n, len(tt.replies)-1)]
+				if rp.retryAfter != "" {
+					w.Header().Set("Retry-After", rp.retryAfter)
+				}
+				w.WriteHeader(rp.status)
+				w.Write([]byte(rp.body))
+			}))
+			defer server.Close()
+
+			f := &Fetcher{Client: server.Client(), Limiter: NewTokenBucket(60000, 10), Retries: 2, Backoff: time.Millisecond}
+			var out struct{ Price string }
+			start := time.Now()
+			err := f.GetJSON(context.Background(), server.URL, &out)
+
+			if got := requests.Load(); got != tt.requests {
+				t.Errorf("%d requests, want %d", got, tt.requests)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			var status *StatusError
+			if errors.As(err, &status) != (tt.status != 0) || (status != nil && status.StatusCode != tt.status) {
+				t.Errorf("error = %v, want status %d", err, tt.status)
+			}
+			if err == nil && out.Price != "1.5" {
+				t.Errorf("decoded %+v", out)
+			}
+			if elapsed := time.Since(start); elapsed < tt.min {
+				t.Errorf("took %s, want at least %s", elapsed, tt.min)
+			}
+		})
+	}
+}
+
+func TestFetcherHidesURL(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL + "/query?apikey=secret"
+	server.Close() // connections are refused
+
+	f := &Fetcher{Client: &http.Client{}, Limiter: NewTokenBucket(60000, 10), Retries: 1, Backoff: time.Millisecond}
+	err := f.GetJSON(context.Background(), url, &struct{}{})
+	if err == nil {
+		t.Fatal("expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks the URL: %v", err)
+	}
+}
@@ -1,29 +1,79 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	neturl "net/url"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
+// Errors of the market data API that callers tell apart from the others
+var (
+	ErrRateLimited = errors.New("rate limit exceeded")
+	ErrNotFound    = errors.New("symbol not found")
+)
+
+// Alpha Vantage quota defaults, those of the free plan. Paid plans set
+// ALPHA_REQUESTS_PER_MINUTE and ALPHA_CONCURRENCY to match theirs.
+const (
+	defaultAlphaPerMinute   = 5
+	defaultAlphaConcurrency = 4
+)
+
 type FinanceService struct {
 	Logger          *zap.SugaredLogger
+	Quotes          QuoteProvider // where FetchQuotes gets prices from
+	Cache           *QuoteCache
 	alphaApiBaseUrl string
 	alphaApiKey     string
+	alpha           *Fetcher
+	workers         int // quotes fetched at a time by FetchQuotes
 }
 
 func NewFinanceService(logger *zap.SugaredLogger) *FinanceService {
-	return &FinanceService{
+	perMinute := positiveEnv(logger, "ALPHA_REQUESTS_PER_MINUTE", defaultAlphaPerMinute)
+	s := &FinanceService{
 		Logger:          logger,
 		alphaApiBaseUrl: os.Getenv("ALPHA_API_BASE_URL"),
 		alphaApiKey:     os.Getenv("ALPHA_API_KEY"),
+		alpha: &Fetcher{
+			Client:  NewHTTPClient(),
+			Limiter: NewTokenBucket(perMinute, max(1, perMinute/5)),
+			Retries: 3,
+			Backoff: time.Second,
+		},
+		workers: positiveEnv(logger, "ALPHA_CONCURRENCY", defaultAlphaConcurrency),
+		Cache:   NewQuoteCache(logger),
+	}
+
+	// Bulk quotes are a premium endpoint, so they are opted into
+	s.Quotes = alphaQuotes{s}
+	if bulk, _ := strconv.ParseBool(os.Getenv("ALPHA_BULK_QUOTES")); bulk {
+		s.Quotes = alphaBulkQuotes{alphaQuotes{s}}
 	}
+	return s
+}
+
+// positiveEnv reads a positive integer setting, falling back to def when it is unset or invalid
+func positiveEnv(logger *zap.SugaredLogger, name string, def int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		logger.Warnf("Invalid %s %q, using %d", name, raw, def)
+		return def
+	}
+	return n
 }
 
 type apiResponse struct {
@@ -33,11 +83,11 @@ type apiResponse struct {
 	} `json:"Global Quote"`
 
 	Information string `json:"Information"`
+	Note        string `json:"Note"`
 }
 
 // buildAlphaSymbol converts our internal symbol to the Alpha Vantage query symbol.
 // BRL stocks get .SAO appended. Symbols with "/" (e.g. BTC/USD) become BTCUSD.
-// TODO: Implement unit functions for this function
 func buildAlphaSymbol(symbol string, currency string) string {
 	// Remove slash for crypto pairs like BTC/USD -> BTCUSD
 	alphaSymbol := strings.ReplaceAll(symbol, "/", "")
@@ -50,8 +100,92 @@ func buildAlphaSymbol(symbol string, currency string) string {
 	return alphaSymbol
 }
 
+// fromAlphaSymbol converts an Alpha Vantage symbol back to ours, undoing buildAlphaSymbol
+func fromAlphaSymbol(alphaSymbol string) string {
+	return strings.TrimSuffix(alphaSymbol, ".SAO")
+}
+
+// alphaExchanges names the exchange of an Alpha Vantage symbol by its suffix
+var alphaExchanges = map[string]string{
+	"SAO": "B3",
+	"LON": "LSE",
+	"TRT": "TSX",
+	"TRV": "TSXV",
+	"DEX": "XETRA",
+	"FRK": "FWB",
+	"AMS": "Euronext Amsterdam",
+	"PAR": "Euronext Paris",
+	"BSE": "BSE",
+	"SHH": "SSE",
+	"SHZ": "SZSE",
+}
+
+// alphaExchange names the exchange of a symbol, falling back to its region
+func alphaExchange(alphaSymbol, region string) string {
+	if i := strings.LastIndex(alphaSymbol, "."); i >= 0 {
+		if exchange, ok := alphaExchanges[alphaSymbol[i+1:]]; ok {
+			return exchange
+		}
+	}
+	if region == "United States" {
+		return "US"
+	}
+	return region
+}
+
+type symbolSearchResponse struct {
+	BestMatches []struct {
+		Symbol     string `json:"1. symbol"`
+		Name       string `json:"2. name"`
+		Type       string `json:"3. type"`
+		Region     string `json:"4. region"`
+		Currency   string `json:"8. currency"`
+		MatchScore string `json:"9. matchScore"`
+	} `json:"bestMatches"`
+
+	Information string `json:"Information"`
+	Note        string `json:"Note"`
+}
+
+// SearchSymbols finds the listings matching keywords (SYMBOL_SEARCH), best first
+func (s *FinanceService) SearchSymbols(ctx context.Context, keywords string) ([]models.SymbolMatch, error) {
+	if s.alphaApiKey == "" {
+		return nil, fmt.Errorf("API key is missing")
+	}
+
+	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=SYMBOL_SEARCH&keywords=%s&apikey=%s",
+		neturl.QueryEscape(keywords), s.alphaApiKey)
+	s.Logger.Infof("Searching symbols for %q", keywords)
+
+	var data symbolSearchResponse
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
+		return nil, err
+	}
+	if strings.Contains(data.Information, "rate limit") || strings.Contains(data.Note, "call frequency") {
+		return nil, ErrRateLimited
+	}
+	if data.BestMatches == nil && data.Information != "" {
+		return nil, fmt.Errorf("symbol search unavailable: %s", data.Information)
+	}
+
+	matches := make([]models.SymbolMatch, 0, len(data.BestMatches))
+	for _, m := range data.BestMatches {
+		score, _ := strconv.ParseFloat(m.MatchScore, 64)
+		matches = append(matches, models.SymbolMatch{
+			Symbol:     fromAlphaSymbol(m.Symbol),
+			Name:       m.Name,
+			Exchange:   alphaExchange(m.Symbol, m.Region),
+			Currency:   strings.ToUpper(m.Currency),
+			AssetType:  m.Type,
+			Region:     m.Region,
+			MatchScore: score,
+		})
+	}
+	return matches, nil
+}
+
 // UpdateTickerFromAPI updates some infos about the ticker
-func (s *FinanceService) UpdateTickerFromAPI(symbol string, currency string) (models.Ticker, error) {
+func (s *FinanceService) UpdateTickerFromAPI(ctx context.Context, symbol string, currency string) (models.Ticker, error) {
 	var updatedTicker models.Ticker
 	if s.alphaApiKey == "" {
 		return updatedTicker, fmt.Errorf("API key is missing")
@@ -60,34 +194,25 @@ func (s *FinanceService) UpdateTickerFromAPI(symbol string, currency string) (mo
 	alphaSymbol := buildAlphaSymbol(symbol, currency)
 
 	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=GLOBAL_QUOTE&symbol=%s&apikey=%s", alphaSymbol, s.alphaApiKey)
-	s.Logger.Infof("Fetching price for %s (query: %s, url: %s)", symbol, alphaSymbol, url)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return updatedTicker, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return updatedTicker, fmt.Errorf("API returned status %d", resp.StatusCode)
-	}
+	s.Logger.Infof("Fetching price for %s (query: %s)", symbol, alphaSymbol)
 
 	var data apiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
 		return updatedTicker, err
 	}
 
 	s.Logger.Debug("Data received: ", data)
 
-	if strings.Contains(data.Information, "rate limit") {
-		return updatedTicker, fmt.Errorf("rate limit exceeded")
+	if strings.Contains(data.Information, "rate limit") || strings.Contains(data.Note, "call frequency") {
+		return updatedTicker, ErrRateLimited
 	}
 
-	if data.GlobalQuote.Price == "" && resp.StatusCode != 200 {
-		return updatedTicker, fmt.Errorf("no price data returned for %s", alphaSymbol)
+	// Unknown symbols get an empty quote
+	if data.GlobalQuote.Price == "" {
+		return updatedTicker, fmt.Errorf("%w: no price data returned for %s", ErrNotFound, alphaSymbol)
 	}
 
-	price, err := strconv.ParseFloat(data.GlobalQuote.Price, 64)
+	price, err := decimal.NewFromString(data.GlobalQuote.Price)
 	if err != nil {
 		return updatedTicker, err
 	}
@@ -97,12 +222,104 @@ func (s *FinanceService) UpdateTickerFromAPI(symbol string, currency string) (mo
 		return updatedTicker, err
 	}
 
-	s.Logger.Infof("Price for %s: %.2f", symbol, price)
+	s.Logger.Infof("Price for %s: %s", symbol, price)
+	now := time.Now()
 	updatedTicker.Price = price
 	updatedTicker.DayChangePercent = dayChangePercent
+	updatedTicker.Source = models.QuoteSourceAlphaVantage
+	updatedTicker.FetchedAt = &now
 	return updatedTicker, nil
 }
 
+// alphaQuotes fetches quotes from Alpha Vantage, one GLOBAL_QUOTE request per symbol
+type alphaQuotes struct {
+	s *FinanceService
+}
+
+func (a alphaQuotes) Quote(ctx context.Context, ticker models.Ticker) (models.Ticker, error) {
+	return a.s.UpdateTickerFromAPI(ctx, ticker.Symbol, ticker.Currency)
+}
+
+// alphaBulkQuotesSize is the most symbols REALTIME_BULK_QUOTES takes at once
+const alphaBulkQuotesSize = 100
+
+// alphaBulkQuotes also fetches up to 100 symbols per REALTIME_BULK_QUOTES
+// request. It only covers US listings; other symbols come back without data.
+type alphaBulkQuotes struct {
+	alphaQuotes
+}
+
+type bulkQuotesResponse struct {
+	Data []struct {
+		Symbol        string `json:"symbol"`
+		Close         string `json:"close"`
+		ChangePercent string `json:"change_percent"`
+	} `json:"data"`
+
+	Message     string `json:"message"`
+	Information string `json:"Information"`
+	Note        string `json:"Note"`
+}
+
+func (a alphaBulkQuotes) BatchSize() int {
+	return alphaBulkQuotesSize
+}
+
+func (a alphaBulkQuotes) Quotes(ctx context.Context, tickers []models.Ticker) (map[string]models.Ticker, error) {
+	s := a.s
+	if s.alphaApiKey == "" {
+		return nil, fmt.Errorf("API key is missing")
+	}
+
+	symbols := make(map[string]string, len(tickers)) // ours by Alpha Vantage's
+	query := make([]string, len(tickers))
+	for i, ticker := range tickers {
+		query[i] = buildAlphaSymbol(ticker.Symbol, ticker.Currency)
+		symbols[query[i]] = ticker.Symbol
+	}
+
+	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=REALTIME_BULK_QUOTES&symbol=%s&apikey=%s",
+		strings.Join(query, ","), s.alphaApiKey)
+	s.Logger.Infof("Fetching prices for %d symbols", len(tickers))
+
+	var data bulkQuotesResponse
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
+		return nil, err
+	}
+	if strings.Contains(data.Information, "rate limit") || strings.Contains(data.Note, "call frequency") {
+		return nil, ErrRateLimited
+	}
+	if data.Data == nil && data.Information != "" {
+		return nil, fmt.Errorf("bulk quotes unavailable: %s", data.Information)
+	}
+
+	now := time.Now()
+	quotes := make(map[string]models.Ticker, len(data.Data))
+	for _, q := range data.Data {
+		symbol, ok := symbols[q.Symbol]
+		if !ok || q.Close == "" {
+			continue
+		}
+		price, err := decimal.NewFromString(q.Close)
+		if err != nil {
+			s.Logger.Warnf("Invalid bulk price for %s: %v", q.Symbol, err)
+			continue
+		}
+		dayChangePercent, err := strconv.ParseFloat(strings.TrimSuffix(q.ChangePercent, "%"), 64)
+		if err != nil {
+			s.Logger.Warnf("Invalid bulk change percent for %s: %v", q.Symbol, err)
+			continue
+		}
+		quotes[symbol] = models.Ticker{
+			Symbol: symbol, Price: price, DayChangePercent: dayChangePercent,
+			Source: models.QuoteSourceAlphaVantage, FetchedAt: &now,
+		}
+	}
+
+	s.Logger.Infof("Bulk prices for %d of %d symbols", len(quotes), len(tickers))
+	return quotes, nil
+}
+
 type currencyExchangeResponse struct {
 	RealtimeCurrencyExchangeRate struct {
 		ExchangeRate string `json:"5. Exchange Rate"`
@@ -110,39 +327,87 @@ type currencyExchangeResponse struct {
 }
 
 // GetExchangeRate fetches the exchange rate from one currency to another (e.g., USD to BRL)
-func (s *FinanceService) GetExchangeRate(fromCurrency, toCurrency string) (float64, error) {
+func (s *FinanceService) GetExchangeRate(ctx context.Context, fromCurrency, toCurrency string) (decimal.Decimal, error) {
 	if s.alphaApiKey == "" {
-		return 0, fmt.Errorf("API key is missing")
+		return decimal.Zero, fmt.Errorf("API key is missing")
 	}
 
 	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=CURRENCY_EXCHANGE_RATE&from_currency=%s&to_currency=%s&apikey=%s",
 		fromCurrency, toCurrency, s.alphaApiKey)
 	s.Logger.Infof("Fetching exchange rate %s to %s", fromCurrency, toCurrency)
 
-	resp, err := http.Get(url)
+	var data currencyExchangeResponse
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
+		return decimal.Zero, err
+	}
+
+	if data.RealtimeCurrencyExchangeRate.ExchangeRate == "" {
+		return decimal.Zero, fmt.Errorf("no exchange rate data returned for %s/%s", fromCurrency, toCurrency)
+	}
+
+	rate, err := decimal.NewFromString(data.RealtimeCurrencyExchangeRate.ExchangeRate)
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return 0, fmt.Errorf("API returned status %d", resp.StatusCode)
+	s.Logger.Infof("Exchange rate %s to %s: %s", fromCurrency, toCurrency, rate)
+	return rate, nil
+}
+
+type fxDailyResponse struct {
+	TimeSeries map[string]struct {
+		Close string `json:"4. close"`
+	} `json:"Time Series FX (Daily)"`
+
+	ErrorMessage string `json:"Error Message"`
+	Information  string `json:"Information"`
+	Note         string `json:"Note"`
+}
+
+// GetDailyRates fetches the daily closing rates of a pair (FX_DAILY, physical
+// currencies only). Without full, only the last 100 days are returned.
+func (s *FinanceService) GetDailyRates(ctx context.Context, fromCurrency, toCurrency string, full bool) ([]models.HistoricalRate, error) {
+	if s.alphaApiKey == "" {
+		return nil, fmt.Errorf("API key is missing")
 	}
 
-	var data currencyExchangeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, err
+	outputSize := "compact"
+	if full {
+		outputSize = "full"
 	}
+	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=FX_DAILY&from_symbol=%s&to_symbol=%s&outputsize=%s&apikey=%s",
+		fromCurrency, toCurrency, outputSize, s.alphaApiKey)
+	s.Logger.Infof("Fetching daily rates %s to %s (%s)", fromCurrency, toCurrency, outputSize)
 
-	if data.RealtimeCurrencyExchangeRate.ExchangeRate == "" {
-		return 0, fmt.Errorf("no exchange rate data returned for %s/%s", fromCurrency, toCurrency)
+	var data fxDailyResponse
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
+		return nil, err
 	}
 
-	rate, err := strconv.ParseFloat(data.RealtimeCurrencyExchangeRate.ExchangeRate, 64)
-	if err != nil {
-		return 0, err
+	switch {
+	case data.ErrorMessage != "":
+		return nil, fmt.Errorf("no daily rates for %s/%s: %s", fromCurrency, toCurrency, data.ErrorMessage)
+	case strings.Contains(data.Information, "rate limit") || strings.Contains(data.Note, "call frequency"):
+		return nil, ErrRateLimited
+	case len(data.TimeSeries) == 0:
+		return nil, fmt.Errorf("no daily rates returned for %s/%s", fromCurrency, toCurrency)
 	}
 
-	s.Logger.Infof("Exchange rate %s to %s: %.4f", fromCurrency, toCurrency, rate)
-	return rate, nil
+	rates := make([]models.HistoricalRate, 0, len(data.TimeSeries))
+	for day, values := range data.TimeSeries {
+		date, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q: %w", day, err)
+		}
+		rate, err := decimal.NewFromString(values.Close)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate on %s: %w", day, err)
+		}
+		rates = append(rates, models.HistoricalRate{
+			Base: fromCurrency, Quote: toCurrency, Date: date, Rate: rate, Source: models.RateSourceAlphaVantage,
+		})
+	}
+
+	s.Logger.Infof("Fetched %d daily rates %s to %s", len(rates), fromCurrency, toCurrency)
+	return rates, nil
 }
@@ -0,0 +1,38 @@
+package services
+
+import "testing"
+
+func TestBuildAlphaSymbol(t *testing.T) {
+	tests := []struct {
+		symbol, currency string
+		want             string
+	}{
+		{"AAPL", "USD", "AAPL"},
+		{"BTC/USD", "USD", "BTCUSD"},
+		{"ETH/BRL", "BRL", "ETHBRL.SAO"},
+		{"PETR4", "BRL", "PETR4.SAO"},
+		{"PETR4.SAO", "BRL", "PETR4.SAO"},
+		{"SHOP.TRT", "CAD", "SHOP.TRT"},
+		{"", "USD", ""},
+	}
+	for _, tt := range tests {
+		if got := buildAlphaSymbol(tt.symbol, tt.currency); got != tt.want {
+			t.Errorf("buildAlphaSymbol(%q, %q) = %q, want %q", tt.symbol, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestFromAlphaSymbol(t *testing.T) {
+	tests := []struct {
+		symbol, currency string
+	}{
+		{"AAPL", "USD"},
+		{"PETR4", "BRL"},
+		{"SHOP.TRT", "CAD"},
+	}
+	for _, tt := range tests {
+		if got := fromAlphaSymbol(buildAlphaSymbol(tt.symbol, tt.currency)); got != tt.symbol {
+			t.Errorf("fromAlphaSymbol(buildAlphaSymbol(%q, %q)) = %q", tt.symbol, tt.currency, got)
+		}
+	}
+}
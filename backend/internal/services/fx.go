@@ -0,0 +1,234 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// DefaultBaseCurrency is the reporting currency when BASE_CURRENCY is not set
+const DefaultBaseCurrency = "BRL"
+
+// rateDecimals is the precision kept for derived (inverse and cross) rates
+const rateDecimals = 10
+
+// ErrNoRate is returned when no chain of stored rates links two currencies
+var ErrNoRate = errors.New("no exchange rate")
+
+// BaseCurrency reads BASE_CURRENCY, the currency summaries are reported in
+// unless a request asks for another one.
+func BaseCurrency() (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(os.Getenv("BASE_CURRENCY")))
+	if code == "" {
+		return DefaultBaseCurrency, nil
+	}
+	if !models.SupportedCurrencies[code] {
+		return "", fmt.Errorf("invalid BASE_CURRENCY %q: not a supported currency", code)
+	}
+	return code, nil
+}
+
+// Quote is the rate from one currency to another, possibly derived.
+// Path lists the currencies it went through, e.g. [EUR USD BRL] for a cross rate.
+type Quote struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Path      []string        `json:"path"`
+	UpdatedAt time.Time       `json:"updated_at"` // of the oldest rate used; zero for identical currencies
+}
+
+// edge converts to a neighbour currency by multiplying by rate, or dividing
+// for inverses, so rounding only happens once per chain
+type edge struct {
+	rate      decimal.Decimal
+	inverse   bool
+	updatedAt time.Time
+}
+
+// RateTable converts between any two currencies using the stored pairs, their
+// inverses and, when no pair links them, cross rates through other currencies.
+type RateTable struct {
+	edges map[string]map[string]edge
+}
+
+// NewRateTable builds a table from stored rates. A stored pair always wins over
+// the inverse of the opposite pair.
+func NewRateTable(rates []models.ExchangeRate) *RateTable {
+	t := &RateTable{edges: make(map[string]map[string]edge)}
+	for _, r := range rates {
+		if r.Rate.IsPositive() {
+			t.set(r.Quote, r.Base, edge{r.Rate, true, r.UpdatedAt})
+		}
+	}
+	for _, r := range rates {
+		if r.Rate.IsPositive() {
+			t.set(r.Base, r.Quote, edge{r.Rate, false, r.UpdatedAt})
+		}
+	}
+	return t
+}
+
+// LoadRates builds a table from the exchange_rates table
+func LoadRates(db *gorm.DB) (*RateTable, error) {
+	var rates []models.ExchangeRate
+	if err := db.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return NewRateTable(rates), nil
+}
+
+func (t *RateTable) set(from, to string, e edge) {
+	if t.edges[from] == nil {
+		t.edges[from] = make(map[string]edge)
+	}
+	t.edges[from][to] = e
+}
+
+// Rate finds the rate with the fewest conversions from one currency to another
+func (t *RateTable) Rate(from, to string) (Quote, error) {
+	if from == to {
+		return Quote{From: from, To: to, Rate: decimal.NewFromInt(1), Path: []string{from}}, nil
+	}
+
+	// Breadth-first search, remembering how each currency was reached
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 && prev[to] == "" {
+		current := queue[0]
+		queue = queue[1:]
+		// Sorted, so equally short paths are always picked the same way
+		neighbours := make([]string, 0, len(t.edges[current]))
+		for next := range t.edges[current] {
+			neighbours = append(neighbours, next)
+		}
+		sort.Strings(neighbours)
+		for _, next := range neighbours {
+			if _, seen := prev[next]; !seen {
+				prev[next] = current
+				queue = append(queue, next)
+			}
+		}
+	}
+	if _, ok := prev[to]; !ok {
+		return Quote{}, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+	}
+
+	path := []string{to}
+	for c := prev[to]; c != ""; c = prev[c] {
+		path = append([]string{c}, path...)
+	}
+
+	// Multiply all the direct rates and divide once by the product of the inverse ones
+	q := Quote{From: from, To: to, Path: path}
+	num, den := decimal.NewFromInt(1), decimal.NewFromInt(1)
+	for i := 0; i+1 < len(path); i++ {
+		e := t.edges[path[i]][path[i+1]]
+		if e.inverse {
+			den = den.Mul(e.rate)
+		} else {
+			num = num.Mul(e.rate)
+		}
+		if q.UpdatedAt.IsZero() || e.updatedAt.Before(q.UpdatedAt) {
+			q.UpdatedAt = e.updatedAt
+		}
+	}
+	q.Rate = num
+	if !den.Equal(decimal.NewFromInt(1)) || len(path) > 2 {
+		q.Rate = num.DivRound(den, rateDecimals)
+	}
+	return q, nil
+}
+
+// Convert converts an amount to another currency, rounded to its minor unit
+func (t *RateTable) Convert(m models.Money, to string) (models.Money, error) {
+	q, err := t.Rate(m.Currency, to)
+	if err != nil {
+		return models.Money{}, err
+	}
+	return models.NewMoney(m.Amount.Mul(q.Rate), to), nil
+}
+
+// LoadValuation loads the current and historical rates to value amounts in currency
+func LoadValuation(db *gorm.DB, currency string) (Valuation, error) {
+	rates, err := LoadRates(db)
+	if err != nil {
+		return Valuation{}, err
+	}
+	history, err := LoadHistory(db)
+	if err != nil {
+		return Valuation{}, err
+	}
+	return Valuation{Currency: currency, Rates: rates, History: history}, nil
+}
+
+// maxHistoryGap is how far back History looks for a rate when a day has none
+// (weekends, holidays and gaps in the imported series)
+const maxHistoryGap = 10 * 24 * time.Hour
+
+// Day returns the calendar day of t as midnight UTC, the form historical rates are stored in
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// History converts between currencies at the rates of a given day
+type History struct {
+	series map[string][]models.HistoricalRate // by pair, oldest first
+	tables map[time.Time]*RateTable
+}
+
+// NewHistory builds a history from daily rates, in any order
+func NewHistory(rates []models.HistoricalRate) *History {
+	h := &History{series: make(map[string][]models.HistoricalRate), tables: make(map[time.Time]*RateTable)}
+	for _, r := range rates {
+		if r.Rate.IsPositive() {
+			h.series[r.Pair()] = append(h.series[r.Pair()], r)
+		}
+	}
+	for _, s := range h.series {
+		sort.Slice(s, func(i, j int) bool { return s[i].Date.Before(s[j].Date) })
+	}
+	return h
+}
+
+// LoadHistory builds a history from the historical_rates table
+func LoadHistory(db *gorm.DB) (*History, error) {
+	var rates []models.HistoricalRate
+	if err := db.Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return NewHistory(rates), nil
+}
+
+// On returns the rates of a day: for every pair, the latest rate on or
+// before it, if not older than maxHistoryGap
+func (h *History) On(date time.Time) *RateTable {
+	day := Day(date)
+	if t, ok := h.tables[day]; ok {
+		return t
+	}
+
+	var rates []models.ExchangeRate
+	for _, s := range h.series {
+		i := sort.Search(len(s), func(i int) bool { return s[i].Date.After(day) }) - 1
+		if i < 0 || day.Sub(s[i].Date) > maxHistoryGap {
+			continue
+		}
+		rates = append(rates, models.ExchangeRate{Base: s[i].Base, Quote: s[i].Quote, Rate: s[i].Rate, UpdatedAt: s[i].Date})
+	}
+	t := NewRateTable(rates)
+	h.tables[day] = t
+	return t
+}
+
+// Rate returns the rate between two currencies on a day
+func (h *History) Rate(from, to string, date time.Time) (Quote, error) {
+	return h.On(date).Rate(from, to)
+}
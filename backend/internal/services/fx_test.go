@@ -0,0 +1,178 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestRateTableRate(t *testing.T) {
+	rate := func(base, quote, r, updated string) models.ExchangeRate {
+		return models.ExchangeRate{Base: base, Quote: quote, Rate: decimal.RequireFromString(r), UpdatedAt: date(updated)}
+	}
+	table := NewRateTable([]models.ExchangeRate{
+		rate("USD", "BRL", "5", "2024-01-01"),
+		rate("EUR", "USD", "1.1", "2024-01-02"),
+		rate("BRL", "ARS", "200", "2024-01-03"),
+		rate("GBP", "JPY", "190", "2024-01-04"), // linked to nothing else
+		rate("CHF", "CAD", "1.5", "2024-01-05"),
+		rate("CAD", "CHF", "0.7", "2024-01-05"), // both ways: each stored pair wins over the inverse
+		rate("BTC", "USD", "0", "2024-01-05"),   // ignored
+	})
+
+	tests := []struct {
+		name     string
+		from, to string
+		rate     string
+		path     []string
+		updated  string // of the oldest rate used
+		err      error
+	}{
+		{"same currency", "BRL", "BRL", "1", []string{"BRL"}, "", nil},
+		{"direct", "USD", "BRL", "5", []string{"USD", "BRL"}, "2024-01-01", nil},
+		{"inverse", "BRL", "USD", "0.2", []string{"BRL", "USD"}, "2024-01-01", nil},
+		{"cross", "EUR", "BRL", "5.5", []string{"EUR", "USD", "BRL"}, "2024-01-01", nil},
+		{"cross of inverses, rounded once", "BRL", "EUR", "0.1818181818", []string{"BRL", "USD", "EUR"}, "2024-01-01", nil},
+		{"three steps", "ARS", "EUR", "0.0009090909", []string{"ARS", "BRL", "USD", "EUR"}, "2024-01-01", nil},
+		{"stored pair over the inverse", "CAD", "CHF", "0.7", []string{"CAD", "CHF"}, "2024-01-05", nil},
+		{"missing pair", "USD", "GBP", "", nil, "", ErrNoRate},
+		{"unknown currency", "XYZ", "BRL", "", nil, "", ErrNoRate},
+		{"zero rates are skipped", "BTC", "USD", "", nil, "", ErrNoRate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := table.Rate(tt.from, tt.to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if q.Rate.String() != tt.rate || !slices.Equal(q.Path, tt.path) {
+				t.Errorf("rate %s via %v, want %s via %v", q.Rate, q.Path, tt.rate, tt.path)
+			}
+			if updated := q.UpdatedAt.Format("2006-01-02"); tt.updated != "" && updated != tt.updated {
+				t.Errorf("updated %s, want %s", updated, tt.updated)
+			}
+		})
+	}
+}
+
+func TestHistoryOn(t *testing.T) {
+	rate := func(base, day, r string) models.HistoricalRate {
+		return models.HistoricalRate{Base: base, Quote: "BRL", Date: date(day), Rate: decimal.RequireFromString(r)}
+	}
+	history := NewHistory([]models.HistoricalRate{
+		rate("USD", "2024-01-08", "4.95"), // Monday; given out of order
+		rate("USD", "2024-01-05", "4.9"),  // Friday
+		rate("EUR", "2024-01-02", "5.4"),
+	})
+
+	tests := []struct {
+		name     string
+		from     string
+		on       time.Time
+		rate     string // empty when there is none
+		ratedate string
+	}{
+		{"on the day", "USD", date("2024-01-05"), "4.9", "2024-01-05"},
+		{"weekend uses Friday", "USD", date("2024-01-06"), "4.9", "2024-01-05"},
+		{"time of day is ignored", "USD", date("2024-01-07").Add(23 * time.Hour), "4.9", "2024-01-05"},
+		{"next rate", "USD", date("2024-01-08"), "4.95", "2024-01-08"},
+		{"before the series", "USD", date("2024-01-04"), "", ""},
+		{"10 days later", "USD", date("2024-01-18"), "4.95", "2024-01-08"},
+		{"11 days later", "USD", date("2024-01-19"), "", ""},
+		{"holiday gap of another pair", "EUR", date("2024-01-12"), "5.4", "2024-01-02"},
+		{"past the gap of another pair", "EUR", date("2024-01-13"), "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := history.Rate(tt.from, "BRL", tt.on)
+			if tt.rate == "" {
+				if !errors.Is(err, ErrNoRate) {
+					t.Errorf("rate %s (%v), want none", q.Rate, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if q.Rate.String() != tt.rate || !q.UpdatedAt.Equal(date(tt.ratedate)) {
+				t.Errorf("rate %s of %s, want %s of %s", q.Rate, q.UpdatedAt.Format("2006-01-02"), tt.rate, tt.ratedate)
+			}
+		})
+	}
+
+	// Cross rates use the rates of the same day
+	q, err := history.Rate("EUR", "USD", date("2024-01-08"))
+	if err != nil || q.Rate.String() != "1.0909090909" {
+		t.Errorf("EUR/USD on 2024-01-08 = %s (%v), want 5.4 / 4.95", q.Rate, err)
+	}
+}
+
+func TestParsePTAX(t *testing.T) {
+	csv := strings.Join([]string{
+		"\ufeffData;Cod Moeda;Tipo;Moeda;Taxa Compra;Taxa Venda;Paridade Compra;Paridade Venda",
+		"02012024;220;A;USD;4,8910;4,8916;1,0000;1,0000",
+		"3012024;978;B;EUR;5,3400;5,3500;1,0900;1,0920", // the day lost its leading zero
+		"04/01/2024;220;A;USD;4,9000;4,9010;1;1",
+		"",
+		"05012024;220;A;USD;4,9",
+		"32012024;220;A;USD;4,9;4,9;1;1",
+		"05012024;999;A;XYZ;1;1;1;1",
+		"05012024;790;A;BRL;1;1;1;1", // BRL to BRL, skipped
+		"05012024;220;A;USD;abc;0;1;1",
+		"08012024;220;A;USD;1.234,5;1.234,6;1;1", // thousands separator
+	}, "\r\n")
+
+	wantErrors := []LineError{
+		{6, "expected at least 6 fields, got 5"},
+		{7, `invalid date "32012024"`},
+		{8, "unknown currency 'XYZ'"},
+		{10, "invalid rate"},
+	}
+	for _, tt := range []struct {
+		useBuy bool
+		want   []string // base, date and rate of each row
+	}{
+		{false, []string{"USD 2024-01-02 4.8916", "EUR 2024-01-03 5.35", "USD 2024-01-04 4.901", "USD 2024-01-08 1234.6"}},
+		{true, []string{"USD 2024-01-02 4.891", "EUR 2024-01-03 5.34", "USD 2024-01-04 4.9", "USD 2024-01-08 1234.5"}},
+	} {
+		rates, errs, err := ParsePTAX(strings.NewReader(csv), tt.useBuy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range rates {
+			if r.Quote != "BRL" || r.Source != models.RateSourcePTAX {
+				t.Errorf("rate %+v, want a PTAX rate to BRL", r)
+			}
+			got = append(got, r.Base+" "+r.Date.Format("2006-01-02")+" "+r.Rate.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("useBuy %v: rates %q, want %q", tt.useBuy, got, tt.want)
+		}
+		if len(errs) != len(wantErrors) {
+			t.Fatalf("errors %+v, want %+v", errs, wantErrors)
+		}
+		for i, e := range errs {
+			if e.Line != wantErrors[i].Line || !strings.HasPrefix(e.Message, wantErrors[i].Message) {
+				t.Errorf("error %d = %+v, want %+v", i, e, wantErrors[i])
+			}
+		}
+	}
+}
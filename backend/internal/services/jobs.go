@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrJobNotRunning is returned when cancelling a job that already finished
+var ErrJobNotRunning = errors.New("job is not running")
+
+// JobFunc does the work of a job, saving its progress with JobService.Save.
+// The request carries the user and request ID of the one that started the
+// job, for the audit log; its context is cancelled when the job is.
+type JobFunc func(r *http.Request, job *models.Job) error
+
+// JobService runs jobs in the background and keeps their state in the jobs
+// table, so it can be read while they run and after they finish.
+type JobService struct {
+	DB     *gorm.DB
+	Logger *zap.SugaredLogger
+
+	mu      sync.Mutex
+	running map[uint]context.CancelFunc
+}
+
+func NewJobService(db *gorm.DB, logger *zap.SugaredLogger) *JobService {
+	return &JobService{DB: db, Logger: logger, running: make(map[uint]context.CancelFunc)}
+}
+
+// Start stores the job and runs fn in the background. Only one job of a kind
+// runs at a time in a portfolio: when one is already running, job is set to it
+// and started is false.
+func (s *JobService) Start(r *http.Request, job *models.Job, fn JobFunc) (started bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var running models.Job
+	err = s.DB.Where("portfolio_id = ? AND kind = ? AND status = ?", job.PortfolioID, job.Kind, models.JobRunning).Order("id").Limit(1).Find(&running).Error
+	if err != nil {
+		return false, err
+	}
+	if running.ID != 0 {
+		*job = running
+		return false, nil
+	}
+
+	job.Status = models.JobRunning
+	if err := s.DB.Create(job).Error; err != nil {
+		return false, err
+	}
+
+	// The job outlives the request but keeps its values, e.g. the user
+	ctx, cancel := context.WithCancel(context.WithoutCancel(r.Context()))
+	s.running[job.ID] = cancel
+	work := *job
+	go s.run(r.WithContext(ctx), &work, fn)
+	return true, nil
+}
+
+func (s *JobService) run(r *http.Request, job *models.Job, fn JobFunc) {
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if cancel, ok := s.running[job.ID]; ok {
+			cancel()
+			delete(s.running, job.ID)
+		}
+	}()
+
+	err := s.call(r, job, fn)
+	switch {
+	case r.Context().Err() != nil:
+		job.Status = models.JobCancelled
+	case err != nil:
+		job.Status = models.JobFailed
+		job.Error = err.Error()
+		s.Logger.Errorw("job failed", "job_id", job.ID, "kind", job.Kind, "error", err)
+	default:
+		job.Status = models.JobCompleted
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+	if err := s.Save(job); err != nil {
+		s.Logger.Errorw("failed to save job", "job_id", job.ID, "error", err)
+	}
+}
+
+// call runs fn, turning a panic into an error so a bug in one job fails it
+// instead of taking the server down
+func (s *JobService) call(r *http.Request, job *models.Job, fn JobFunc) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			s.Logger.Errorw("job panicked", "job_id", job.ID, "kind", job.Kind, "panic", p, "stack", string(debug.Stack()))
+			err = fmt.Errorf("internal error: %v", p)
+		}
+	}()
+	return fn(r, job)
+}
+
+// Save stores the progress of a job
+func (s *JobService) Save(job *models.Job) error {
+	return s.DB.Save(job).Error
+}
+
+// Cancel stops a running job. Work already done is kept.
+func (s *JobService) Cancel(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel, ok := s.running[id]
+	if !ok {
+		return ErrJobNotRunning
+	}
+	cancel()
+	delete(s.running, id)
+	return nil
+}
+
+// FailInterrupted marks the jobs a previous process left running as failed.
+// It must run at startup, before any job starts.
+func (s *JobService) FailInterrupted() error {
+	return s.DB.Model(&models.Job{}).Where("status = ?", models.JobRunning).
+		Updates(map[string]any{"status": models.JobFailed, "error": "interrupted by a restart", "finished_at": time.Now()}).Error
+}
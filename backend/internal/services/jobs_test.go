@@ -0,0 +1,93 @@
+package services
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// waitForJob waits until the job with id has finished
+func waitForJob(t *testing.T, db *gorm.DB, id uint) models.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var job models.Job
+		if err := db.First(&job, id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != models.JobRunning {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %d still running", id)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobsRunOncePerPortfolio(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+			t.Fatal(err)
+		}
+		s := NewJobService(db, zap.NewNop().Sugar())
+		r := httptest.NewRequest("POST", "/prices/refresh", nil)
+
+		release := make(chan struct{})
+		block := func(r *http.Request, job *models.Job) error {
+			<-release
+			return nil
+		}
+
+		first := models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices}
+		if started, err := s.Start(r, &first, block); err != nil || !started {
+			t.Fatalf("first job: started %v, %v", started, err)
+		}
+		again := models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices}
+		if started, err := s.Start(r, &again, block); err != nil || started || again.ID != first.ID {
+			t.Errorf("second job of the portfolio: started %v, ID %d, %v; want the running job %d", started, again.ID, err, first.ID)
+		}
+		other := models.Job{PortfolioID: 2, Kind: models.JobRefreshPrices}
+		if started, err := s.Start(r, &other, block); err != nil || !started || other.ID == first.ID {
+			t.Errorf("job of another portfolio: started %v, ID %d, %v; want a new job", started, other.ID, err)
+		}
+
+		close(release)
+		for _, id := range []uint{first.ID, other.ID} {
+			if job := waitForJob(t, db, id); job.Status != models.JobCompleted {
+				t.Errorf("job %d: status %s, want %s", id, job.Status, models.JobCompleted)
+			}
+		}
+	})
+}
+
+func TestJobPanicFails(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+			t.Fatal(err)
+		}
+		s := NewJobService(db, zap.NewNop().Sugar())
+		r := httptest.NewRequest("POST", "/prices/refresh", nil)
+
+		job := models.Job{PortfolioID: 1, Kind: models.JobRefreshPrices}
+		_, err := s.Start(r, &job, func(r *http.Request, job *models.Job) error {
+			panic("boom")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		job = waitForJob(t, db, job.ID)
+		if job.Status != models.JobFailed || job.Error == "" || job.FinishedAt == nil {
+			t.Errorf("job = %+v, want failed with the panic as its error", job)
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// TickerMetadata describes a ticker as the provider knows it. Empty fields are
+// unknown. The provider has no ISIN or logo; those are only set by hand.
+type TickerMetadata struct {
+	Name      string `json:"name"`
+	Exchange  string `json:"exchange"`
+	Country   string `json:"country"`
+	Sector    string `json:"sector"`
+	Industry  string `json:"industry"`
+	AssetType string `json:"asset_type"`
+}
+
+// ApplyMetadata copies the known fields of m into t. Without overwrite only
+// empty fields are filled, so values edited by hand are kept. It reports
+// whether anything changed.
+func ApplyMetadata(t *models.Ticker, m TickerMetadata, overwrite bool) bool {
+	changed := false
+	set := func(field *string, value string) {
+		if value != "" && *field != value && (overwrite || *field == "") {
+			*field = value
+			changed = true
+		}
+	}
+	set(&t.Name, m.Name)
+	set(&t.Exchange, m.Exchange)
+	set(&t.Country, m.Country)
+	set(&t.Sector, m.Sector)
+	set(&t.Industry, m.Industry)
+	set(&t.AssetType, m.AssetType)
+	return changed
+}
+
+// ListingMetadata is what a symbol search listing tells about a ticker. The
+// listing may be nil (unlisted symbols), leaving what the symbol itself tells.
+func ListingMetadata(symbol string, listing *models.SymbolMatch) TickerMetadata {
+	if listing == nil {
+		return TickerMetadata{AssetType: assetType(symbol, "", "")}
+	}
+	return TickerMetadata{
+		Name:      listing.Name,
+		Exchange:  listing.Exchange,
+		Country:   countryName(listing.Region),
+		AssetType: assetType(symbol, listing.Exchange, listing.AssetType),
+	}
+}
+
+// assetType maps the provider's type of a listing to ours, or "" when it can't
+// tell. B3 lists FIIs, ETFs and units alike under the 11 suffix, so those are
+// left to be set by hand.
+func assetType(symbol, exchange, providerType string) string {
+	if (models.Ticker{Symbol: symbol}).AssetClass() == models.AssetCrypto {
+		return models.AssetTypeCrypto
+	}
+	t := strings.ToLower(providerType)
+	switch {
+	case strings.Contains(t, "crypto"):
+		return models.AssetTypeCrypto
+	case strings.Contains(t, "etf"):
+		return models.AssetTypeETF
+	case strings.Contains(t, "bond"):
+		return models.AssetTypeBond
+	case t == "equity" || t == "common stock":
+		if exchange == "B3" && isBDR(symbol) {
+			return models.AssetTypeBDR
+		}
+		return models.AssetTypeStock
+	}
+	return ""
+}
+
+// isBDR tells BDRs by their B3 suffix (e.g. AAPL34)
+func isBDR(symbol string) bool {
+	for _, suffix := range []string{"31", "32", "33", "34", "35", "39"} {
+		if strings.HasSuffix(symbol, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// countryNames normalizes the provider's countries and regions, which differ between endpoints
+var countryNames = map[string]string{
+	"USA": "United States",
+	"US":  "United States",
+	"UK":  "United Kingdom",
+}
+
+// countryName normalizes a country or region (e.g. Brazil/Sao Paolo) to a country name
+func countryName(region string) string {
+	region = strings.TrimSpace(region)
+	if name, ok := countryNames[region]; ok {
+		return name
+	}
+	country, _, _ := strings.Cut(region, "/")
+	return strings.TrimSpace(country)
+}
+
+// titleCase turns the provider's upper-case sectors (e.g. LIFE SCIENCES) into Life Sciences
+func titleCase(s string) string {
+	if s != strings.ToUpper(s) {
+		return s
+	}
+	words := strings.Fields(strings.ToLower(s))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+type overviewResponse struct {
+	Symbol    string `json:"Symbol"`
+	AssetType string `json:"AssetType"`
+	Name      string `json:"Name"`
+	Exchange  string `json:"Exchange"`
+	Country   string `json:"Country"`
+	Sector    string `json:"Sector"`
+	Industry  string `json:"Industry"`
+
+	Information string `json:"Information"`
+	Note        string `json:"Note"`
+}
+
+// Overview fetches the company overview of a symbol. The provider only covers
+// some markets (mostly US stocks); it returns ErrNotFound for the others.
+func (s *FinanceService) Overview(ctx context.Context, symbol, currency string) (TickerMetadata, error) {
+	if s.alphaApiKey == "" {
+		return TickerMetadata{}, fmt.Errorf("API key is missing")
+	}
+
+	alphaSymbol := buildAlphaSymbol(symbol, currency)
+	url := fmt.Sprintf(s.alphaApiBaseUrl+"/query?function=OVERVIEW&symbol=%s&apikey=%s", alphaSymbol, s.alphaApiKey)
+	s.Logger.Infof("Fetching overview for %s (query: %s)", symbol, alphaSymbol)
+
+	var data overviewResponse
+	if err := s.alpha.GetJSON(ctx, url, &data); err != nil {
+		return TickerMetadata{}, err
+	}
+	if strings.Contains(data.Information, "rate limit") || strings.Contains(data.Note, "call frequency") {
+		return TickerMetadata{}, ErrRateLimited
+	}
+	if data.Symbol == "" {
+		return TickerMetadata{}, ErrNotFound
+	}
+
+	exchange := overviewValue(data.Exchange)
+	if exchange == "BOVESPA" {
+		exchange = "B3"
+	}
+	return TickerMetadata{
+		Name:      overviewValue(data.Name),
+		Exchange:  exchange,
+		Country:   countryName(overviewValue(data.Country)),
+		Sector:    titleCase(overviewValue(data.Sector)),
+		Industry:  titleCase(overviewValue(data.Industry)),
+		AssetType: assetType(symbol, exchange, data.AssetType),
+	}, nil
+}
+
+// overviewValue drops the placeholders the overview uses for missing fields
+func overviewValue(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "None" || s == "-" {
+		return ""
+	}
+	return s
+}
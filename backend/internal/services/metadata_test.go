@@ -0,0 +1,53 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestAssetType(t *testing.T) {
+	tests := []struct {
+		symbol, exchange, providerType string
+		want                           string
+	}{
+		{"BTC/USD", "", "", models.AssetTypeCrypto},
+		{"BTC", "", "Digital Currency / Crypto", models.AssetTypeCrypto},
+		{"AAPL", "NASDAQ", "Common Stock", models.AssetTypeStock},
+		{"AAPL", "NASDAQ", "Equity", models.AssetTypeStock},
+		{"PETR4", "B3", "Equity", models.AssetTypeStock},
+		{"AAPL34", "B3", "Equity", models.AssetTypeBDR},
+		{"ABCD34", "NYSE", "Equity", models.AssetTypeStock}, // the BDR suffix only means something on B3
+		{"SPY", "NYSE ARCA", "ETF", models.AssetTypeETF},
+		{"BOVA11", "B3", "etf", models.AssetTypeETF},
+		{"TLT", "NASDAQ", "Bond", models.AssetTypeBond},
+		{"HGLG11", "B3", "Mutual Fund", ""},
+		{"XYZ", "", "", ""},
+	}
+	for _, tt := range tests {
+		if got := assetType(tt.symbol, tt.exchange, tt.providerType); got != tt.want {
+			t.Errorf("assetType(%q, %q, %q) = %q, want %q", tt.symbol, tt.exchange, tt.providerType, got, tt.want)
+		}
+	}
+}
+
+func TestCountryName(t *testing.T) {
+	tests := []struct {
+		region, want string
+	}{
+		{"USA", "United States"},
+		{"US", "United States"},
+		{"UK", "United Kingdom"},
+		{" USA ", "United States"},
+		{"United States", "United States"},
+		{"Brazil/Sao Paolo", "Brazil"},
+		{"Brazil / Sao Paolo", "Brazil"},
+		{"Canada", "Canada"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := countryName(tt.region); got != tt.want {
+			t.Errorf("countryName(%q) = %q, want %q", tt.region, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// averageCostPlaces is the precision of average costs, which are unit prices and
+// therefore not rounded to the currency's minor unit
+const averageCostPlaces = 8
+
+// Position is the current holding of a single symbol, built from its transactions
+// using the average cost method. Amounts are rounded to the currency's minor unit.
+type Position struct {
+	Symbol       string          `json:"symbol"`
+	Currency     string          `json:"currency"`
+	Category     string          `json:"category"`
+	Name         string          `json:"name"`
+	Exchange     string          `json:"exchange"`
+	Country      string          `json:"country"`
+	Sector       string          `json:"sector"`
+	Industry     string          `json:"industry"`
+	AssetType    string          `json:"asset_type"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	AverageCost  decimal.Decimal `json:"average_cost"`
+	CostBasis    decimal.Decimal `json:"cost_basis"`
+	CurrentPrice decimal.Decimal `json:"current_price"`
+	MarketValue  decimal.Decimal `json:"market_value"`
+	PnL          decimal.Decimal `json:"pnl"`
+	PnLPercent   float64         `json:"pnl_percent"`
+
+	// Set when the position is valued in a report currency (see ValuePositions)
+	Report *PositionReport `json:"report,omitempty"`
+}
+
+// PositionReport is a position valued in the report currency. The cost is
+// converted at the rate of each purchase date and the market value at the
+// current rate, so PnL splits into what the asset and the currency did.
+type PositionReport struct {
+	Rate           decimal.Decimal `json:"rate"` // current
+	CostBasis      models.Money    `json:"cost_basis"`
+	MarketValue    models.Money    `json:"market_value"`
+	PnL            models.Money    `json:"pnl"`
+	AssetReturn    models.Money    `json:"asset_return"`    // the price change, at the current rate
+	CurrencyReturn models.Money    `json:"currency_return"` // the rate change since the purchases
+	EstimatedCost  bool            `json:"estimated_cost"`  // some purchases had no rate for their date and used the current one
+}
+
+// Summary totals the positions of a portfolio in one currency
+type Summary struct {
+	Currency       string       `json:"currency"`
+	CostBasis      models.Money `json:"cost_basis"`
+	MarketValue    models.Money `json:"market_value"`
+	PnL            models.Money `json:"pnl"`
+	PnLPercent     float64      `json:"pnl_percent"`
+	AssetReturn    models.Money `json:"asset_return"`
+	CurrencyReturn models.Money `json:"currency_return"`
+	// Currencies with no rate to the report currency; their positions are left out of the totals
+	MissingRates []string   `json:"missing_rates"`
+	Positions    []Position `json:"positions"`
+}
+
+// RealizedGain is the result of a single SELL transaction.
+type RealizedGain struct {
+	TransactionID string          `json:"transaction_id"`
+	Date          string          `json:"date"`
+	Symbol        string          `json:"symbol"`
+	Currency      string          `json:"currency"`
+	Quantity      decimal.Decimal `json:"quantity"`
+	Proceeds      decimal.Decimal `json:"proceeds"`
+	CostBasis     decimal.Decimal `json:"cost_basis"`
+	Fee           decimal.Decimal `json:"fee"`
+	Gain          decimal.Decimal `json:"gain"`
+
+	// Set when the report is built in a report currency
+	Report *GainReport `json:"report,omitempty"`
+}
+
+// GainReport is a sale in the report currency: proceeds and fee at the rate of
+// the sale date, cost at the rates of the purchase dates
+type GainReport struct {
+	Rate      decimal.Decimal `json:"rate"` // on the sale date
+	Proceeds  models.Money    `json:"proceeds"`
+	CostBasis models.Money    `json:"cost_basis"`
+	Fee       models.Money    `json:"fee"`
+	Gain      models.Money    `json:"gain"`
+	Estimated bool            `json:"estimated"` // some rates were missing for their date and the current one was used
+}
+
+// MonthlyTaxSummary groups realized gains by month and currency, which is how
+// sales are declared.
+type MonthlyTaxSummary struct {
+	Month     string          `json:"month"` // YYYY-MM
+	Currency  string          `json:"currency"`
+	Sales     decimal.Decimal `json:"sales"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	Fees      decimal.Decimal `json:"fees"`
+	Gain      decimal.Decimal `json:"gain"`
+}
+
+// TaxReport is the realized gains report for a given year. In a report
+// currency, the months total the converted sales in that currency only.
+type TaxReport struct {
+	Year         int                 `json:"year"`
+	Currency     string              `json:"currency,omitempty"`
+	MissingRates []string            `json:"missing_rates,omitempty"` // sales left out of the months
+	Months       []MonthlyTaxSummary `json:"months"`
+	Details      []RealizedGain      `json:"details"`
+}
+
+// Valuation converts amounts to a report currency: at the rate of their date
+// when History has one, at the current rate otherwise
+type Valuation struct {
+	Currency string
+	Rates    *RateTable // current rates
+	History  *History   // optional daily rates
+}
+
+// rateOn returns the rate on date and whether it is a historical one
+func (v *Valuation) rateOn(from string, date time.Time) (decimal.Decimal, bool, error) {
+	if v.History != nil {
+		if q, err := v.History.Rate(from, v.Currency, date); err == nil {
+			return q.Rate, true, nil
+		}
+	}
+	q, err := v.Rates.Rate(from, v.Currency)
+	return q.Rate, false, err
+}
+
+type holding struct {
+	quantity decimal.Decimal
+	cost     decimal.Decimal
+	currency string
+
+	// Only kept when replaying with a Valuation
+	reportCost decimal.Decimal // at the rate of each purchase date
+	estimated  bool            // a purchase used the current rate
+	noRate     bool            // a purchase could not be converted at all
+}
+
+// sortedByDate returns a copy of the transactions in chronological order.
+func sortedByDate(transactions []models.Transaction) []models.Transaction {
+	sorted := make([]models.Transaction, len(transactions))
+	copy(sorted, transactions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	return sorted
+}
+
+// replay walks the transactions in order, keeping the average cost of each holding,
+// and calls onSell for every sale with the part of the holding it removed.
+// With a Valuation, the cost is also kept in the report currency.
+// Costs are carried unrounded; only the reported amounts are rounded.
+func replay(transactions []models.Transaction, v *Valuation, onSell func(t models.Transaction, removed holding)) map[string]*holding {
+	holdings := make(map[string]*holding)
+	for _, t := range sortedByDate(transactions) {
+		h, ok := holdings[t.Symbol]
+		if !ok {
+			h = &holding{currency: t.Currency}
+			holdings[t.Symbol] = h
+		}
+
+		switch t.Type {
+		case models.Sell:
+			removed := holding{currency: h.currency, estimated: h.estimated, noRate: h.noRate}
+			if h.quantity.IsPositive() {
+				removed.quantity = decimal.Min(t.Quantity, h.quantity)
+				if removed.quantity.Equal(h.quantity) {
+					removed.cost, removed.reportCost = h.cost, h.reportCost
+				} else {
+					removed.cost = h.cost.Mul(removed.quantity).Div(h.quantity)
+					removed.reportCost = h.reportCost.Mul(removed.quantity).Div(h.quantity)
+				}
+				h.cost = h.cost.Sub(removed.cost)
+				h.reportCost = h.reportCost.Sub(removed.reportCost)
+				h.quantity = h.quantity.Sub(removed.quantity)
+				if h.quantity.IsZero() {
+					*h = holding{currency: h.currency}
+				}
+			}
+			if onSell != nil {
+				onSell(t, removed)
+			}
+		default:
+			cost := t.Price.Mul(t.Quantity).Add(t.Fee)
+			h.quantity = h.quantity.Add(t.Quantity)
+			h.cost = h.cost.Add(cost)
+			if v != nil {
+				rate, historical, err := v.rateOn(t.Currency, t.Date)
+				if err != nil {
+					h.noRate = true
+				} else {
+					h.reportCost = h.reportCost.Add(cost.Mul(rate))
+					h.estimated = h.estimated || !historical
+				}
+			}
+		}
+	}
+	return holdings
+}
+
+// BuildPositions computes the open positions from the transactions, valued at the
+// cached ticker prices. Symbols that were fully sold are left out.
+func BuildPositions(transactions []models.Transaction, tickers []models.Ticker) []Position {
+	positions, _ := buildPositions(transactions, tickers, nil)
+	return positions
+}
+
+// ValuePositions computes the open positions like BuildPositions and values
+// them in the report currency too. It returns the currencies it had no rate
+// for; those positions have no Report. Positions without a price are valued at cost.
+func ValuePositions(transactions []models.Transaction, tickers []models.Ticker, v Valuation) ([]Position, []string) {
+	return buildPositions(transactions, tickers, &v)
+}
+
+func buildPositions(transactions []models.Transaction, tickers []models.Ticker, v *Valuation) ([]Position, []string) {
+	tickerMap := make(map[string]models.Ticker)
+	for _, t := range tickers {
+		tickerMap[t.Symbol] = t
+	}
+
+	holdings := replay(transactions, v, nil)
+
+	positions := make([]Position, 0, len(holdings))
+	missing := []string{}
+	for symbol, h := range holdings {
+		if !h.quantity.IsPositive() {
+			continue
+		}
+
+		p := Position{
+			Symbol:      symbol,
+			Currency:    h.currency,
+			Quantity:    h.quantity,
+			AverageCost: h.cost.DivRound(h.quantity, averageCostPlaces),
+		}
+
+		if ticker, ok := tickerMap[symbol]; ok {
+			p.Category = ticker.Category
+			p.Name = ticker.Name
+			p.Exchange = ticker.Exchange
+			p.Country = ticker.Country
+			p.Sector = ticker.Sector
+			p.Industry = ticker.Industry
+			p.AssetType = ticker.AssetType
+			if ticker.Currency != "" {
+				p.Currency = ticker.Currency
+			}
+			p.CurrentPrice = ticker.Price
+		}
+
+		p.CostBasis = models.RoundMoney(h.cost, p.Currency)
+
+		// Only value the position if we have a valid price
+		if p.CurrentPrice.IsPositive() {
+			p.MarketValue = models.RoundMoney(p.CurrentPrice.Mul(p.Quantity), p.Currency)
+			p.PnL = p.MarketValue.Sub(p.CostBasis)
+			p.PnLPercent = PercentOf(p.PnL, p.CostBasis)
+		}
+
+		if v != nil {
+			if q, err := v.Rates.Rate(p.Currency, v.Currency); err != nil || h.noRate {
+				if !slices.Contains(missing, p.Currency) {
+					missing = append(missing, p.Currency)
+				}
+			} else {
+				p.Report = positionReport(p, h, q.Rate, v.Currency)
+			}
+		}
+
+		positions = append(positions, p)
+	}
+
+	sort.Slice(positions, func(i, j int) bool { return positions[i].Symbol < positions[j].Symbol })
+	sort.Strings(missing)
+	return positions, missing
+}
+
+func positionReport(p Position, h *holding, rate decimal.Decimal, currency string) *PositionReport {
+	value := p.CurrentPrice.Mul(p.Quantity)
+	if !p.CurrentPrice.IsPositive() {
+		value = h.cost
+	}
+
+	report := &PositionReport{
+		Rate:          rate,
+		CostBasis:     models.NewMoney(h.reportCost, currency),
+		MarketValue:   models.NewMoney(value.Mul(rate), currency),
+		AssetReturn:   models.NewMoney(value.Sub(h.cost).Mul(rate), currency),
+		EstimatedCost: h.estimated,
+	}
+	report.PnL = report.MarketValue.Sub(report.CostBasis)
+	report.CurrencyReturn = report.PnL.Sub(report.AssetReturn)
+	return report
+}
+
+// Summarize totals positions valued by ValuePositions
+func Summarize(positions []Position, missing []string, currency string) Summary {
+	zero := models.NewMoney(decimal.Zero, currency)
+	summary := Summary{
+		Currency: currency, CostBasis: zero, MarketValue: zero, AssetReturn: zero,
+		MissingRates: missing, Positions: positions,
+	}
+
+	for _, p := range positions {
+		if p.Report == nil {
+			continue
+		}
+		summary.CostBasis = summary.CostBasis.Add(p.Report.CostBasis)
+		summary.MarketValue = summary.MarketValue.Add(p.Report.MarketValue)
+		summary.AssetReturn = summary.AssetReturn.Add(p.Report.AssetReturn)
+	}
+	summary.PnL = summary.MarketValue.Sub(summary.CostBasis)
+	summary.CurrencyReturn = summary.PnL.Sub(summary.AssetReturn)
+	summary.PnLPercent = PercentOf(summary.PnL.Amount, summary.CostBasis.Amount)
+	return summary
+}
+
+// BuildTaxReport computes the realized gains of every sale made in the given year.
+// All transactions are needed (not only the ones of that year) to know the average cost.
+// With a Valuation, every sale is converted and the months are in its currency.
+func BuildTaxReport(transactions []models.Transaction, year int, v *Valuation) TaxReport {
+	report := TaxReport{Year: year, Months: []MonthlyTaxSummary{}}
+	var missing []string
+	report.Details, missing = realizedGains(transactions, v, func(t models.Transaction) bool { return t.Date.Year() == year })
+	if v != nil {
+		report.Currency = v.Currency
+		report.MissingRates = missing
+	}
+
+	for _, p := range groupGains(report.Details, v, func(date string) string { return date[:7] }) {
+		report.Months = append(report.Months, MonthlyTaxSummary{
+			Month: p.Period, Currency: p.Currency, Sales: p.Sales, CostBasis: p.CostBasis, Fees: p.Fees, Gain: p.Gain,
+		})
+	}
+	return report
+}
+
+// Income periods
+const (
+	PeriodMonth   = "month"
+	PeriodQuarter = "quarter"
+	PeriodYear    = "year"
+)
+
+// IncomePeriod totals the realized gains of the sales of a period in one currency
+type IncomePeriod struct {
+	Period    string          `json:"period"` // YYYY-MM, YYYY-Qn or YYYY
+	Currency  string          `json:"currency"`
+	Count     int             `json:"count"` // sales
+	Sales     decimal.Decimal `json:"sales"`
+	CostBasis decimal.Decimal `json:"cost_basis"`
+	Fees      decimal.Decimal `json:"fees"`
+	Gain      decimal.Decimal `json:"gain"`
+}
+
+// IncomeReport is the realized gains of every period with sales. In a report
+// currency, the periods total the converted sales in that currency only.
+type IncomeReport struct {
+	Period       string         `json:"period"` // month, quarter or year
+	Year         int            `json:"year,omitempty"`
+	Currency     string         `json:"currency,omitempty"`
+	MissingRates []string       `json:"missing_rates,omitempty"` // sales left out of the periods
+	Periods      []IncomePeriod `json:"periods"`
+}
+
+// BuildIncomeReport totals the realized gains by period: month, quarter or year.
+// With a year, only its sales are counted; with 0, every sale is.
+func BuildIncomeReport(transactions []models.Transaction, period string, year int, v *Valuation) IncomeReport {
+	report := IncomeReport{Period: period, Year: year, Periods: []IncomePeriod{}}
+	gains, missing := realizedGains(transactions, v, func(t models.Transaction) bool { return year == 0 || t.Date.Year() == year })
+	if v != nil {
+		report.Currency = v.Currency
+		report.MissingRates = missing
+	}
+
+	key := func(date string) string { return date[:7] }
+	switch period {
+	case PeriodYear:
+		key = func(date string) string { return date[:4] }
+	case PeriodQuarter:
+		key = func(date string) string {
+			month, _ := strconv.Atoi(date[5:7])
+			return fmt.Sprintf("%s-Q%d", date[:4], (month+2)/3)
+		}
+	}
+	report.Periods = append(report.Periods, groupGains(gains, v, key)...)
+	return report
+}
+
+// realizedGains replays the transactions and returns the gain of every sale
+// kept by keep, in date order, converted when v is set. It also returns the
+// currencies of the sales it had no rate for; those have no Report.
+func realizedGains(transactions []models.Transaction, v *Valuation, keep func(models.Transaction) bool) ([]RealizedGain, []string) {
+	gains := []RealizedGain{}
+	missing := []string{}
+
+	replay(transactions, v, func(t models.Transaction, removed holding) {
+		if !keep(t) {
+			return
+		}
+
+		gain := RealizedGain{
+			TransactionID: t.ID,
+			Date:          t.Date.Format("2006-01-02"),
+			Symbol:        t.Symbol,
+			Currency:      t.Currency,
+			Quantity:      t.Quantity,
+			Proceeds:      models.RoundMoney(t.Price.Mul(t.Quantity), t.Currency),
+			CostBasis:     models.RoundMoney(removed.cost, t.Currency),
+			Fee:           models.RoundMoney(t.Fee, t.Currency),
+		}
+		gain.Gain = gain.Proceeds.Sub(gain.CostBasis).Sub(gain.Fee)
+
+		if v != nil {
+			rate, historical, err := v.rateOn(t.Currency, t.Date)
+			if err != nil || removed.noRate {
+				if !slices.Contains(missing, t.Currency) {
+					missing = append(missing, t.Currency)
+				}
+				gains = append(gains, gain)
+				return
+			}
+			gain.Report = &GainReport{
+				Rate:      rate,
+				Proceeds:  models.NewMoney(t.Price.Mul(t.Quantity).Mul(rate), v.Currency),
+				CostBasis: models.NewMoney(removed.reportCost, v.Currency),
+				Fee:       models.NewMoney(t.Fee.Mul(rate), v.Currency),
+				Estimated: removed.estimated || !historical,
+			}
+			gain.Report.Gain = gain.Report.Proceeds.Sub(gain.Report.CostBasis).Sub(gain.Report.Fee)
+		}
+		gains = append(gains, gain)
+	})
+
+	sort.Strings(missing)
+	return gains, missing
+}
+
+// groupGains totals gains by the period key of their date and by currency,
+// sorted by period. With a Valuation, the converted amounts are totalled and
+// the gains without a rate are left out.
+func groupGains(gains []RealizedGain, v *Valuation, period func(date string) string) []IncomePeriod {
+	groups := make(map[string]*IncomePeriod)
+	for _, g := range gains {
+		currency, sales, cost, fee, amount := g.Currency, g.Proceeds, g.CostBasis, g.Fee, g.Gain
+		if v != nil {
+			if g.Report == nil {
+				continue
+			}
+			currency = v.Currency
+			sales, cost, fee, amount = g.Report.Proceeds.Amount, g.Report.CostBasis.Amount, g.Report.Fee.Amount, g.Report.Gain.Amount
+		}
+
+		key := period(g.Date) + "|" + currency
+		p, ok := groups[key]
+		if !ok {
+			p = &IncomePeriod{Period: period(g.Date), Currency: currency}
+			groups[key] = p
+		}
+		p.Count++
+		p.Sales = p.Sales.Add(sales)
+		p.CostBasis = p.CostBasis.Add(cost)
+		p.Fees = p.Fees.Add(fee)
+		p.Gain = p.Gain.Add(amount)
+	}
+
+	periods := make([]IncomePeriod, 0, len(groups))
+	for _, p := range groups {
+		periods = append(periods, *p)
+	}
+	sort.Slice(periods, func(i, j int) bool {
+		if periods[i].Period == periods[j].Period {
+			return periods[i].Currency < periods[j].Currency
+		}
+		return periods[i].Period < periods[j].Period
+	})
+	return periods
+}
+
+// PercentOf returns part as a percentage of whole, rounded to two decimals, or 0
+// when whole is not positive
+func PercentOf(part, whole decimal.Decimal) float64 {
+	if !whole.IsPositive() {
+		return 0
+	}
+	return part.Mul(decimal.NewFromInt(100)).DivRound(whole, 2).InexactFloat64()
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func trade(symbol string, typ models.TransactionType, quantity, price, fee, currency, day string) models.Transaction {
+	return models.Transaction{
+		ID: symbol + " " + string(typ) + " " + day, Symbol: symbol, Type: typ, Currency: currency, Date: date(day),
+		Quantity: decimal.RequireFromString(quantity), Price: decimal.RequireFromString(price), Fee: decimal.RequireFromString(fee),
+	}
+}
+
+// describe formats a position as "SYMBOL CUR quantity @average cost ... value ... pnl ..."
+func describe(p Position) string {
+	return fmt.Sprintf("%s %s %s @%s cost %s value %s pnl %s",
+		p.Symbol, p.Currency, p.Quantity, p.AverageCost, p.CostBasis, p.MarketValue, p.PnL)
+}
+
+func TestBuildPositions(t *testing.T) {
+	tickers := []models.Ticker{
+		{Symbol: "AAPL", Currency: "USD", Price: decimal.RequireFromString("150")},
+		{Symbol: "PETR4", Currency: "BRL", Price: decimal.RequireFromString("30")},
+	}
+	buy := trade("AAPL", models.Buy, "10", "100", "1", "USD", "2024-01-10")
+	partial := trade("AAPL", models.Sell, "4", "150", "2", "USD", "2024-02-15")
+	exit := trade("AAPL", models.Sell, "6", "90", "0", "USD", "2024-03-01")
+
+	tests := []struct {
+		name         string
+		transactions []models.Transaction
+		want         []string
+	}{
+		{"the buy fee is part of the cost", []models.Transaction{buy},
+			[]string{"AAPL USD 10 @100.1 cost 1001 value 1500 pnl 499"}},
+		{"a partial sell keeps the average", []models.Transaction{buy, partial},
+			[]string{"AAPL USD 6 @100.1 cost 600.6 value 900 pnl 299.4"}},
+		{"a full exit is left out", []models.Transaction{buy, partial, exit}, nil},
+		{"a buy after a full exit starts a new average", []models.Transaction{
+			buy, partial, exit, trade("AAPL", models.Buy, "5", "80", "0", "USD", "2024-04-01"),
+		}, []string{"AAPL USD 5 @80 cost 400 value 750 pnl 350"}},
+		{"selling more than held closes the position", []models.Transaction{
+			trade("AAPL", models.Buy, "2", "100", "0", "USD", "2024-01-10"),
+			trade("AAPL", models.Sell, "5", "110", "0", "USD", "2024-01-11"),
+			trade("AAPL", models.Buy, "1", "10", "0", "USD", "2024-01-12"),
+		}, []string{"AAPL USD 1 @10 cost 10 value 150 pnl 140"}},
+		{"a sell with nothing held", []models.Transaction{
+			trade("AAPL", models.Sell, "3", "100", "0", "USD", "2024-01-10"),
+		}, nil},
+		{"replayed by date, not by input order", []models.Transaction{partial, buy},
+			[]string{"AAPL USD 6 @100.1 cost 600.6 value 900 pnl 299.4"}},
+		{"several buys are averaged", []models.Transaction{
+			buy,
+			trade("AAPL", models.Buy, "10", "110", "3", "USD", "2024-01-11"),
+			trade("AAPL", models.Sell, "5", "120", "0", "USD", "2024-01-12"),
+		}, []string{"AAPL USD 15 @105.2 cost 1578 value 2250 pnl 672"}},
+		{"the cost is carried unrounded", []models.Transaction{
+			trade("AAPL", models.Buy, "3", "10", "0.01", "USD", "2024-01-10"),
+			trade("AAPL", models.Sell, "1", "12", "0", "USD", "2024-01-11"),
+		}, []string{"AAPL USD 2 @10.00333333 cost 20.01 value 300 pnl 279.99"}},
+		{"mixed currencies, and positions without a price", []models.Transaction{
+			buy,
+			trade("PETR4", models.Buy, "100", "25", "5", "BRL", "2024-01-10"),
+			trade("MSFT", models.Buy, "1", "300", "0", "USD", "2024-01-10"),
+			trade("7203.T", models.Buy, "3", "1000.5", "0", "JPY", "2024-01-10"),
+		}, []string{
+			"7203.T JPY 3 @1000.5 cost 3002 value 0 pnl 0",
+			"AAPL USD 10 @100.1 cost 1001 value 1500 pnl 499",
+			"MSFT USD 1 @300 cost 300 value 0 pnl 0",
+			"PETR4 BRL 100 @25.05 cost 2505 value 3000 pnl 495",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range BuildPositions(tt.transactions, tickers) {
+				got = append(got, describe(p))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTaxReport(t *testing.T) {
+	transactions := []models.Transaction{
+		trade("AAPL", models.Buy, "10", "100", "1", "USD", "2023-12-01"),
+		trade("AAPL", models.Sell, "2", "120", "0", "USD", "2023-12-20"),
+		trade("AAPL", models.Sell, "4", "150", "2", "USD", "2024-01-15"),
+		trade("AAPL", models.Sell, "4", "90", "0", "USD", "2024-01-20"),
+		trade("AAPL", models.Buy, "5", "80", "0", "USD", "2024-02-01"),
+		trade("AAPL", models.Sell, "5", "100", "1", "USD", "2024-02-10"),
+		trade("PETR4", models.Buy, "20", "25", "4", "BRL", "2024-02-01"),
+		trade("PETR4", models.Sell, "10", "30", "0", "BRL", "2024-02-12"),
+	}
+
+	tests := []struct {
+		name    string
+		year    int
+		details []string // date symbol quantity proceeds cost fee gain
+		months  []string // month currency sales cost fees gain
+	}{
+		{"earlier years only set the cost", 2023,
+			[]string{"2023-12-20 AAPL 2 240 200.2 0 39.8"},
+			[]string{"2023-12 USD 240 200.2 0 39.8"}},
+		{"partial sells, a full exit and a new buy", 2024,
+			[]string{
+				"2024-01-15 AAPL 4 600 400.4 2 197.6",
+				"2024-01-20 AAPL 4 360 400.4 0 -40.4",
+				"2024-02-10 AAPL 5 500 400 1 99",
+				"2024-02-12 PETR4 10 300 252 0 48",
+			},
+			[]string{
+				"2024-01 USD 960 800.8 2 157.2",
+				"2024-02 BRL 300 252 0 48",
+				"2024-02 USD 500 400 1 99",
+			}},
+		{"a year without sales", 2025, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := BuildTaxReport(transactions, tt.year, nil)
+			var details, months []string
+			for _, g := range report.Details {
+				details = append(details, fmt.Sprintf("%s %s %s %s %s %s %s", g.Date, g.Symbol, g.Quantity, g.Proceeds, g.CostBasis, g.Fee, g.Gain))
+				if g.Report != nil {
+					t.Errorf("%s %s has a report without a valuation", g.Date, g.Symbol)
+				}
+			}
+			for _, m := range report.Months {
+				months = append(months, fmt.Sprintf("%s %s %s %s %s %s", m.Month, m.Currency, m.Sales, m.CostBasis, m.Fees, m.Gain))
+			}
+			if !slices.Equal(details, tt.details) {
+				t.Errorf("details = %q, want %q", details, tt.details)
+			}
+			if !slices.Equal(months, tt.months) {
+				t.Errorf("months = %q, want %q", months, tt.months)
+			}
+			if report.Currency != "" || report.MissingRates != nil {
+				t.Errorf("currency %q, missing %q, want neither without a valuation", report.Currency, report.MissingRates)
+			}
+		})
+	}
+}
+
+// valuation reports in BRL at USD/BRL 5 now, 4.9 on 2024-01-10 and 5.2 on 2024-02-15.
+// There is no EUR rate at all.
+func valuation() Valuation {
+	rate := func(day, r string) models.HistoricalRate {
+		return models.HistoricalRate{Base: "USD", Quote: "BRL", Date: date(day), Rate: decimal.RequireFromString(r)}
+	}
+	return Valuation{
+		Currency: "BRL",
+		Rates:    NewRateTable([]models.ExchangeRate{{Base: "USD", Quote: "BRL", Rate: decimal.RequireFromString("5")}}),
+		History:  NewHistory([]models.HistoricalRate{rate("2024-01-10", "4.9"), rate("2024-02-15", "5.2")}),
+	}
+}
+
+func TestValuePositions(t *testing.T) {
+	tickers := []models.Ticker{
+		{Symbol: "AAPL", Currency: "USD", Price: decimal.RequireFromString("150")},
+		{Symbol: "PETR4", Currency: "BRL", Price: decimal.RequireFromString("30")},
+	}
+	transactions := []models.Transaction{
+		trade("AAPL", models.Buy, "10", "100", "1", "USD", "2024-01-10"),
+		trade("AAPL", models.Sell, "4", "150", "2", "USD", "2024-02-15"),
+		trade("MSFT", models.Buy, "1", "300", "0", "USD", "2023-01-02"),
+		trade("PETR4", models.Buy, "100", "25", "5", "BRL", "2024-01-10"),
+		trade("SAP", models.Buy, "2", "100", "0", "EUR", "2024-01-10"),
+	}
+	positions, missing := ValuePositions(transactions, tickers, valuation())
+
+	tests := []struct {
+		symbol string
+		report string // rate cost value pnl asset currency estimated
+	}{
+		// Bought at 4.9, valued at 5
+		{"AAPL", "5 2942.94 4500 1557.06 1497 60.06 false"},
+		// No rate on the purchase date, so the current one; no price, so valued at cost
+		{"MSFT", "5 1500 1500 0 0 0 true"},
+		{"PETR4", "1 2505 3000 495 495 0 false"},
+		{"SAP", ""},
+	}
+	if len(positions) != len(tests) {
+		t.Fatalf("got %d positions, want %d", len(positions), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			p := positions[i]
+			var got string
+			if r := p.Report; r != nil {
+				got = fmt.Sprintf("%s %s %s %s %s %s %t", r.Rate, r.CostBasis.Amount, r.MarketValue.Amount, r.PnL.Amount,
+					r.AssetReturn.Amount, r.CurrencyReturn.Amount, r.EstimatedCost)
+			}
+			if p.Symbol != tt.symbol || got != tt.report {
+				t.Errorf("%s report = %q, want %s %q", p.Symbol, got, tt.symbol, tt.report)
+			}
+		})
+	}
+	if !slices.Equal(missing, []string{"EUR"}) {
+		t.Errorf("missing = %q, want EUR", missing)
+	}
+
+	s := Summarize(positions, missing, "BRL")
+	got := fmt.Sprintf("%s %s %s %s %s", s.CostBasis.Amount, s.MarketValue.Amount, s.PnL.Amount, s.AssetReturn.Amount, s.CurrencyReturn.Amount)
+	if want := "6947.94 9000 2052.06 1992 60.06"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestBuildTaxReportValuation(t *testing.T) {
+	v := valuation()
+	report := BuildTaxReport([]models.Transaction{
+		trade("AAPL", models.Buy, "10", "100", "1", "USD", "2024-01-10"),
+		trade("AAPL", models.Sell, "4", "150", "2", "USD", "2024-02-15"),
+		trade("MSFT", models.Buy, "2", "300", "0", "USD", "2023-01-02"),
+		trade("MSFT", models.Sell, "1", "310", "0", "USD", "2024-02-15"),
+		trade("SAP", models.Buy, "2", "100", "0", "EUR", "2024-01-10"),
+		trade("SAP", models.Sell, "1", "120", "0", "EUR", "2024-02-15"),
+	}, 2024, &v)
+
+	tests := []struct {
+		symbol string
+		report string // rate proceeds cost fee gain estimated
+	}{
+		// Sold at 5.2, bought at 4.9
+		{"AAPL", "5.2 3120 1961.96 10.4 1147.64 false"},
+		// Bought when there was no rate for the date
+		{"MSFT", "5.2 1612 1500 0 112 true"},
+		{"SAP", ""},
+	}
+	if len(report.Details) != len(tests) {
+		t.Fatalf("got %d sales, want %d", len(report.Details), len(tests))
+	}
+	for i, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			g := report.Details[i]
+			var got string
+			if r := g.Report; r != nil {
+				got = fmt.Sprintf("%s %s %s %s %s %t", r.Rate, r.Proceeds.Amount, r.CostBasis.Amount, r.Fee.Amount, r.Gain.Amount, r.Estimated)
+			}
+			if g.Symbol != tt.symbol || got != tt.report {
+				t.Errorf("%s report = %q, want %s %q", g.Symbol, got, tt.symbol, tt.report)
+			}
+		})
+	}
+
+	// The months total the converted sales only
+	want := []MonthlyTaxSummary{{Month: "2024-02", Currency: "BRL", Sales: decimal.RequireFromString("4732"),
+		CostBasis: decimal.RequireFromString("3461.96"), Fees: decimal.RequireFromString("10.4"), Gain: decimal.RequireFromString("1259.64")}}
+	if len(report.Months) != 1 || !sameMonth(report.Months[0], want[0]) {
+		t.Errorf("months = %+v, want %+v", report.Months, want)
+	}
+	if report.Currency != "BRL" || !slices.Equal(report.MissingRates, []string{"EUR"}) {
+		t.Errorf("currency %q, missing %q, want BRL and EUR", report.Currency, report.MissingRates)
+	}
+}
+
+func sameMonth(a, b MonthlyTaxSummary) bool {
+	return a.Month == b.Month && a.Currency == b.Currency && a.Sales.Equal(b.Sales) &&
+		a.CostBasis.Equal(b.CostBasis) && a.Fees.Equal(b.Fees) && a.Gain.Equal(b.Gain)
+}
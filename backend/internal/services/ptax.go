@@ -0,0 +1,91 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// LineError is a line of an imported file that could not be read
+type LineError struct {
+	Line    int
+	Message string
+}
+
+// ParsePTAX reads the closing rates published by Banco Central do Brasil
+// (PTAX), one per line:
+//
+//	02012024;220;A;USD;4,8910;4,8916;1,0000;1,0000
+//
+// date (DDMMYYYY or DD/MM/YYYY), currency number, type, symbol, buy rate,
+// sell rate and the two parities. Rates are in BRL, with a decimal comma.
+// The sell rate is used unless useBuy is set. Blank lines and a header line
+// are skipped; other unreadable lines are returned as errors.
+func ParsePTAX(r io.Reader, useBuy bool) ([]models.HistoricalRate, []LineError, error) {
+	var rates []models.HistoricalRate
+	var errs []LineError
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\ufeff"))
+		if text == "" {
+			continue
+		}
+		fields := strings.Split(text, ";")
+		if len(fields) < 6 {
+			errs = append(errs, LineError{line, fmt.Sprintf("expected at least 6 fields, got %d", len(fields))})
+			continue
+		}
+
+		date, err := parsePTAXDate(strings.TrimSpace(fields[0]))
+		if err != nil {
+			if line == 1 {
+				continue // header
+			}
+			errs = append(errs, LineError{line, fmt.Sprintf("invalid date %q", fields[0])})
+			continue
+		}
+
+		symbol := strings.ToUpper(strings.TrimSpace(fields[3]))
+		if !models.SupportedCurrencies[symbol] {
+			errs = append(errs, LineError{line, fmt.Sprintf("unknown currency '%s'", symbol)})
+			continue
+		}
+		if symbol == "BRL" {
+			continue
+		}
+
+		value := fields[5]
+		if useBuy {
+			value = fields[4]
+		}
+		rate, err := decimal.NewFromString(strings.ReplaceAll(strings.ReplaceAll(strings.TrimSpace(value), ".", ""), ",", "."))
+		if err != nil || !rate.IsPositive() {
+			errs = append(errs, LineError{line, fmt.Sprintf("invalid rate %q", value)})
+			continue
+		}
+
+		rates = append(rates, models.HistoricalRate{
+			Base: symbol, Quote: "BRL", Date: date, Rate: rate, Source: models.RateSourcePTAX,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return rates, errs, nil
+}
+
+func parsePTAXDate(s string) (time.Time, error) {
+	if strings.Contains(s, "/") {
+		return time.Parse("02/01/2006", s)
+	}
+	if len(s) == 7 {
+		s = "0" + s // days below 10 sometimes lose their leading zero
+	}
+	return time.Parse("02012006", s)
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"os"
+	"time"
+	_ "time/tzdata" // market hours are in the exchanges' time zones
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"go.uber.org/zap"
+)
+
+// Default quote TTLs: crypto trades around the clock, equities move during
+// the session and not at all once the market closes.
+const (
+	defaultCryptoTTL = 5 * time.Minute
+	defaultEquityTTL = 15 * time.Minute
+	defaultClosedTTL = 12 * time.Hour
+)
+
+// session is the regular trading hours of the exchange an equity trades on
+type session struct {
+	location    *time.Location
+	open, close time.Duration // since midnight, local time
+}
+
+// sessions by the currency equities are quoted in; others are taken as always open
+var sessions = map[string]session{
+	"USD": {mustLoadLocation("America/New_York"), 9*time.Hour + 30*time.Minute, 16 * time.Hour},
+	"BRL": {mustLoadLocation("America/Sao_Paulo"), 10 * time.Hour, 17 * time.Hour},
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// isOpen reports whether the session is trading at t. Holidays are not known.
+func (s session) isOpen(t time.Time) bool {
+	local := t.In(s.location)
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		return false
+	}
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, s.location)
+	elapsed := local.Sub(midnight)
+	return elapsed >= s.open && elapsed < s.close
+}
+
+// QuoteCache decides when a ticker's stored price is recent enough to be used
+// instead of fetching it again. How long depends on the asset class and on
+// whether its market is open.
+type QuoteCache struct {
+	CryptoTTL time.Duration
+	EquityTTL time.Duration // while the market is open
+	ClosedTTL time.Duration // while the market is closed
+}
+
+// NewQuoteCache reads the TTLs from QUOTE_TTL_CRYPTO, QUOTE_TTL_EQUITY and
+// QUOTE_TTL_CLOSED (Go durations, e.g. 10m), falling back to the defaults
+func NewQuoteCache(logger *zap.SugaredLogger) *QuoteCache {
+	return &QuoteCache{
+		CryptoTTL: durationEnv(logger, "QUOTE_TTL_CRYPTO", defaultCryptoTTL),
+		EquityTTL: durationEnv(logger, "QUOTE_TTL_EQUITY", defaultEquityTTL),
+		ClosedTTL: durationEnv(logger, "QUOTE_TTL_CLOSED", defaultClosedTTL),
+	}
+}
+
+// durationEnv reads a duration setting, falling back to def when it is unset or invalid
+func durationEnv(logger *zap.SugaredLogger, name string, def time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		logger.Warnf("Invalid %s %q, using %s", name, raw, def)
+		return def
+	}
+	return d
+}
+
+// Fresh reports whether the price of a ticker can be used without fetching it again
+func (c *QuoteCache) Fresh(t models.Ticker, now time.Time) bool {
+	return t.FetchedAt != nil && now.Sub(*t.FetchedAt) < c.ttl(t, *t.FetchedAt, now)
+}
+
+// ttl is how long a price fetched at fetchedAt stays fresh. The closed market
+// TTL only applies to prices also fetched while the market was closed, so the
+// last price of a session is fetched again after the close.
+func (c *QuoteCache) ttl(t models.Ticker, fetchedAt, now time.Time) time.Duration {
+	if t.AssetClass() == models.AssetCrypto {
+		return c.CryptoTTL
+	}
+	if s, ok := sessions[t.Currency]; ok && !s.isOpen(now) && !s.isOpen(fetchedAt) {
+		return c.ClosedTTL
+	}
+	return c.EquityTTL
+}
+
+// Mark sets the Stale flag of tickers
+func (c *QuoteCache) Mark(tickers ...*models.Ticker) {
+	now := time.Now()
+	for _, t := range tickers {
+		t.Stale = !c.Fresh(*t, now)
+	}
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+func TestQuoteCacheTTL(t *testing.T) {
+	c := &QuoteCache{CryptoTTL: time.Minute, EquityTTL: 10 * time.Minute, ClosedTTL: time.Hour}
+	newYork, saoPaulo := sessions["USD"].location, sessions["BRL"].location
+	at := func(loc *time.Location, day, hour, minute int) time.Time {
+		return time.Date(2026, time.October, day, hour, minute, 0, 0, loc) // the 12th is a Monday
+	}
+
+	tests := []struct {
+		name           string
+		ticker         models.Ticker
+		fetchedAt, now time.Time
+		want           time.Duration
+	}{
+		{"crypto pair", models.Ticker{Symbol: "BTC/USD", Currency: "USD"}, at(newYork, 17, 3, 0), at(newYork, 17, 3, 0), time.Minute},
+		{"crypto asset type", models.Ticker{Symbol: "BTC", Currency: "USD", AssetType: models.AssetTypeCrypto}, at(newYork, 14, 12, 0), at(newYork, 14, 12, 0), time.Minute},
+		{"US equity in session", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 14, 12, 0), at(newYork, 14, 12, 5), 10 * time.Minute},
+		{"US equity at the open", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 14, 9, 30), at(newYork, 14, 9, 30), 10 * time.Minute},
+		{"US equity overnight", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 14, 20, 0), at(newYork, 14, 22, 0), time.Hour},
+		{"US equity at the close", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 14, 16, 0), at(newYork, 14, 16, 30), time.Hour},
+		{"US equity fetched before the close", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 14, 15, 55), at(newYork, 14, 16, 30), 10 * time.Minute},
+		{"US equity on the weekend", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(newYork, 17, 12, 0), at(newYork, 18, 12, 0), time.Hour},
+		{"US equity in New York hours, not UTC", models.Ticker{Symbol: "AAPL", Currency: "USD"}, at(time.UTC, 14, 15, 0), at(time.UTC, 14, 15, 0), 10 * time.Minute},
+		{"Brazilian equity in session", models.Ticker{Symbol: "PETR4.SA", Currency: "BRL"}, at(saoPaulo, 14, 16, 0), at(saoPaulo, 14, 16, 0), 10 * time.Minute},
+		{"Brazilian equity before the open", models.Ticker{Symbol: "PETR4.SA", Currency: "BRL"}, at(saoPaulo, 14, 9, 0), at(saoPaulo, 14, 9, 30), time.Hour},
+		{"unknown market is always open", models.Ticker{Symbol: "SAP.DE", Currency: "EUR"}, at(time.UTC, 17, 3, 0), at(time.UTC, 17, 4, 0), 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.ttl(tt.ticker, tt.fetchedAt, tt.now); got != tt.want {
+				t.Errorf("ttl = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteCacheFresh(t *testing.T) {
+	c := &QuoteCache{CryptoTTL: time.Minute, EquityTTL: 10 * time.Minute, ClosedTTL: time.Hour}
+	now := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		fetchedAt *time.Time
+		want      bool
+	}{
+		{"never fetched", nil, false},
+		{"within the TTL", ago(59 * time.Second), true},
+		{"at the TTL", ago(time.Minute), false},
+		{"past the TTL", ago(time.Hour), false},
+	}
+	for _, tt := range tests {
+		ticker := models.Ticker{Symbol: "BTC/USD", Currency: "USD", FetchedAt: tt.fetchedAt}
+		if got := c.Fresh(ticker, now); got != tt.want {
+			t.Errorf("%s: Fresh = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// QuoteProvider fetches the latest price and day change of a ticker
+type QuoteProvider interface {
+	Quote(ctx context.Context, ticker models.Ticker) (models.Ticker, error)
+}
+
+// BatchQuoter is implemented by providers with a multi-symbol quote endpoint.
+// Quotes returns the quotes found, by symbol, for up to BatchSize tickers;
+// the ones missing are fetched again with Quote.
+type BatchQuoter interface {
+	QuoteProvider
+	BatchSize() int
+	Quotes(ctx context.Context, tickers []models.Ticker) (map[string]models.Ticker, error)
+}
+
+// QuoteResult is the outcome of fetching the quote of one ticker
+type QuoteResult struct {
+	Ticker models.Ticker // as passed to FetchQuotes
+	Quote  models.Ticker // the new price, day change, source and fetch time
+	Err    error
+}
+
+// FetchQuotes fetches the quotes of tickers and sends every result as soon as
+// it arrives. Providers with batch support get as few requests as possible;
+// the tickers they return no data for, and all of them otherwise, are fetched
+// a few at a time within the provider's quota. The channel is closed once all
+// are done; after ctx is cancelled, the remaining tickers are not sent at all.
+func (s *FinanceService) FetchQuotes(ctx context.Context, tickers []models.Ticker) <-chan QuoteResult {
+	results := make(chan QuoteResult)
+	go func() {
+		defer close(results)
+		singles := tickers
+		if batch, ok := s.Quotes.(BatchQuoter); ok {
+			singles = s.fetchBatches(ctx, batch, tickers, results)
+		}
+		s.fetchSingles(ctx, singles, results)
+	}()
+	return results
+}
+
+// fetchBatches sends the results of the batch requests and returns the
+// tickers left for single requests
+func (s *FinanceService) fetchBatches(ctx context.Context, batch BatchQuoter, tickers []models.Ticker, results chan<- QuoteResult) []models.Ticker {
+	var missing []models.Ticker
+	for chunk := range slices.Chunk(tickers, max(1, batch.BatchSize())) {
+		quotes, err := batch.Quotes(ctx, chunk)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, ErrRateLimited) {
+			s.Logger.Warnf("Batch quote request failed, falling back to single requests: %v", err)
+			missing = append(missing, chunk...)
+			continue
+		}
+
+		for _, ticker := range chunk {
+			quote, ok := quotes[ticker.Symbol]
+			if err == nil && !ok {
+				missing = append(missing, ticker)
+				continue
+			}
+			// Rate limited: single requests would only spend more of the quota
+			select {
+			case results <- QuoteResult{Ticker: ticker, Quote: quote, Err: err}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+	if len(missing) > 0 {
+		s.Logger.Infof("Fetching %d symbols missing from batch quotes one by one", len(missing))
+	}
+	return missing
+}
+
+// fetchSingles fetches one ticker per request with a bounded pool of workers
+func (s *FinanceService) fetchSingles(ctx context.Context, tickers []models.Ticker, results chan<- QuoteResult) {
+	queue := make(chan models.Ticker)
+	var wg sync.WaitGroup
+	for range min(s.workers, max(1, len(tickers))) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ticker := range queue {
+				quote, err := s.Quotes.Quote(ctx, ticker)
+				if err != nil && ctx.Err() != nil {
+					return // cut short by the cancellation, not a failure of the ticker
+				}
+				select {
+				case results <- QuoteResult{Ticker: ticker, Quote: quote, Err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, ticker := range tickers {
+		select {
+		case queue <- ticker:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
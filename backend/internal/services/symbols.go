@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// How long symbol searches are reused. Listings rarely change, but a search
+// that found nothing is tried again sooner, in case the symbol was just listed.
+const (
+	symbolSearchTTL      = 7 * 24 * time.Hour
+	emptySymbolSearchTTL = 24 * time.Hour
+)
+
+// SymbolService searches the listings of the market data provider, keeping
+// the results in the symbol_searches table
+type SymbolService struct {
+	DB      *gorm.DB
+	Logger  *zap.SugaredLogger
+	Finance *FinanceService
+}
+
+func NewSymbolService(db *gorm.DB, logger *zap.SugaredLogger, finance *FinanceService) *SymbolService {
+	return &SymbolService{DB: db, Logger: logger, Finance: finance}
+}
+
+// Search returns the listings matching query, best first
+func (s *SymbolService) Search(ctx context.Context, query string) ([]models.SymbolMatch, error) {
+	query = strings.ToUpper(strings.TrimSpace(query))
+
+	var cached models.SymbolSearch
+	if err := s.DB.Where("query = ?", query).Limit(1).Find(&cached).Error; err != nil {
+		return nil, err
+	}
+	ttl := symbolSearchTTL
+	if len(cached.Matches) == 0 {
+		ttl = emptySymbolSearchTTL
+	}
+	if cached.Query != "" && time.Since(cached.SearchedAt) < ttl {
+		return cached.Matches, nil
+	}
+
+	matches, err := s.Finance.SearchSymbols(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	search := models.SymbolSearch{Query: query, Matches: matches, SearchedAt: time.Now()}
+	if err := s.DB.Save(&search).Error; err != nil {
+		s.Logger.Warnf("Failed to cache symbol search %q: %v", query, err)
+	}
+	return matches, nil
+}
+
+// Lookup finds the listing of a symbol in a currency, or in any currency when
+// it is empty. Without one, listing is nil and matches are the listings found
+// instead, to suggest. Crypto pairs (e.g. BTC/USD) are not searchable and are
+// taken as listed in their quote currency.
+func (s *SymbolService) Lookup(ctx context.Context, symbol, currency string) (listing *models.SymbolMatch, matches []models.SymbolMatch, err error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if (models.Ticker{Symbol: symbol}).AssetClass() == models.AssetCrypto {
+		pair := models.SymbolMatch{Symbol: symbol, Currency: symbol[strings.LastIndex(symbol, "/")+1:], AssetType: "Crypto", MatchScore: 1}
+		if currency != "" && currency != pair.Currency {
+			return nil, []models.SymbolMatch{pair}, nil
+		}
+		return &pair, nil, nil
+	}
+
+	matches, err = s.Search(ctx, symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, m := range matches {
+		if m.Symbol == fromAlphaSymbol(symbol) && (currency == "" || m.Currency == currency) {
+			return &matches[i], matches, nil
+		}
+	}
+	return nil, matches, nil
+}
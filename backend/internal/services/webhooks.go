@@ -0,0 +1,255 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Headers of webhook requests
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// webhookAttempts is how many times a delivery is tried before it fails for good
+const webhookAttempts = 6
+
+// webhookBacklog is how many events may wait to be queued before Handle
+// falls back to a goroutine per event
+const webhookBacklog = 256
+
+// webhookPoll is how often due retries are looked for when nothing wakes the sender up
+const webhookPoll = 15 * time.Second
+
+// SignWebhook returns the signature of a webhook body:
+// "sha256=" and the hex HMAC-SHA256 of "timestamp.body" keyed with the secret.
+// Receivers recompute it to check the request came from us and was not replayed.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookService turns events into deliveries and sends them. Deliveries are
+// stored first, so they survive restarts, and failed ones are retried with
+// exponential backoff: Backoff, then twice as long after every failure.
+type WebhookService struct {
+	DB      *gorm.DB
+	Logger  *zap.SugaredLogger
+	Client  *http.Client
+	Backoff time.Duration
+
+	wake     chan struct{}
+	incoming chan events.Event
+}
+
+func NewWebhookService(db *gorm.DB, logger *zap.SugaredLogger) *WebhookService {
+	return &WebhookService{
+		DB:       db,
+		Logger:   logger,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+		Backoff:  time.Minute,
+		wake:     make(chan struct{}, 1),
+		incoming: make(chan events.Event, webhookBacklog),
+	}
+}
+
+// Handle queues an event for every enabled webhook that subscribes to it.
+// It is meant to be subscribed to the events bus: the deliveries are stored
+// in the background (see Run), so the publisher doesn't wait on the database.
+func (s *WebhookService) Handle(e events.Event) {
+	if !slices.Contains(events.Types, e.Type) {
+		return
+	}
+	select {
+	case s.incoming <- e:
+	default: // backlog full, don't block the publisher
+		go s.queueEvent(e)
+	}
+}
+
+// queueEvent stores a delivery of the event for every webhook that wants it
+func (s *WebhookService) queueEvent(e events.Event) {
+	query := s.DB.Where("enabled = ?", true)
+	if e.PortfolioID != 0 {
+		query = query.Where("portfolio_id = ?", e.PortfolioID)
+	}
+	var hooks []models.Webhook
+	if err := query.Find(&hooks).Error; err != nil {
+		s.Logger.Errorw("failed to fetch webhooks", "event", e.Type, "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if !hook.Wants(e.Type) {
+			continue
+		}
+		if _, err := s.Queue(hook, e); err != nil {
+			s.Logger.Errorw("failed to queue webhook delivery", "webhook_id", hook.ID, "event", e.Type, "error", err)
+		}
+	}
+}
+
+// Queue stores a delivery of the event to the webhook and wakes the sender up
+func (s *WebhookService) Queue(hook models.Webhook, e events.Event) (models.WebhookDelivery, error) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return s.queue(models.WebhookDelivery{WebhookID: hook.ID, EventID: e.ID, Event: e.Type, Payload: string(payload)})
+}
+
+// Redeliver queues the payload of a past delivery again, as a new delivery
+func (s *WebhookService) Redeliver(d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	return s.queue(models.WebhookDelivery{WebhookID: d.WebhookID, EventID: d.EventID, Event: d.Event, Payload: d.Payload})
+}
+
+func (s *WebhookService) queue(d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	now := time.Now()
+	d.Status = models.DeliveryPending
+	d.NextAttemptAt = &now
+	if err := s.DB.Create(&d).Error; err != nil {
+		return d, err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default: // already awake
+	}
+	return d, nil
+}
+
+// Run queues the handled events and sends the due deliveries until ctx is done
+func (s *WebhookService) Run(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-s.incoming:
+				s.queueEvent(e)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(webhookPoll)
+	defer ticker.Stop()
+	for {
+		s.sendDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// sendDue sends the pending deliveries whose attempt is due, oldest first
+func (s *WebhookService) sendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		var due []models.WebhookDelivery
+		err := s.DB.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, time.Now()).
+			Order("id").Limit(50).Find(&due).Error
+		if err != nil {
+			s.Logger.Errorw("failed to fetch webhook deliveries", "error", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+		for i := range due {
+			s.attempt(ctx, &due[i])
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, d *models.WebhookDelivery) {
+	var hook models.Webhook
+	err := s.DB.First(&hook, d.WebhookID).Error
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		err = fmt.Errorf("webhook deleted")
+		d.Attempts = webhookAttempts - 1 // no point in retrying
+	case err != nil: // the database, try again later
+	case !hook.Enabled: // disabled after the delivery was queued
+		err = fmt.Errorf("webhook disabled")
+		d.Attempts = webhookAttempts - 1
+	default:
+		d.StatusCode, err = s.send(ctx, hook, d)
+	}
+
+	now := time.Now()
+	d.Attempts++
+	switch {
+	case err == nil:
+		d.Status = models.DeliveryDelivered
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+		d.Error = ""
+	case d.Attempts >= webhookAttempts:
+		d.Status = models.DeliveryFailed
+		d.NextAttemptAt = nil
+		d.Error = err.Error()
+	default:
+		next := now.Add(s.Backoff << (d.Attempts - 1))
+		d.NextAttemptAt = &next
+		d.Error = err.Error()
+	}
+	if err != nil {
+		s.Logger.Warnw("webhook delivery failed", "delivery_id", d.ID, "webhook_id", d.WebhookID,
+			"attempt", d.Attempts, "status", d.Status, "error", err)
+	}
+
+	if err := s.DB.Save(d).Error; err != nil {
+		s.Logger.Errorw("failed to save webhook delivery", "delivery_id", d.ID, "error", err)
+	}
+}
+
+// send posts the signed payload and returns the response status.
+// Any status outside 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, hook models.Webhook, d *models.WebhookDelivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gemini-stocks-webhooks")
+	req.Header.Set(WebhookEventHeader, d.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(hook.Secret, timestamp, body))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/dbtest"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/migrations"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+)
+
+// receiver records the webhook requests it gets and answers them with statuses, in order
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedWebhook
+}
+
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	status := http.StatusOK
+	if n := len(rc.requests); n < len(rc.statuses) {
+		status = rc.statuses[n]
+	}
+	rc.requests = append(rc.requests, receivedWebhook{r.Header.Clone(), body})
+	w.WriteHeader(status)
+}
+
+func (rc *receiver) received() []receivedWebhook {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]receivedWebhook(nil), rc.requests...)
+}
+
+func newWebhookTest(t *testing.T, db *gorm.DB, statuses ...int) (*WebhookService, *receiver, models.Webhook) {
+	t.Helper()
+	if _, err := migrations.Up(db, zap.NewNop().Sugar(), 0); err != nil {
+		t.Fatal(err)
+	}
+	rc := &receiver{statuses: statuses}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	hook := models.Webhook{PortfolioID: 1, URL: server.URL, Secret: "secret", Enabled: true,
+		Events: []string{events.TransactionCreated}}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewWebhookService(db, zap.NewNop().Sugar())
+	s.Backoff = 0 // retry right away
+	return s, rc, hook
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		s, rc, hook := newWebhookTest(t, db, http.StatusInternalServerError)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go s.Run(ctx)
+
+		s.Handle(events.New(events.TransactionUpdated, 1, nil)) // not subscribed
+		s.Handle(events.New(events.TransactionCreated, 2, nil)) // another portfolio
+		e := events.New(events.TransactionCreated, 1, map[string]string{"symbol": "AAPL"})
+		s.Handle(e)
+
+		var d models.WebhookDelivery
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			err := db.Where("webhook_id = ?", hook.ID).First(&d).Error
+			if err == nil && d.Status != models.DeliveryPending {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("delivery not sent in time: %+v (%v)", d, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		if d.Status != models.DeliveryDelivered || d.Attempts != 2 || d.StatusCode != http.StatusOK || d.DeliveredAt == nil {
+			t.Errorf("delivery log = %+v, want delivered with 200 on the second attempt", d)
+		}
+		if d.EventID != e.ID || d.Event != events.TransactionCreated {
+			t.Errorf("delivery is for event %s %s, want %s %s", d.Event, d.EventID, e.Type, e.ID)
+		}
+		var count int64
+		db.Model(&models.WebhookDelivery{}).Count(&count)
+		if count != 1 {
+			t.Errorf("%d deliveries logged, want 1", count)
+		}
+
+		requests := rc.received()
+		if len(requests) != 2 {
+			t.Fatalf("receiver got %d requests, want 2 (a 500 and its retry)", len(requests))
+		}
+		for i, req := range requests {
+			if string(req.body) != d.Payload {
+				t.Errorf("request %d: body %s, want the logged payload %s", i, req.body, d.Payload)
+			}
+			ts := req.header.Get(WebhookTimestampHeader)
+			if want := SignWebhook(hook.Secret, ts, req.body); req.header.Get(WebhookSignatureHeader) != want {
+				t.Errorf("request %d: signature %q, want %q", i, req.header.Get(WebhookSignatureHeader), want)
+			}
+			if req.header.Get(WebhookEventHeader) != events.TransactionCreated ||
+				req.header.Get(WebhookDeliveryHeader) != strconv.FormatUint(uint64(d.ID), 10) {
+				t.Errorf("request %d: headers %v", i, req.header)
+			}
+		}
+	})
+}
+
+func TestWebhookDisabledAfterQueueing(t *testing.T) {
+	dbtest.Each(t, func(t *testing.T, db *gorm.DB) {
+		s, rc, hook := newWebhookTest(t, db)
+
+		d, err := s.Queue(hook, events.New(events.TransactionCreated, 1, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Model(&hook).Update("enabled", false).Error; err != nil {
+			t.Fatal(err)
+		}
+		s.sendDue(context.Background())
+
+		if err := db.First(&d, d.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if d.Status != models.DeliveryFailed || d.Error != "webhook disabled" {
+			t.Errorf("delivery log = %+v, want failed because the webhook is disabled", d)
+		}
+		if n := len(rc.received()); n != 0 {
+			t.Errorf("disabled webhook got %d requests", n)
+		}
+	})
+}
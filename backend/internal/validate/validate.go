@@ -0,0 +1,267 @@
+// Package validate checks structs against declarative rules in `validate` tags.
+//
+//	Quantity decimal.Decimal `json:"quantity" validate:"required,gt=0"`
+//
+// Rules are separated by commas:
+//
+//	required   the value is not empty (zero, blank string, nil pointer)
+//	gt=N       number greater than N        gte=N  number at least N (ints, floats and decimals)
+//	lte=N      number at most N             max=N  string or slice length at most N
+//	min=N      string length at least N     email  string is an e-mail address
+//	oneof=a b  string is one of the listed values
+//	currency   string is a supported currency code (see models.SupportedCurrencies)
+//	url        string is an absolute http or https URL
+//	isin       string is an ISIN with a valid check digit
+//	notfuture  time is not in the future
+//
+// Rules other than required are skipped for empty values, and pointers are
+// checked only when set, so optional fields only need the rules themselves.
+// Nested structs and slices of structs are validated too.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// FieldError describes one invalid field, named by its JSON path (e.g. allocations[0].percentage)
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// futureTolerance absorbs time zone differences for dates sent as midnight
+const futureTolerance = 24 * time.Hour
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+)
+
+// Struct validates v (a struct or a pointer to one) and returns every field error
+func Struct(v any) []FieldError {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs []FieldError
+	walkStruct(rv, "", &errs)
+	return errs
+}
+
+func walkStruct(rv reflect.Value, prefix string, errs *[]FieldError) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		value := rv.Field(i)
+
+		// Embedded structs share the JSON namespace of their parent
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			walkStruct(value, prefix, errs)
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if tag := f.Tag.Get("validate"); tag != "" {
+			if msg := checkField(value, tag); msg != "" {
+				*errs = append(*errs, FieldError{Field: path, Message: msg})
+				continue
+			}
+		}
+
+		walkNested(value, path, errs)
+	}
+}
+
+// walkNested descends into struct and slice-of-struct fields
+func walkNested(value reflect.Value, path string, errs *[]FieldError) {
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() != timeType && value.Type() != decimalType {
+			walkStruct(value, path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			walkNested(value.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	}
+}
+
+// checkField applies the rules of a tag and returns the first failure message
+func checkField(value reflect.Value, tag string) string {
+	rules := strings.Split(tag, ",")
+
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			for _, rule := range rules {
+				if rule == "required" {
+					return "is required"
+				}
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	empty := isEmpty(value)
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			if empty {
+				return "is required"
+			}
+			continue
+		}
+		if empty {
+			continue
+		}
+		if msg := checkRule(value, name, arg); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func checkRule(value reflect.Value, name, arg string) string {
+	switch name {
+	case "gt", "gte", "lte":
+		n, ok := number(value)
+		if !ok {
+			return ""
+		}
+		limit, _ := decimal.NewFromString(arg)
+		switch {
+		case name == "gt" && n.LessThanOrEqual(limit):
+			return "must be greater than " + arg
+		case name == "gte" && n.LessThan(limit):
+			return "must be at least " + arg
+		case name == "lte" && n.GreaterThan(limit):
+			return "must be at most " + arg
+		}
+
+	case "min":
+		limit, _ := strconv.Atoi(arg)
+		if value.Kind() == reflect.String && utf8.RuneCountInString(value.String()) < limit {
+			return fmt.Sprintf("must have at least %d characters", limit)
+		}
+
+	case "email":
+		if addr, err := mail.ParseAddress(value.String()); err != nil || addr.Address != value.String() {
+			return "must be a valid e-mail address"
+		}
+
+	case "max":
+		limit, _ := strconv.Atoi(arg)
+		if value.Kind() == reflect.String && value.Len() > limit {
+			return fmt.Sprintf("must have at most %d characters", limit)
+		}
+		if value.Kind() == reflect.Slice && value.Len() > limit {
+			return fmt.Sprintf("must have at most %d items", limit)
+		}
+
+	case "oneof":
+		allowed := strings.Fields(arg)
+		for _, a := range allowed {
+			if value.String() == a {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(allowed, ", ")
+
+	case "url":
+		if u, err := url.Parse(value.String()); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "must be an http or https URL"
+		}
+
+	case "currency":
+		if !models.SupportedCurrencies[strings.ToUpper(value.String())] {
+			return fmt.Sprintf("unknown currency '%s'", value.String())
+		}
+
+	case "isin":
+		if !models.ValidISIN(value.String()) {
+			return "must be a valid ISIN"
+		}
+
+	case "notfuture":
+		if t, ok := value.Interface().(time.Time); ok && t.After(time.Now().Add(futureTolerance)) {
+			return "must not be in the future"
+		}
+
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q", name))
+	}
+	return ""
+}
+
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	case reflect.Struct:
+		switch v := value.Interface().(type) {
+		case time.Time:
+			return v.IsZero()
+		case decimal.Decimal:
+			return v.IsZero()
+		}
+		return false
+	default:
+		return value.IsZero()
+	}
+}
+
+func number(value reflect.Value) (decimal.Decimal, bool) {
+	switch value.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return decimal.NewFromFloat(value.Float()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return decimal.NewFromInt(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return decimal.NewFromUint64(value.Uint()), true
+	case reflect.Struct:
+		if d, ok := value.Interface().(decimal.Decimal); ok {
+			return d, true
+		}
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,1021 @@
+// Package client is a typed Go client for the Gemini Stocks API.
+//
+// It follows the OpenAPI document served at /openapi.json: one method per
+// operation, named after its operationId. It depends on nothing but the
+// standard library and shopspring/decimal, so it can be imported without the
+// server's database drivers.
+//
+//	c := client.New("http://localhost:8080")
+//	if _, err := c.Login(ctx, client.LoginRequest{Email: email, Password: password}); err != nil { ... }
+//	txs, err := c.ListTransactions(ctx, client.TransactionFilter{Symbol: "AAPL"})
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scopes of personal API tokens
+const (
+	ScopeReadPortfolio     = "read:portfolio"
+	ScopeWriteTransactions = "write:transactions"
+	ScopeAdmin             = "admin"
+)
+
+// Portfolio roles
+const (
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+// Transaction types
+const (
+	Buy  TransactionType = "BUY"
+	Sell TransactionType = "SELL"
+)
+
+// Restore strategies
+const (
+	RestoreSkip      RestoreStrategy = "skip"
+	RestoreOverwrite RestoreStrategy = "overwrite"
+	RestoreReplace   RestoreStrategy = "replace"
+	RestoreFail      RestoreStrategy = "fail"
+)
+
+// portfolioHeader selects the portfolio of a request
+const portfolioHeader = "X-Portfolio-ID"
+
+// Client calls the API. The zero value is not usable; use New.
+// Token is the session or personal API token sent with every request; Register and Login set it.
+// PortfolioID selects the portfolio of the portfolio endpoints; 0 uses the default one.
+type Client struct {
+	BaseURL     string
+	HTTPClient  *http.Client
+	Token       string
+	PortfolioID uint
+}
+
+// New creates a client for the API at baseURL (e.g. http://localhost:8080)
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Error is returned for any non-2xx response, decoded from the error envelope
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Fields     []FieldError
+	RequestID  string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("api error %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	for _, f := range e.Fields {
+		msg += fmt.Sprintf("; %s %s", f.Field, f.Message)
+	}
+	return msg
+}
+
+// newError reads the error envelope of a failed response
+func newError(resp *http.Response) *Error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var envelope errorResponse
+	if err := json.Unmarshal(raw, &envelope); err != nil || envelope.Error.Code == "" {
+		// Not an envelope (e.g. a proxy error page)
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+	}
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Code:       envelope.Error.Code,
+		Message:    envelope.Error.Message,
+		Fields:     envelope.Error.Fields,
+		RequestID:  envelope.Error.RequestID,
+	}
+}
+
+// do sends the request and decodes a JSON response into out (if not nil)
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string, out any) (*http.Response, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.authorize(req)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, newError(resp)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp, fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return resp, nil
+}
+
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out any) (*http.Response, error) {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(raw)
+		contentType = "application/json"
+	}
+	return c.do(ctx, method, path, query, body, contentType, out)
+}
+
+// download returns the raw body of a GET request; the caller must close it
+func (c *Client) download(ctx context.Context, path string, query url.Values) (io.ReadCloser, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authorize(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, newError(resp)
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) authorize(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.PortfolioID != 0 {
+		req.Header.Set(portfolioHeader, strconv.FormatUint(uint64(c.PortfolioID), 10))
+	}
+}
+
+// Health calls GET /health
+func (c *Client) Health(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodGet, "/health", nil, nil, "", nil)
+	return err
+}
+
+// Register calls POST /auth/register and uses the new session for the next calls
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (AuthResponse, error) {
+	var out AuthResponse
+	if _, err := c.doJSON(ctx, http.MethodPost, "/auth/register", nil, req, &out); err != nil {
+		return out, err
+	}
+	c.Token = out.Token
+	return out, nil
+}
+
+// Login calls POST /auth/login and uses the session for the next calls
+func (c *Client) Login(ctx context.Context, req LoginRequest) (AuthResponse, error) {
+	var out AuthResponse
+	if _, err := c.doJSON(ctx, http.MethodPost, "/auth/login", nil, req, &out); err != nil {
+		return out, err
+	}
+	c.Token = out.Token
+	return out, nil
+}
+
+// Logout calls POST /auth/logout and forgets the token
+func (c *Client) Logout(ctx context.Context) error {
+	_, err := c.doJSON(ctx, http.MethodPost, "/auth/logout", nil, nil, nil)
+	if err == nil {
+		c.Token = ""
+	}
+	return err
+}
+
+// GetCurrentUser calls GET /auth/me
+func (c *Client) GetCurrentUser(ctx context.Context) (User, error) {
+	var out User
+	_, err := c.doJSON(ctx, http.MethodGet, "/auth/me", nil, nil, &out)
+	return out, err
+}
+
+// ListTokens calls GET /tokens
+func (c *Client) ListTokens(ctx context.Context) ([]APIToken, error) {
+	var out []APIToken
+	_, err := c.doJSON(ctx, http.MethodGet, "/tokens", nil, nil, &out)
+	return out, err
+}
+
+// CreateToken calls POST /tokens. The returned token cannot be read again.
+func (c *Client) CreateToken(ctx context.Context, req CreateTokenRequest) (CreateTokenResponse, error) {
+	var out CreateTokenResponse
+	_, err := c.doJSON(ctx, http.MethodPost, "/tokens", nil, req, &out)
+	return out, err
+}
+
+// RevokeToken calls DELETE /tokens/{id}
+func (c *Client) RevokeToken(ctx context.Context, id uint) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/tokens/"+strconv.FormatUint(uint64(id), 10), nil, nil, nil)
+	return err
+}
+
+// ListNotifications calls GET /notifications
+func (c *Client) ListNotifications(ctx context.Context) (NotificationSettings, error) {
+	var out NotificationSettings
+	_, err := c.doJSON(ctx, http.MethodGet, "/notifications", nil, nil, &out)
+	return out, err
+}
+
+// SaveNotification calls PUT /notifications/{channel}
+func (c *Client) SaveNotification(ctx context.Context, channel string, req NotificationPreferenceRequest) (NotificationPreference, error) {
+	var out NotificationPreference
+	_, err := c.doJSON(ctx, http.MethodPut, "/notifications/"+url.PathEscape(channel), nil, req, &out)
+	return out, err
+}
+
+// DeleteNotification calls DELETE /notifications/{channel}
+func (c *Client) DeleteNotification(ctx context.Context, channel string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/notifications/"+url.PathEscape(channel), nil, nil, nil)
+	return err
+}
+
+// TestNotification calls POST /notifications/{channel}/test
+func (c *Client) TestNotification(ctx context.Context, channel string) error {
+	_, err := c.doJSON(ctx, http.MethodPost, "/notifications/"+url.PathEscape(channel)+"/test", nil, nil, nil)
+	return err
+}
+
+// SendTaxSummary calls POST /notifications/{channel}/tax-summary. An empty
+// month (YYYY-MM) sends the previous one.
+func (c *Client) SendTaxSummary(ctx context.Context, channel, month string) (TaxSummaryResult, error) {
+	q := url.Values{}
+	if month != "" {
+		q.Set("month", month)
+	}
+	var out TaxSummaryResult
+	_, err := c.doJSON(ctx, http.MethodPost, "/notifications/"+url.PathEscape(channel)+"/tax-summary", q, nil, &out)
+	return out, err
+}
+
+// ListPortfolios calls GET /portfolios
+func (c *Client) ListPortfolios(ctx context.Context) ([]PortfolioResponse, error) {
+	var out []PortfolioResponse
+	_, err := c.doJSON(ctx, http.MethodGet, "/portfolios", nil, nil, &out)
+	return out, err
+}
+
+// CreatePortfolio calls POST /portfolios
+func (c *Client) CreatePortfolio(ctx context.Context, name string) (PortfolioResponse, error) {
+	var out PortfolioResponse
+	_, err := c.doJSON(ctx, http.MethodPost, "/portfolios", nil, SavePortfolioRequest{Name: name}, &out)
+	return out, err
+}
+
+// UpdatePortfolio calls PUT /portfolios/{id}
+func (c *Client) UpdatePortfolio(ctx context.Context, id uint, name string) (PortfolioResponse, error) {
+	var out PortfolioResponse
+	_, err := c.doJSON(ctx, http.MethodPut, portfolioPath(id), nil, SavePortfolioRequest{Name: name}, &out)
+	return out, err
+}
+
+// ListMembers calls GET /portfolios/{id}/members
+func (c *Client) ListMembers(ctx context.Context, portfolioID uint) ([]MemberResponse, error) {
+	var out []MemberResponse
+	_, err := c.doJSON(ctx, http.MethodGet, portfolioPath(portfolioID)+"/members", nil, nil, &out)
+	return out, err
+}
+
+// AddMember calls POST /portfolios/{id}/members
+func (c *Client) AddMember(ctx context.Context, portfolioID uint, email string, role Role) (MemberResponse, error) {
+	var out MemberResponse
+	_, err := c.doJSON(ctx, http.MethodPost, portfolioPath(portfolioID)+"/members", nil, AddMemberRequest{Email: email, Role: role}, &out)
+	return out, err
+}
+
+// UpdateMember calls PUT /portfolios/{id}/members/{userID}
+func (c *Client) UpdateMember(ctx context.Context, portfolioID, userID uint, role Role) (PortfolioMember, error) {
+	var out PortfolioMember
+	path := portfolioPath(portfolioID) + "/members/" + strconv.FormatUint(uint64(userID), 10)
+	_, err := c.doJSON(ctx, http.MethodPut, path, nil, UpdateMemberRequest{Role: role}, &out)
+	return out, err
+}
+
+// RemoveMember calls DELETE /portfolios/{id}/members/{userID}
+func (c *Client) RemoveMember(ctx context.Context, portfolioID, userID uint) error {
+	path := portfolioPath(portfolioID) + "/members/" + strconv.FormatUint(uint64(userID), 10)
+	_, err := c.doJSON(ctx, http.MethodDelete, path, nil, nil, nil)
+	return err
+}
+
+func portfolioPath(id uint) string {
+	return "/portfolios/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// TransactionFilter holds the filters and sort of listTransactions and exportTransactions
+type TransactionFilter struct {
+	Symbol   string
+	Type     TransactionType
+	Currency string
+	Category string
+	Search   string
+	From     time.Time // inclusive, zero for no bound
+	To       time.Time // inclusive, zero for no bound
+	Sort     string    // e.g. "-date"
+}
+
+func (f TransactionFilter) values() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	set("symbol", f.Symbol)
+	set("type", string(f.Type))
+	set("currency", f.Currency)
+	set("category", f.Category)
+	set("q", f.Search)
+	set("sort", f.Sort)
+	if !f.From.IsZero() {
+		q.Set("from", f.From.Format("2006-01-02"))
+	}
+	if !f.To.IsZero() {
+		q.Set("to", f.To.Format("2006-01-02"))
+	}
+	return q
+}
+
+// TransactionPage is one page of listTransactions
+type TransactionPage struct {
+	Transactions []TransactionResponse
+	Total        int
+	NextCursor   string // empty on the last page
+}
+
+// ListTransactions calls GET /transactions and returns every matching transaction
+func (c *Client) ListTransactions(ctx context.Context, filter TransactionFilter) ([]TransactionResponse, error) {
+	var out []TransactionResponse
+	_, err := c.doJSON(ctx, http.MethodGet, "/transactions", filter.values(), nil, &out)
+	return out, err
+}
+
+// ListTransactionsPage calls GET /transactions with pagination.
+// Pass the NextCursor of the previous page to get the next one.
+func (c *Client) ListTransactionsPage(ctx context.Context, filter TransactionFilter, limit int, cursor string) (TransactionPage, error) {
+	q := filter.values()
+	q.Set("limit", strconv.Itoa(limit))
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+
+	var page TransactionPage
+	resp, err := c.doJSON(ctx, http.MethodGet, "/transactions", q, nil, &page.Transactions)
+	if err != nil {
+		return page, err
+	}
+	page.Total, _ = strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	page.NextCursor = resp.Header.Get("X-Next-Cursor")
+	return page, nil
+}
+
+// unlistedQuery is the query accepting new symbols the provider does not list
+func unlistedQuery(unlisted bool) url.Values {
+	if !unlisted {
+		return nil
+	}
+	return url.Values{"unlisted": {"true"}}
+}
+
+// CreateTransaction calls POST /transactions. A new symbol must be listed by
+// the market data provider unless unlisted is set.
+func (c *Client) CreateTransaction(ctx context.Context, tx Transaction, unlisted bool) (Transaction, error) {
+	var out Transaction
+	_, err := c.doJSON(ctx, http.MethodPost, "/transactions", unlistedQuery(unlisted), tx, &out)
+	return out, err
+}
+
+// UpdateTransaction calls PUT /transactions/{id}
+func (c *Client) UpdateTransaction(ctx context.Context, id string, tx Transaction) (Transaction, error) {
+	var out Transaction
+	_, err := c.doJSON(ctx, http.MethodPut, "/transactions/"+url.PathEscape(id), nil, tx, &out)
+	return out, err
+}
+
+// DeleteTransaction calls DELETE /transactions/{id}
+func (c *Client) DeleteTransaction(ctx context.Context, id string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/transactions/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// ListDeletedTransactions calls GET /transactions/deleted
+func (c *Client) ListDeletedTransactions(ctx context.Context) ([]Transaction, error) {
+	var out []Transaction
+	_, err := c.doJSON(ctx, http.MethodGet, "/transactions/deleted", nil, nil, &out)
+	return out, err
+}
+
+// RestoreTransaction calls POST /transactions/deleted/{id}/restore
+func (c *Client) RestoreTransaction(ctx context.Context, id string) (Transaction, error) {
+	var out Transaction
+	_, err := c.doJSON(ctx, http.MethodPost, "/transactions/deleted/"+url.PathEscape(id)+"/restore", nil, nil, &out)
+	return out, err
+}
+
+// PurgeTransaction calls DELETE /transactions/deleted/{id}
+func (c *Client) PurgeTransaction(ctx context.Context, id string) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/transactions/deleted/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+// EmptyRecycleBin calls DELETE /transactions/deleted
+func (c *Client) EmptyRecycleBin(ctx context.Context) (PurgeResult, error) {
+	var out PurgeResult
+	_, err := c.doJSON(ctx, http.MethodDelete, "/transactions/deleted", nil, nil, &out)
+	return out, err
+}
+
+// ImportTransactions calls POST /transactions/import with an Excel file. A new
+// symbol must be listed by the market data provider unless unlisted is set.
+func (c *Client) ImportTransactions(ctx context.Context, symbol, currency, filename string, file io.Reader, unlisted bool) (ImportResult, error) {
+	var result ImportResult
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("symbol", symbol)
+	if currency != "" {
+		mw.WriteField("currency", currency)
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return result, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return result, err
+	}
+	if err := mw.Close(); err != nil {
+		return result, err
+	}
+
+	_, err = c.do(ctx, http.MethodPost, "/transactions/import", unlistedQuery(unlisted), &buf, mw.FormDataContentType(), &result)
+	return result, err
+}
+
+// SearchSymbols calls GET /symbols/search
+func (c *Client) SearchSymbols(ctx context.Context, q string) ([]SymbolMatch, error) {
+	var out []SymbolMatch
+	_, err := c.doJSON(ctx, http.MethodGet, "/symbols/search", url.Values{"q": {q}}, nil, &out)
+	return out, err
+}
+
+// ListPrices calls GET /prices
+func (c *Client) ListPrices(ctx context.Context) ([]Ticker, error) {
+	var out []Ticker
+	_, err := c.doJSON(ctx, http.MethodGet, "/prices", nil, nil, &out)
+	return out, err
+}
+
+// RefreshPrices calls POST /prices/refresh. The refresh runs as a job; follow
+// it with GetJob, or WaitJob to block until it finishes. Prices still fresh
+// are kept unless force is set.
+func (c *Client) RefreshPrices(ctx context.Context, force bool) (Job, error) {
+	var q url.Values
+	if force {
+		q = url.Values{"force": {"true"}}
+	}
+	var out Job
+	_, err := c.doJSON(ctx, http.MethodPost, "/prices/refresh", q, nil, &out)
+	return out, err
+}
+
+// UpdatePrice calls PUT /prices
+func (c *Client) UpdatePrice(ctx context.Context, req UpdatePriceRequest) (Ticker, error) {
+	var out Ticker
+	_, err := c.doJSON(ctx, http.MethodPut, "/prices", nil, req, &out)
+	return out, err
+}
+
+// RefreshTickerMetadata calls POST /prices/metadata
+func (c *Client) RefreshTickerMetadata(ctx context.Context, req RefreshMetadataRequest) (Ticker, error) {
+	var out Ticker
+	_, err := c.doJSON(ctx, http.MethodPost, "/prices/metadata", nil, req, &out)
+	return out, err
+}
+
+// JobFilter selects jobs of ListJobs. Zero values match everything.
+type JobFilter struct {
+	Kind   string
+	Status string
+	Before uint // job ID, for paging
+	Limit  int
+}
+
+// ListJobs calls GET /jobs
+func (c *Client) ListJobs(ctx context.Context, filter JobFilter) ([]Job, error) {
+	q := url.Values{}
+	if filter.Kind != "" {
+		q.Set("kind", filter.Kind)
+	}
+	if filter.Status != "" {
+		q.Set("status", filter.Status)
+	}
+	if filter.Before != 0 {
+		q.Set("before", strconv.FormatUint(uint64(filter.Before), 10))
+	}
+	if filter.Limit != 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out []Job
+	_, err := c.doJSON(ctx, http.MethodGet, "/jobs", q, nil, &out)
+	return out, err
+}
+
+// GetJob calls GET /jobs/{id}
+func (c *Client) GetJob(ctx context.Context, id uint) (Job, error) {
+	var out Job
+	_, err := c.doJSON(ctx, http.MethodGet, "/jobs/"+strconv.FormatUint(uint64(id), 10), nil, nil, &out)
+	return out, err
+}
+
+// CancelJob calls POST /jobs/{id}/cancel
+func (c *Client) CancelJob(ctx context.Context, id uint) (Job, error) {
+	var out Job
+	_, err := c.doJSON(ctx, http.MethodPost, "/jobs/"+strconv.FormatUint(uint64(id), 10)+"/cancel", nil, nil, &out)
+	return out, err
+}
+
+// WaitJob polls GetJob every interval until the job finishes or ctx is done
+func (c *Client) WaitJob(ctx context.Context, id uint, interval time.Duration) (Job, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job, err := c.GetJob(ctx, id)
+		if err != nil || job.Finished() {
+			return job, err
+		}
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetGoal calls GET /goal
+func (c *Client) GetGoal(ctx context.Context) (PortfolioGoal, error) {
+	var out PortfolioGoal
+	_, err := c.doJSON(ctx, http.MethodGet, "/goal", nil, nil, &out)
+	return out, err
+}
+
+// SaveGoal calls POST /goal
+func (c *Client) SaveGoal(ctx context.Context, req SaveGoalRequest) (PortfolioGoal, error) {
+	var out PortfolioGoal
+	_, err := c.doJSON(ctx, http.MethodPost, "/goal", nil, req, &out)
+	return out, err
+}
+
+// ListAlerts calls GET /alerts
+func (c *Client) ListAlerts(ctx context.Context) ([]AlertRule, error) {
+	var out []AlertRule
+	_, err := c.doJSON(ctx, http.MethodGet, "/alerts", nil, nil, &out)
+	return out, err
+}
+
+// CreateAlert calls POST /alerts
+func (c *Client) CreateAlert(ctx context.Context, req AlertRuleRequest) (AlertRule, error) {
+	var out AlertRule
+	_, err := c.doJSON(ctx, http.MethodPost, "/alerts", nil, req, &out)
+	return out, err
+}
+
+// UpdateAlert calls PUT /alerts/{id}
+func (c *Client) UpdateAlert(ctx context.Context, id uint, req AlertRuleRequest) (AlertRule, error) {
+	var out AlertRule
+	_, err := c.doJSON(ctx, http.MethodPut, "/alerts/"+strconv.FormatUint(uint64(id), 10), nil, req, &out)
+	return out, err
+}
+
+// DeleteAlert calls DELETE /alerts/{id}
+func (c *Client) DeleteAlert(ctx context.Context, id uint) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/alerts/"+strconv.FormatUint(uint64(id), 10), nil, nil, nil)
+	return err
+}
+
+// AlertEventFilter selects alerts of ListAlertEvents. Zero values match everything.
+type AlertEventFilter struct {
+	RuleID uint
+	Before uint // only alerts older than this ID
+	Limit  int  // default 50
+}
+
+// ListAlertEvents calls GET /alerts/events
+func (c *Client) ListAlertEvents(ctx context.Context, filter AlertEventFilter) ([]AlertEvent, error) {
+	q := url.Values{}
+	if filter.RuleID > 0 {
+		q.Set("rule_id", strconv.FormatUint(uint64(filter.RuleID), 10))
+	}
+	if filter.Before > 0 {
+		q.Set("before", strconv.FormatUint(uint64(filter.Before), 10))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out []AlertEvent
+	_, err := c.doJSON(ctx, http.MethodGet, "/alerts/events", q, nil, &out)
+	return out, err
+}
+
+// ListWebhooks calls GET /webhooks
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var out []Webhook
+	_, err := c.doJSON(ctx, http.MethodGet, "/webhooks", nil, nil, &out)
+	return out, err
+}
+
+// CreateWebhook calls POST /webhooks. The response has the signing secret, which cannot be read again.
+func (c *Client) CreateWebhook(ctx context.Context, req WebhookRequest) (CreateWebhookResponse, error) {
+	var out CreateWebhookResponse
+	_, err := c.doJSON(ctx, http.MethodPost, "/webhooks", nil, req, &out)
+	return out, err
+}
+
+// UpdateWebhook calls PUT /webhooks/{id}
+func (c *Client) UpdateWebhook(ctx context.Context, id uint, req WebhookRequest) (Webhook, error) {
+	var out Webhook
+	_, err := c.doJSON(ctx, http.MethodPut, "/webhooks/"+strconv.FormatUint(uint64(id), 10), nil, req, &out)
+	return out, err
+}
+
+// DeleteWebhook calls DELETE /webhooks/{id}
+func (c *Client) DeleteWebhook(ctx context.Context, id uint) error {
+	_, err := c.doJSON(ctx, http.MethodDelete, "/webhooks/"+strconv.FormatUint(uint64(id), 10), nil, nil, nil)
+	return err
+}
+
+// PingWebhook calls POST /webhooks/{id}/ping
+func (c *Client) PingWebhook(ctx context.Context, id uint) (WebhookDelivery, error) {
+	var out WebhookDelivery
+	_, err := c.doJSON(ctx, http.MethodPost, "/webhooks/"+strconv.FormatUint(uint64(id), 10)+"/ping", nil, nil, &out)
+	return out, err
+}
+
+// DeliveryFilter selects deliveries of ListWebhookDeliveries. Zero values match everything.
+type DeliveryFilter struct {
+	Status string // pending, delivered or failed
+	Before uint   // only deliveries older than this ID
+	Limit  int    // default 50
+}
+
+// ListWebhookDeliveries calls GET /webhooks/{id}/deliveries
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id uint, filter DeliveryFilter) ([]WebhookDelivery, error) {
+	q := url.Values{}
+	if filter.Status != "" {
+		q.Set("status", filter.Status)
+	}
+	if filter.Before > 0 {
+		q.Set("before", strconv.FormatUint(uint64(filter.Before), 10))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out []WebhookDelivery
+	_, err := c.doJSON(ctx, http.MethodGet, "/webhooks/"+strconv.FormatUint(uint64(id), 10)+"/deliveries", q, nil, &out)
+	return out, err
+}
+
+// RedeliverWebhook calls POST /webhooks/{id}/deliveries/{deliveryID}/redeliver
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryID uint) (WebhookDelivery, error) {
+	var out WebhookDelivery
+	path := "/webhooks/" + strconv.FormatUint(uint64(id), 10) + "/deliveries/" + strconv.FormatUint(uint64(deliveryID), 10) + "/redeliver"
+	_, err := c.doJSON(ctx, http.MethodPost, path, nil, nil, &out)
+	return out, err
+}
+
+// VerifyWebhook reports whether a webhook request was signed with secret.
+// Pass the X-Webhook-Timestamp and X-Webhook-Signature headers and the raw body;
+// checking that the timestamp is recent is left to the caller.
+func VerifyWebhook(secret, timestamp, signature string, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hmac.Equal([]byte("sha256="+hex.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
+
+// StreamEvents calls GET /events and hands every event to fn until ctx is
+// done, the stream ends or fn returns an error. Data is decoded as generic
+// JSON. A lastEventID replays the recent events after it; the ID of the last
+// event seen is the one to resume from after a disconnect.
+func (c *Client) StreamEvents(ctx context.Context, lastEventID string, fn func(Event) error) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+"/events", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	c.authorize(req)
+
+	// The stream has no end, so the client's timeout does not apply
+	hc := *c.HTTPClient
+	hc.Timeout = 0
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var e Event
+			if err := json.Unmarshal([]byte(data.String()), &e); err != nil {
+				return fmt.Errorf("decode event: %w", err)
+			}
+			data.Reset()
+			if err := fn(e); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return scanner.Err()
+}
+
+// ListCurrencies calls GET /currencies
+func (c *Client) ListCurrencies(ctx context.Context) (CurrencyList, error) {
+	var out CurrencyList
+	_, err := c.doJSON(ctx, http.MethodGet, "/currencies", nil, nil, &out)
+	return out, err
+}
+
+// GetRate calls GET /currencies/rate. An empty to means the base currency.
+func (c *Client) GetRate(ctx context.Context, from, to string) (RateQuote, error) {
+	var out RateQuote
+	q := url.Values{"from": {from}}
+	if to != "" {
+		q.Set("to", to)
+	}
+	_, err := c.doJSON(ctx, http.MethodGet, "/currencies/rate", q, nil, &out)
+	return out, err
+}
+
+// SetRate calls PUT /currencies/rates
+func (c *Client) SetRate(ctx context.Context, req SetRateRequest) (ExchangeRate, error) {
+	var out ExchangeRate
+	_, err := c.doJSON(ctx, http.MethodPut, "/currencies/rates", nil, req, &out)
+	return out, err
+}
+
+// RefreshRates calls POST /currencies/refresh
+func (c *Client) RefreshRates(ctx context.Context) (RefreshResult, error) {
+	var out RefreshResult
+	_, err := c.doJSON(ctx, http.MethodPost, "/currencies/refresh", nil, nil, &out)
+	return out, err
+}
+
+// RateHistoryFilter selects rates of ListRateHistory. Zero values match everything.
+type RateHistoryFilter struct {
+	Base  string
+	Quote string
+	Start string // YYYY-MM-DD
+	End   string // YYYY-MM-DD
+	Limit int
+}
+
+// ListRateHistory calls GET /currencies/history
+func (c *Client) ListRateHistory(ctx context.Context, filter RateHistoryFilter) ([]HistoricalRate, error) {
+	q := url.Values{}
+	if filter.Base != "" {
+		q.Set("base", filter.Base)
+	}
+	if filter.Quote != "" {
+		q.Set("quote", filter.Quote)
+	}
+	if filter.Start != "" {
+		q.Set("start", filter.Start)
+	}
+	if filter.End != "" {
+		q.Set("end", filter.End)
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out []HistoricalRate
+	_, err := c.doJSON(ctx, http.MethodGet, "/currencies/history", q, nil, &out)
+	return out, err
+}
+
+// BackfillRateHistory calls POST /currencies/history/backfill
+func (c *Client) BackfillRateHistory(ctx context.Context, req BackfillRequest) (HistoryResult, error) {
+	var out HistoryResult
+	_, err := c.doJSON(ctx, http.MethodPost, "/currencies/history/backfill", nil, req, &out)
+	return out, err
+}
+
+// ImportRateHistory calls POST /currencies/history/import with a PTAX CSV.
+// Rate is sell (the default when empty) or buy.
+func (c *Client) ImportRateHistory(ctx context.Context, rate, filename string, file io.Reader) (HistoryResult, error) {
+	var result HistoryResult
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if rate != "" {
+		mw.WriteField("rate", rate)
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return result, err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return result, err
+	}
+	if err := mw.Close(); err != nil {
+		return result, err
+	}
+
+	_, err = c.do(ctx, http.MethodPost, "/currencies/history/import", nil, &buf, mw.FormDataContentType(), &result)
+	return result, err
+}
+
+// GetSummary calls GET /data/summary. An empty currency means the base currency.
+func (c *Client) GetSummary(ctx context.Context, currency string) (Summary, error) {
+	var out Summary
+	q := url.Values{}
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+	_, err := c.doJSON(ctx, http.MethodGet, "/data/summary", q, nil, &out)
+	return out, err
+}
+
+// GetAllocation calls GET /data/allocation. An empty by groups by category and
+// an empty currency means the base currency.
+func (c *Client) GetAllocation(ctx context.Context, by, currency string) (Allocation, error) {
+	var out Allocation
+	q := url.Values{}
+	if by != "" {
+		q.Set("by", by)
+	}
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+	_, err := c.doJSON(ctx, http.MethodGet, "/data/allocation", q, nil, &out)
+	return out, err
+}
+
+// AuditFilter selects entries of listAudit. Zero values match everything.
+type AuditFilter struct {
+	Entity   string // transaction, ticker, goal or exchange_rate
+	EntityID string
+	Before   uint // only entries older than this ID
+	Limit    int  // default 50
+}
+
+// ListAudit calls GET /audit
+func (c *Client) ListAudit(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	q := url.Values{}
+	if filter.Entity != "" {
+		q.Set("entity", filter.Entity)
+	}
+	if filter.EntityID != "" {
+		q.Set("entity_id", filter.EntityID)
+	}
+	if filter.Before > 0 {
+		q.Set("before", strconv.FormatUint(uint64(filter.Before), 10))
+	}
+	if filter.Limit > 0 {
+		q.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out []AuditEntry
+	_, err := c.doJSON(ctx, http.MethodGet, "/audit", q, nil, &out)
+	return out, err
+}
+
+// RevertChange calls POST /audit/{id}/revert. Force reverts even if the record changed since.
+func (c *Client) RevertChange(ctx context.Context, id uint, force bool) (AuditEntry, error) {
+	var q url.Values
+	if force {
+		q = url.Values{"force": {"true"}}
+	}
+	var out AuditEntry
+	_, err := c.doJSON(ctx, http.MethodPost, "/audit/"+strconv.FormatUint(uint64(id), 10)+"/revert", q, nil, &out)
+	return out, err
+}
+
+// ExportTransactions calls GET /export/transactions. Format is xlsx, csv or json.
+// The caller must close the returned file.
+func (c *Client) ExportTransactions(ctx context.Context, format string, filter TransactionFilter) (io.ReadCloser, error) {
+	q := filter.values()
+	q.Set("format", format)
+	return c.download(ctx, "/export/transactions", q)
+}
+
+// ExportPositions calls GET /export/positions, valued in currency too unless it
+// is empty. The caller must close the returned file.
+func (c *Client) ExportPositions(ctx context.Context, format, currency string) (io.ReadCloser, error) {
+	q := url.Values{"format": {format}}
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+	return c.download(ctx, "/export/positions", q)
+}
+
+// ExportTaxReport calls GET /export/tax, converted to currency too unless it
+// is empty. CSV exports need sheet (months or sales), the other formats ignore
+// it. The caller must close the returned file.
+func (c *Client) ExportTaxReport(ctx context.Context, format string, year int, currency, sheet string) (io.ReadCloser, error) {
+	q := url.Values{"format": {format}, "year": {strconv.Itoa(year)}}
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+	if sheet != "" {
+		q.Set("sheet", sheet)
+	}
+	return c.download(ctx, "/export/tax", q)
+}
+
+// ExportIncome calls GET /export/income: the realized gains by period (month,
+// quarter or year), of year or of every year when it is 0, converted to
+// currency too unless it is empty. The caller must close the returned file.
+func (c *Client) ExportIncome(ctx context.Context, format, period string, year int, currency string) (io.ReadCloser, error) {
+	q := url.Values{"format": {format}}
+	if period != "" {
+		q.Set("period", period)
+	}
+	if year != 0 {
+		q.Set("year", strconv.Itoa(year))
+	}
+	if currency != "" {
+		q.Set("currency", currency)
+	}
+	return c.download(ctx, "/export/income", q)
+}
+
+// Backup calls GET /admin/backup. Format is zip or json. The caller must close the returned file.
+func (c *Client) Backup(ctx context.Context, format string) (io.ReadCloser, error) {
+	return c.download(ctx, "/admin/backup", url.Values{"format": {format}})
+}
+
+// Restore calls POST /admin/restore with a backup archive
+func (c *Client) Restore(ctx context.Context, archive io.Reader, strategy RestoreStrategy) (RestoreResult, error) {
+	var out RestoreResult
+	q := url.Values{}
+	if strategy != "" {
+		q.Set("strategy", string(strategy))
+	}
+	_, err := c.do(ctx, http.MethodPost, "/admin/restore", q, archive, "application/octet-stream", &out)
+	return out, err
+}
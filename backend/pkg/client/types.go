@@ -0,0 +1,631 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The payload types mirror the JSON the API sends and accepts. They are
+// declared here rather than taken from the server so that importing the client
+// does not pull in the server's database drivers; TestPayloadTypes keeps the
+// two in sync.
+
+// Transaction is a buy or sell of a symbol. The server fills ID, the timestamps
+// and the portfolio fields.
+type Transaction struct {
+	ID        string          `json:"ID"`
+	CreatedAt time.Time       `json:"CreatedAt"`
+	UpdatedAt time.Time       `json:"UpdatedAt"`
+	DeletedAt *time.Time      `json:"DeletedAt"`
+	Symbol    string          `json:"symbol"`
+	Type      TransactionType `json:"type"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Price     decimal.Decimal `json:"price"`
+	Currency  string          `json:"currency"`
+	Fee       decimal.Decimal `json:"fee"`
+	Date      time.Time       `json:"date"`
+	Note      string          `json:"note"`
+	// Set by the server: the portfolio and who created and last changed the record
+	PortfolioID uint `json:"portfolio_id"`
+	CreatedByID uint `json:"created_by_id"`
+	UpdatedByID uint `json:"updated_by_id"`
+}
+
+// TransactionType is Buy or Sell
+type TransactionType string
+
+// TransactionResponse is a transaction valued at the current price of its symbol
+type TransactionResponse struct {
+	Transaction
+	CurrentPrice decimal.Decimal `json:"current_price"`
+	MarketValue  decimal.Decimal `json:"market_value"`
+	PnL          decimal.Decimal `json:"pnl"`
+	PnLPercent   float64         `json:"pnl_percent"`
+}
+
+// ImportResult is the response of POST /transactions/import
+type ImportResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is a row of an import that was not imported, or a field of it
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Ticker acts as a cache for the latest market price
+type Ticker struct {
+	Symbol           string          `json:"symbol"`
+	Price            decimal.Decimal `json:"price"`
+	DayChangePercent float64         `json:"day_change_percent"`
+	Tags             string          `json:"tags"`
+	Category         string          `json:"category"`
+	Currency         string          `json:"currency"`
+	Exchange         string          `json:"exchange"` // where it is listed, e.g. B3; empty when unknown
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Source           string          `json:"source"`     // where Price came from; empty before it was tracked
+	FetchedAt        *time.Time      `json:"fetched_at"` // when Price was fetched or set by hand
+	Stale            bool            `json:"stale"`
+	// Metadata, filled from the provider when the ticker is created or refreshed and editable by hand
+	Name              string     `json:"name"`
+	Country           string     `json:"country"`
+	Sector            string     `json:"sector"`
+	Industry          string     `json:"industry"`
+	AssetType         string     `json:"asset_type"`
+	ISIN              string     `json:"isin"`
+	LogoURL           string     `json:"logo_url"`
+	MetadataFetchedAt *time.Time `json:"metadata_fetched_at"` // when the provider's overview was last applied
+}
+
+// SymbolMatch is a listing found by a symbol search
+type SymbolMatch struct {
+	Symbol     string  `json:"symbol"` // as used in tickers, e.g. PETR4 for PETR4.SAO
+	Name       string  `json:"name"`
+	Exchange   string  `json:"exchange"`
+	Currency   string  `json:"currency"`
+	AssetType  string  `json:"asset_type"` // as named by the provider, e.g. Equity or ETF
+	Region     string  `json:"region"`
+	MatchScore float64 `json:"match_score"` // from 0 to 1
+}
+
+// UpdatePriceRequest is the body of PUT /prices. Only the fields sent are changed.
+type UpdatePriceRequest struct {
+	Symbol    string           `json:"symbol"`
+	Price     *decimal.Decimal `json:"price"`
+	Tags      *string          `json:"tags"`
+	Category  *string          `json:"category"`
+	Currency  *string          `json:"currency"`
+	Name      *string          `json:"name"`
+	Exchange  *string          `json:"exchange"`
+	Country   *string          `json:"country"`
+	Sector    *string          `json:"sector"`
+	Industry  *string          `json:"industry"`
+	AssetType *string          `json:"asset_type"`
+	ISIN      *string          `json:"isin"`
+	LogoURL   *string          `json:"logo_url"`
+}
+
+// RefreshMetadataRequest is the body of POST /prices/metadata
+type RefreshMetadataRequest struct {
+	Symbol    string `json:"symbol"`
+	Overwrite bool   `json:"overwrite"` // replace the fields that are already set too
+}
+
+// PortfolioGoal is the target total of a portfolio and how it should be split by category
+type PortfolioGoal struct {
+	ID          uint
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time
+	PortfolioID uint             `json:"portfolio_id"`
+	UpdatedByID uint             `json:"updated_by_id"`
+	GoalTotal   decimal.Decimal  `json:"goal_total"`
+	Allocations []GoalAllocation `json:"allocations"`
+}
+
+// GoalAllocation is the target share of one category, in percent
+type GoalAllocation struct {
+	ID              uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       *time.Time
+	PortfolioGoalID uint    `json:"portfolio_goal_id"`
+	Category        string  `json:"category"`
+	Percentage      float64 `json:"percentage"`
+}
+
+// SaveGoalRequest is the body of POST /goal
+type SaveGoalRequest struct {
+	GoalTotal   decimal.Decimal `json:"goal_total"`
+	Allocations []struct {
+		Category   string  `json:"category"`
+		Percentage float64 `json:"percentage"`
+	} `json:"allocations"`
+}
+
+// ExchangeRate is the price of one unit of Base in Quote (e.g., USD/BRL 5.50
+// means 1 USD = 5.50 BRL). Inverse and cross rates are derived from the stored
+// pairs.
+type ExchangeRate struct {
+	Base      string          `json:"base"`
+	Quote     string          `json:"quote"`
+	Rate      decimal.Decimal `json:"rate"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// CurrencyList is the response of GET /currencies
+type CurrencyList struct {
+	Base      string         `json:"base"` // reporting currency (BASE_CURRENCY)
+	Supported []string       `json:"supported"`
+	Rates     []ExchangeRate `json:"rates"`
+}
+
+// SetRateRequest is the body of PUT /currencies/rates
+type SetRateRequest struct {
+	Base  string          `json:"base"`
+	Quote string          `json:"quote"`
+	Rate  decimal.Decimal `json:"rate"`
+}
+
+// RateQuote is the rate from one currency to another, possibly derived.
+// Path lists the currencies it went through, e.g. [EUR USD BRL] for a cross rate.
+type RateQuote struct {
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	Rate      decimal.Decimal `json:"rate"`
+	Path      []string        `json:"path"`
+	UpdatedAt time.Time       `json:"updated_at"` // of the oldest rate used; zero for identical currencies
+}
+
+// HistoricalRate is the closing rate of a pair on a day, used to value past
+// transactions at the rate of their date. Date is midnight UTC.
+type HistoricalRate struct {
+	Base   string          `json:"base"`
+	Quote  string          `json:"quote"`
+	Date   time.Time       `json:"date"`
+	Rate   decimal.Decimal `json:"rate"`
+	Source string          `json:"source"`
+}
+
+// BackfillRequest is the body of POST /currencies/history/backfill
+type BackfillRequest struct {
+	Base  string `json:"base"`
+	Quote string `json:"quote"` // default: the base currency
+	Full  bool   `json:"full"`  // the whole series instead of the last 100 days
+}
+
+// HistoryResult is the response of the historical rates imports
+type HistoryResult struct {
+	Imported int              `json:"imported"`
+	Failed   int              `json:"failed"`
+	Errors   []ImportRowError `json:"errors"`
+}
+
+// Job is work that runs in the background after the request that started it,
+// e.g. a price refresh. Items report on each unit of work, e.g. each symbol.
+type Job struct {
+	ID          uint       `json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	PortfolioID uint       `json:"portfolio_id"`
+	CreatedByID uint       `json:"created_by_id"`
+	Kind        string     `json:"kind"`
+	Status      string     `json:"status"`
+	Total       int        `json:"total"`
+	Done        int        `json:"done"`
+	Updated     int        `json:"updated"`
+	Failed      int        `json:"failed"`
+	Alerts      int        `json:"alerts"` // alerts triggered once the job finished
+	Items       []JobItem  `json:"items"`  // in processing order
+	Error       string     `json:"error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at"`
+}
+
+// Finished reports whether the job is no longer running
+func (j Job) Finished() bool {
+	return j.Status != "running"
+}
+
+// JobItem is the outcome of one unit of work of a job
+type JobItem struct {
+	Symbol string           `json:"symbol"`
+	Status string           `json:"status"`
+	Price  *decimal.Decimal `json:"price,omitempty"` // the new price, once updated
+	Error  string           `json:"error,omitempty"`
+}
+
+// AlertRule is checked after every price refresh. Once it triggers, it stays
+// quiet for CooldownMinutes even if the condition still holds.
+type AlertRule struct {
+	ID              uint
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       *time.Time
+	PortfolioID     uint            `json:"portfolio_id"`
+	CreatedByID     uint            `json:"created_by_id"`
+	Type            AlertType       `json:"type"`
+	Symbol          string          `json:"symbol"`   // every type but goal_drift
+	Category        string          `json:"category"` // goal_drift only; empty for every category of the goal
+	Threshold       decimal.Decimal `json:"threshold"`
+	CooldownMinutes int             `json:"cooldown_minutes"`
+	Enabled         bool            `json:"enabled"`
+	LastTriggeredAt *time.Time      `json:"last_triggered_at"`
+}
+
+// AlertRuleRequest is the body of POST /alerts and PUT /alerts/{id}
+type AlertRuleRequest struct {
+	Type            AlertType       `json:"type"`
+	Symbol          string          `json:"symbol"`
+	Category        string          `json:"category"`
+	Threshold       decimal.Decimal `json:"threshold"`
+	CooldownMinutes *int            `json:"cooldown_minutes"` // default: a day
+	Enabled         *bool           `json:"enabled"`          // default: true
+}
+
+// AlertEvent records a rule that triggered. Value is what was observed (a
+// price or a percentage), so the history survives later edits of the rule.
+type AlertEvent struct {
+	ID          uint            `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	RuleID      uint            `json:"rule_id"`
+	PortfolioID uint            `json:"portfolio_id"`
+	Type        AlertType       `json:"type"`
+	Symbol      string          `json:"symbol"`
+	Category    string          `json:"category"`
+	Value       decimal.Decimal `json:"value"`
+	Threshold   decimal.Decimal `json:"threshold"`
+	Message     string          `json:"message"`
+}
+
+// AlertType is the condition an alert rule watches
+type AlertType string
+
+// Webhook sends the events of a portfolio to a URL, signed with the secret
+// returned when it was created (see VerifyWebhook)
+type Webhook struct {
+	ID          uint
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   *time.Time
+	PortfolioID uint     `json:"portfolio_id"`
+	CreatedByID uint     `json:"created_by_id"`
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"` // empty for every event
+	Enabled     bool     `json:"enabled"`
+}
+
+// WebhookRequest is the body of POST /webhooks and PUT /webhooks/{id}
+type WebhookRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`  // empty for every event
+	Enabled     *bool    `json:"enabled"` // default: true
+}
+
+// CreateWebhookResponse is returned once, when the webhook is created. The secret cannot be read again.
+type CreateWebhookResponse struct {
+	Secret string `json:"secret"`
+	Webhook
+}
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// last attempt
+type WebhookDelivery struct {
+	ID            uint       `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	WebhookID     uint       `json:"webhook_id"`
+	EventID       string     `json:"event_id"`
+	Event         string     `json:"event"`
+	Payload       string     `json:"payload"` // the JSON body sent
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	StatusCode    int        `json:"status_code"` // of the last attempt; 0 if no response
+	Error         string     `json:"error"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+}
+
+// Event is one thing that happened. Data is the record it is about, e.g.
+// the created transaction.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	PortfolioID uint      `json:"portfolio_id"` // 0 for market data shared by every portfolio
+	CreatedAt   time.Time `json:"created_at"`
+	Data        any       `json:"data"`
+}
+
+// Money is an amount in a currency, rounded to the currency's minor unit
+type Money struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// Summary totals the positions of a portfolio in one currency
+type Summary struct {
+	Currency       string  `json:"currency"`
+	CostBasis      Money   `json:"cost_basis"`
+	MarketValue    Money   `json:"market_value"`
+	PnL            Money   `json:"pnl"`
+	PnLPercent     float64 `json:"pnl_percent"`
+	AssetReturn    Money   `json:"asset_return"`
+	CurrencyReturn Money   `json:"currency_return"`
+	// Currencies with no rate to the report currency; their positions are left out of the totals
+	MissingRates []string   `json:"missing_rates"`
+	Positions    []Position `json:"positions"`
+}
+
+// Position is the current holding of a single symbol, built from its transactions
+// using the average cost method. Amounts are rounded to the currency's minor unit.
+type Position struct {
+	Symbol       string          `json:"symbol"`
+	Currency     string          `json:"currency"`
+	Category     string          `json:"category"`
+	Name         string          `json:"name"`
+	Exchange     string          `json:"exchange"`
+	Country      string          `json:"country"`
+	Sector       string          `json:"sector"`
+	Industry     string          `json:"industry"`
+	AssetType    string          `json:"asset_type"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	AverageCost  decimal.Decimal `json:"average_cost"`
+	CostBasis    decimal.Decimal `json:"cost_basis"`
+	CurrentPrice decimal.Decimal `json:"current_price"`
+	MarketValue  decimal.Decimal `json:"market_value"`
+	PnL          decimal.Decimal `json:"pnl"`
+	PnLPercent   float64         `json:"pnl_percent"`
+	// Set when the position is valued in a report currency
+	Report *PositionReport `json:"report,omitempty"`
+}
+
+// Allocation splits the market value of a portfolio by one position field
+type Allocation struct {
+	Currency    string `json:"currency"`
+	By          string `json:"by"`
+	MarketValue Money  `json:"market_value"`
+	// Currencies with no rate to the report currency; their positions are left out
+	MissingRates []string          `json:"missing_rates"`
+	Groups       []AllocationGroup `json:"groups"`
+}
+
+// AllocationGroup is the share of the portfolio of the positions with the same
+// value of the grouped field. Key is empty for positions without one.
+type AllocationGroup struct {
+	Key         string   `json:"key"`
+	MarketValue Money    `json:"market_value"`
+	Percent     float64  `json:"percent"`
+	Symbols     []string `json:"symbols"`
+}
+
+// RefreshResult counts the outcome of a refresh. It is the response of POST
+// /currencies/refresh and the data of prices.refreshed events.
+type RefreshResult struct {
+	JobID   uint `json:"job_id,omitempty"` // the price refresh job
+	Total   int  `json:"total"`
+	Updated int  `json:"updated"`
+	Failed  int  `json:"failed"`
+	Alerts  int  `json:"alerts,omitempty"` // alerts triggered by the new prices
+}
+
+// FieldError describes one invalid field, named by its JSON path (e.g. allocations[0].percentage)
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// User owns transactions and goals. Admins are set up with ADMIN_EMAIL or `api create-admin`.
+type User struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	IsAdmin   bool   `json:"is_admin"`
+}
+
+// RegisterRequest is the body of POST /auth/register
+type RegisterRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"` // bcrypt ignores bytes past 72
+	Name     string `json:"name"`
+}
+
+// LoginRequest is the body of POST /auth/login
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// AuthResponse is returned by register and login. Send the token as "Authorization: Bearer <token>".
+type AuthResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
+
+// APIToken is a personal access token for scripts and dashboards.
+// Only the SHA-256 of the token is stored; Prefix identifies it in listings.
+type APIToken struct {
+	ID         uint
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	DeletedAt  *time.Time
+	UserID     uint       `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// CreateTokenRequest is the body of POST /tokens
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"` // null for a token that never expires
+}
+
+// CreateTokenResponse is returned once, when the token is created. The token cannot be read again.
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+	APIToken
+}
+
+// NotificationSettings is the response of GET /notifications
+type NotificationSettings struct {
+	Channels    []string                 `json:"channels"` // configured on this server
+	Topics      []string                 `json:"topics"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// NotificationPreference is how a user wants to be told about things on one channel
+type NotificationPreference struct {
+	UserID    uint      `json:"user_id"`
+	Channel   string    `json:"channel"`
+	Address   string    `json:"address"` // e-mail address or Telegram chat ID
+	Topics    []string  `json:"topics"`  // empty for every topic
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// YYYY-MM of the last monthly tax summary sent, so it goes out once
+	TaxSummarySentFor string `json:"tax_summary_sent_for"`
+}
+
+// NotificationPreferenceRequest is the body of PUT /notifications/{channel}
+type NotificationPreferenceRequest struct {
+	Address string   `json:"address"` // e-mail address (default: the account's) or Telegram chat ID
+	Topics  []string `json:"topics"`  // empty for every topic
+	Enabled *bool    `json:"enabled"` // default: true
+}
+
+// TaxSummaryResult is the response of POST /notifications/{channel}/tax-summary
+type TaxSummaryResult struct {
+	Month string `json:"month"`
+	Sent  bool   `json:"sent"` // false when the month had no sales
+}
+
+// Role is the access level of a member in a portfolio
+type Role string
+
+// Portfolio owns transactions and a goal. Users reach it through memberships.
+type Portfolio struct {
+	ID        uint
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt *time.Time
+	Name      string `json:"name"`
+}
+
+// PortfolioMember gives a user a role in a portfolio
+type PortfolioMember struct {
+	PortfolioID uint      `json:"portfolio_id"`
+	UserID      uint      `json:"user_id"`
+	Role        Role      `json:"role"`
+	AddedByID   uint      `json:"added_by_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PortfolioResponse is a portfolio with the caller's role in it
+type PortfolioResponse struct {
+	Portfolio
+	Role Role `json:"role"`
+}
+
+// MemberResponse is a membership with the member's name and e-mail
+type MemberResponse struct {
+	PortfolioMember
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+// SavePortfolioRequest is the body of POST /portfolios and PUT /portfolios/{id}
+type SavePortfolioRequest struct {
+	Name string `json:"name"`
+}
+
+// AddMemberRequest is the body of POST /portfolios/{id}/members. The user must already have an account.
+type AddMemberRequest struct {
+	Email string `json:"email"`
+	Role  Role   `json:"role"`
+}
+
+// UpdateMemberRequest is the body of PUT /portfolios/{id}/members/{userID}
+type UpdateMemberRequest struct {
+	Role Role `json:"role"`
+}
+
+// PurgeResult is the response of DELETE /transactions/deleted
+type PurgeResult struct {
+	Purged int `json:"purged"`
+}
+
+// AuditEntry records one change. Entries are append-only: reverting a change
+// adds a new entry pointing at the reverted one.
+type AuditEntry struct {
+	ID          uint            `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Entity      string          `json:"entity"`
+	EntityID    string          `json:"entity_id"`
+	Action      AuditAction     `json:"action"`
+	Before      json.RawMessage `json:"before"`       // null for creates
+	After       json.RawMessage `json:"after"`        // null for deletes
+	PortfolioID uint            `json:"portfolio_id"` // 0 for market data (tickers and currencies)
+	ActorID     uint            `json:"actor_id"`
+	RequestID   string          `json:"request_id"`
+	RevertOfID  *uint           `json:"revert_of_id,omitempty"`
+}
+
+// AuditAction is what a change did to a record
+type AuditAction string
+
+// RestoreStrategy decides what happens when a restored row already exists
+type RestoreStrategy string
+
+// RestoreResult reports a whole restore
+type RestoreResult struct {
+	Strategy RestoreStrategy        `json:"strategy"`
+	Tables   map[string]TableResult `json:"tables"`
+}
+
+// errorResponse is the error envelope
+type errorResponse struct {
+	Error errorBody `json:"error"`
+}
+
+// errorBody is the content of the error envelope
+type errorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// PositionReport is a position valued in the report currency. The cost is
+// converted at the rate of each purchase date and the market value at the
+// current rate, so PnL splits into what the asset and the currency did.
+type PositionReport struct {
+	Rate           decimal.Decimal `json:"rate"` // current
+	CostBasis      Money           `json:"cost_basis"`
+	MarketValue    Money           `json:"market_value"`
+	PnL            Money           `json:"pnl"`
+	AssetReturn    Money           `json:"asset_return"`    // the price change, at the current rate
+	CurrencyReturn Money           `json:"currency_return"` // the rate change since the purchases
+	EstimatedCost  bool            `json:"estimated_cost"`  // some purchases had no rate for their date and used the current one
+}
+
+// TableResult reports what happened to the rows of one table
+type TableResult struct {
+	Inserted int `json:"inserted"`
+	Updated  int `json:"updated"`
+	Skipped  int `json:"skipped"`
+}
@@ -0,0 +1,209 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/Felipalds/gemini-stocks/internal/auth"
+	"github.com/Felipalds/gemini-stocks/internal/backup"
+	"github.com/Felipalds/gemini-stocks/internal/events"
+	"github.com/Felipalds/gemini-stocks/internal/handlers"
+	"github.com/Felipalds/gemini-stocks/internal/httpx"
+	"github.com/Felipalds/gemini-stocks/internal/models"
+	"github.com/Felipalds/gemini-stocks/internal/services"
+	"github.com/Felipalds/gemini-stocks/internal/validate"
+)
+
+// TestPayloadTypes checks that every payload type has the same JSON shape as
+// the server type it mirrors.
+func TestPayloadTypes(t *testing.T) {
+	tests := []struct {
+		client, server any
+	}{
+		{Transaction{}, models.Transaction{}},
+		{TransactionType(""), models.TransactionType("")},
+		{TransactionResponse{}, handlers.TransactionResponse{}},
+		{ImportResult{}, handlers.ImportResult{}},
+		{ImportRowError{}, handlers.ImportRowError{}},
+		{Ticker{}, models.Ticker{}},
+		{SymbolMatch{}, models.SymbolMatch{}},
+		{UpdatePriceRequest{}, handlers.UpdatePriceRequest{}},
+		{RefreshMetadataRequest{}, handlers.RefreshMetadataRequest{}},
+		{PortfolioGoal{}, models.PortfolioGoal{}},
+		{GoalAllocation{}, models.GoalAllocation{}},
+		{SaveGoalRequest{}, handlers.SaveGoalRequest{}},
+		{ExchangeRate{}, models.ExchangeRate{}},
+		{CurrencyList{}, handlers.CurrencyList{}},
+		{SetRateRequest{}, handlers.SetRateRequest{}},
+		{RateQuote{}, services.Quote{}},
+		{HistoricalRate{}, models.HistoricalRate{}},
+		{BackfillRequest{}, handlers.BackfillRequest{}},
+		{HistoryResult{}, handlers.HistoryResult{}},
+		{Job{}, models.Job{}},
+		{JobItem{}, models.JobItem{}},
+		{AlertRule{}, models.AlertRule{}},
+		{AlertRuleRequest{}, handlers.AlertRuleRequest{}},
+		{AlertEvent{}, models.AlertEvent{}},
+		{Webhook{}, models.Webhook{}},
+		{WebhookRequest{}, handlers.WebhookRequest{}},
+		{CreateWebhookResponse{}, handlers.CreateWebhookResponse{}},
+		{WebhookDelivery{}, models.WebhookDelivery{}},
+		{Event{}, events.Event{}},
+		{Money{}, models.Money{}},
+		{Summary{}, services.Summary{}},
+		{Position{}, services.Position{}},
+		{PositionReport{}, services.PositionReport{}},
+		{Allocation{}, services.Allocation{}},
+		{AllocationGroup{}, services.AllocationGroup{}},
+		{RefreshResult{}, handlers.RefreshResult{}},
+		{FieldError{}, validate.FieldError{}},
+		{User{}, models.User{}},
+		{RegisterRequest{}, handlers.RegisterRequest{}},
+		{LoginRequest{}, handlers.LoginRequest{}},
+		{AuthResponse{}, handlers.AuthResponse{}},
+		{APIToken{}, models.APIToken{}},
+		{CreateTokenRequest{}, handlers.CreateTokenRequest{}},
+		{CreateTokenResponse{}, handlers.CreateTokenResponse{}},
+		{NotificationSettings{}, handlers.NotificationSettings{}},
+		{NotificationPreference{}, models.NotificationPreference{}},
+		{NotificationPreferenceRequest{}, handlers.NotificationPreferenceRequest{}},
+		{TaxSummaryResult{}, handlers.TaxSummaryResult{}},
+		{Portfolio{}, models.Portfolio{}},
+		{PortfolioMember{}, models.PortfolioMember{}},
+		{PortfolioResponse{}, handlers.PortfolioResponse{}},
+		{MemberResponse{}, handlers.MemberResponse{}},
+		{SavePortfolioRequest{}, handlers.SavePortfolioRequest{}},
+		{AddMemberRequest{}, handlers.AddMemberRequest{}},
+		{UpdateMemberRequest{}, handlers.UpdateMemberRequest{}},
+		{PurgeResult{}, handlers.PurgeResult{}},
+		{AuditEntry{}, models.AuditEntry{}},
+		{RestoreResult{}, backup.Result{}},
+		{TableResult{}, backup.TableResult{}},
+		{errorResponse{}, httpx.ErrorResponse{}},
+	}
+	for _, tt := range tests {
+		c, s := reflect.TypeOf(tt.client), reflect.TypeOf(tt.server)
+		t.Run(c.Name(), func(t *testing.T) {
+			if got, want := jsonShape(c), jsonShape(s); got != want {
+				t.Errorf("client %s does not match %s\nclient: %s\nserver: %s", c, s, got, want)
+			}
+		})
+	}
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	decimalType   = reflect.TypeOf(decimal.Decimal{})
+	rawType       = reflect.TypeOf(json.RawMessage{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// jsonShape describes how a type encodes to JSON: field names and options,
+// with embedded structs flattened, down to the leaf kinds.
+func jsonShape(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "time"
+	case decimalType:
+		return "decimal"
+	case rawType:
+		return "raw"
+	case deletedAtType:
+		return "*time"
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + jsonShape(t.Elem())
+	case reflect.Slice, reflect.Array:
+		return "[]" + jsonShape(t.Elem())
+	case reflect.Map:
+		return "map[" + jsonShape(t.Key()) + "]" + jsonShape(t.Elem())
+	case reflect.Interface:
+		return "any"
+	case reflect.Struct:
+		return "{" + strings.Join(structFields(t), ", ") + "}"
+	}
+	return t.Kind().String()
+}
+
+func structFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && tag == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType && ft != decimalType {
+				fields = append(fields, structFields(ft)...)
+				continue
+			}
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		if opts != "" {
+			name += "," + opts
+		}
+		fields = append(fields, name+" "+jsonShape(f.Type))
+	}
+	return fields
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		name         string
+		client, want string
+	}{
+		{"ScopeReadPortfolio", ScopeReadPortfolio, auth.ScopeReadPortfolio},
+		{"ScopeWriteTransactions", ScopeWriteTransactions, auth.ScopeWriteTransactions},
+		{"ScopeAdmin", ScopeAdmin, auth.ScopeAdmin},
+		{"RoleViewer", string(RoleViewer), string(models.RoleViewer)},
+		{"RoleEditor", string(RoleEditor), string(models.RoleEditor)},
+		{"RoleOwner", string(RoleOwner), string(models.RoleOwner)},
+		{"Buy", string(Buy), string(models.Buy)},
+		{"Sell", string(Sell), string(models.Sell)},
+		{"RestoreSkip", string(RestoreSkip), string(backup.StrategySkip)},
+		{"RestoreOverwrite", string(RestoreOverwrite), string(backup.StrategyOverwrite)},
+		{"RestoreReplace", string(RestoreReplace), string(backup.StrategyReplace)},
+		{"RestoreFail", string(RestoreFail), string(backup.StrategyFail)},
+		{"portfolioHeader", portfolioHeader, auth.PortfolioHeader},
+	}
+	for _, tt := range tests {
+		if tt.client != tt.want {
+			t.Errorf("%s = %q, server has %q", tt.name, tt.client, tt.want)
+		}
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"type":"transaction.created"}`)
+	signature := services.SignWebhook("secret", "1700000000", body)
+	tests := []struct {
+		name            string
+		secret, ts, sig string
+		want            bool
+	}{
+		{"valid", "secret", "1700000000", signature, true},
+		{"wrong secret", "other", "1700000000", signature, false},
+		{"wrong timestamp", "secret", "1700000001", signature, false},
+		{"empty signature", "secret", "1700000000", "", false},
+	}
+	for _, tt := range tests {
+		if got := VerifyWebhook(tt.secret, tt.ts, tt.sig, body); got != tt.want {
+			t.Errorf("%s: VerifyWebhook = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,19 +1,41 @@
-import { BrowserRouter, Routes, Route } from "react-router-dom";
+import { BrowserRouter, Routes, Route, Navigate } from "react-router-dom";
+import { type ReactNode } from "react";
 import { AppProvider } from "@/contexts/AppContext";
 import DashboardPage from "@/pages/Dashboard";
 import AddTransactionPage from "@/pages/AddTransaction";
+import LoginPage from "@/pages/Login";
 import { Toaster } from "@/components/ui/sonner";
+import { getToken } from "@/lib/api";
+
+// RequireAuth sends visitors without a session to the login page
+function RequireAuth({ children }: { children: ReactNode }) {
+  if (!getToken()) return <Navigate to="/login" replace />;
+  return <AppProvider>{children}</AppProvider>;
+}
 
 function App() {
   return (
     <BrowserRouter>
-      <AppProvider>
-        <Routes>
-          <Route path="/" element={<DashboardPage />} />
-          <Route path="/add" element={<AddTransactionPage />} />
-        </Routes>
-        <Toaster />
-      </AppProvider>
+      <Routes>
+        <Route path="/login" element={<LoginPage />} />
+        <Route
+          path="/"
+          element={
+            <RequireAuth>
+              <DashboardPage />
+            </RequireAuth>
+          }
+        />
+        <Route
+          path="/add"
+          element={
+            <RequireAuth>
+              <AddTransactionPage />
+            </RequireAuth>
+          }
+        />
+      </Routes>
+      <Toaster />
     </BrowserRouter>
   );
 }
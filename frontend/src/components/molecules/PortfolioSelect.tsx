@@ -0,0 +1,66 @@
+import { useEffect, useState } from "react";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
+import { apiFetch, getPortfolio, setPortfolio } from "@/lib/api";
+
+interface PortfolioOption {
+  ID: number;
+  name: string;
+  role: "viewer" | "editor" | "owner";
+}
+
+interface PortfolioSelectProps {
+  onChange: () => void;
+}
+
+// PortfolioSelect switches the portfolio every request is scoped to.
+// It only shows up once the user has access to more than one.
+export function PortfolioSelect({ onChange }: PortfolioSelectProps) {
+  const [portfolios, setPortfolios] = useState<PortfolioOption[]>([]);
+  const [selected, setSelected] = useState<string | null>(getPortfolio());
+
+  useEffect(() => {
+    apiFetch("/portfolios")
+      .then((r) => (r.ok ? r.json() : []))
+      .then((data: PortfolioOption[]) => {
+        setPortfolios(data || []);
+        // Drop a selection we lost access to and fall back to the default
+        const current = getPortfolio();
+        if (current && !data?.some((p) => String(p.ID) === current)) {
+          setPortfolio(null);
+          setSelected(null);
+          onChange();
+        }
+      })
+      .catch(() => {});
+  }, [onChange]);
+
+  if (portfolios.length < 2) return null;
+
+  function select(id: string) {
+    setPortfolio(id);
+    setSelected(id);
+    onChange();
+  }
+
+  return (
+    <Select value={selected ?? undefined} onValueChange={select}>
+      <SelectTrigger className="w-[180px]">
+        <SelectValue placeholder="Portfolio" />
+      </SelectTrigger>
+      <SelectContent>
+        {portfolios.map((p) => (
+          <SelectItem key={p.ID} value={String(p.ID)}>
+            {p.name}
+            {p.role !== "owner" && ` (${p.role})`}
+          </SelectItem>
+        ))}
+      </SelectContent>
+    </Select>
+  );
+}
@@ -1,3 +1,4 @@
+import { useCallback, useEffect, useState } from "react";
 import {
   Dialog,
   DialogContent,
@@ -5,14 +6,25 @@ import {
   DialogTitle,
   DialogDescription,
 } from "@/components/ui/dialog";
+import { Input } from "@/components/ui/input";
+import { Button } from "@/components/ui/button";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 import { TransactionList } from "@/components/organisms/TransactionList";
+import { useApp } from "@/contexts/AppContext";
 import { type Transaction } from "@/types";
+import { apiFetch } from "@/lib/api";
+
+const PAGE_SIZE = 50;
 
 interface AllTransactionsDialogProps {
   open: boolean;
   onOpenChange: (open: boolean) => void;
-  transactions: Transaction[];
-  isLoading: boolean;
   onDelete: (id: string) => void;
   onEdited: () => void;
 }
@@ -20,11 +32,57 @@ interface AllTransactionsDialogProps {
 export function AllTransactionsDialog({
   open,
   onOpenChange,
-  transactions,
-  isLoading,
   onDelete,
   onEdited,
 }: AllTransactionsDialogProps) {
+  // Refetch whenever the dashboard data changes (delete, edit, import...)
+  const { transactions: dashboardTransactions } = useApp();
+
+  const [items, setItems] = useState<Transaction[]>([]);
+  const [total, setTotal] = useState(0);
+  const [nextCursor, setNextCursor] = useState<string | null>(null);
+  const [isLoading, setIsLoading] = useState(false);
+
+  const [symbol, setSymbol] = useState("");
+  const [type, setType] = useState("ALL");
+  const [search, setSearch] = useState("");
+
+  const fetchPage = useCallback(
+    async (cursor: string | null) => {
+      const params = new URLSearchParams({
+        limit: String(PAGE_SIZE),
+        sort: "-date",
+      });
+      if (symbol.trim()) params.set("symbol", symbol.trim());
+      if (type !== "ALL") params.set("type", type);
+      if (search.trim()) params.set("q", search.trim());
+      if (cursor) params.set("cursor", cursor);
+
+      setIsLoading(true);
+      try {
+        const res = await apiFetch(`/transactions?${params}`);
+        if (!res.ok) return;
+
+        const page: Transaction[] = (await res.json()) || [];
+        setItems((prev) => (cursor ? [...prev, ...page] : page));
+        setTotal(Number(res.headers.get("X-Total-Count") ?? page.length));
+        setNextCursor(res.headers.get("X-Next-Cursor"));
+      } catch (err) {
+        console.error("Error fetching transactions:", err);
+      } finally {
+        setIsLoading(false);
+      }
+    },
+    [symbol, type, search],
+  );
+
+  // Debounce typing in the filters
+  useEffect(() => {
+    if (!open) return;
+    const timeout = setTimeout(() => fetchPage(null), 300);
+    return () => clearTimeout(timeout);
+  }, [open, fetchPage, dashboardTransactions]);
+
   return (
     <Dialog open={open} onOpenChange={onOpenChange}>
       <DialogContent className="w-[90vw] max-w-6xl max-h-[85vh] flex flex-col">
@@ -33,17 +91,53 @@ export function AllTransactionsDialog({
             All Transactions
           </DialogTitle>
           <DialogDescription>
-            {transactions.length} transaction{transactions.length !== 1 ? "s" : ""} total
+            {total} transaction{total !== 1 ? "s" : ""} total
           </DialogDescription>
         </DialogHeader>
 
+        <div className="flex gap-2 mt-2">
+          <Input
+            placeholder="Symbol"
+            value={symbol}
+            onChange={(e) => setSymbol(e.target.value)}
+            className="w-32"
+          />
+          <Select value={type} onValueChange={setType}>
+            <SelectTrigger className="w-32">
+              <SelectValue />
+            </SelectTrigger>
+            <SelectContent>
+              <SelectItem value="ALL">All types</SelectItem>
+              <SelectItem value="BUY">Buy</SelectItem>
+              <SelectItem value="SELL">Sell</SelectItem>
+            </SelectContent>
+          </Select>
+          <Input
+            placeholder="Search notes..."
+            value={search}
+            onChange={(e) => setSearch(e.target.value)}
+            className="flex-1"
+          />
+        </div>
+
         <div className="flex-1 overflow-auto mt-4">
           <TransactionList
-            transactions={transactions}
+            transactions={items}
             isLoading={isLoading}
             onDelete={onDelete}
             onEdited={onEdited}
           />
+          {nextCursor && (
+            <div className="text-center mt-4">
+              <Button
+                variant="outline"
+                disabled={isLoading}
+                onClick={() => fetchPage(nextCursor)}
+              >
+                Load more ({items.length} of {total})
+              </Button>
+            </div>
+          )}
         </div>
       </DialogContent>
     </Dialog>
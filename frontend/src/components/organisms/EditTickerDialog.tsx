@@ -18,8 +18,37 @@ import {
   SelectTrigger,
   SelectValue,
 } from "@/components/ui/select";
-import { X, Plus } from "lucide-react";
+import { X, Plus, Download } from "lucide-react";
 import { toast } from "sonner";
+import { apiFetch, readError } from "@/lib/api";
+import { ASSET_TYPES, type TickerMetadata } from "@/types";
+
+const EMPTY_METADATA: TickerMetadata = {
+  name: "",
+  exchange: "",
+  country: "",
+  sector: "",
+  industry: "",
+  asset_type: "",
+  isin: "",
+  logo_url: "",
+};
+
+// Select items can't have an empty value, so this one stands for "not set"
+const UNKNOWN_TYPE = "unknown";
+
+function pickMetadata(source: TickerMetadata): TickerMetadata {
+  return {
+    name: source.name ?? "",
+    exchange: source.exchange ?? "",
+    country: source.country ?? "",
+    sector: source.sector ?? "",
+    industry: source.industry ?? "",
+    asset_type: source.asset_type ?? "",
+    isin: source.isin ?? "",
+    logo_url: source.logo_url ?? "",
+  };
+}
 
 interface EditTickerDialogProps {
   open: boolean;
@@ -29,6 +58,7 @@ interface EditTickerDialogProps {
   currentTags: string[];
   currentCategory: string;
   currentCurrency: string;
+  currentMetadata?: TickerMetadata;
   onSaved: () => void;
 }
 
@@ -40,6 +70,7 @@ export function EditTickerDialog({
   currentTags,
   currentCategory,
   currentCurrency,
+  currentMetadata,
   onSaved,
 }: EditTickerDialogProps) {
   const [price, setPrice] = useState(String(currentPrice));
@@ -47,7 +78,45 @@ export function EditTickerDialog({
   const [tagInput, setTagInput] = useState("");
   const [category, setCategory] = useState(currentCategory);
   const [currency, setCurrency] = useState(currentCurrency || "USD");
+  const [metadata, setMetadata] = useState<TickerMetadata>(
+    currentMetadata ? pickMetadata(currentMetadata) : EMPTY_METADATA,
+  );
   const [saving, setSaving] = useState(false);
+  const [fetching, setFetching] = useState(false);
+
+  const setField = (field: keyof TickerMetadata, value: string) => {
+    setMetadata((m) => ({ ...m, [field]: value }));
+  };
+
+  // Fills the metadata from the provider. The backend saves it right away.
+  const handleFetchMetadata = async () => {
+    setFetching(true);
+    const toastId = toast.loading("Fetching details...");
+
+    try {
+      const res = await apiFetch("/prices/metadata", {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ symbol, overwrite: true }),
+      });
+
+      if (res.ok) {
+        setMetadata(pickMetadata(await res.json()));
+        toast.success("Details updated", { id: toastId });
+        onSaved();
+      } else {
+        const text = await readError(res);
+        toast.error("Could not fetch details", {
+          id: toastId,
+          description: text,
+        });
+      }
+    } catch {
+      toast.error("Connection error", { id: toastId });
+    } finally {
+      setFetching(false);
+    }
+  };
 
   const handleAddTag = () => {
     const tag = tagInput.trim().toUpperCase();
@@ -77,7 +146,7 @@ export function EditTickerDialog({
     const toastId = toast.loading("Saving changes...");
 
     try {
-      const res = await fetch("http://localhost:8080/prices", {
+      const res = await apiFetch("/prices", {
         method: "PUT",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({
@@ -86,6 +155,8 @@ export function EditTickerDialog({
           tags: tags.join(","),
           category,
           currency,
+          ...metadata,
+          isin: metadata.isin.trim().toUpperCase(),
         }),
       });
 
@@ -94,7 +165,7 @@ export function EditTickerDialog({
         onSaved();
         onOpenChange(false);
       } else {
-        const text = await res.text();
+        const text = await readError(res);
         toast.error("Update failed", { id: toastId, description: text });
       }
     } catch {
@@ -106,15 +177,16 @@ export function EditTickerDialog({
 
   return (
     <Dialog open={open} onOpenChange={onOpenChange}>
-      <DialogContent className="sm:max-w-[425px]">
+      <DialogContent className="sm:max-w-[525px]">
         <DialogHeader>
           <DialogTitle>Edit {symbol}</DialogTitle>
           <DialogDescription>
-            Update the current price manually or manage tags.
+            Update the current price manually, manage tags or edit the asset
+            details.
           </DialogDescription>
         </DialogHeader>
 
-        <div className="space-y-4 py-4">
+        <div className="space-y-4 py-4 max-h-[65vh] overflow-y-auto px-1">
           <div className="space-y-2">
             <Label htmlFor="price">Current Price</Label>
             <Input
@@ -187,6 +259,117 @@ export function EditTickerDialog({
               </div>
             )}
           </div>
+
+          <div className="space-y-3 border-t pt-4">
+            <div className="flex items-center justify-between">
+              <Label>Details</Label>
+              <Button
+                type="button"
+                variant="outline"
+                size="sm"
+                onClick={handleFetchMetadata}
+                disabled={fetching}
+              >
+                <Download className="mr-1 h-3 w-3" />
+                {fetching ? "Fetching..." : "Fetch from provider"}
+              </Button>
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="name">Name</Label>
+              <Input
+                id="name"
+                placeholder="e.g. Apple Inc"
+                value={metadata.name}
+                onChange={(e) => setField("name", e.target.value)}
+              />
+            </div>
+
+            <div className="grid grid-cols-2 gap-3">
+              <div className="space-y-2">
+                <Label>Asset type</Label>
+                <Select
+                  value={metadata.asset_type || UNKNOWN_TYPE}
+                  onValueChange={(v) =>
+                    setField("asset_type", v === UNKNOWN_TYPE ? "" : v)
+                  }
+                >
+                  <SelectTrigger>
+                    <SelectValue />
+                  </SelectTrigger>
+                  <SelectContent>
+                    <SelectItem value={UNKNOWN_TYPE}>Unknown</SelectItem>
+                    {ASSET_TYPES.map((t) => (
+                      <SelectItem key={t.value} value={t.value}>
+                        {t.label}
+                      </SelectItem>
+                    ))}
+                  </SelectContent>
+                </Select>
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="exchange">Exchange</Label>
+                <Input
+                  id="exchange"
+                  placeholder="e.g. B3, NASDAQ"
+                  value={metadata.exchange}
+                  onChange={(e) => setField("exchange", e.target.value)}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="sector">Sector</Label>
+                <Input
+                  id="sector"
+                  placeholder="e.g. Technology"
+                  value={metadata.sector}
+                  onChange={(e) => setField("sector", e.target.value)}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="industry">Industry</Label>
+                <Input
+                  id="industry"
+                  value={metadata.industry}
+                  onChange={(e) => setField("industry", e.target.value)}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="country">Country</Label>
+                <Input
+                  id="country"
+                  placeholder="e.g. Brazil"
+                  value={metadata.country}
+                  onChange={(e) => setField("country", e.target.value)}
+                />
+              </div>
+
+              <div className="space-y-2">
+                <Label htmlFor="isin">ISIN</Label>
+                <Input
+                  id="isin"
+                  placeholder="e.g. US0378331005"
+                  maxLength={12}
+                  value={metadata.isin}
+                  onChange={(e) => setField("isin", e.target.value)}
+                />
+              </div>
+            </div>
+
+            <div className="space-y-2">
+              <Label htmlFor="logo">Logo URL</Label>
+              <Input
+                id="logo"
+                type="url"
+                placeholder="https://..."
+                value={metadata.logo_url}
+                onChange={(e) => setField("logo_url", e.target.value)}
+              />
+            </div>
+          </div>
         </div>
 
         <DialogFooter>
@@ -12,6 +12,7 @@ import {
 } from "@/components/organisms/TransactionForm";
 import { type Transaction } from "@/types";
 import { toast } from "sonner";
+import { apiFetch, readError } from "@/lib/api";
 
 interface EditTransactionDialogProps {
   open: boolean;
@@ -49,21 +50,18 @@ export function EditTransactionDialog({
         date: new Date(values.date).toISOString(),
       };
 
-      const res = await fetch(
-        `http://localhost:8080/transactions/${transaction.ID}`,
-        {
-          method: "PUT",
-          headers: { "Content-Type": "application/json" },
-          body: JSON.stringify(payload),
-        },
-      );
+      const res = await apiFetch(`/transactions/${transaction.ID}`, {
+        method: "PUT",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(payload),
+      });
 
       if (res.ok) {
         toast.success("Transaction updated", { id: toastId });
         onSaved();
         onOpenChange(false);
       } else {
-        const text = await res.text();
+        const text = await readError(res);
         toast.error("Update failed", { id: toastId, description: text });
       }
     } catch {
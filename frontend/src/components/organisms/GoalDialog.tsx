@@ -10,6 +10,7 @@ import { Button } from "@/components/ui/button";
 import { Input } from "@/components/ui/input";
 import { Label } from "@/components/ui/label";
 import { useApp } from "@/contexts/AppContext";
+import { apiFetch, readError } from "@/lib/api";
 import { PortfolioPieChart } from "@/components/organisms/PortfolioPieChart";
 import { toast } from "sonner";
 
@@ -43,7 +44,7 @@ export function GoalDialog({ open, onOpenChange }: GoalDialogProps) {
       return;
     }
 
-    fetch("http://localhost:8080/goal")
+    apiFetch("/goal")
       .then((res) => {
         if (res.status === 404) return null;
         return res.json();
@@ -99,7 +100,7 @@ export function GoalDialog({ open, onOpenChange }: GoalDialogProps) {
       .filter((a) => a.percentage > 0);
 
     try {
-      const res = await fetch("http://localhost:8080/goal", {
+      const res = await apiFetch("/goal", {
         method: "POST",
         headers: { "Content-Type": "application/json" },
         body: JSON.stringify({
@@ -112,7 +113,7 @@ export function GoalDialog({ open, onOpenChange }: GoalDialogProps) {
         toast.success("Goal saved", { id: toastId });
         onOpenChange(false);
       } else {
-        const text = await res.text();
+        const text = await readError(res);
         toast.error("Failed to save goal", { id: toastId, description: text });
       }
     } catch {
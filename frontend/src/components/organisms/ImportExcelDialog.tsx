@@ -28,6 +28,7 @@ import {
   SelectValue,
 } from "@/components/ui/select";
 import { toast } from "sonner";
+import { apiFetch, readError } from "@/lib/api";
 
 const formSchema = z.object({
   symbol: z
@@ -82,11 +83,24 @@ export function ImportExcelDialog({
       formData.append("currency", values.currency);
       formData.append("file", file);
 
-      const res = await fetch("http://localhost:8080/transactions/import", {
+      const res = await apiFetch("/transactions/import", {
         method: "POST",
         body: formData,
       });
 
+      // A file where every row failed still returns the import result;
+      // any other failure is an error envelope
+      if (!res.ok) {
+        const body = await res.clone().json().catch(() => null);
+        if (!body || body.error) {
+          toast.error("Import failed", {
+            id: toastId,
+            description: await readError(res),
+          });
+          return;
+        }
+      }
+
       const result = await res.json();
 
       if (result.imported > 0 && result.failed === 0) {
@@ -100,8 +114,8 @@ export function ImportExcelDialog({
             id: toastId,
             description: result.errors
               ?.map(
-                (e: { row: number; message: string }) =>
-                  `Row ${e.row}: ${e.message}`,
+                (e: { row: number; field?: string; message: string }) =>
+                  `Row ${e.row}${e.field ? ` (${e.field})` : ""}: ${e.message}`,
               )
               .join("; "),
           },
@@ -111,8 +125,8 @@ export function ImportExcelDialog({
           id: toastId,
           description: result.errors
             ?.map(
-              (e: { row: number; message: string }) =>
-                `Row ${e.row}: ${e.message}`,
+              (e: { row: number; field?: string; message: string }) =>
+                `Row ${e.row}${e.field ? ` (${e.field})` : ""}: ${e.message}`,
             )
             .join("; "),
         });
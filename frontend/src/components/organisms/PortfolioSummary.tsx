@@ -1,7 +1,7 @@
 import { useMemo, useState } from "react";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { DollarSign } from "lucide-react";
-import { type Transaction } from "@/types";
+import { ASSET_TYPES, type Transaction } from "@/types";
 import { type StockPriceInfo } from "@/contexts/AppContext";
 import { formatCurrency } from "@/lib/format";
 import {
@@ -9,6 +9,13 @@ import {
   type PieSlice,
 } from "@/components/organisms/PortfolioPieChart";
 import { PieChartDialog } from "@/components/organisms/PieChartDialog";
+import {
+  Select,
+  SelectContent,
+  SelectItem,
+  SelectTrigger,
+  SelectValue,
+} from "@/components/ui/select";
 
 interface PortfolioSummaryProps {
   transactions: Transaction[];
@@ -20,6 +27,23 @@ interface PortfolioSummaryProps {
 
 const HIDDEN = "••••••";
 
+// Ticker fields the allocation chart can be grouped by
+const GROUPINGS = [
+  { value: "category", label: "Category" },
+  { value: "sector", label: "Sector" },
+  { value: "country", label: "Country" },
+  { value: "asset_type", label: "Asset Type" },
+] as const;
+
+type Grouping = (typeof GROUPINGS)[number]["value"];
+
+function groupLabel(sp: StockPriceInfo, grouping: Grouping): string {
+  if (grouping === "asset_type") {
+    return ASSET_TYPES.find((t) => t.value === sp.asset_type)?.label ?? "";
+  }
+  return sp[grouping] ?? "";
+}
+
 export function PortfolioSummary({
   transactions,
   stockPrices,
@@ -29,6 +53,9 @@ export function PortfolioSummary({
 }: PortfolioSummaryProps) {
   const [assetDialogOpen, setAssetDialogOpen] = useState(false);
   const [categoryDialogOpen, setCategoryDialogOpen] = useState(false);
+  const [grouping, setGrouping] = useState<Grouping>("category");
+  const groupingLabel =
+    GROUPINGS.find((g) => g.value === grouping)?.label ?? "Category";
 
   const {
     totalUSD,
@@ -38,10 +65,10 @@ export function PortfolioSummary({
     assetSlices,
     categorySlices,
   } = useMemo(() => {
-    // Build category lookup from stock prices
+    // Build the group lookup (category, sector...) from stock prices
     const categoryMap = new Map<string, string>();
     for (const sp of stockPrices) {
-      categoryMap.set(sp.symbol, sp.category || "Other");
+      categoryMap.set(sp.symbol, groupLabel(sp, grouping) || "Other");
     }
 
     const symbolMap = new Map<
@@ -110,7 +137,7 @@ export function PortfolioSummary({
 
       chartSlices.push({ name: symbol, value: absValue });
 
-      // Aggregate by category
+      // Aggregate by the chosen grouping
       const category = categoryMap.get(symbol) || "Other";
       catTotals.set(category, (catTotals.get(category) || 0) + absValue);
     }
@@ -129,7 +156,7 @@ export function PortfolioSummary({
       assetSlices: chartSlices,
       categorySlices: catSlices,
     };
-  }, [transactions, stockPrices, dollarRate]);
+  }, [transactions, stockPrices, dollarRate, grouping]);
 
   const totalInBRL = totalBRL + totalUSD * dollarRate;
   const totalPnlInBRL = totalPnlBRL + totalPnlUSD * dollarRate;
@@ -196,15 +223,30 @@ export function PortfolioSummary({
       </Card>
 
       <Card className="cursor-pointer hover:shadow-lg transition-shadow">
-        <CardHeader className="pb-2">
+        <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-2">
           <CardTitle className="text-sm font-medium">
             Portfolio Distribution
           </CardTitle>
+          <Select
+            value={grouping}
+            onValueChange={(v) => setGrouping(v as Grouping)}
+          >
+            <SelectTrigger className="h-7 w-[120px] text-xs">
+              <SelectValue />
+            </SelectTrigger>
+            <SelectContent>
+              {GROUPINGS.map((g) => (
+                <SelectItem key={g.value} value={g.value}>
+                  {g.label}
+                </SelectItem>
+              ))}
+            </SelectContent>
+          </Select>
         </CardHeader>
         <CardContent>
           <PortfolioPieChart
             data={categorySlices}
-            title="By Category"
+            title={`By ${groupingLabel}`}
             className="h-[200px]"
             onClick={() => setCategoryDialogOpen(true)}
           />
@@ -221,7 +263,7 @@ export function PortfolioSummary({
       <PieChartDialog
         open={categoryDialogOpen}
         onOpenChange={setCategoryDialogOpen}
-        title="Portfolio by Category"
+        title={`Portfolio by ${groupingLabel}`}
         data={categorySlices}
       />
     </div>
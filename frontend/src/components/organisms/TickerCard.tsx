@@ -2,10 +2,11 @@ import { useState } from "react";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { Badge } from "@/components/ui/badge";
 import { Button } from "@/components/ui/button";
-import { TrendingUp, TrendingDown, Pencil } from "lucide-react";
+import { TrendingUp, TrendingDown, Pencil, Clock } from "lucide-react";
 import { EditTickerDialog } from "@/components/organisms/EditTickerDialog";
 import { StockDetailDialog } from "@/components/organisms/StockDetailDialog";
 import { formatCurrency } from "@/lib/format";
+import { type TickerMetadata } from "@/types";
 
 export interface TickerData {
   symbol: string;
@@ -19,6 +20,9 @@ export interface TickerData {
   tags: string[];
   category: string;
   currency: string;
+  stale?: boolean; // the price is outdated
+  fetchedAt?: string | null;
+  metadata?: TickerMetadata;
   // Original currency values (for USD assets)
   originalCurrency?: string;
   avgBuyPriceOriginal?: number;
@@ -53,6 +57,12 @@ export function TickerCard({
     formatCurrency(val, ticker.originalCurrency || "USD");
   const isPositive = ticker.pnl >= 0;
   const hasOriginalCurrency = ticker.originalCurrency === "USD";
+  const staleTitle = ticker.fetchedAt
+    ? `Outdated price, fetched ${new Date(ticker.fetchedAt).toLocaleDateString(
+        "pt-BR",
+        { day: "2-digit", month: "2-digit", hour: "2-digit", minute: "2-digit" },
+      )}`
+    : "Price never fetched";
 
   // Scale opacity from 0.08 (0%) to 0.35 (>=20%) based on |pnlPercent|
   const intensity = Math.min(Math.abs(ticker.pnlPercent) / 20, 1);
@@ -71,9 +81,22 @@ export function TickerCard({
         <CardHeader className="flex flex-row items-center justify-between space-y-0 pb-1 px-3 pt-2">
           <div>
             <CardTitle
-              className={`${compact ? "text-sm" : "text-lg"} font-bold`}
+              className={`${compact ? "text-sm" : "text-lg"} font-bold flex items-center gap-1`}
+              title={ticker.metadata?.name || undefined}
             >
+              {ticker.metadata?.logo_url && !compact && (
+                <img
+                  src={ticker.metadata.logo_url}
+                  alt=""
+                  className="h-5 w-5 rounded-sm object-contain"
+                />
+              )}
               {ticker.symbol}
+              {ticker.stale && (
+                <span title={staleTitle} aria-label={staleTitle}>
+                  <Clock className="h-3 w-3 text-amber-500" />
+                </span>
+              )}
             </CardTitle>
             {ticker.category && !compact && (
               <span className="text-xs text-muted-foreground">
@@ -208,6 +231,7 @@ export function TickerCard({
         currentTags={ticker.tags}
         currentCategory={ticker.category}
         currentCurrency={ticker.currency}
+        currentMetadata={ticker.metadata}
         onSaved={onEdited}
       />
 
@@ -1,3 +1,4 @@
+import { useEffect, useState } from "react";
 import { useForm } from "react-hook-form";
 import { zodResolver } from "@hookform/resolvers/zod";
 import * as z from "zod";
@@ -19,6 +20,10 @@ import {
   SelectValue,
 } from "@/components/ui/select";
 import { Textarea } from "@/components/ui/textarea";
+import { apiFetch } from "@/lib/api";
+import { type SymbolMatch } from "@/types";
+
+const SEARCH_DEBOUNCE_MS = 300;
 
 // 1. Define the Validation Schema (Zod)
 export const transactionFormSchema = z.object({
@@ -67,6 +72,40 @@ export function TransactionFormFields({
     },
   });
 
+  // Suggest listings as the symbol is typed; picking one sets its currency
+  const [matches, setMatches] = useState<SymbolMatch[]>([]);
+  const symbol = form.watch("symbol");
+  useEffect(() => {
+    const q = symbol.trim();
+    if (q.length < 2) {
+      setMatches([]);
+      return;
+    }
+    const controller = new AbortController();
+    const timer = setTimeout(async () => {
+      try {
+        const res = await apiFetch(
+          `/symbols/search?q=${encodeURIComponent(q)}`,
+          { signal: controller.signal },
+        );
+        if (res.ok) setMatches(await res.json());
+      } catch {
+        // Aborted or offline: no suggestions
+      }
+    }, SEARCH_DEBOUNCE_MS);
+    return () => {
+      clearTimeout(timer);
+      controller.abort();
+    };
+  }, [symbol]);
+
+  useEffect(() => {
+    const match = matches.find((m) => m.symbol === symbol.toUpperCase());
+    if (match && (match.currency === "USD" || match.currency === "BRL")) {
+      form.setValue("currency", match.currency);
+    }
+  }, [matches, symbol, form]);
+
   return (
     <Form {...form}>
       <form onSubmit={form.handleSubmit(onSubmit)} className="space-y-4">
@@ -79,8 +118,15 @@ export function TransactionFormFields({
               <FormItem>
                 <FormLabel>Ticker Symbol</FormLabel>
                 <FormControl>
-                  <Input placeholder="AAPL" {...field} />
+                  <Input placeholder="AAPL" list="symbol-matches" {...field} />
                 </FormControl>
+                <datalist id="symbol-matches">
+                  {matches.map((m) => (
+                    <option key={m.symbol} value={m.symbol}>
+                      {[m.name, m.exchange].filter(Boolean).join(" · ")}
+                    </option>
+                  ))}
+                </datalist>
                 <FormMessage />
               </FormItem>
             )}
@@ -167,7 +213,7 @@ export function TransactionFormFields({
           render={({ field }) => (
             <FormItem>
               <FormLabel>Currency</FormLabel>
-              <Select onValueChange={field.onChange} defaultValue={field.value}>
+              <Select onValueChange={field.onChange} value={field.value}>
                 <FormControl>
                   <SelectTrigger>
                     <SelectValue placeholder="Select currency" />
@@ -1,5 +1,5 @@
 import { ReactNode } from "react";
-import { Link } from "react-router-dom"; // <--- Add this import
+import { Link, useNavigate } from "react-router-dom";
 import { Button } from "@/components/ui/button";
 import {
   RefreshCcw,
@@ -10,8 +10,12 @@ import {
   EyeOff,
   Target,
   List,
+  LogOut,
 } from "lucide-react";
 import { ModeToggle } from "@/components/ui/mode-toggle";
+import { PortfolioSelect } from "@/components/molecules/PortfolioSelect";
+import { apiFetch, setToken } from "@/lib/api";
+import { type RefreshProgress } from "@/lib/events";
 
 interface DashboardLayoutProps {
   children: ReactNode;
@@ -19,6 +23,7 @@ interface DashboardLayoutProps {
   isLoading: boolean;
   onSyncPrices?: () => void;
   isSyncing?: boolean;
+  syncProgress?: RefreshProgress | null;
   onImportExcel?: () => void;
   onGoal?: () => void;
   onAllTransactions?: () => void;
@@ -32,12 +37,22 @@ export function DashboardLayout({
   isLoading,
   onSyncPrices,
   isSyncing,
+  syncProgress,
   onImportExcel,
   onGoal,
   onAllTransactions,
   hideValues,
   onToggleHideValues,
 }: DashboardLayoutProps) {
+  const navigate = useNavigate();
+
+  async function logout() {
+    // Revoke the session on the server; the local token is dropped either way
+    await apiFetch("/auth/logout", { method: "POST" }).catch(() => {});
+    setToken(null);
+    navigate("/login", { replace: true });
+  }
+
   return (
     <div className="min-h-screen bg-muted/40 p-8 font-sans">
       <div className="max-w-7xl mx-auto space-y-6">
@@ -54,6 +69,8 @@ export function DashboardLayout({
           </div>
 
           <div className="flex gap-2">
+            <PortfolioSelect onChange={onRefresh} />
+
             {/* SYNC PRICES BUTTON */}
             <Button
               onClick={onSyncPrices}
@@ -63,7 +80,15 @@ export function DashboardLayout({
               <CloudDownload
                 className={`mr-2 h-4 w-4 ${isSyncing ? "animate-bounce" : ""}`}
               />
-              {isSyncing ? "Syncing..." : "Update Prices"}
+              {isSyncing
+                ? syncProgress
+                  ? `Syncing ${syncProgress.done}/${syncProgress.total}${
+                      syncProgress.failed > 0
+                        ? ` (${syncProgress.failed} failed)`
+                        : ""
+                    }...`
+                  : "Syncing..."
+                : "Update Prices"}
             </Button>
 
             {/* REFRESH LIST BUTTON */}
@@ -117,6 +142,10 @@ export function DashboardLayout({
                 )}
               </Button>
             )}
+
+            <Button variant="ghost" size="icon" onClick={logout} title="Log out">
+              <LogOut className="h-4 w-4" />
+            </Button>
           </div>
         </header>
 
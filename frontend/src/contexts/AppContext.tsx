@@ -3,13 +3,20 @@ import {
   useCallback,
   useContext,
   useEffect,
+  useRef,
   useState,
   type ReactNode,
 } from "react";
-import { type Transaction } from "@/types";
+import { type TickerMetadata, type Transaction } from "@/types";
 import { toast } from "sonner";
+import { apiFetch, getPortfolio, readError } from "@/lib/api";
+import {
+  streamEvents,
+  type ApiEvent,
+  type RefreshProgress,
+} from "@/lib/events";
 
-export interface StockPriceInfo {
+export interface StockPriceInfo extends TickerMetadata {
   symbol: string;
   price: number;
   day_change_percent: number;
@@ -17,6 +24,9 @@ export interface StockPriceInfo {
   category: string;
   currency: string;
   updated_at: string;
+  source: string;
+  fetched_at: string | null;
+  stale: boolean; // older than the quote cache allows
 }
 
 export interface CurrencyInfo {
@@ -30,6 +40,7 @@ interface AppContextValue {
   stockPrices: StockPriceInfo[];
   loading: boolean;
   syncing: boolean;
+  refreshProgress: RefreshProgress | null;
   dollarRate: number;
   dollarRateUpdatedAt: string | null;
   hideValues: boolean;
@@ -38,6 +49,17 @@ interface AppContextValue {
   syncPrices: () => Promise<void>;
 }
 
+// A background job, as returned by POST /prices/refresh and GET /jobs/{id}
+interface Job {
+  id: number;
+  status: "running" | "completed" | "failed" | "cancelled";
+  updated: number;
+  failed: number;
+  error?: string;
+}
+
+const JOB_POLL_MS = 1000;
+
 const AppContext = createContext<AppContextValue | null>(null);
 
 export function AppProvider({ children }: { children: ReactNode }) {
@@ -45,6 +67,12 @@ export function AppProvider({ children }: { children: ReactNode }) {
   const [stockPrices, setStockPrices] = useState<StockPriceInfo[]>([]);
   const [loading, setLoading] = useState(true);
   const [syncing, setSyncing] = useState(false);
+  const [refreshProgress, setRefreshProgress] =
+    useState<RefreshProgress | null>(null);
+  // The refresh job started by syncPrices, whose progress is shown
+  const syncJob = useRef<number | null>(null);
+  // The portfolio the event stream follows; it reconnects when this changes
+  const [streamPortfolio, setStreamPortfolio] = useState(getPortfolio());
   const [dollarRate, setDollarRate] = useState(5.5);
   const [dollarRateUpdatedAt, setDollarRateUpdatedAt] = useState<string | null>(
     null,
@@ -53,10 +81,11 @@ export function AppProvider({ children }: { children: ReactNode }) {
 
   const refreshData = useCallback(() => {
     setLoading(true);
+    setStreamPortfolio(getPortfolio());
     Promise.all([
-      fetch("http://localhost:8080/transactions").then((r) => r.json()),
-      fetch("http://localhost:8080/prices").then((r) => r.json()),
-      fetch("http://localhost:8080/currencies/usd").then((r) => r.json()),
+      apiFetch("/transactions").then((r) => r.json()),
+      apiFetch("/prices").then((r) => r.json()),
+      apiFetch("/currencies/rate?from=USD&to=BRL").then((r) => r.json()),
     ])
       .then(([txData, priceData, currencyData]) => {
         setTransactions(txData || []);
@@ -77,20 +106,39 @@ export function AppProvider({ children }: { children: ReactNode }) {
     });
 
     try {
-      const res = await fetch("http://localhost:8080/prices/refresh", {
+      const res = await apiFetch("/prices/refresh", {
         method: "POST",
       });
 
       if (res.ok) {
+        // The refresh runs as a job on the server; wait for it to finish
+        let job: Job = await res.json();
+        syncJob.current = job.id;
+        while (job.status === "running") {
+          await new Promise((resolve) => setTimeout(resolve, JOB_POLL_MS));
+          const poll = await apiFetch(`/jobs/${job.id}`);
+          if (!poll.ok) throw new Error(await readError(poll));
+          job = await poll.json();
+        }
         refreshData();
-        toast.success("Prices Updated", {
-          id: toastId,
-          description: "Your portfolio values are now up to date.",
-        });
+        if (job.status === "completed") {
+          toast.success("Prices Updated", {
+            id: toastId,
+            description:
+              job.failed > 0
+                ? `${job.updated} updated, ${job.failed} failed.`
+                : "Your portfolio values are now up to date.",
+          });
+        } else {
+          toast.error("Update Failed", {
+            id: toastId,
+            description: job.error || `The refresh was ${job.status}.`,
+          });
+        }
       } else {
         toast.error("Update Failed", {
           id: toastId,
-          description: "Could not update prices. Try again later.",
+          description: await readError(res),
         });
       }
     } catch {
@@ -99,10 +147,64 @@ export function AppProvider({ children }: { children: ReactNode }) {
         description: "Failed to reach the server.",
       });
     } finally {
+      syncJob.current = null;
+      setRefreshProgress(null);
       setSyncing(false);
     }
   }, [refreshData]);
 
+  // Keep the data current as events arrive, without a full reload
+  const handleEvent = useCallback((event: ApiEvent) => {
+    switch (event.type) {
+      case "ticker.updated": {
+        const ticker = event.data as StockPriceInfo;
+        setStockPrices((prices) =>
+          prices.some((p) => p.symbol === ticker.symbol)
+            ? prices.map((p) => (p.symbol === ticker.symbol ? ticker : p))
+            : [...prices, ticker],
+        );
+        break;
+      }
+      case "prices.refresh_progress": {
+        const progress = event.data as RefreshProgress;
+        if (progress.job_id !== syncJob.current) break;
+        setRefreshProgress(progress.done < progress.total ? progress : null);
+        break;
+      }
+      case "prices.refreshed":
+        setRefreshProgress(null);
+        apiFetch("/currencies/rate?from=USD&to=BRL")
+          .then((r) => r.json())
+          .then((currencyData) => {
+            if (currencyData && currencyData.rate) {
+              setDollarRate(currencyData.rate);
+              setDollarRateUpdatedAt(currencyData.updated_at || null);
+            }
+          })
+          .catch((err) => console.error("Error fetching rate:", err));
+        break;
+      case "transaction.created":
+      case "transaction.updated":
+      case "transaction.deleted":
+        apiFetch("/transactions")
+          .then((r) => r.json())
+          .then((txData) => setTransactions(txData || []))
+          .catch((err) => console.error("Error fetching:", err));
+        break;
+      case "alert.triggered": {
+        const alert = event.data as { message: string };
+        toast.warning("Alert triggered", { description: alert.message });
+        break;
+      }
+    }
+  }, []);
+
+  useEffect(() => {
+    const controller = new AbortController();
+    streamEvents(handleEvent, controller.signal);
+    return () => controller.abort();
+  }, [handleEvent, streamPortfolio]);
+
   const toggleHideValues = useCallback(() => {
     setHideValues((v) => !v);
   }, []);
@@ -119,6 +221,7 @@ export function AppProvider({ children }: { children: ReactNode }) {
         stockPrices,
         loading,
         syncing,
+        refreshProgress,
         dollarRate,
         dollarRateUpdatedAt,
         hideValues,
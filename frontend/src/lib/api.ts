@@ -0,0 +1,66 @@
+// Error envelope returned by the API for every non-2xx response
+export interface ApiError {
+  code: string;
+  message: string;
+  fields?: { field: string; message: string }[];
+  request_id?: string;
+}
+
+// readError turns a failed response into a human readable message
+export async function readError(res: Response): Promise<string> {
+  const text = await res.text();
+  try {
+    const { error } = JSON.parse(text) as { error: ApiError };
+    if (!error?.message) return text;
+    if (!error.fields?.length) return error.message;
+    return error.fields.map((f) => `${f.field} ${f.message}`).join("; ");
+  } catch {
+    return text;
+  }
+}
+
+export const API_URL = "http://localhost:8080";
+
+const TOKEN_KEY = "gemini-stocks-token";
+
+export function getToken(): string | null {
+  return localStorage.getItem(TOKEN_KEY);
+}
+
+export function setToken(token: string | null) {
+  if (token) localStorage.setItem(TOKEN_KEY, token);
+  else localStorage.removeItem(TOKEN_KEY);
+}
+
+const PORTFOLIO_KEY = "gemini-stocks-portfolio";
+
+// getPortfolio returns the selected portfolio; null means the server default
+export function getPortfolio(): string | null {
+  return localStorage.getItem(PORTFOLIO_KEY);
+}
+
+export function setPortfolio(id: string | null) {
+  if (id) localStorage.setItem(PORTFOLIO_KEY, id);
+  else localStorage.removeItem(PORTFOLIO_KEY);
+}
+
+// apiFetch calls the API with the session token and the selected portfolio. A 401 means the session is
+// gone (expired or logged out elsewhere), so it sends the user to the login page.
+export async function apiFetch(
+  path: string,
+  init: RequestInit = {},
+): Promise<Response> {
+  const headers = new Headers(init.headers);
+  const token = getToken();
+  if (token) headers.set("Authorization", `Bearer ${token}`);
+  const portfolio = getPortfolio();
+  if (portfolio) headers.set("X-Portfolio-ID", portfolio);
+
+  const res = await fetch(`${API_URL}${path}`, { ...init, headers });
+  if (res.status === 401 && !path.startsWith("/auth/")) {
+    setToken(null);
+    setPortfolio(null);
+    window.location.assign("/login");
+  }
+  return res;
+}
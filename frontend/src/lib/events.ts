@@ -0,0 +1,103 @@
+import { apiFetch } from "@/lib/api";
+
+// An event of the portfolio, as sent by GET /events
+export interface ApiEvent<T = unknown> {
+  id: string;
+  type: string;
+  portfolio_id: number; // 0 for market data shared by every portfolio
+  created_at: string;
+  data: T;
+}
+
+// Outcome of one ticker of a price refresh job
+export type JobItemStatus =
+  | "pending"
+  | "updated"
+  | "cached"
+  | "rate_limited"
+  | "not_found"
+  | "error"
+  | "skipped";
+
+// Data of prices.refresh_progress, sent after every ticker of a price refresh.
+// Only the portfolio that started the job receives it.
+export interface RefreshProgress {
+  job_id: number;
+  symbol: string;
+  status: JobItemStatus;
+  done: number;
+  total: number;
+  updated: number;
+  failed: number;
+}
+
+const RETRY_MS = 3000;
+
+// streamEvents calls onEvent with every event of the selected portfolio until signal
+// aborts. It reads the stream with fetch rather than EventSource so the token travels
+// in the Authorization header, and reconnects after errors, replaying what was missed.
+export async function streamEvents(
+  onEvent: (event: ApiEvent) => void,
+  signal: AbortSignal,
+): Promise<void> {
+  let lastEventId = "";
+  while (!signal.aborted) {
+    try {
+      const headers: Record<string, string> = { Accept: "text/event-stream" };
+      if (lastEventId) headers["Last-Event-ID"] = lastEventId;
+      const res = await apiFetch("/events", { headers, signal });
+      if (res.ok && res.body) {
+        await readStream(res.body, signal, (event) => {
+          lastEventId = event.id;
+          onEvent(event);
+        });
+      }
+    } catch (err) {
+      if (signal.aborted) return;
+      console.error("Event stream error:", err);
+    }
+    await sleep(RETRY_MS, signal);
+  }
+}
+
+// readStream parses text/event-stream messages; only their data lines are needed,
+// since the event JSON carries its own ID and type
+async function readStream(
+  body: ReadableStream<Uint8Array>,
+  signal: AbortSignal,
+  onEvent: (event: ApiEvent) => void,
+) {
+  const reader = body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = "";
+  let data: string[] = [];
+  while (!signal.aborted) {
+    const { value, done } = await reader.read();
+    if (done) return;
+    buffer += value;
+    let newline: number;
+    while ((newline = buffer.indexOf("\n")) >= 0) {
+      const line = buffer.slice(0, newline).replace(/\r$/, "");
+      buffer = buffer.slice(newline + 1);
+      if (line === "") {
+        if (data.length) onEvent(JSON.parse(data.join("\n")) as ApiEvent);
+        data = [];
+      } else if (line.startsWith("data:")) {
+        data.push(line.slice(5).replace(/^ /, ""));
+      }
+    }
+  }
+}
+
+function sleep(ms: number, signal: AbortSignal) {
+  return new Promise<void>((resolve) => {
+    const timer = setTimeout(resolve, ms);
+    signal.addEventListener(
+      "abort",
+      () => {
+        clearTimeout(timer);
+        resolve();
+      },
+      { once: true },
+    );
+  });
+}
@@ -6,6 +6,7 @@ import {
 } from "@/components/organisms/TransactionForm";
 import { Card, CardContent, CardHeader, CardTitle } from "@/components/ui/card";
 import { useApp } from "@/contexts/AppContext";
+import { apiFetch, readError } from "@/lib/api";
 
 export default function AddTransactionPage() {
   const navigate = useNavigate();
@@ -17,21 +18,35 @@ export default function AddTransactionPage() {
       date: new Date(values.date).toISOString(),
     };
 
-    fetch("http://localhost:8080/transactions", {
-      method: "POST",
-      headers: { "Content-Type": "application/json" },
-      body: JSON.stringify(payload),
-    })
-      .then((res) => {
-        if (res.ok) {
-          refreshData();
-          navigate("/");
-        } else {
-          console.error("Failed to save");
-          alert("Error saving transaction");
-        }
-      })
-      .catch((error) => console.error(error));
+    save(payload, false).catch((error) => console.error(error));
+  }
+
+  // save posts the transaction. When the symbol is not a known listing the
+  // user may confirm it as unlisted, which skips the provider check.
+  async function save(payload: TransactionFormValues, unlisted: boolean) {
+    const res = await apiFetch(
+      unlisted ? "/transactions?unlisted=true" : "/transactions",
+      {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify(payload),
+      },
+    );
+    if (res.ok) {
+      refreshData();
+      navigate("/");
+      return;
+    }
+    const message = await readError(res);
+    if (
+      res.status === 422 &&
+      !unlisted &&
+      message.startsWith("symbol ") &&
+      confirm(`${message}\n\nSave it as an unlisted asset anyway?`)
+    ) {
+      return save(payload, true);
+    }
+    alert(`Error saving transaction: ${message}`);
   }
 
   return (
@@ -6,8 +6,9 @@ import { ImportExcelDialog } from "@/components/organisms/ImportExcelDialog";
 import { GoalDialog } from "@/components/organisms/GoalDialog";
 import { AllTransactionsDialog } from "@/components/organisms/AllTransactionsDialog";
 import { TickerCard, type TickerData } from "@/components/organisms/TickerCard";
-import { useApp } from "@/contexts/AppContext";
+import { useApp, type StockPriceInfo } from "@/contexts/AppContext";
 import { toast } from "sonner";
+import { apiFetch } from "@/lib/api";
 
 export default function DashboardPage() {
   const {
@@ -15,6 +16,7 @@ export default function DashboardPage() {
     stockPrices,
     loading,
     syncing,
+    refreshProgress,
     dollarRate,
     dollarRateUpdatedAt,
     hideValues,
@@ -33,7 +35,9 @@ export default function DashboardPage() {
     const tagsMap = new Map<string, string[]>();
     const categoryMap = new Map<string, string>();
     const currencyMap = new Map<string, string>();
+    const quoteMap = new Map<string, StockPriceInfo>();
     for (const sp of stockPrices) {
+      quoteMap.set(sp.symbol, sp);
       const tags = sp.tags
         ? sp.tags.split(",").filter((t) => t.trim() !== "")
         : [];
@@ -107,6 +111,9 @@ export default function DashboardPage() {
         tags: tagsMap.get(symbol) ?? [],
         category: categoryMap.get(symbol) ?? "",
         currency: "BRL",
+        stale: quoteMap.get(symbol)?.stale ?? false,
+        fetchedAt: quoteMap.get(symbol)?.fetched_at ?? null,
+        metadata: quoteMap.get(symbol),
       };
 
       if (currency === "USD") {
@@ -144,7 +151,7 @@ export default function DashboardPage() {
     const toastId = toast.loading("Deleting transaction...");
 
     try {
-      const res = await fetch(`http://localhost:8080/transactions/${id}`, {
+      const res = await apiFetch(`/transactions/${id}`, {
         method: "DELETE",
       });
 
@@ -165,7 +172,8 @@ export default function DashboardPage() {
       onRefresh={refreshData}
       isLoading={loading}
       onSyncPrices={syncPrices}
-      isSyncing={syncing}
+      isSyncing={syncing || refreshProgress !== null}
+      syncProgress={refreshProgress}
       onImportExcel={() => setImportDialogOpen(true)}
       onGoal={() => setGoalDialogOpen(true)}
       onAllTransactions={() => setAllTransactionsDialogOpen(true)}
@@ -237,8 +245,6 @@ export default function DashboardPage() {
       <AllTransactionsDialog
         open={allTransactionsDialogOpen}
         onOpenChange={setAllTransactionsDialogOpen}
-        transactions={transactions}
-        isLoading={loading}
         onDelete={handleDelete}
         onEdited={refreshData}
       />
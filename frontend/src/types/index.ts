@@ -17,3 +17,35 @@ export interface Transaction {
   pnl?: number; // Profit/Loss ($)
   pnl_percent?: number; // Profit/Loss (%)
 }
+
+// A listing found by GET /symbols/search
+export interface SymbolMatch {
+  symbol: string;
+  name: string;
+  exchange: string;
+  currency: string;
+  asset_type: string;
+  region: string;
+  match_score: number;
+}
+
+// Descriptive fields of a ticker, from the provider or edited by hand
+export interface TickerMetadata {
+  name: string;
+  exchange: string;
+  country: string;
+  sector: string;
+  industry: string;
+  asset_type: string;
+  isin: string;
+  logo_url: string;
+}
+
+export const ASSET_TYPES: { value: string; label: string }[] = [
+  { value: "stock", label: "Stock" },
+  { value: "fii", label: "FII" },
+  { value: "etf", label: "ETF" },
+  { value: "bdr", label: "BDR" },
+  { value: "crypto", label: "Crypto" },
+  { value: "bond", label: "Bond" },
+];